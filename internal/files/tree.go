@@ -0,0 +1,255 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package files
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+)
+
+// Tree is a set of files rendered in memory, keyed by their slash separated path relative to the directory the tree
+// is written to.
+type Tree map[string][]byte
+
+// Template applies a config to a Template and adds the result to the tree as name.
+func (tree Tree) Template(name string, tpl *template.Template, conf interface{}) error {
+	var bs bytes.Buffer
+	err := tpl.Execute(
+		&bs,
+		conf,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to execute template because %v",
+			err,
+		)
+	}
+	tree[name] = bs.Bytes()
+	return nil
+}
+
+// JSON encodes an object to json and adds the result to the tree as name.
+func (tree Tree) JSON(name string, conf interface{}) error {
+	return tree.encode(
+		EncodeJSON,
+		name,
+		conf,
+	)
+}
+
+// YAML encodes an object to yaml and adds the result to the tree as name.
+func (tree Tree) YAML(name string, conf interface{}) error {
+	return tree.encode(
+		EncodeYAML,
+		name,
+		conf,
+	)
+}
+
+func (tree Tree) encode(enc encoder, name string, conf interface{}) error {
+	var bs bytes.Buffer
+	err := enc(
+		&bs,
+		conf,
+	)
+	if err != nil {
+		return err
+	}
+	tree[name] = bs.Bytes()
+	return nil
+}
+
+// Merge adds every file of another tree to this one under the directory dir.
+func (tree Tree) Merge(dir string, other Tree) {
+	for name, contents := range other {
+		tree[filepath.ToSlash(
+			filepath.Join(
+				dir,
+				name,
+			),
+		)] = contents
+	}
+}
+
+/*
+Write writes every file of the tree under basepath, creating the directories they are in. The files named in private
+are only readable and writable by their owner, any other file is created with the default permissions.
+*/
+func (tree Tree) Write(basepath string, private ...string) error {
+	for _, name := range slices.Sorted(maps.Keys(tree)) {
+		path := filepath.Join(
+			basepath,
+			filepath.FromSlash(name),
+		)
+		err := os.MkdirAll(
+			filepath.Dir(path),
+			0777,
+		)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0666)
+		if slices.Contains(
+			private,
+			name,
+		) {
+			mode = 0600
+		}
+		err = os.WriteFile(
+			path,
+			tree[name],
+			mode,
+		)
+		if err == nil && mode == 0600 {
+			// The mode is only applied to new files, an existing file may have been readable by others.
+			err = os.Chmod(
+				path,
+				mode,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf(
+				"failed to write %s because %v",
+				path,
+				err,
+			)
+		}
+		log.Printf(
+			"wrote %d bytes to %s\n",
+			len(tree[name]),
+			path,
+		)
+	}
+	return nil
+}
+
+/*
+Replace replaces the files of the tree under basepath as one change. Every file is written next to the one it replaces
+first, and only once all of them were written are they renamed over the existing files, so a file that fails to write
+leaves every file as it was. The files keep the permissions of the files they replace, the files named in private are
+only readable and writable by their owner.
+*/
+func (tree Tree) Replace(basepath string, private ...string) error {
+	names := slices.Sorted(maps.Keys(tree))
+	staged := make(map[string]string)
+	defer func() {
+		for _, temp := range staged {
+			_ = os.Remove(temp)
+		}
+	}()
+	for _, name := range names {
+		path := filepath.Join(
+			basepath,
+			filepath.FromSlash(name),
+		)
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if slices.Contains(
+			private,
+			name,
+		) {
+			mode = 0600
+		}
+		temp, err := stage(
+			path,
+			tree[name],
+			mode,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to write %s because %v",
+				path,
+				err,
+			)
+		}
+		staged[name] = temp
+	}
+	for _, name := range names {
+		path := filepath.Join(
+			basepath,
+			filepath.FromSlash(name),
+		)
+		err := os.Rename(
+			staged[name],
+			path,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to replace %s because %v",
+				path,
+				err,
+			)
+		}
+		delete(
+			staged,
+			name,
+		)
+		log.Printf(
+			"wrote %d bytes to %s\n",
+			len(tree[name]),
+			path,
+		)
+	}
+	return nil
+}
+
+// stage writes the contents to a new file in the directory of path, returning the new file's path.
+func stage(path string, contents []byte, mode os.FileMode) (temp string, err error) {
+	err = os.MkdirAll(
+		filepath.Dir(path),
+		0777,
+	)
+	if err != nil {
+		return temp, err
+	}
+	file, err := os.CreateTemp(
+		filepath.Dir(path),
+		"."+filepath.Base(path)+".*",
+	)
+	if err != nil {
+		return temp, err
+	}
+	temp = file.Name()
+	_, err = file.Write(contents)
+	if err == nil {
+		err = file.Chmod(mode)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(temp)
+		return "", err
+	}
+	return temp, nil
+}
@@ -33,6 +33,13 @@ var (
 	RuntimeTimestampShort string
 )
 
+// SetRuntime sets the runtime of the program along with its derived timestamps.
+func SetRuntime(runtime time.Time) {
+	Runtime = runtime
+	RuntimeTimestamp = Runtime.Format(time.RFC3339Nano)
+	RuntimeTimestampShort = Runtime.Format("20060102150405")
+}
+
 // StringInSlice returns whether a string exists in a given slice.
 func StringInSlice(
 	a string, list []string,
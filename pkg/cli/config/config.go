@@ -27,10 +27,14 @@ package config
 import (
 	"log"
 
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/diff"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/export"
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize"
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize/sls"
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/shcd"
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/template"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/validate"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/verify"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -48,17 +52,26 @@ func NewCommand() *cobra.Command {
 	}
 
 	c.AddCommand(
+		initialize.NewAddCabinetCommand(),
+		initialize.NewAddNCNCommand(),
+		diff.NewCommand(),
 		dumpCommand(),
+		export.NewCommand(),
 		initialize.NewCommand(),
 		shcd.NewCommand(),
 		sls.NewCommand(),
 		template.NewCommand(),
+		initialize.NewUpgradeCommand(),
+		validate.NewCommand(),
+		verify.NewCommand(),
 	)
 	return c
 }
 
+// yamlStringSettings returns the settings of v as YAML, with the initialize.SecretKeys redacted.
 func yamlStringSettings(v *viper.Viper) string {
 	c := v.AllSettings()
+	initialize.RedactSecrets(c)
 	bs, err := yaml.Marshal(c)
 	if err != nil {
 		log.Fatalf(
@@ -0,0 +1,110 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewCommand represents the diff command.
+func NewCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "diff DIR_A DIR_B",
+		Short: "Shows what changed between two generated system directories",
+		Long: `Shows what changed between two system directories generated by 'csi config init'.
+
+	Rather than a line diff, the generated files are compared by what they describe:
+	1. Networks and subnets that were added or removed, and their CIDR, VLAN, gateway, and DHCP range changes
+	2. IP reservations that were added, removed, re-addressed, or moved from one host to another
+	3. SLS hardware that was added, removed, or changed, by xname
+	4. Changes to the cloud-init data (basecamp/data.json) and customizations.yaml values
+	`,
+		Args:              cobra.ExactArgs(2),
+		DisableAutoGenTag: true,
+		Run: func(c *cobra.Command, args []string) {
+			v := viper.GetViper()
+			err := v.BindPFlags(c.Flags())
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			report, err := Compare(
+				args[0],
+				args[1],
+			)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			switch output := v.GetString("output"); output {
+			case "text":
+				if report.Empty() {
+					fmt.Println("No differences.")
+				}
+				err = report.WriteText(os.Stdout)
+				if err != nil {
+					log.Fatalln(err)
+				}
+			case "json":
+				b, err := json.MarshalIndent(
+					report,
+					"",
+					"  ",
+				)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				fmt.Println(string(b))
+			default:
+				log.Fatalf(
+					"unsupported output format %q, must be text or json",
+					output,
+				)
+			}
+
+			if v.GetBool("exit-code") && !report.Empty() {
+				os.Exit(1)
+			}
+		},
+	}
+	c.Flags().StringP(
+		"output",
+		"o",
+		"text",
+		"output format text,json",
+	)
+	c.Flags().Bool(
+		"exit-code",
+		false,
+		"Exit with status 1 when there are differences",
+	)
+	return c
+}
@@ -0,0 +1,783 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+)
+
+const (
+	// SLSFile is the SLS input file of a generated system directory.
+	SLSFile = "sls_input_file.json"
+
+	// CloudInitFile is the basecamp cloud-init data of a generated system directory.
+	CloudInitFile = "basecamp/data.json"
+
+	// CustomizationsFile is the customizations of a generated system directory.
+	CustomizationsFile = "customizations.yaml"
+)
+
+// Kinds of changes.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+	Moved   = "moved"
+)
+
+// Change is a single difference between two generated system directories.
+type Change struct {
+	Kind string      `json:"kind"`
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Report is every difference between two generated system directories, grouped by what changed.
+type Report struct {
+	Networks       []Change `json:"networks"`
+	Subnets        []Change `json:"subnets"`
+	Reservations   []Change `json:"reservations"`
+	Hardware       []Change `json:"hardware"`
+	CloudInit      []Change `json:"cloud-init"`
+	Customizations []Change `json:"customizations"`
+}
+
+// system is the parts of a generated system directory that are compared.
+type system struct {
+	sls            slsCommon.SLSState
+	cloudInit      map[string]interface{}
+	customizations map[string]interface{}
+}
+
+// Empty returns whether the report has no changes.
+func (report Report) Empty() bool {
+	for _, section := range report.sections() {
+		if len(section.changes) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteText writes the report in a human-readable form.
+func (report Report) WriteText(w io.Writer) (err error) {
+	symbols := map[string]string{
+		Added:   "+",
+		Removed: "-",
+		Changed: "~",
+		Moved:   ">",
+	}
+	for _, section := range report.sections() {
+		if len(section.changes) == 0 {
+			continue
+		}
+		_, err = fmt.Fprintf(
+			w,
+			"%s:\n",
+			section.name,
+		)
+		if err != nil {
+			return err
+		}
+		for _, change := range section.changes {
+			switch change.Kind {
+			case Added:
+				_, err = fmt.Fprintf(
+					w,
+					"  %s %s: %v\n",
+					symbols[change.Kind],
+					change.Path,
+					formatValue(change.New),
+				)
+			case Removed:
+				_, err = fmt.Fprintf(
+					w,
+					"  %s %s: %v\n",
+					symbols[change.Kind],
+					change.Path,
+					formatValue(change.Old),
+				)
+			default:
+				_, err = fmt.Fprintf(
+					w,
+					"  %s %s: %v -> %v\n",
+					symbols[change.Kind],
+					change.Path,
+					formatValue(change.Old),
+					formatValue(change.New),
+				)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type section struct {
+	name    string
+	changes []Change
+}
+
+func (report Report) sections() []section {
+	return []section{
+		{
+			"networks",
+			report.Networks,
+		},
+		{
+			"subnets",
+			report.Subnets,
+		},
+		{
+			"reservations",
+			report.Reservations,
+		},
+		{
+			"hardware",
+			report.Hardware,
+		},
+		{
+			"cloud-init",
+			report.CloudInit,
+		},
+		{
+			"customizations",
+			report.Customizations,
+		},
+	}
+}
+
+// Compare reports the differences between two generated system directories, going from a to b.
+func Compare(a string, b string) (report Report, err error) {
+	systemA, err := loadSystem(a)
+	if err != nil {
+		return report, err
+	}
+	systemB, err := loadSystem(b)
+	if err != nil {
+		return report, err
+	}
+
+	err = compareNetworks(
+		&report,
+		systemA.sls.Networks,
+		systemB.sls.Networks,
+	)
+	if err != nil {
+		return report, err
+	}
+	report.Hardware = compareHardware(
+		systemA.sls.Hardware,
+		systemB.sls.Hardware,
+	)
+	report.CloudInit = compareValues(
+		"",
+		systemA.cloudInit,
+		systemB.cloudInit,
+	)
+	report.Customizations = compareValues(
+		"",
+		systemA.customizations,
+		systemB.customizations,
+	)
+
+	// Always list every section, even when it has no changes.
+	for _, changes := range []*[]Change{
+		&report.Networks,
+		&report.Subnets,
+		&report.Reservations,
+		&report.Hardware,
+		&report.CloudInit,
+		&report.Customizations,
+	} {
+		if *changes == nil {
+			*changes = []Change{}
+		}
+	}
+	return report, nil
+}
+
+// loadSystem reads the comparable files of a generated system directory. The cloud-init data and customizations are
+// optional, a directory without them compares as if they were empty.
+func loadSystem(path string) (s system, err error) {
+	err = files.ReadJSONConfig(
+		filepath.Join(
+			path,
+			SLSFile,
+		),
+		&s.sls,
+	)
+	if err != nil {
+		return s, fmt.Errorf(
+			"failed to read %s from %s because %v",
+			SLSFile,
+			path,
+			err,
+		)
+	}
+	err = files.ReadJSONConfig(
+		filepath.Join(
+			path,
+			CloudInitFile,
+		),
+		&s.cloudInit,
+	)
+	if err != nil && !errors.Is(
+		err,
+		fs.ErrNotExist,
+	) {
+		return s, fmt.Errorf(
+			"failed to read %s from %s because %v",
+			CloudInitFile,
+			path,
+			err,
+		)
+	}
+	err = files.ReadYAMLConfig(
+		filepath.Join(
+			path,
+			CustomizationsFile,
+		),
+		&s.customizations,
+	)
+	if err != nil && !errors.Is(
+		err,
+		fs.ErrNotExist,
+	) {
+		return s, fmt.Errorf(
+			"failed to read %s from %s because %v",
+			CustomizationsFile,
+			path,
+			err,
+		)
+	}
+	return s, nil
+}
+
+// compareNetworks adds the network, subnet, and reservation changes between two sets of SLS networks to the report.
+func compareNetworks(report *Report, a map[string]slsCommon.Network, b map[string]slsCommon.Network) error {
+	for _, name := range sortedKeys(
+		a,
+		b,
+	) {
+		networkA, inA := a[name]
+		networkB, inB := b[name]
+		if !inB {
+			report.Networks = append(
+				report.Networks,
+				Change{
+					Kind: Removed,
+					Path: name,
+					Old:  networkA.FullName,
+				},
+			)
+			continue
+		}
+		if !inA {
+			report.Networks = append(
+				report.Networks,
+				Change{
+					Kind: Added,
+					Path: name,
+					New:  networkB.FullName,
+				},
+			)
+			continue
+		}
+		propertiesA, err := sls.UnmarshalNetworkExtraProperties(&networkA)
+		if err != nil {
+			return err
+		}
+		propertiesB, err := sls.UnmarshalNetworkExtraProperties(&networkB)
+		if err != nil {
+			return err
+		}
+		report.Networks = append(
+			report.Networks,
+			compareFields(
+				name,
+				[]field{
+					{
+						"cidr",
+						propertiesA.CIDR,
+						propertiesB.CIDR,
+					},
+					{
+						"cidr6",
+						propertiesA.CIDR6,
+						propertiesB.CIDR6,
+					},
+					{
+						"vlan-range",
+						propertiesA.VlanRange,
+						propertiesB.VlanRange,
+					},
+					{
+						"mtu",
+						propertiesA.MTU,
+						propertiesB.MTU,
+					},
+				},
+			)...,
+		)
+		compareSubnets(
+			report,
+			name,
+			propertiesA.Subnets,
+			propertiesB.Subnets,
+		)
+	}
+	return nil
+}
+
+// compareSubnets adds the subnet and reservation changes between two lists of subnets in the same network to the report.
+func compareSubnets(report *Report, network string, a []slsCommon.IPSubnet, b []slsCommon.IPSubnet) {
+	subnetsA := make(map[string]slsCommon.IPSubnet)
+	for _, subnet := range a {
+		subnetsA[subnet.Name] = subnet
+	}
+	subnetsB := make(map[string]slsCommon.IPSubnet)
+	for _, subnet := range b {
+		subnetsB[subnet.Name] = subnet
+	}
+	for _, name := range sortedKeys(
+		subnetsA,
+		subnetsB,
+	) {
+		path := fmt.Sprintf(
+			"%s/%s",
+			network,
+			name,
+		)
+		subnetA, inA := subnetsA[name]
+		subnetB, inB := subnetsB[name]
+		if !inB {
+			report.Subnets = append(
+				report.Subnets,
+				Change{
+					Kind: Removed,
+					Path: path,
+					Old:  subnetA.CIDR,
+				},
+			)
+			continue
+		}
+		if !inA {
+			report.Subnets = append(
+				report.Subnets,
+				Change{
+					Kind: Added,
+					Path: path,
+					New:  subnetB.CIDR,
+				},
+			)
+			continue
+		}
+		report.Subnets = append(
+			report.Subnets,
+			compareFields(
+				path,
+				[]field{
+					{
+						"cidr",
+						subnetA.CIDR,
+						subnetB.CIDR,
+					},
+					{
+						"cidr6",
+						subnetA.CIDR6,
+						subnetB.CIDR6,
+					},
+					{
+						"vlan",
+						subnetA.VlanID,
+						subnetB.VlanID,
+					},
+					{
+						"gateway",
+						subnetA.Gateway.String(),
+						subnetB.Gateway.String(),
+					},
+					{
+						"gateway6",
+						subnetA.Gateway6.String(),
+						subnetB.Gateway6.String(),
+					},
+					{
+						"dhcp-start",
+						subnetA.DHCPStart.String(),
+						subnetB.DHCPStart.String(),
+					},
+					{
+						"dhcp-end",
+						subnetA.DHCPEnd.String(),
+						subnetB.DHCPEnd.String(),
+					},
+				},
+			)...,
+		)
+		report.Reservations = append(
+			report.Reservations,
+			compareReservations(
+				path,
+				subnetA.IPReservations,
+				subnetB.IPReservations,
+			)...,
+		)
+	}
+}
+
+// compareReservations reports reservations that were added, removed, or given a new IPv4 or IPv6 address, and
+// addresses that moved from one host to another.
+func compareReservations(path string, a []slsCommon.IPReservation, b []slsCommon.IPReservation) (changes []Change) {
+	addressesA, hostsA := reservationAddresses(a)
+	addressesB, hostsB := reservationAddresses(b)
+
+	for _, name := range sortedKeys(
+		addressesA,
+		addressesB,
+	) {
+		addressA, inA := addressesA[name]
+		addressB, inB := addressesB[name]
+		reservationPath := fmt.Sprintf(
+			"%s/%s",
+			path,
+			name,
+		)
+		switch {
+		case !inB:
+			changes = append(
+				changes,
+				Change{
+					Kind: Removed,
+					Path: reservationPath,
+					Old:  addressA,
+				},
+			)
+		case !inA:
+			changes = append(
+				changes,
+				Change{
+					Kind: Added,
+					Path: reservationPath,
+					New:  addressB,
+				},
+			)
+		case addressA != addressB:
+			changes = append(
+				changes,
+				Change{
+					Kind: Changed,
+					Path: reservationPath,
+					Old:  addressA,
+					New:  addressB,
+				},
+			)
+		}
+	}
+
+	// An address held by both sides, but by different hosts, has moved.
+	for _, address := range sortedKeys(
+		hostsA,
+		hostsB,
+	) {
+		hostA, inA := hostsA[address]
+		hostB, inB := hostsB[address]
+		if inA && inB && hostA != hostB {
+			changes = append(
+				changes,
+				Change{
+					Kind: Moved,
+					Path: fmt.Sprintf(
+						"%s/%s",
+						path,
+						address,
+					),
+					Old: hostA,
+					New: hostB,
+				},
+			)
+		}
+	}
+	return changes
+}
+
+// reservationAddresses returns the addresses of every reservation keyed by its name, its IPv4 and IPv6 addresses
+// separated by a space, and the name of the reservation holding every address. Addresses a reservation does not have
+// are left out.
+func reservationAddresses(reservations []slsCommon.IPReservation) (addresses map[string]string, hosts map[string]string) {
+	addresses = make(map[string]string)
+	hosts = make(map[string]string)
+	for _, reservation := range reservations {
+		var held []string
+		for _, address := range []net.IP{
+			reservation.IPAddress,
+			reservation.IPAddress6,
+		} {
+			if address == nil {
+				continue
+			}
+			held = append(
+				held,
+				address.String(),
+			)
+			hosts[address.String()] = reservation.Name
+		}
+		addresses[reservation.Name] = strings.Join(
+			held,
+			" ",
+		)
+	}
+	return addresses, hosts
+}
+
+// compareHardware reports the SLS hardware that was added or removed, and the changes to hardware present in both.
+func compareHardware(a map[string]slsCommon.GenericHardware, b map[string]slsCommon.GenericHardware) (changes []Change) {
+	for _, xname := range sortedKeys(
+		a,
+		b,
+	) {
+		hardwareA, inA := a[xname]
+		hardwareB, inB := b[xname]
+		switch {
+		case !inB:
+			changes = append(
+				changes,
+				Change{
+					Kind: Removed,
+					Path: xname,
+					Old:  string(hardwareA.TypeString),
+				},
+			)
+		case !inA:
+			changes = append(
+				changes,
+				Change{
+					Kind: Added,
+					Path: xname,
+					New:  string(hardwareB.TypeString),
+				},
+			)
+		default:
+			changes = append(
+				changes,
+				compareValues(
+					xname,
+					hardwareValues(hardwareA),
+					hardwareValues(hardwareB),
+				)...,
+			)
+		}
+	}
+	return changes
+}
+
+// hardwareValues returns the comparable values of a piece of hardware, leaving out when it was last updated.
+func hardwareValues(hardware slsCommon.GenericHardware) (values map[string]interface{}) {
+	hardware.LastUpdated = 0
+	hardware.LastUpdatedTime = ""
+	raw, err := json.Marshal(hardware)
+	if err != nil {
+		return values
+	}
+	_ = json.Unmarshal(
+		raw,
+		&values,
+	)
+	return values
+}
+
+// compareValues reports the differences between two decoded JSON or YAML documents, one change per differing leaf.
+func compareValues(path string, a interface{}, b interface{}) (changes []Change) {
+	mapA, isMapA := a.(map[string]interface{})
+	mapB, isMapB := b.(map[string]interface{})
+	if isMapA && isMapB {
+		for _, key := range sortedKeys(
+			mapA,
+			mapB,
+		) {
+			changes = append(
+				changes,
+				compareValues(
+					joinPath(
+						path,
+						key,
+					),
+					mapA[key],
+					mapB[key],
+				)...,
+			)
+		}
+		return changes
+	}
+	listA, isListA := a.([]interface{})
+	listB, isListB := b.([]interface{})
+	if isListA && isListB && len(listA) == len(listB) {
+		for i := range listA {
+			changes = append(
+				changes,
+				compareValues(
+					fmt.Sprintf(
+						"%s[%d]",
+						path,
+						i,
+					),
+					listA[i],
+					listB[i],
+				)...,
+			)
+		}
+		return changes
+	}
+	switch {
+	case reflect.DeepEqual(
+		a,
+		b,
+	):
+	case a == nil:
+		changes = append(
+			changes,
+			Change{
+				Kind: Added,
+				Path: path,
+				New:  b,
+			},
+		)
+	case b == nil:
+		changes = append(
+			changes,
+			Change{
+				Kind: Removed,
+				Path: path,
+				Old:  a,
+			},
+		)
+	default:
+		changes = append(
+			changes,
+			Change{
+				Kind: Changed,
+				Path: path,
+				Old:  a,
+				New:  b,
+			},
+		)
+	}
+	return changes
+}
+
+type field struct {
+	name string
+	a    interface{}
+	b    interface{}
+}
+
+// compareFields reports each field whose value differs.
+func compareFields(path string, fields []field) (changes []Change) {
+	for _, f := range fields {
+		if reflect.DeepEqual(
+			f.a,
+			f.b,
+		) {
+			continue
+		}
+		changes = append(
+			changes,
+			Change{
+				Kind: Changed,
+				Path: joinPath(
+					path,
+					f.name,
+				),
+				Old: f.a,
+				New: f.b,
+			},
+		)
+	}
+	return changes
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// formatValue renders a value for the text report, using JSON for anything that isn't a plain value.
+func formatValue(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		if strings.Contains(
+			typed,
+			"\n",
+		) {
+			return strconv.Quote(typed)
+		}
+	case map[string]interface{}, []interface{}:
+		raw, err := json.Marshal(value)
+		if err == nil {
+			return string(raw)
+		}
+	}
+	return fmt.Sprintf(
+		"%v",
+		value,
+	)
+}
+
+// sortedKeys returns the union of the keys of a and b in order.
+func sortedKeys[V any](a map[string]V, b map[string]V) []string {
+	keys := slices.Collect(maps.Keys(a))
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(
+				keys,
+				key,
+			)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,334 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package diff
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/stretchr/testify/suite"
+)
+
+const slsA = `{
+  "Hardware": {
+    "x3000c0s1b0n0": {"Parent": "x3000c0s1b0", "Xname": "x3000c0s1b0n0", "Type": "comptype_node", "Class": "River", "TypeString": "Node", "LastUpdated": 1, "ExtraProperties": {"Role": "Management", "Aliases": ["ncn-m001"]}},
+    "x3000c0s2b0n0": {"Parent": "x3000c0s2b0", "Xname": "x3000c0s2b0n0", "Type": "comptype_node", "Class": "River", "TypeString": "Node", "ExtraProperties": {"Role": "Management", "Aliases": ["ncn-m002"]}}
+  },
+  "Networks": {
+    "NMN": {"Name": "NMN", "FullName": "Node Management Network", "IPRanges": ["10.252.0.0/17"], "Type": "ethernet", "ExtraProperties": {
+      "CIDR": "10.252.0.0/17", "VlanRange": [2], "MTU": 9000, "Subnets": [
+        {"FullName": "NMN Bootstrap DHCP Subnet", "CIDR": "10.252.1.0/24", "Name": "bootstrap_dhcp", "VlanID": 2, "Gateway": "10.252.0.1", "DHCPStart": "10.252.1.10", "DHCPEnd": "10.252.1.200", "IPReservations": [
+          {"Name": "ncn-m001", "IPAddress": "10.252.1.4"},
+          {"Name": "ncn-m002", "IPAddress": "10.252.1.5"}
+        ]},
+        {"FullName": "NMN Management Network Infrastructure", "CIDR": "10.252.0.0/24", "Name": "network_hardware", "VlanID": 2, "Gateway": "10.252.0.1"}
+      ]}},
+    "CAN": {"Name": "CAN", "FullName": "Customer Access Network", "IPRanges": ["10.102.10.0/23"], "Type": "ethernet", "ExtraProperties": {"CIDR": "10.102.10.0/23", "VlanRange": [6], "Subnets": []}}
+  }
+}`
+
+const slsB = `{
+  "Hardware": {
+    "x3000c0s1b0n0": {"Parent": "x3000c0s1b0", "Xname": "x3000c0s1b0n0", "Type": "comptype_node", "Class": "River", "TypeString": "Node", "LastUpdated": 2, "ExtraProperties": {"Role": "Management", "Aliases": ["ncn-m001", "ncn-m001-nmn"]}},
+    "x3000c0s3b0n0": {"Parent": "x3000c0s3b0", "Xname": "x3000c0s3b0n0", "Type": "comptype_node", "Class": "River", "TypeString": "Node", "ExtraProperties": {"Role": "Management", "Aliases": ["ncn-m003"]}}
+  },
+  "Networks": {
+    "NMN": {"Name": "NMN", "FullName": "Node Management Network", "IPRanges": ["10.252.0.0/17"], "Type": "ethernet", "ExtraProperties": {
+      "CIDR": "10.252.0.0/17", "VlanRange": [3], "MTU": 9000, "Subnets": [
+        {"FullName": "NMN Bootstrap DHCP Subnet", "CIDR": "10.252.1.0/24", "Name": "bootstrap_dhcp", "VlanID": 3, "Gateway": "10.252.0.1", "DHCPStart": "10.252.1.10", "DHCPEnd": "10.252.1.200", "IPReservations": [
+          {"Name": "ncn-m001", "IPAddress": "10.252.1.6"},
+          {"Name": "ncn-m003", "IPAddress": "10.252.1.5"}
+        ]}
+      ]}},
+    "CHN": {"Name": "CHN", "FullName": "Customer High-Speed Network", "IPRanges": ["10.104.7.0/24"], "Type": "ethernet", "ExtraProperties": {"CIDR": "10.104.7.0/24", "VlanRange": [5], "Subnets": []}}
+  }
+}`
+
+type ReportTestSuite struct {
+	suite.Suite
+	a string
+	b string
+}
+
+func (suite *ReportTestSuite) SetupTest() {
+	suite.a = suite.T().TempDir()
+	suite.b = suite.T().TempDir()
+	suite.write(
+		suite.a,
+		SLSFile,
+		slsA,
+	)
+	suite.write(
+		suite.b,
+		SLSFile,
+		slsB,
+	)
+	suite.write(
+		suite.a,
+		CloudInitFile,
+		`{"Global": {"meta-data": {"dns-server": "10.92.100.225", "can-gw": "10.102.10.1"}}}`,
+	)
+	suite.write(
+		suite.b,
+		CloudInitFile,
+		`{"Global": {"meta-data": {"dns-server": "10.92.100.225 10.94.100.225"}}}`,
+	)
+	suite.write(
+		suite.a,
+		CustomizationsFile,
+		"network:\n  netstaticips:\n    nmn_tftp: 10.92.100.60\n",
+	)
+	suite.write(
+		suite.b,
+		CustomizationsFile,
+		"network:\n  netstaticips:\n    nmn_tftp: 10.92.100.61\n",
+	)
+}
+
+func (suite *ReportTestSuite) write(dir string, name string, contents string) {
+	path := filepath.Join(
+		dir,
+		name,
+	)
+	suite.Require().NoError(
+		os.MkdirAll(
+			filepath.Dir(path),
+			0755,
+		),
+	)
+	suite.Require().NoError(
+		os.WriteFile(
+			path,
+			[]byte(contents),
+			0644,
+		),
+	)
+}
+
+func (suite *ReportTestSuite) TestCompare() {
+	report, err := Compare(
+		suite.a,
+		suite.b,
+	)
+	suite.Require().NoError(err)
+	suite.False(report.Empty())
+
+	suite.Equal(
+		[]Change{
+			{
+				Kind: Removed,
+				Path: "CAN",
+				Old:  "Customer Access Network",
+			},
+			{
+				Kind: Added,
+				Path: "CHN",
+				New:  "Customer High-Speed Network",
+			},
+			{
+				Kind: Changed,
+				Path: "NMN.vlan-range",
+				Old:  []int16{2},
+				New:  []int16{3},
+			},
+		},
+		report.Networks,
+	)
+	suite.Equal(
+		[]Change{
+			{
+				Kind: Changed,
+				Path: "NMN/bootstrap_dhcp.vlan",
+				Old:  int16(2),
+				New:  int16(3),
+			},
+			{
+				Kind: Removed,
+				Path: "NMN/network_hardware",
+				Old:  "10.252.0.0/24",
+			},
+		},
+		report.Subnets,
+	)
+	suite.Equal(
+		[]Change{
+			{
+				Kind: Changed,
+				Path: "NMN/bootstrap_dhcp/ncn-m001",
+				Old:  "10.252.1.4",
+				New:  "10.252.1.6",
+			},
+			{
+				Kind: Removed,
+				Path: "NMN/bootstrap_dhcp/ncn-m002",
+				Old:  "10.252.1.5",
+			},
+			{
+				Kind: Added,
+				Path: "NMN/bootstrap_dhcp/ncn-m003",
+				New:  "10.252.1.5",
+			},
+			{
+				Kind: Moved,
+				Path: "NMN/bootstrap_dhcp/10.252.1.5",
+				Old:  "ncn-m002",
+				New:  "ncn-m003",
+			},
+		},
+		report.Reservations,
+	)
+	suite.Equal(
+		[]string{
+			"x3000c0s1b0n0.ExtraProperties.Aliases",
+			"x3000c0s2b0n0",
+			"x3000c0s3b0n0",
+		},
+		paths(report.Hardware),
+	)
+	suite.Equal(
+		[]Change{
+			{
+				Kind: Removed,
+				Path: "Global.meta-data.can-gw",
+				Old:  "10.102.10.1",
+			},
+			{
+				Kind: Changed,
+				Path: "Global.meta-data.dns-server",
+				Old:  "10.92.100.225",
+				New:  "10.92.100.225 10.94.100.225",
+			},
+		},
+		report.CloudInit,
+	)
+	suite.Equal(
+		[]Change{
+			{
+				Kind: Changed,
+				Path: "network.netstaticips.nmn_tftp",
+				Old:  "10.92.100.60",
+				New:  "10.92.100.61",
+			},
+		},
+		report.Customizations,
+	)
+
+	var text bytes.Buffer
+	suite.NoError(report.WriteText(&text))
+	suite.Contains(
+		text.String(),
+		"  > NMN/bootstrap_dhcp/10.252.1.5: ncn-m002 -> ncn-m003\n",
+	)
+}
+
+func (suite *ReportTestSuite) TestCompare_Identical() {
+	report, err := Compare(
+		suite.a,
+		suite.a,
+	)
+	suite.Require().NoError(err)
+	suite.True(report.Empty())
+	suite.NotNil(report.Networks)
+}
+
+func (suite *ReportTestSuite) TestCompare_MissingSLS() {
+	_, err := Compare(
+		suite.a,
+		suite.T().TempDir(),
+	)
+	suite.ErrorContains(
+		err,
+		SLSFile,
+	)
+}
+
+func (suite *ReportTestSuite) TestCompareReservations_IPv6() {
+	a := []slsCommon.IPReservation{
+		{
+			Name:       "ncn-m001",
+			IPAddress:  net.ParseIP("10.252.1.4"),
+			IPAddress6: net.ParseIP("fd00:252::4"),
+		},
+		{
+			Name:       "ncn-m002",
+			IPAddress6: net.ParseIP("fd00:252::5"),
+		},
+		{
+			Name:       "ncn-m003",
+			IPAddress6: net.ParseIP("fd00:252::6"),
+		},
+	}
+	b := []slsCommon.IPReservation{
+		{
+			Name:       "ncn-m001",
+			IPAddress:  net.ParseIP("10.252.1.4"),
+			IPAddress6: net.ParseIP("fd00:252::7"),
+		},
+		{
+			Name:       "ncn-m002",
+			IPAddress6: net.ParseIP("fd00:252::5"),
+		},
+		{
+			Name:       "ncn-m003",
+			IPAddress6: net.ParseIP("fd00:252::6"),
+		},
+	}
+	suite.Equal(
+		[]Change{
+			{
+				Kind: Changed,
+				Path: "NMN/bootstrap_dhcp/ncn-m001",
+				Old:  "10.252.1.4 fd00:252::4",
+				New:  "10.252.1.4 fd00:252::7",
+			},
+		},
+		compareReservations(
+			"NMN/bootstrap_dhcp",
+			a,
+			b,
+		),
+	)
+}
+
+func paths(changes []Change) (paths []string) {
+	for _, change := range changes {
+		paths = append(
+			paths,
+			change.Path,
+		)
+	}
+	return paths
+}
+
+func TestReportTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(ReportTestSuite),
+	)
+}
@@ -0,0 +1,500 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package export
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"net/netip"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/Cray-HPE/hms-xname/xnametypes"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+)
+
+// groupNameRegexp matches the characters that are not allowed in an Ansible group or variable name.
+var groupNameRegexp = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// nodeGroups are the inventory groups of the SLS node subroles, other subroles are grouped under their own name.
+var nodeGroups = map[string]string{
+	"Master":  "masters",
+	"Worker":  "workers",
+	"Storage": "storage",
+	"UAN":     "uans",
+	"Gateway": "gateways",
+}
+
+// switchGroups are the inventory groups of the management switches by the prefix of their name, sw-leaf-bmc before
+// sw-leaf.
+var switchGroups = []struct {
+	Prefix string
+	Group  string
+}{
+	{
+		Prefix: "sw-spine",
+		Group:  "spine_switches",
+	},
+	{
+		Prefix: "sw-leaf-bmc",
+		Group:  "leaf_bmc_switches",
+	},
+	{
+		Prefix: "sw-leaf",
+		Group:  "leaf_switches",
+	},
+	{
+		Prefix: "sw-cdu",
+		Group:  "cdu_switches",
+	},
+	{
+		Prefix: "sw-edge",
+		Group:  "edge_switches",
+	},
+}
+
+// switchTypeGroups are the inventory groups of the management switches whose name has none of the switchGroups
+// prefixes.
+var switchTypeGroups = map[xnametypes.HMSType]string{
+	xnametypes.MgmtSwitch:    "leaf_bmc_switches",
+	xnametypes.MgmtHLSwitch:  "leaf_switches",
+	xnametypes.CDUMgmtSwitch: "cdu_switches",
+}
+
+// AnsibleHost is the host vars of a host in an Ansible inventory.
+type AnsibleHost struct {
+	// AnsibleHost is the NMN address of the host, or the HMN address of a BMC.
+	AnsibleHost string   `yaml:"ansible_host,omitempty"`
+	Xname       string   `yaml:"xname"`
+	Aliases     []string `yaml:"aliases,omitempty"`
+	Cabinet     string   `yaml:"cabinet,omitempty"`
+	// IPs and IP6s are the addresses of the host keyed by the lowercase name of their SLS network.
+	IPs   map[string]string `yaml:"ips,omitempty"`
+	IP6s  map[string]string `yaml:"ip6s,omitempty"`
+	BMCIP string            `yaml:"bmc_ip,omitempty"`
+	Brand string            `yaml:"brand,omitempty"`
+	Model string            `yaml:"model,omitempty"`
+	MACs  []string          `yaml:"macs,omitempty"`
+}
+
+// AnsibleGroup is a group of an Ansible YAML inventory. A host has its vars in the one group it is defined in, every
+// other group it belongs to lists it without vars.
+type AnsibleGroup struct {
+	Hosts    map[string]*AnsibleHost  `yaml:"hosts,omitempty"`
+	Vars     map[string]interface{}   `yaml:"vars,omitempty"`
+	Children map[string]*AnsibleGroup `yaml:"children,omitempty"`
+}
+
+// AnsibleInventory is an Ansible YAML inventory.
+type AnsibleInventory struct {
+	All AnsibleGroup `yaml:"all"`
+}
+
+// basecampData is the cloud-init meta-data of data.json, keyed by MAC address or Global.
+type basecampData map[string]struct {
+	MetaData map[string]interface{} `json:"meta-data"`
+}
+
+// reservation is an SLS IP reservation along with the network it is in.
+type reservation struct {
+	Network     string
+	Reservation slsCommon.IPReservation
+}
+
+func newAnsibleCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "ansible SYSTEM_DIR",
+		Short: "Exports the hardware of a system directory as an Ansible inventory",
+		Long: `Exports the hardware of the sls_input_file.json and the cloud-init data of the basecamp/data.json of a system
+directory as an Ansible YAML inventory.
+
+	1. Nodes are grouped by role, and by subrole within it: management (masters, workers, storage), application (uans,
+	   gateways), and compute. Hosts are named after their first SLS alias (ncn-m001, uan01, nid000001)
+	2. Management switches are grouped by type (spine_switches, leaf_switches, leaf_bmc_switches, cdu_switches,
+	   edge_switches) and by brand (e.g. aruba_switches), both under switches
+	3. The BMCs of the nodes with a reservation for their BMC are in bmcs, named after its alias (ncn-m001-mgmt)
+	4. Hosts have the xname, aliases, cabinet, ips and ip6s by network, and bmc_ip vars. ansible_host is the NMN
+	   address, or the HMN address of a BMC. Switches have the brand and model vars, NCNs the macs of data.json
+	5. The global meta-data of data.json are the vars of all, with their dashes replaced by underscores (system_name)
+`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(c *cobra.Command, args []string) {
+			v := viper.GetViper()
+			err := v.BindPFlags(c.Flags())
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			basepath := args[0]
+			state, err := initialize.LoadPreviousSLS(basepath)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			var data basecampData
+			err = files.ReadJSONConfig(
+				filepath.Join(
+					basepath,
+					initialize.BasecampDataFile,
+				),
+				&data,
+			)
+			if err != nil {
+				log.Fatalf(
+					"Failed to read %s because %v\n",
+					initialize.BasecampDataFile,
+					err,
+				)
+			}
+			inventory, err := NewAnsibleInventory(
+				state,
+				data,
+			)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			path := v.GetString("output-file")
+			err = files.WriteYAMLConfig(
+				path,
+				inventory,
+			)
+			if err != nil {
+				log.Fatalf(
+					"Failed to write %s because %v\n",
+					path,
+					err,
+				)
+			}
+			fmt.Printf(
+				"Exported %d hosts to %s\n",
+				inventory.Len(),
+				path,
+			)
+		},
+	}
+	c.Flags().String(
+		"output-file",
+		"inventory.yaml",
+		"File to write the inventory to",
+	)
+	return c
+}
+
+// NewAnsibleInventory makes the inventory of the nodes, management switches, and BMCs of an SLS state. The meta-data of
+// the basecamp data gives the vars of all, and the MAC addresses of the NCNs.
+func NewAnsibleInventory(state *slsCommon.SLSState, data basecampData) (inventory AnsibleInventory, err error) {
+	reservations, err := reservationsByName(state)
+	if err != nil {
+		return inventory, err
+	}
+	macs := make(map[string][]string)
+	for _, mac := range slices.Sorted(maps.Keys(data)) {
+		if mac == "Global" {
+			continue
+		}
+		if hostname, ok := data[mac].MetaData["local-hostname"].(string); ok {
+			macs[hostname] = append(
+				macs[hostname],
+				mac,
+			)
+		}
+	}
+
+	for _, xname := range slices.Sorted(maps.Keys(state.Hardware)) {
+		hardware := state.Hardware[xname]
+		switch hardware.TypeString {
+		case xnametypes.Node:
+			err = inventory.addNode(
+				hardware,
+				reservations,
+				macs,
+			)
+		case xnametypes.MgmtSwitch, xnametypes.MgmtHLSwitch, xnametypes.CDUMgmtSwitch:
+			err = inventory.addSwitch(
+				hardware,
+				reservations,
+			)
+		}
+		if err != nil {
+			return inventory, err
+		}
+	}
+
+	if metaData := data["Global"].MetaData; len(metaData) > 0 {
+		inventory.All.Vars = make(map[string]interface{})
+		for key, value := range metaData {
+			// The host records are in the inventory itself.
+			if key == "host_records" {
+				continue
+			}
+			inventory.All.Vars[strings.ReplaceAll(
+				key,
+				"-",
+				"_",
+			)] = value
+		}
+	}
+	return inventory, nil
+}
+
+// Len returns the number of hosts in the inventory.
+func (inventory AnsibleInventory) Len() int {
+	hosts := make(map[string]bool)
+	var walk func(group *AnsibleGroup)
+	walk = func(group *AnsibleGroup) {
+		for name := range group.Hosts {
+			hosts[name] = true
+		}
+		for _, child := range group.Children {
+			walk(child)
+		}
+	}
+	walk(&inventory.All)
+	return len(hosts)
+}
+
+// addNode defines a node in the group of its subrole, or of its role if it has none. The BMC of the node is defined
+// in bmcs when it has a reservation.
+func (inventory *AnsibleInventory) addNode(
+	hardware slsCommon.GenericHardware, reservations map[string][]reservation, macs map[string][]string,
+) error {
+	extraProperties, err := sls.UnmarshalComptypeNode(&hardware)
+	if err != nil {
+		return err
+	}
+	name, host := newAnsibleHost(
+		hardware.Xname,
+		extraProperties.Aliases,
+		reservations,
+	)
+	host.MACs = macs[name]
+	if bmcReservations := reservations[hardware.Parent]; len(bmcReservations) > 0 {
+		bmcName, bmc := newAnsibleHost(
+			hardware.Parent,
+			bmcReservations[0].Reservation.Aliases,
+			reservations,
+		)
+		bmc.AnsibleHost = bmc.IPs["hmn"]
+		host.BMCIP = bmc.AnsibleHost
+		inventory.group("bmcs").addHost(
+			bmcName,
+			bmc,
+		)
+	}
+
+	group := inventory.group(groupName(extraProperties.Role))
+	if extraProperties.SubRole != "" {
+		subroleGroup, ok := nodeGroups[extraProperties.SubRole]
+		if !ok {
+			subroleGroup = groupName(extraProperties.SubRole)
+		}
+		group = group.group(subroleGroup)
+	}
+	group.addHost(
+		name,
+		host,
+	)
+	return nil
+}
+
+// addSwitch defines a management switch in the group of its type, and lists it in the group of its brand.
+func (inventory *AnsibleInventory) addSwitch(
+	hardware slsCommon.GenericHardware, reservations map[string][]reservation,
+) error {
+	extraProperties, err := sls.UnmarshalComptypeMgmtSwitch(&hardware)
+	if err != nil {
+		return err
+	}
+	name, host := newAnsibleHost(
+		hardware.Xname,
+		extraProperties.Aliases,
+		reservations,
+	)
+	host.Brand = extraProperties.Brand
+	host.Model = extraProperties.Model
+
+	typeGroup := switchTypeGroups[hardware.TypeString]
+	for _, switchGroup := range switchGroups {
+		if strings.HasPrefix(
+			name,
+			switchGroup.Prefix,
+		) {
+			typeGroup = switchGroup.Group
+			break
+		}
+	}
+	switches := inventory.group("switches")
+	switches.group(typeGroup).addHost(
+		name,
+		host,
+	)
+	if extraProperties.Brand != "" {
+		switches.group(groupName(extraProperties.Brand)+"_switches").addHost(
+			name,
+			nil,
+		)
+	}
+	return nil
+}
+
+// newAnsibleHost returns the name and vars of the hardware with the given xname and SLS aliases. The host is named
+// after its first alias, and has the addresses of the reservations with its name or xname. Its other aliases are those
+// of the hardware and the aliases of its reservations that start with its name (ncn-m001-nmn, not time-nmn).
+func newAnsibleHost(xname string, aliases []string, reservations map[string][]reservation) (string, *AnsibleHost) {
+	name := xname
+	if len(aliases) > 0 {
+		name = aliases[0]
+	}
+	host := &AnsibleHost{
+		Xname:   xname,
+		Cabinet: cabinet(xname),
+	}
+	names := make(map[string]bool)
+	for _, alias := range aliases {
+		names[alias] = true
+	}
+	hostReservations := reservations[name]
+	if xname != name {
+		hostReservations = append(
+			hostReservations,
+			reservations[xname]...,
+		)
+	}
+	for _, r := range hostReservations {
+		network := strings.ToLower(r.Network)
+		if address, ok := netip.AddrFromSlice(r.Reservation.IPAddress); ok {
+			if host.IPs == nil {
+				host.IPs = make(map[string]string)
+			}
+			host.IPs[network] = address.Unmap().String()
+		}
+		if address, ok := netip.AddrFromSlice(r.Reservation.IPAddress6); ok {
+			if host.IP6s == nil {
+				host.IP6s = make(map[string]string)
+			}
+			host.IP6s[network] = address.String()
+		}
+		for _, alias := range r.Reservation.Aliases {
+			if strings.HasPrefix(
+				alias,
+				name+"-",
+			) || strings.HasPrefix(
+				alias,
+				name+".",
+			) {
+				names[alias] = true
+			}
+		}
+	}
+	delete(
+		names,
+		name,
+	)
+	host.Aliases = slices.Sorted(maps.Keys(names))
+	host.AnsibleHost = host.IPs["nmn"]
+	return name, host
+}
+
+// reservationsByName returns the reservations of an SLS state keyed by their name, in the order of their networks'
+// names.
+func reservationsByName(state *slsCommon.SLSState) (map[string][]reservation, error) {
+	reservations := make(map[string][]reservation)
+	for _, networkName := range slices.Sorted(maps.Keys(state.Networks)) {
+		network := state.Networks[networkName]
+		extraProperties, err := sls.UnmarshalNetworkExtraProperties(&network)
+		if err != nil {
+			return nil, err
+		}
+		for _, subnet := range extraProperties.Subnets {
+			for _, r := range subnet.IPReservations {
+				reservations[r.Name] = append(
+					reservations[r.Name],
+					reservation{
+						Network:     networkName,
+						Reservation: r,
+					},
+				)
+			}
+		}
+	}
+	return reservations, nil
+}
+
+// cabinet returns the cabinet xname of the hardware with the given xname, or an empty string for hardware outside of
+// a cabinet (e.g. the CDU switches).
+func cabinet(xname string) string {
+	for {
+		switch xnametypes.GetHMSType(xname) {
+		case xnametypes.Cabinet:
+			return xname
+		case xnametypes.System, xnametypes.HMSTypeInvalid:
+			return ""
+		}
+		xname = xnametypes.GetHMSCompParent(xname)
+	}
+}
+
+// groupName returns a name as an Ansible group name, e.g. LNETRouter is lnetrouter.
+func groupName(name string) string {
+	return groupNameRegexp.ReplaceAllString(
+		strings.ToLower(name),
+		"_",
+	)
+}
+
+// group returns the child group of all with the given name, adding it if it does not exist.
+func (inventory *AnsibleInventory) group(name string) *AnsibleGroup {
+	return inventory.All.group(name)
+}
+
+// group returns the child group with the given name, adding it if it does not exist.
+func (group *AnsibleGroup) group(name string) *AnsibleGroup {
+	if group.Children == nil {
+		group.Children = make(map[string]*AnsibleGroup)
+	}
+	child, ok := group.Children[name]
+	if !ok {
+		child = &AnsibleGroup{}
+		group.Children[name] = child
+	}
+	return child
+}
+
+// addHost adds a host to the group, a nil host lists a host that is defined in another group.
+func (group *AnsibleGroup) addHost(name string, host *AnsibleHost) {
+	if group.Hosts == nil {
+		group.Hosts = make(map[string]*AnsibleHost)
+	}
+	group.Hosts[name] = host
+}
@@ -0,0 +1,290 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package export
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+)
+
+type AnsibleTestSuite struct {
+	suite.Suite
+	state *slsCommon.SLSState
+	data  basecampData
+}
+
+func (suite *AnsibleTestSuite) SetupTest() {
+	suite.state = &slsCommon.SLSState{
+		Hardware: map[string]slsCommon.GenericHardware{
+			"x3000c0s1b0n0": slsCommon.NewGenericHardware(
+				"x3000c0s1b0n0",
+				slsCommon.ClassRiver,
+				slsCommon.ComptypeNode{
+					Role:    "Management",
+					SubRole: "Master",
+					Aliases: []string{"ncn-m001"},
+				},
+			),
+			"x3000c0s15b0n0": slsCommon.NewGenericHardware(
+				"x3000c0s15b0n0",
+				slsCommon.ClassRiver,
+				slsCommon.ComptypeNode{
+					Role:    "Application",
+					SubRole: "LNETRouter",
+					Aliases: []string{"lnet01"},
+				},
+			),
+			"x1000c0s0b0n0": slsCommon.NewGenericHardware(
+				"x1000c0s0b0n0",
+				slsCommon.ClassMountain,
+				slsCommon.ComptypeNode{
+					NID:     1,
+					Role:    "Compute",
+					Aliases: []string{"nid000001"},
+				},
+			),
+			"x3000c0w31": slsCommon.NewGenericHardware(
+				"x3000c0w31",
+				slsCommon.ClassRiver,
+				slsCommon.ComptypeMgmtSwitch{
+					Brand:   "Dell",
+					Model:   "S3048-ON",
+					Aliases: []string{"sw-leaf-bmc-001"},
+				},
+			),
+			"x3000c0h37s1": slsCommon.NewGenericHardware(
+				"x3000c0h37s1",
+				slsCommon.ClassRiver,
+				slsCommon.ComptypeMgmtHLSwitch{
+					Brand:   "Mellanox",
+					Aliases: []string{"sw-spine-001"},
+				},
+			),
+			"d0w1": slsCommon.NewGenericHardware(
+				"d0w1",
+				slsCommon.ClassRiver,
+				slsCommon.ComptypeCDUMgmtSwitch{
+					Brand:   "Dell",
+					Aliases: []string{"sw-cdu-001"},
+				},
+			),
+		},
+		Networks: map[string]slsCommon.Network{
+			"NMN": network(
+				"NMN",
+				slsCommon.IPReservation{
+					Name:       "ncn-m001",
+					IPAddress:  net.ParseIP("10.252.1.10"),
+					IPAddress6: net.ParseIP("fd00:252::a"),
+					Aliases: []string{
+						"ncn-m001-nmn",
+						"time-nmn",
+						"x3000c0s1b0n0",
+					},
+				},
+				slsCommon.IPReservation{
+					Name:      "sw-leaf-bmc-001",
+					IPAddress: net.ParseIP("10.252.0.4"),
+				},
+			),
+			"HMN": network(
+				"HMN",
+				slsCommon.IPReservation{
+					Name:      "x3000c0s1b0",
+					IPAddress: net.ParseIP("10.254.1.9"),
+					Aliases:   []string{"ncn-m001-mgmt"},
+				},
+				slsCommon.IPReservation{
+					Name:      "ncn-m001",
+					IPAddress: net.ParseIP("10.254.1.10"),
+					Aliases:   []string{"ncn-m001-hmn"},
+				},
+			),
+		},
+	}
+	suite.data = basecampData{
+		"Global": {
+			MetaData: map[string]interface{}{
+				"system-name":       "eniac",
+				"num_storage_nodes": 3,
+				"host_records":      []interface{}{},
+			},
+		},
+		"94:40:c9:00:00:02": {
+			MetaData: map[string]interface{}{
+				"local-hostname": "ncn-m001",
+			},
+		},
+		"14:02:ec:00:00:01": {
+			MetaData: map[string]interface{}{
+				"local-hostname": "ncn-m001",
+			},
+		},
+	}
+}
+
+func (suite *AnsibleTestSuite) TestNewAnsibleInventory() {
+	inventory, err := NewAnsibleInventory(
+		suite.state,
+		suite.data,
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		7,
+		inventory.Len(),
+	)
+	suite.Equal(
+		map[string]interface{}{
+			"system_name":       "eniac",
+			"num_storage_nodes": 3,
+		},
+		inventory.All.Vars,
+	)
+
+	all := inventory.All.Children
+	suite.Equal(
+		&AnsibleHost{
+			AnsibleHost: "10.252.1.10",
+			Xname:       "x3000c0s1b0n0",
+			Aliases: []string{
+				"ncn-m001-hmn",
+				"ncn-m001-nmn",
+			},
+			Cabinet: "x3000",
+			IPs: map[string]string{
+				"hmn": "10.254.1.10",
+				"nmn": "10.252.1.10",
+			},
+			IP6s: map[string]string{
+				"nmn": "fd00:252::a",
+			},
+			BMCIP: "10.254.1.9",
+			MACs: []string{
+				"14:02:ec:00:00:01",
+				"94:40:c9:00:00:02",
+			},
+		},
+		all["management"].Children["masters"].Hosts["ncn-m001"],
+	)
+	suite.Equal(
+		&AnsibleHost{
+			AnsibleHost: "10.254.1.9",
+			Xname:       "x3000c0s1b0",
+			Cabinet:     "x3000",
+			IPs: map[string]string{
+				"hmn": "10.254.1.9",
+			},
+		},
+		all["bmcs"].Hosts["ncn-m001-mgmt"],
+	)
+	suite.Contains(
+		all["application"].Children["lnetrouter"].Hosts,
+		"lnet01",
+	)
+	suite.Equal(
+		"x1000",
+		all["compute"].Hosts["nid000001"].Cabinet,
+	)
+
+	switches := all["switches"].Children
+	suite.Equal(
+		"S3048-ON",
+		switches["leaf_bmc_switches"].Hosts["sw-leaf-bmc-001"].Model,
+	)
+	suite.Equal(
+		"10.252.0.4",
+		switches["leaf_bmc_switches"].Hosts["sw-leaf-bmc-001"].AnsibleHost,
+	)
+	suite.Contains(
+		switches["spine_switches"].Hosts,
+		"sw-spine-001",
+	)
+	// The CDU switches are not in a cabinet.
+	suite.Empty(switches["cdu_switches"].Hosts["sw-cdu-001"].Cabinet)
+	suite.Equal(
+		map[string]*AnsibleHost{
+			"sw-cdu-001":      nil,
+			"sw-leaf-bmc-001": nil,
+		},
+		switches["dell_switches"].Hosts,
+	)
+	suite.Contains(
+		switches["mellanox_switches"].Hosts,
+		"sw-spine-001",
+	)
+}
+
+func (suite *AnsibleTestSuite) TestAnsibleInventory_YAML() {
+	inventory, err := NewAnsibleInventory(
+		suite.state,
+		suite.data,
+	)
+	suite.Require().NoError(err)
+	var out bytes.Buffer
+	suite.Require().NoError(
+		files.EncodeYAML(
+			&out,
+			inventory,
+		),
+	)
+	// Hosts are defined in one group, the groups of their brand only list them.
+	suite.Contains(
+		out.String(),
+		"        dell_switches:\n          hosts:\n            sw-cdu-001: null\n            sw-leaf-bmc-001: null\n",
+	)
+	suite.Contains(
+		out.String(),
+		"            ncn-m001:\n              ansible_host: 10.252.1.10\n              xname: x3000c0s1b0n0\n",
+	)
+}
+
+func (suite *AnsibleTestSuite) TestCabinet() {
+	for xname, expected := range map[string]string{
+		"x3000c0s1b0n0": "x3000",
+		"x3000c0w31":    "x3000",
+		"x3000":         "x3000",
+		"d0w1":          "",
+		"ncn-m001":      "",
+	} {
+		suite.Equal(
+			expected,
+			cabinet(xname),
+			xname,
+		)
+	}
+}
+
+func TestAnsibleTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(AnsibleTestSuite),
+	)
+}
@@ -0,0 +1,467 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package export
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"net/netip"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+)
+
+// hostnameRegexp matches a single hostname label (RFC 1123), the names of the SLS reservations that can be exported.
+var hostnameRegexp = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// DNSRecord is an address record of a forward zone, its Name is relative to the zone.
+type DNSRecord struct {
+	Name    string
+	Address netip.Addr
+}
+
+// DNSPointer is a PTR record of a reverse zone, its Name is relative to the zone and its Target is fully qualified.
+type DNSPointer struct {
+	Name    string
+	Address netip.Addr
+	Target  string
+}
+
+// DNSZone is a forward zone with Records, or a reverse zone with Pointers. Its Name has no trailing dot.
+type DNSZone struct {
+	Name     string
+	Records  []DNSRecord
+	Pointers []DNSPointer
+}
+
+// DNSCollision is a name that more than one SLS network has a reservation for, only the records of the first network
+// are exported.
+type DNSCollision struct {
+	Name     string
+	Networks []string
+}
+
+// DNSExport holds the zones of the SLS reservations of a system.
+type DNSExport struct {
+	Domain     string
+	Forward    []DNSZone
+	Reverse    []DNSZone
+	Collisions []DNSCollision
+	// Skipped are the reservation names and aliases that are not a hostname (e.g. time-cmn.local or api_gw_service).
+	Skipped []string
+}
+
+func newDNSCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "dns SYSTEM_DIR",
+		Short: "Exports the SLS reservations of a system directory as DNS zones",
+		Long: `Exports the IP reservations of the sls_input_file.json of a system directory as forward and reverse DNS zones.
+
+	1. Every SLS network has a forward zone named after its suffix in the dnsmasq host records and the domain, e.g.
+	   nmn.eniac.example.com. The load balancer and cabinet networks share the zone of their network (NMNLB and NMN_MTN
+	   are in the nmn zone). The domain is the system-name and site-domain of the system_config.yaml, or --domain
+	2. The name and aliases of every reservation are given its IPv4 and IPv6 addresses. Names that are not a hostname
+	   (e.g. time-cmn.local or api_gw_service) are skipped
+	3. The reservation names are given PTR records in reverse zones, /24 zones for IPv4 and /64 zones for IPv6
+	4. A name that more than one SLS network has a reservation for is reported as a collision, only the records of the
+	   first network (by name) are exported
+
+	The zones are written to --output-dir in one of these formats:
+	  bind     a db.<zone> zone file for every zone, and named.conf.zones declaring them
+	  unbound  unbound.conf with the local-zone, local-data, and local-data-ptr of every zone
+	  coredns  a Corefile with a hosts block for every forward zone, and one answering the reverse zones
+	`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(c *cobra.Command, args []string) {
+			v := viper.GetViper()
+			err := v.BindPFlags(c.Flags())
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			basepath := args[0]
+			state, err := initialize.LoadPreviousSLS(basepath)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			domain := v.GetString("domain")
+			if domain == "" {
+				domain, err = systemDomain(basepath)
+				if err != nil {
+					log.Fatalln(err)
+				}
+			}
+			export, err := NewDNSExport(
+				state,
+				domain,
+			)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			settings := DNSSettings{
+				TTL:        v.GetUint32("ttl"),
+				Serial:     v.GetUint32("serial"),
+				Nameserver: v.GetString("nameserver"),
+			}
+			if settings.Serial == 0 {
+				settings.Serial = uint32(time.Now().Unix())
+			}
+			if settings.Nameserver == "" {
+				settings.Nameserver = "ns." + domain
+			}
+			err = export.Write(
+				v.GetString("format"),
+				v.GetString("output-dir"),
+				settings,
+			)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			records := 0
+			for _, zone := range export.Forward {
+				records += len(zone.Records)
+			}
+			fmt.Printf(
+				"Exported %d records in %d forward and %d reverse zones of %s to %s\n",
+				records,
+				len(export.Forward),
+				len(export.Reverse),
+				domain,
+				v.GetString("output-dir"),
+			)
+			for _, collision := range export.Collisions {
+				log.Printf(
+					"WARNING: %s collides across the %s networks, only the %s records were exported\n",
+					collision.Name,
+					strings.Join(
+						collision.Networks,
+						", ",
+					),
+					collision.Networks[0],
+				)
+			}
+		},
+	}
+	c.Flags().StringP(
+		"format",
+		"f",
+		"bind",
+		"output format bind,unbound,coredns",
+	)
+	c.Flags().String(
+		"output-dir",
+		"dns",
+		"Directory to write the zones to",
+	)
+	c.Flags().String(
+		"domain",
+		"",
+		"Domain of the zones (defaults to the system-name and site-domain of the system_config.yaml)",
+	)
+	c.Flags().String(
+		"nameserver",
+		"",
+		"Nameserver of the BIND zones' SOA and NS records (defaults to ns.<domain>)",
+	)
+	c.Flags().Uint32(
+		"ttl",
+		3600,
+		"TTL of the records, in seconds",
+	)
+	c.Flags().Uint32(
+		"serial",
+		0,
+		"Serial of the BIND zones' SOA records (defaults to the current Unix time)",
+	)
+	return c
+}
+
+// systemDomain returns the domain of a system directory, its system-name followed by its site-domain.
+func systemDomain(basepath string) (domain string, err error) {
+	config := viper.New()
+	config.SetConfigFile(
+		filepath.Join(
+			basepath,
+			"system_config.yaml",
+		),
+	)
+	err = config.ReadInConfig()
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to read the system config because %v",
+			err,
+		)
+	}
+	domain = config.GetString("system-name")
+	if domain == "" {
+		return "", fmt.Errorf("the system config has no system-name, give the domain with --domain")
+	}
+	if siteDomain := strings.Trim(
+		config.GetString("site-domain"),
+		".",
+	); siteDomain != "" {
+		domain += "." + siteDomain
+	}
+	return domain, nil
+}
+
+// DNSSuffix returns the suffix of the names of an SLS network, as used by the dnsmasq host records. The load balancer
+// and cabinet networks share the suffix of their network (NMNLB and NMN_MTN are .nmn).
+func DNSSuffix(network string) string {
+	suffix, _, _ := strings.Cut(
+		strings.ToLower(network),
+		"_",
+	)
+	return strings.TrimSuffix(
+		suffix,
+		"lb",
+	)
+}
+
+// NewDNSExport makes the forward and reverse zones of the reservations of an SLS state under the given domain.
+func NewDNSExport(state *slsCommon.SLSState, domain string) (export DNSExport, err error) {
+	domain = strings.Trim(
+		domain,
+		".",
+	)
+	export.Domain = domain
+	forward := make(map[string]*DNSZone)
+	reverse := make(map[string]*DNSZone)
+	// The network each fully qualified name was first exported from, and the networks that collide with it.
+	owners := make(map[string]string)
+	collisions := make(map[string][]string)
+	skipped := make(map[string]bool)
+
+	for _, networkName := range slices.Sorted(maps.Keys(state.Networks)) {
+		network := state.Networks[networkName]
+		extraProperties, err := sls.UnmarshalNetworkExtraProperties(&network)
+		if err != nil {
+			return export, err
+		}
+		zoneName := DNSSuffix(networkName) + "." + domain
+		for _, subnet := range extraProperties.Subnets {
+			for _, reservation := range subnet.IPReservations {
+				var addresses []netip.Addr
+				for _, ip := range []([]byte){
+					reservation.IPAddress,
+					reservation.IPAddress6,
+				} {
+					if address, ok := netip.AddrFromSlice(ip); ok {
+						addresses = append(
+							addresses,
+							address.Unmap(),
+						)
+					}
+				}
+				names := append(
+					[]string{reservation.Name},
+					reservation.Aliases...,
+				)
+				for i, name := range names {
+					if !hostnameRegexp.MatchString(name) {
+						skipped[name] = true
+						continue
+					}
+					fqdn := name + "." + zoneName
+					if owner, ok := owners[fqdn]; !ok {
+						owners[fqdn] = networkName
+					} else if owner != networkName {
+						if !slices.Contains(
+							collisions[fqdn],
+							networkName,
+						) {
+							collisions[fqdn] = append(
+								collisions[fqdn],
+								networkName,
+							)
+						}
+						continue
+					}
+					zone := forward[zoneName]
+					if zone == nil {
+						zone = &DNSZone{
+							Name: zoneName,
+						}
+						forward[zoneName] = zone
+					}
+					for _, address := range addresses {
+						record := DNSRecord{
+							Name:    name,
+							Address: address,
+						}
+						if !slices.Contains(
+							zone.Records,
+							record,
+						) {
+							zone.Records = append(
+								zone.Records,
+								record,
+							)
+						}
+						// Only the reservation name points back to its address, the first one to claim it.
+						if i == 0 {
+							addPointer(
+								reverse,
+								address,
+								fqdn,
+							)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(forward)) {
+		zone := forward[name]
+		slices.SortFunc(
+			zone.Records,
+			func(a, b DNSRecord) int {
+				if c := strings.Compare(
+					a.Name,
+					b.Name,
+				); c != 0 {
+					return c
+				}
+				return a.Address.Compare(b.Address)
+			},
+		)
+		export.Forward = append(
+			export.Forward,
+			*zone,
+		)
+	}
+	for _, name := range slices.Sorted(maps.Keys(reverse)) {
+		zone := reverse[name]
+		slices.SortFunc(
+			zone.Pointers,
+			func(a, b DNSPointer) int {
+				return a.Address.Compare(b.Address)
+			},
+		)
+		export.Reverse = append(
+			export.Reverse,
+			*zone,
+		)
+	}
+	for _, name := range slices.Sorted(maps.Keys(collisions)) {
+		export.Collisions = append(
+			export.Collisions,
+			DNSCollision{
+				Name: name,
+				Networks: append(
+					[]string{owners[name]},
+					collisions[name]...,
+				),
+			},
+		)
+	}
+	export.Skipped = slices.Sorted(maps.Keys(skipped))
+	return export, nil
+}
+
+// addPointer adds a PTR record of an address to its reverse zone, unless the address already has one.
+func addPointer(reverse map[string]*DNSZone, address netip.Addr, target string) {
+	zoneName, name := ReverseZone(address)
+	zone := reverse[zoneName]
+	if zone == nil {
+		zone = &DNSZone{
+			Name: zoneName,
+		}
+		reverse[zoneName] = zone
+	}
+	for _, pointer := range zone.Pointers {
+		if pointer.Address == address {
+			return
+		}
+	}
+	zone.Pointers = append(
+		zone.Pointers,
+		DNSPointer{
+			Name:    name,
+			Address: address,
+			Target:  target,
+		},
+	)
+}
+
+// ReverseZone returns the reverse zone of an address and the name of its PTR record in that zone. IPv4 addresses are
+// in /24 zones (1.252.10.in-addr.arpa) and IPv6 addresses in /64 zones.
+func ReverseZone(address netip.Addr) (zone string, name string) {
+	var labels []string
+	var hostLabels int
+	suffix := "in-addr.arpa"
+	if address.Is4() {
+		for _, octet := range address.As4() {
+			labels = append(
+				labels,
+				fmt.Sprint(octet),
+			)
+		}
+		hostLabels = 1
+	} else {
+		for _, b := range address.As16() {
+			labels = append(
+				labels,
+				fmt.Sprintf(
+					"%x",
+					b>>4,
+				),
+				fmt.Sprintf(
+					"%x",
+					b&0xf,
+				),
+			)
+		}
+		hostLabels = 16
+		suffix = "ip6.arpa"
+	}
+	slices.Reverse(labels)
+	name = strings.Join(
+		labels[:hostLabels],
+		".",
+	)
+	zone = strings.Join(
+		append(
+			labels[hostLabels:],
+			suffix,
+		),
+		".",
+	)
+	return zone, name
+}
@@ -0,0 +1,330 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize"
+)
+
+// BINDZoneTemplate is a BIND zone file of a forward or reverse DNSZone.
+var BINDZoneTemplate = []byte(`
+{{- /* remove leading whitespace */ -}}
+;
+;; This file was generated by cray-site-init.
+;; Version: {{ .Version }}
+;; Generated time: {{ .Timestamp }}
+;
+$ORIGIN {{ .Data.Zone.Name }}.
+$TTL {{ .Data.Settings.TTL }}
+@	IN	SOA	{{ .Data.Settings.Nameserver }}. hostmaster.{{ .Data.Domain }}. (
+	{{ .Data.Settings.Serial }}	; serial
+	3600	; refresh
+	600	; retry
+	604800	; expire
+	{{ .Data.Settings.TTL }}	; minimum
+)
+@	IN	NS	{{ .Data.Settings.Nameserver }}.
+{{- range .Data.Zone.Records }}
+{{ .Name }}	IN	{{ if .Address.Is4 }}A{{ else }}AAAA{{ end }}	{{ .Address }}
+{{- end }}
+{{- range .Data.Zone.Pointers }}
+{{ .Name }}	IN	PTR	{{ .Target }}.
+{{- end }}
+`)
+
+// BINDZonesTemplate declares the BIND zone files in named.conf.
+var BINDZonesTemplate = []byte(`
+{{- /* remove leading whitespace */ -}}
+//
+// This file was generated by cray-site-init.
+// Version: {{ .Version }}
+// Generated time: {{ .Timestamp }}
+//
+{{- range .Data }}
+zone "{{ .Name }}" {
+	type master;
+	file "db.{{ .Name }}";
+};
+{{- end }}
+`)
+
+// UnboundTemplate is the local data of every DNSZone for unbound.conf.
+var UnboundTemplate = []byte(`
+{{- /* remove leading whitespace */ -}}
+#
+## This file was generated by cray-site-init.
+## Version: {{ .Version }}
+## Generated time: {{ .Timestamp }}
+#
+server:
+{{- range $zone := .Data.Export.Forward }}
+	local-zone: "{{ $zone.Name }}." static
+{{- range $zone.Records }}
+	local-data: "{{ .Name }}.{{ $zone.Name }}. {{ $.Data.Settings.TTL }} IN {{ if .Address.Is4 }}A{{ else }}AAAA{{ end }} {{ .Address }}"
+{{- end }}
+{{- end }}
+{{- range .Data.Export.Reverse }}
+{{- range .Pointers }}
+	local-data-ptr: "{{ .Address }} {{ $.Data.Settings.TTL }} {{ .Target }}."
+{{- end }}
+{{- end }}
+`)
+
+// CoreDNSTemplate is a Corefile with a hosts block for every forward DNSZone, and one answering the reverse zones.
+var CoreDNSTemplate = []byte(`
+{{- /* remove leading whitespace */ -}}
+#
+## This file was generated by cray-site-init.
+## Version: {{ .Version }}
+## Generated time: {{ .Timestamp }}
+#
+{{- range .Data.Blocks }}
+{{ .Zones }} {
+    hosts {
+{{- range .Hosts }}
+        {{ . }}
+{{- end }}
+        ttl {{ $.Data.Settings.TTL }}
+{{- if .NoReverse }}
+        no_reverse
+{{- end }}
+    }
+}
+{{- end }}
+`)
+
+// DNSFormats are the formats DNSExport.Write can write.
+var DNSFormats = []string{
+	"bind",
+	"unbound",
+	"coredns",
+}
+
+// DNSSettings are the settings of the written zones.
+type DNSSettings struct {
+	TTL    uint32
+	Serial uint32
+	// Nameserver is the SOA and NS of the BIND zones.
+	Nameserver string
+}
+
+// coreDNSBlock is a server block of a Corefile, each of its Hosts is an address followed by its names.
+type coreDNSBlock struct {
+	Zones     string
+	Hosts     []string
+	NoReverse bool
+}
+
+// Write writes the zones to the path in one of the DNSFormats.
+func (export DNSExport) Write(format string, path string, settings DNSSettings) (err error) {
+	err = os.MkdirAll(
+		path,
+		0755,
+	)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "bind":
+		return export.writeBIND(
+			path,
+			settings,
+		)
+	case "unbound":
+		return writeTemplate(
+			filepath.Join(
+				path,
+				"unbound.conf",
+			),
+			UnboundTemplate,
+			struct {
+				Export   DNSExport
+				Settings DNSSettings
+			}{
+				Export:   export,
+				Settings: settings,
+			},
+		)
+	case "coredns":
+		return export.writeCoreDNS(
+			path,
+			settings,
+		)
+	}
+	return fmt.Errorf(
+		"unsupported DNS format %q, must be one of %s",
+		format,
+		strings.Join(
+			DNSFormats,
+			",",
+		),
+	)
+}
+
+// writeBIND writes a db.<zone> file for every zone, and named.conf.zones declaring them.
+func (export DNSExport) writeBIND(path string, settings DNSSettings) error {
+	zones := append(
+		append(
+			[]DNSZone{},
+			export.Forward...,
+		),
+		export.Reverse...,
+	)
+	for _, zone := range zones {
+		err := writeTemplate(
+			filepath.Join(
+				path,
+				"db."+zone.Name,
+			),
+			BINDZoneTemplate,
+			struct {
+				Domain   string
+				Zone     DNSZone
+				Settings DNSSettings
+			}{
+				Domain:   export.Domain,
+				Zone:     zone,
+				Settings: settings,
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return writeTemplate(
+		filepath.Join(
+			path,
+			"named.conf.zones",
+		),
+		BINDZonesTemplate,
+		zones,
+	)
+}
+
+// writeCoreDNS writes a Corefile, the names of each address of a forward zone are on one line of its hosts block.
+func (export DNSExport) writeCoreDNS(path string, settings DNSSettings) error {
+	var blocks []coreDNSBlock
+	for _, zone := range export.Forward {
+		block := coreDNSBlock{
+			Zones:     zone.Name,
+			NoReverse: true,
+		}
+		names := make(map[string][]string)
+		var addresses []string
+		for _, record := range zone.Records {
+			address := record.Address.String()
+			if _, ok := names[address]; !ok {
+				addresses = append(
+					addresses,
+					address,
+				)
+			}
+			names[address] = append(
+				names[address],
+				record.Name+"."+zone.Name,
+			)
+		}
+		for _, address := range addresses {
+			block.Hosts = append(
+				block.Hosts,
+				address+" "+strings.Join(
+					names[address],
+					" ",
+				),
+			)
+		}
+		blocks = append(
+			blocks,
+			block,
+		)
+	}
+	if len(export.Reverse) > 0 {
+		block := coreDNSBlock{}
+		var zones []string
+		for _, zone := range export.Reverse {
+			zones = append(
+				zones,
+				zone.Name,
+			)
+			for _, pointer := range zone.Pointers {
+				block.Hosts = append(
+					block.Hosts,
+					pointer.Address.String()+" "+pointer.Target,
+				)
+			}
+		}
+		block.Zones = strings.Join(
+			zones,
+			" ",
+		)
+		blocks = append(
+			blocks,
+			block,
+		)
+	}
+	return writeTemplate(
+		filepath.Join(
+			path,
+			"Corefile",
+		),
+		CoreDNSTemplate,
+		struct {
+			Blocks   []coreDNSBlock
+			Settings DNSSettings
+		}{
+			Blocks:   blocks,
+			Settings: settings,
+		},
+	)
+}
+
+// writeTemplate renders a template with the given data to a file.
+func writeTemplate(path string, text []byte, data interface{}) error {
+	tpl := template.Must(template.New(filepath.Base(path)).Parse(string(text)))
+	err := files.WriteTemplate(
+		path,
+		tpl,
+		initialize.MakeTemplateData(
+			data,
+			cli.Runtime,
+		),
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to write %s because %v",
+			path,
+			err,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package export
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/stretchr/testify/suite"
+)
+
+type DNSTestSuite struct {
+	suite.Suite
+	state *slsCommon.SLSState
+}
+
+// network returns an SLS network with a single subnet of the given reservations.
+func network(name string, reservations ...slsCommon.IPReservation) slsCommon.Network {
+	return slsCommon.Network{
+		Name: name,
+		ExtraPropertiesRaw: slsCommon.NetworkExtraProperties{
+			Subnets: []slsCommon.IPSubnet{
+				{
+					Name:           "subnet",
+					IPReservations: reservations,
+				},
+			},
+		},
+	}
+}
+
+func (suite *DNSTestSuite) SetupTest() {
+	suite.state = &slsCommon.SLSState{
+		Networks: map[string]slsCommon.Network{
+			"NMN": network(
+				"NMN",
+				slsCommon.IPReservation{
+					Name:       "ncn-m001",
+					IPAddress:  net.ParseIP("10.252.1.10"),
+					IPAddress6: net.ParseIP("fd00:252::a"),
+					Aliases: []string{
+						"ncn-m001-nmn",
+						"time-nmn.local",
+					},
+				},
+				slsCommon.IPReservation{
+					Name:      "x3000c0s1b0n0",
+					IPAddress: net.ParseIP("10.252.1.10"),
+				},
+			),
+			"NMNLB": network(
+				"NMNLB",
+				slsCommon.IPReservation{
+					Name:      "istio-ingressgateway",
+					IPAddress: net.ParseIP("10.92.100.71"),
+					Aliases: []string{
+						"api_gw_service",
+						"packages",
+					},
+				},
+			),
+			"NMN_MTN": network(
+				"NMN_MTN",
+				slsCommon.IPReservation{
+					Name:      "packages",
+					IPAddress: net.ParseIP("10.100.0.5"),
+				},
+			),
+			"HMN": network(
+				"HMN",
+				slsCommon.IPReservation{
+					Name:      "ncn-m001-mgmt",
+					IPAddress: net.ParseIP("10.254.1.10"),
+				},
+			),
+		},
+	}
+}
+
+func (suite *DNSTestSuite) TestDNSSuffix() {
+	for network, suffix := range map[string]string{
+		"NMN":     "nmn",
+		"NMNLB":   "nmn",
+		"NMN_MTN": "nmn",
+		"HMN_RVR": "hmn",
+		"CMN":     "cmn",
+		"MTL":     "mtl",
+	} {
+		suite.Equal(
+			suffix,
+			DNSSuffix(network),
+			network,
+		)
+	}
+}
+
+func (suite *DNSTestSuite) TestReverseZone() {
+	zone, name := ReverseZone(netip.MustParseAddr("10.252.1.10"))
+	suite.Equal(
+		"1.252.10.in-addr.arpa",
+		zone,
+	)
+	suite.Equal(
+		"10",
+		name,
+	)
+
+	zone, name = ReverseZone(netip.MustParseAddr("fd00:252::a"))
+	suite.Equal(
+		"0.0.0.0.0.0.0.0.2.5.2.0.0.0.d.f.ip6.arpa",
+		zone,
+	)
+	suite.Equal(
+		"a.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0",
+		name,
+	)
+}
+
+func (suite *DNSTestSuite) TestNewDNSExport() {
+	export, err := NewDNSExport(
+		suite.state,
+		"eniac.example.com.",
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"eniac.example.com",
+		export.Domain,
+	)
+	suite.Require().Len(
+		export.Forward,
+		2,
+	)
+	hmn, nmn := export.Forward[0], export.Forward[1]
+	suite.Equal(
+		"hmn.eniac.example.com",
+		hmn.Name,
+	)
+	suite.Equal(
+		"nmn.eniac.example.com",
+		nmn.Name,
+	)
+	suite.Equal(
+		[]DNSRecord{
+			{
+				Name:    "istio-ingressgateway",
+				Address: netip.MustParseAddr("10.92.100.71"),
+			},
+			{
+				Name:    "ncn-m001",
+				Address: netip.MustParseAddr("10.252.1.10"),
+			},
+			{
+				Name:    "ncn-m001",
+				Address: netip.MustParseAddr("fd00:252::a"),
+			},
+			{
+				Name:    "ncn-m001-nmn",
+				Address: netip.MustParseAddr("10.252.1.10"),
+			},
+			{
+				Name:    "ncn-m001-nmn",
+				Address: netip.MustParseAddr("fd00:252::a"),
+			},
+			{
+				Name:    "packages",
+				Address: netip.MustParseAddr("10.92.100.71"),
+			},
+			{
+				Name:    "x3000c0s1b0n0",
+				Address: netip.MustParseAddr("10.252.1.10"),
+			},
+		},
+		nmn.Records,
+	)
+
+	// The reservation names are given PTR records, the first name of an address keeps it.
+	var pointers []DNSPointer
+	for _, zone := range export.Reverse {
+		pointers = append(
+			pointers,
+			zone.Pointers...,
+		)
+	}
+	suite.Contains(
+		pointers,
+		DNSPointer{
+			Name:    "10",
+			Address: netip.MustParseAddr("10.252.1.10"),
+			Target:  "ncn-m001.nmn.eniac.example.com",
+		},
+	)
+	suite.Len(
+		pointers,
+		4,
+	)
+
+	suite.Equal(
+		[]DNSCollision{
+			{
+				Name: "packages.nmn.eniac.example.com",
+				Networks: []string{
+					"NMNLB",
+					"NMN_MTN",
+				},
+			},
+		},
+		export.Collisions,
+	)
+	suite.Equal(
+		[]string{
+			"api_gw_service",
+			"time-nmn.local",
+		},
+		export.Skipped,
+	)
+}
+
+// readFile returns the contents of a written file.
+func (suite *DNSTestSuite) readFile(path ...string) string {
+	contents, err := os.ReadFile(filepath.Join(path...))
+	suite.Require().NoError(err)
+	return string(contents)
+}
+
+func (suite *DNSTestSuite) TestWrite() {
+	export, err := NewDNSExport(
+		suite.state,
+		"eniac.example.com",
+	)
+	suite.Require().NoError(err)
+	settings := DNSSettings{
+		TTL:        300,
+		Serial:     1,
+		Nameserver: "ns.eniac.example.com",
+	}
+	path := suite.T().TempDir()
+
+	suite.Require().NoError(
+		export.Write(
+			"bind",
+			path,
+			settings,
+		),
+	)
+	zone := suite.readFile(
+		path,
+		"db.nmn.eniac.example.com",
+	)
+	suite.Contains(
+		zone,
+		"$ORIGIN nmn.eniac.example.com.\n$TTL 300\n",
+	)
+	suite.Contains(
+		zone,
+		"ncn-m001\tIN\tAAAA\tfd00:252::a\n",
+	)
+	suite.Contains(
+		suite.readFile(
+			path,
+			"db.1.252.10.in-addr.arpa",
+		),
+		"10\tIN\tPTR\tncn-m001.nmn.eniac.example.com.\n",
+	)
+	suite.Contains(
+		suite.readFile(
+			path,
+			"named.conf.zones",
+		),
+		"zone \"1.254.10.in-addr.arpa\" {\n\ttype master;\n\tfile \"db.1.254.10.in-addr.arpa\";\n};\n",
+	)
+
+	suite.Require().NoError(
+		export.Write(
+			"unbound",
+			path,
+			settings,
+		),
+	)
+	unbound := suite.readFile(
+		path,
+		"unbound.conf",
+	)
+	suite.Contains(
+		unbound,
+		"\tlocal-zone: \"hmn.eniac.example.com.\" static\n",
+	)
+	suite.Contains(
+		unbound,
+		"\tlocal-data: \"ncn-m001-mgmt.hmn.eniac.example.com. 300 IN A 10.254.1.10\"\n",
+	)
+	suite.Contains(
+		unbound,
+		"\tlocal-data-ptr: \"10.92.100.71 300 istio-ingressgateway.nmn.eniac.example.com.\"\n",
+	)
+
+	suite.Require().NoError(
+		export.Write(
+			"coredns",
+			path,
+			settings,
+		),
+	)
+	corefile := suite.readFile(
+		path,
+		"Corefile",
+	)
+	suite.Contains(
+		corefile,
+		"        10.252.1.10 ncn-m001.nmn.eniac.example.com ncn-m001-nmn.nmn.eniac.example.com x3000c0s1b0n0.nmn.eniac.example.com\n",
+	)
+	suite.Contains(
+		corefile,
+		"        ttl 300\n        no_reverse\n",
+	)
+
+	suite.ErrorContains(
+		export.Write(
+			"dnsmasq",
+			path,
+			settings,
+		),
+		`unsupported DNS format "dnsmasq"`,
+	)
+}
+
+func TestDNSTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(DNSTestSuite),
+	)
+}
@@ -0,0 +1,47 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package export
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewCommand represents the export command.
+func NewCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "export",
+		Short: "Exports a generated system directory for other tools",
+		Long: `Exports what a system directory generated by 'csi config init' describes in the formats of other tools, so
+	their configuration does not have to be maintained by hand.
+	`,
+		DisableAutoGenTag: true,
+		Args:              cobra.MinimumNArgs(1),
+	}
+	c.AddCommand(
+		newAnsibleCommand(),
+		newDNSCommand(),
+	)
+	return c
+}
@@ -0,0 +1,946 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/Cray-HPE/hms-xname/xnames"
+	"github.com/Cray-HPE/hms-xname/xnametypes"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+	slsInit "github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/verify"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/networking"
+)
+
+// BasecampDataFile is the cloud-init data file in a system directory.
+const BasecampDataFile = "basecamp/data.json"
+
+// CabinetAddition holds the SLS state and networks of a system after new cabinets were added to it.
+type CabinetAddition struct {
+	SLSState *slsCommon.SLSState
+	Networks map[string]*networking.IPNetwork
+	// Subnets are the added cabinet subnets, as <network>/cabinet_<id>.
+	Subnets []string
+	// Hardware are the xnames of the added SLS hardware.
+	Hardware []string
+	// StaticRoutes are the NCN ifroute files, including the routes to the added cabinet subnets.
+	StaticRoutes []networking.WriteFiles
+	addedSubnets map[string][]slsCommon.IPSubnet
+	signingKey   string
+}
+
+// NewAddCabinetCommand represents the add-cabinet command.
+func NewAddCabinetCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "add-cabinet SYSTEM_DIR",
+		Short: "Adds cabinets to an existing system configuration",
+		Long: `Adds River, Hill, and Mountain cabinets to a system directory generated by 'csi config init'.
+
+	The sls_input_file.json and system_config.yaml of the system directory are loaded, and each cabinet given in the
+	--cabinets-yaml file(s) is added to them:
+	1. A cabinet_<id> subnet is allocated in the NMN and HMN (or their _MTN and _RVR groups), on a VLAN that no other
+	   network uses and that is not among the reserved-vlans of system_config.yaml. A network with an IPv6 CIDR gives
+	   the cabinet a /118 alongside its /22, like config init
+	2. The SLS cabinet, chassis, and compute node hardware is added
+	3. The NCN static routes in basecamp/data.json are updated with the new cabinet subnets
+	4. The VLANs of the new cabinet subnets are added to vlans.json, and manifest.json is updated with the new
+	   sha256 of every file. A signed manifest is signed again with --manifest-signing-key, which is then required
+
+	Every existing subnet, VLAN, and IP reservation is left untouched.
+
+	** NB **
+	system_config.yaml is not updated. To re-initialize the system later on, add the new cabinets to its cabinets-yaml
+	and pass the system directory to 'csi config init --previous-sls'.
+	** NB **
+	`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(c *cobra.Command, args []string) {
+			basepath := args[0]
+			v := viper.New()
+			v.SetConfigFile(
+				filepath.Join(
+					basepath,
+					defaultConfigFilename,
+				),
+			)
+			err := v.ReadInConfig()
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: Unable to read the system config because %v",
+					err,
+				)
+			}
+
+			cabinetFiles, err := c.Flags().GetStringSlice("cabinets-yaml")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			signingKey, err := c.Flags().GetString("manifest-signing-key")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			v.Set(
+				"manifest-signing-key",
+				signingKey,
+			)
+			cabinets, err := LoadCabinetGroups(cabinetFiles)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: %v",
+					err,
+				)
+			}
+
+			state, err := LoadPreviousSLS(basepath)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: %v",
+					err,
+				)
+			}
+
+			addition, err := AddCabinets(
+				v,
+				state,
+				cabinets,
+			)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: %v",
+					err,
+				)
+			}
+			err = addition.Write(basepath)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: %v",
+					err,
+				)
+			}
+
+			for _, subnet := range addition.Subnets {
+				log.Printf(
+					"Added subnet %s\n",
+					subnet,
+				)
+			}
+			log.Printf(
+				"Added %d SLS hardware entries\n",
+				len(addition.Hardware),
+			)
+		},
+	}
+	c.Flags().StringSlice(
+		"cabinets-yaml",
+		[]string{},
+		"YAML file(s) listing the new cabinets, in the same format as config init's --cabinets-yaml",
+	)
+	c.Flags().String(
+		"manifest-signing-key",
+		"",
+		"Path to the ed25519 private key (PEM) to sign the updated manifest.json with",
+	)
+	_ = c.MarkFlagRequired("cabinets-yaml")
+	return c
+}
+
+// LoadCabinetGroups reads the cabinet groups of one or more cabinets-yaml files, merging the groups of the same kind.
+func LoadCabinetGroups(paths []string) (cabinets []sls.CabinetGroupDetail, err error) {
+	groups := make(map[sls.CabinetKind]*sls.CabinetGroupDetail)
+	for _, path := range paths {
+		cabDetailFile, err := sls.LoadCabinetDetailFile(path)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to parse cabinets-yaml file [%s] because %v",
+				path,
+				err,
+			)
+		}
+		for _, group := range cabDetailFile.Cabinets {
+			group.Cabinets = group.Length()
+			group.PopulateIds()
+			if _, ok := groups[group.Kind]; !ok {
+				groups[group.Kind] = &sls.CabinetGroupDetail{
+					Kind: group.Kind,
+				}
+			}
+			groups[group.Kind].CabinetDetails = append(
+				groups[group.Kind].CabinetDetails,
+				group.CabinetDetails...,
+			)
+		}
+	}
+	for _, kind := range sls.ValidCabinetTypes {
+		group, ok := groups[kind]
+		if !ok {
+			continue
+		}
+		group.Cabinets = len(group.CabinetDetails)
+		cabinets = append(
+			cabinets,
+			*group,
+		)
+		delete(
+			groups,
+			kind,
+		)
+	}
+	for kind := range groups {
+		return nil, fmt.Errorf(
+			"unknown cabinet kind (%s)",
+			kind,
+		)
+	}
+	return cabinets, nil
+}
+
+/*
+AddCabinets adds the given cabinets to an existing SLS state. Each cabinet is given a cabinet_<id> subnet in the NMN and
+HMN networks it belongs in, and its SLS cabinet and chassis hardware. The existing subnets, VLANs, IP reservations, and
+hardware of the SLS state are left untouched, a cabinet that already exists is an error.
+*/
+func AddCabinets(v *viper.Viper, state *slsCommon.SLSState, cabinets []sls.CabinetGroupDetail) (
+	addition *CabinetAddition, err error,
+) {
+	knownCabinetIDs := make(map[int]bool)
+	for _, cabinetGroupDetail := range cabinets {
+		for _, id := range cabinetGroupDetail.CabinetIDs() {
+			xname := xnames.Cabinet{
+				Cabinet: id,
+			}.String()
+			if _, exists := state.Hardware[xname]; exists {
+				return nil, fmt.Errorf(
+					"cabinet %s already exists in the SLS state",
+					xname,
+				)
+			}
+			if knownCabinetIDs[id] {
+				return nil, fmt.Errorf(
+					"found duplicate cabinet id: %v",
+					id,
+				)
+			}
+			knownCabinetIDs[id] = true
+		}
+	}
+
+	shastaNetworks, extraProperties, err := networksFromSLS(state)
+	if err != nil {
+		return nil, err
+	}
+
+	addedSubnets := make(map[string][]slsCommon.IPSubnet)
+	addition = &CabinetAddition{
+		SLSState:     state,
+		Networks:     shastaNetworks,
+		addedSubnets: addedSubnets,
+		signingKey:   v.GetString("manifest-signing-key"),
+	}
+	for _, cabinetGroupDetail := range cabinets {
+		for _, cabinetDetail := range cabinetGroupDetail.CabinetDetails {
+			networkNames, err := cabinetNetworks(
+				shastaNetworks,
+				cabinetGroupDetail,
+				cabinetDetail,
+			)
+			if err != nil {
+				return nil, err
+			}
+			for _, networkName := range networkNames {
+				vlans, err := cabinetVLANAllocator(
+					v,
+					shastaNetworks,
+					networkName,
+				)
+				if err != nil {
+					return nil, err
+				}
+				subnet, err := addCabinetSubnet(
+					shastaNetworks[networkName],
+					cabinetDetail,
+					vlans,
+				)
+				if err != nil {
+					return nil, err
+				}
+				addedSubnets[networkName] = append(
+					addedSubnets[networkName],
+					*subnet,
+				)
+				addition.Subnets = append(
+					addition.Subnets,
+					fmt.Sprintf(
+						"%s/%s (%s, VLAN %d)",
+						networkName,
+						subnet.Name,
+						subnet.CIDR,
+						subnet.VlanID,
+					),
+				)
+			}
+		}
+	}
+
+	// Only the networks given a cabinet subnet are rewritten, and only by appending to them.
+	for _, name := range slices.Sorted(maps.Keys(addedSubnets)) {
+		network := state.Networks[name]
+		networkExtraProperties := extraProperties[name]
+		networkExtraProperties.Subnets = append(
+			networkExtraProperties.Subnets,
+			addedSubnets[name]...,
+		)
+		networkExtraProperties.VlanRange = shastaNetworks[name].VlanRange
+		network.ExtraPropertiesRaw = networkExtraProperties
+		state.Networks[name] = network
+	}
+
+	startingNid, err := nextMountainNID(
+		v,
+		state,
+	)
+	if err != nil {
+		return nil, err
+	}
+	slsCabinetMap := slsInit.GenCabinetMap(
+		cabinets,
+		shastaNetworks,
+	)
+	cabinetState := slsInit.GenerateSLSState(
+		slsInit.GeneratorInputState{
+			RiverCabinets:       slsCabinetMap[slsCommon.ClassRiver],
+			HillCabinets:        slsCabinetMap[slsCommon.ClassHill],
+			MountainCabinets:    slsCabinetMap[slsCommon.ClassMountain],
+			MountainStartingNid: startingNid,
+		},
+		nil,
+	)
+	for _, xname := range slices.Sorted(maps.Keys(cabinetState.Hardware)) {
+		if _, exists := state.Hardware[xname]; exists {
+			return nil, fmt.Errorf(
+				"hardware %s already exists in the SLS state",
+				xname,
+			)
+		}
+		state.Hardware[xname] = cabinetState.Hardware[xname]
+		addition.Hardware = append(
+			addition.Hardware,
+			xname,
+		)
+	}
+
+	addition.StaticRoutes, err = getNCNStaticRoutes(
+		v,
+		shastaNetworks,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return addition, nil
+}
+
+/*
+Render renders the files of the given system directory the cabinets are added to, from their current contents: the SLS
+state, the static routes in the cloud-init data, the VLAN report, and the manifest.
+*/
+func (addition *CabinetAddition) Render(basepath string) (files.Tree, error) {
+	tree := make(files.Tree)
+	err := tree.JSON(
+		slsInit.OutputFile,
+		addition.SLSState,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to encode SLS state because %v",
+			err,
+		)
+	}
+	err = updateStaticRoutes(
+		tree,
+		basepath,
+		addition.StaticRoutes,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = addition.updateVLANReport(
+		tree,
+		basepath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = renderManifest(
+		tree,
+		basepath,
+		addition.signingKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Write adds the cabinets to the SLS state, cloud-init data, VLAN report, and manifest of the given system directory.
+func (addition *CabinetAddition) Write(basepath string) error {
+	tree, err := addition.Render(basepath)
+	if err != nil {
+		return err
+	}
+	return tree.Replace(basepath)
+}
+
+// updateVLANReport renders vlans.json with the VLANs of the added cabinet subnets. A system directory without a VLAN
+// report is left without one.
+func (addition *CabinetAddition) updateVLANReport(tree files.Tree, basepath string) error {
+	path := filepath.Join(
+		basepath,
+		VLANsFile,
+	)
+	if _, err := os.Stat(path); errors.Is(
+		err,
+		fs.ErrNotExist,
+	) {
+		return nil
+	}
+	vlans := networking.NewVLANAllocator()
+	err := files.ReadJSONConfig(
+		path,
+		vlans,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to read %s because %v",
+			path,
+			err,
+		)
+	}
+	for _, name := range slices.Sorted(maps.Keys(addition.addedSubnets)) {
+		for _, subnet := range addition.addedSubnets[name] {
+			err = vlans.Assign(
+				subnet.VlanID,
+				name,
+				subnet.Name,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = tree.JSON(
+		VLANsFile,
+		vlans,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to encode VLAN report because %v",
+			err,
+		)
+	}
+	return nil
+}
+
+/*
+renderManifest renders the manifest of the system directory at basepath, with the files of the tree replacing its own,
+and signs it with the key at keyFile if one is given. A signed manifest must be signed again, its signature would no
+longer match.
+*/
+func renderManifest(tree files.Tree, basepath string, keyFile string) error {
+	manifest, err := verify.UpdateTreeManifest(
+		basepath,
+		tree,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to hash the system directory because %v",
+			err,
+		)
+	}
+	err = manifest.Render(tree)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to encode %s because %v",
+			verify.ManifestFile,
+			err,
+		)
+	}
+	if keyFile == "" {
+		if _, err := os.Stat(
+			filepath.Join(
+				basepath,
+				verify.SignatureFile,
+			),
+		); err == nil {
+			return fmt.Errorf(
+				"%s is signed, a manifest-signing-key is needed to sign it again",
+				verify.ManifestFile,
+			)
+		}
+		return nil
+	}
+	err = verify.SignTree(
+		tree,
+		keyFile,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to sign %s because %v",
+			verify.ManifestFile,
+			err,
+		)
+	}
+	return nil
+}
+
+// networksFromSLS returns the IPNetworks of an SLS state, along with the extra properties they were made from.
+func networksFromSLS(state *slsCommon.SLSState) (
+	shastaNetworks map[string]*networking.IPNetwork, extraProperties map[string]slsCommon.NetworkExtraProperties,
+	err error,
+) {
+	extraProperties = make(map[string]slsCommon.NetworkExtraProperties)
+	shastaNetworks = make(map[string]*networking.IPNetwork)
+	for name, network := range state.Networks {
+		extraProperties[name], err = sls.UnmarshalNetworkExtraProperties(&network)
+		if err != nil {
+			return nil, nil, err
+		}
+		shastaNetworks[name] = ipNetworkFromSLS(
+			network,
+			extraProperties[name],
+		)
+	}
+	return shastaNetworks, extraProperties, nil
+}
+
+// ipNetworkFromSLS returns the IPNetwork of an SLS network, with copies of its subnets.
+func ipNetworkFromSLS(network slsCommon.Network, extraProperties slsCommon.NetworkExtraProperties) *networking.IPNetwork {
+	subnets := make(
+		[]*slsCommon.IPSubnet,
+		len(extraProperties.Subnets),
+	)
+	for i, subnet := range extraProperties.Subnets {
+		subnets[i] = &subnet
+	}
+	return &networking.IPNetwork{
+		Name:               network.Name,
+		FullName:           network.FullName,
+		CIDR4:              extraProperties.CIDR,
+		CIDR6:              extraProperties.CIDR6,
+		Subnets:            subnets,
+		VlanRange:          slices.Clone(extraProperties.VlanRange),
+		MTU:                extraProperties.MTU,
+		NetType:            network.Type,
+		Comment:            extraProperties.Comment,
+		PeerASN:            extraProperties.PeerASN,
+		MyASN:              extraProperties.MyASN,
+		SystemDefaultRoute: extraProperties.SystemDefaultRoute,
+	}
+}
+
+/*
+cabinetNetworks returns the networks a cabinet is given a subnet in. These are the _RVR and _MTN groups of the NMN and
+HMN, following the same cabinet filters as config init. Systems without these groups have their cabinet subnets in the
+NMN and HMN themselves.
+*/
+func cabinetNetworks(
+	shastaNetworks map[string]*networking.IPNetwork, cabinetGroupDetail sls.CabinetGroupDetail,
+	cabinetDetail sls.CabinetDetail,
+) (
+	networkNames []string, err error,
+) {
+	class, err := cabinetGroupDetail.Kind.Class()
+	if err != nil {
+		return nil, err
+	}
+	var groups []string
+	if sls.OrCabinetFilter(
+		sls.CabinetClassFilter(slsCommon.ClassRiver),
+		sls.AndCabinetFilter(
+			sls.CabinetKindFilter(sls.CabinetKindEX2500),
+			sls.CabinetAirCooledChassisCountFilter(1),
+		),
+	)(
+		cabinetGroupDetail,
+		cabinetDetail,
+	) {
+		groups = append(
+			groups,
+			"_RVR",
+		)
+	}
+	if class == slsCommon.ClassMountain || class == slsCommon.ClassHill {
+		groups = append(
+			groups,
+			"_MTN",
+		)
+	}
+
+	for _, prefix := range []string{
+		"NMN",
+		"HMN",
+	} {
+		grouped := shastaNetworks[prefix+"_MTN"] != nil || shastaNetworks[prefix+"_RVR"] != nil
+		if !grouped {
+			if shastaNetworks[prefix] == nil {
+				return nil, fmt.Errorf(
+					"the SLS state has no %s network",
+					prefix,
+				)
+			}
+			networkNames = append(
+				networkNames,
+				prefix,
+			)
+			continue
+		}
+		for _, group := range groups {
+			if shastaNetworks[prefix+group] == nil {
+				return nil, fmt.Errorf(
+					"the SLS state has no %s network for %s cabinet %d, re-initialize the system with --previous-sls to add it",
+					prefix+group,
+					cabinetGroupDetail.Kind,
+					cabinetDetail.ID,
+				)
+			}
+			networkNames = append(
+				networkNames,
+				prefix+group,
+			)
+		}
+	}
+	return networkNames, nil
+}
+
+// addCabinetSubnet allocates the cabinet_<id> subnet of a cabinet in the first free space of the network, and a VLAN
+// for it that is free in the network and in the given VLANAllocator.
+func addCabinetSubnet(
+	network *networking.IPNetwork, cabinetDetail sls.CabinetDetail, vlans *networking.VLANAllocator,
+) (
+	subnet *slsCommon.IPSubnet, err error,
+) {
+	name := fmt.Sprintf(
+		"cabinet_%d",
+		cabinetDetail.ID,
+	)
+	if _, err := network.LookUpSubnet(name); err == nil {
+		return nil, fmt.Errorf(
+			"the %s network already has a %s subnet",
+			network.Name,
+			name,
+		)
+	}
+	requestedVlan := cabinetDetail.HMNVlanID
+	if strings.HasPrefix(
+		network.Name,
+		"NMN",
+	) {
+		requestedVlan = cabinetDetail.NMNVlanID
+	}
+	vlanID, err := nextCabinetVLAN(
+		network,
+		requestedVlan,
+		vlans,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like config init, the cabinet subnets are given as many IPv6 addresses as IPv4 addresses.
+	var mask6 net.IPMask
+	if network.CIDR6 != "" {
+		mask6 = networking.IPv6MaskFor(networking.DefaultCabinetMask)
+	}
+	subnet, err = network.CreateSubnetByMask(
+		networking.DefaultCabinetMask,
+		mask6,
+		name,
+		vlanID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"couldn't add %s to the %s network because %v",
+			name,
+			network.Name,
+			err,
+		)
+	}
+	// The cabinet subnets config init generates have no full name.
+	subnet.FullName = ""
+	err = networking.UpdateDHCPRange(
+		subnet,
+		false,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"couldn't update DHCP range for %s because %v",
+			name,
+			err,
+		)
+	}
+
+	// Only widen a well-formed VLAN range, leaving any other range as config init wrote it.
+	if len(network.VlanRange) == 2 && network.VlanRange[0] <= network.VlanRange[1] {
+		network.VlanRange[0] = min(
+			network.VlanRange[0],
+			vlanID,
+		)
+		network.VlanRange[1] = max(
+			network.VlanRange[1],
+			vlanID,
+		)
+	}
+	return subnet, nil
+}
+
+/*
+cabinetVLANAllocator returns a VLANAllocator holding the VLANs of the system, for allocating the VLAN of a cabinet
+subnet in the given network. The VLAN ranges and subnet VLANs of every other network, and the system's reserved-vlans,
+are reserved. The VLANs of the given network itself are left to nextCabinetVLAN.
+*/
+func cabinetVLANAllocator(v *viper.Viper, shastaNetworks map[string]*networking.IPNetwork, network string) (
+	vlans *networking.VLANAllocator, err error,
+) {
+	vlans = networking.NewVLANAllocator()
+	for _, reserved := range v.GetStringSlice("reserved-vlans") {
+		start, end, err := networking.ParseVLANRange(reserved)
+		if err != nil {
+			return nil, err
+		}
+		err = vlans.Reserve(
+			start,
+			end,
+			"reserved-vlans",
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(shastaNetworks)) {
+		if name == network {
+			continue
+		}
+		reason := fmt.Sprintf(
+			"used by the %s network",
+			name,
+		)
+		otherNetwork := shastaNetworks[name]
+		// A VLAN range that is not well-formed only covers the VLANs of its subnets.
+		if len(otherNetwork.VlanRange) > 0 {
+			start := otherNetwork.VlanRange[0]
+			end := otherNetwork.VlanRange[len(otherNetwork.VlanRange)-1]
+			if start > networking.MinVLAN && start <= end && end <= networking.MaxUsableVLAN {
+				err = vlans.Reserve(
+					uint16(start),
+					uint16(end),
+					reason,
+				)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, vlan := range otherNetwork.AllocatedVLANs() {
+			if vlan > networking.MaxUsableVLAN {
+				continue
+			}
+			err = vlans.Reserve(
+				uint16(vlan),
+				uint16(vlan),
+				reason,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return vlans, nil
+}
+
+/*
+nextCabinetVLAN returns the requested VLAN if it is free in the network and in the VLANAllocator, or the first VLAN
+after the highest one in use in the network that is free in both. The VLAN is allocated for the network.
+*/
+func nextCabinetVLAN(network *networking.IPNetwork, requested int16, vlans *networking.VLANAllocator) (
+	vlanID int16, err error,
+) {
+	allocated := network.AllocatedVLANs()
+	if requested != 0 {
+		if slices.Contains(
+			allocated,
+			requested,
+		) {
+			return 0, fmt.Errorf(
+				"VLAN %d is already in use in the %s network",
+				requested,
+				network.Name,
+			)
+		}
+		err = vlans.Allocate(
+			uint16(requested),
+			network.Name,
+		)
+		if err != nil {
+			return 0, fmt.Errorf(
+				"VLAN %d can not be used in the %s network because %v",
+				requested,
+				network.Name,
+				err,
+			)
+		}
+		return requested, nil
+	}
+
+	vlanID = networking.FirstVLAN
+	switch {
+	case len(allocated) > 0:
+		vlanID = slices.Max(allocated) + 1
+	case len(network.VlanRange) > 0 && network.VlanRange[0] > networking.MinVLAN:
+		vlanID = network.VlanRange[0]
+	}
+	for ; vlanID <= networking.MaxUsableVLAN; vlanID++ {
+		if slices.Contains(
+			allocated,
+			vlanID,
+		) {
+			continue
+		}
+		if vlans.Allocate(
+			uint16(vlanID),
+			network.Name,
+		) == nil {
+			return vlanID, nil
+		}
+	}
+	return 0, fmt.Errorf(
+		"no usable VLAN is left for a cabinet in the %s network",
+		network.Name,
+	)
+}
+
+// nextMountainNID returns the first NID after the liquid-cooled compute nodes of the SLS state, and no lower than the
+// system's starting-mountain-nid.
+func nextMountainNID(v *viper.Viper, state *slsCommon.SLSState) (nid int, err error) {
+	nid = v.GetInt("starting-mountain-nid")
+	for _, hardware := range state.Hardware {
+		if hardware.TypeString != xnametypes.Node {
+			continue
+		}
+		if hardware.Class != slsCommon.ClassMountain && hardware.Class != slsCommon.ClassHill {
+			continue
+		}
+		extraProperties, err := sls.UnmarshalComptypeNode(&hardware)
+		if err != nil {
+			return 0, err
+		}
+		if extraProperties.NID >= nid {
+			nid = extraProperties.NID + 1
+		}
+	}
+	return nid, nil
+}
+
+// updateStaticRoutes renders data.json with the NCN ifroute files replaced by the given ones, adding the ones an NCN
+// does not have yet.
+func updateStaticRoutes(tree files.Tree, basepath string, staticRoutes []networking.WriteFiles) error {
+	path := filepath.Join(
+		basepath,
+		BasecampDataFile,
+	)
+	var data map[string]map[string]interface{}
+	err := files.ReadJSONConfig(
+		path,
+		&data,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to read %s because %v",
+			path,
+			err,
+		)
+	}
+
+	routes := make(map[string]networking.WriteFiles)
+	for _, route := range staticRoutes {
+		routes[route.Path] = route
+	}
+	for name, cloudInit := range data {
+		if name == "Global" {
+			continue
+		}
+		userData, ok := cloudInit["user-data"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		writeFiles, _ := userData["write_files"].([]interface{})
+		replaced := make(map[string]bool)
+		for i, writeFile := range writeFiles {
+			writeFile, ok := writeFile.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			filePath, _ := writeFile["path"].(string)
+			if route, ok := routes[filePath]; ok {
+				writeFiles[i] = route
+				replaced[filePath] = true
+			}
+		}
+		for _, route := range staticRoutes {
+			if !replaced[route.Path] {
+				writeFiles = append(
+					writeFiles,
+					route,
+				)
+			}
+		}
+		userData["write_files"] = writeFiles
+	}
+
+	err = tree.JSON(
+		BasecampDataFile,
+		data,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to encode data.json because %v",
+			err,
+		)
+	}
+	return nil
+}
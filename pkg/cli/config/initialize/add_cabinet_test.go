@@ -0,0 +1,423 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/verify"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/networking"
+)
+
+const newCabinetsYAML = `cabinets:
+  - type: mountain
+    cabinets:
+      - id: 1004
+  - type: river
+    cabinets:
+      - id: 3001
+        nmn-vlan: 1800
+`
+
+type AddCabinetTestSuite struct {
+	suite.Suite
+	basepath string
+	v        *viper.Viper
+}
+
+// generateSystemDir writes the system directory of the generate fixtures to a temporary directory.
+func generateSystemDir(suite *suite.Suite) (v *viper.Viper, basepath string) {
+	viper.Reset()
+	v = viper.GetViper()
+	suite.Require().NoError(v.BindPFlags(NewCommand().Flags()))
+	v.SetConfigFile(
+		filepath.Join(
+			generateFixtureDir,
+			"system_config.yaml",
+		),
+	)
+	suite.Require().NoError(v.ReadInConfig())
+
+	inputs, err := CollectInputs(v)
+	suite.Require().NoError(err)
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+	basepath = filepath.Join(
+		suite.T().TempDir(),
+		outputs.SystemName,
+	)
+	suite.Require().NoError(outputs.Write(basepath))
+	return v, basepath
+}
+
+func (suite *AddCabinetTestSuite) SetupTest() {
+	suite.v, suite.basepath = generateSystemDir(&suite.Suite)
+}
+
+func (suite *AddCabinetTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+func (suite *AddCabinetTestSuite) cabinets() []sls.CabinetGroupDetail {
+	path := filepath.Join(
+		suite.T().TempDir(),
+		"cabinets.yaml",
+	)
+	suite.Require().NoError(
+		os.WriteFile(
+			path,
+			[]byte(newCabinetsYAML),
+			0644,
+		),
+	)
+	cabinets, err := LoadCabinetGroups([]string{path})
+	suite.Require().NoError(err)
+	return cabinets
+}
+
+// slsSubnets returns every subnet in the SLS state, keyed by network and subnet.
+func slsSubnets(suite *suite.Suite, state *slsCommon.SLSState) map[string]slsCommon.IPSubnet {
+	subnets := make(map[string]slsCommon.IPSubnet)
+	for name, network := range state.Networks {
+		extraProperties, err := sls.UnmarshalNetworkExtraProperties(&network)
+		suite.Require().NoError(err)
+		for _, subnet := range extraProperties.Subnets {
+			subnets[name+"/"+subnet.Name] = subnet
+		}
+	}
+	return subnets
+}
+
+func (suite *AddCabinetTestSuite) TestAddCabinets() {
+	previous, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+	state, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+
+	addition, err := AddCabinets(
+		suite.v,
+		state,
+		suite.cabinets(),
+	)
+	suite.Require().NoError(err)
+	suite.Len(
+		addition.Subnets,
+		4,
+	)
+
+	// Every existing subnet is kept as it was.
+	subnets := slsSubnets(
+		&suite.Suite,
+		state,
+	)
+	for name, subnet := range slsSubnets(
+		&suite.Suite,
+		previous,
+	) {
+		suite.Equal(
+			subnet,
+			subnets[name],
+			name,
+		)
+	}
+	suite.Equal(
+		"10.100.16.0/22",
+		subnets["NMN_MTN/cabinet_1004"].CIDR,
+	)
+	suite.Equal(
+		int16(2004),
+		subnets["NMN_MTN/cabinet_1004"].VlanID,
+	)
+	suite.Equal(
+		int16(1800),
+		subnets["NMN_RVR/cabinet_3001"].VlanID,
+	)
+	suite.Equal(
+		"10.107.4.1",
+		subnets["HMN_RVR/cabinet_3001"].Gateway.String(),
+	)
+	for xname := range previous.Hardware {
+		suite.Contains(
+			state.Hardware,
+			xname,
+		)
+	}
+
+	// New compute nodes are numbered after the existing ones.
+	hardware := state.Hardware["x1004c0s0b0n0"]
+	node, err := sls.UnmarshalComptypeNode(&hardware)
+	suite.Require().NoError(err)
+	suite.Equal(
+		2024,
+		node.NID,
+	)
+	suite.Contains(
+		state.Hardware,
+		"x3001",
+	)
+
+	suite.Require().NoError(addition.Write(suite.basepath))
+	data, err := os.ReadFile(
+		filepath.Join(
+			suite.basepath,
+			BasecampDataFile,
+		),
+	)
+	suite.Require().NoError(err)
+	suite.Contains(
+		string(data),
+		"10.100.16.0/22 10.252.0.1 - bond0.nmn0",
+	)
+	suite.Contains(
+		string(data),
+		"10.107.4.0/22 10.254.0.1 - bond0.hmn0",
+	)
+
+	// The VLAN report and the manifest follow the added cabinets.
+	var vlans []networking.VLANOwnership
+	suite.Require().NoError(
+		files.ReadJSONConfig(
+			filepath.Join(
+				suite.basepath,
+				VLANsFile,
+			),
+			&vlans,
+		),
+	)
+	owners := make(map[networking.VLANOwner]uint16)
+	for _, ownership := range vlans {
+		for _, owner := range ownership.Owners {
+			owners[owner] = ownership.VLAN
+		}
+	}
+	suite.Equal(
+		uint16(1800),
+		owners[networking.VLANOwner{
+			Network: "NMN_RVR",
+			Subnet:  "cabinet_3001",
+		}],
+	)
+	report, err := verify.Verify(
+		suite.basepath,
+		generateFixtureDir,
+	)
+	suite.Require().NoError(err)
+	suite.True(
+		report.Empty(),
+		report,
+	)
+}
+
+func (suite *AddCabinetTestSuite) TestAddCabinets_SignedManifest() {
+	suite.Require().NoError(
+		os.WriteFile(
+			filepath.Join(
+				suite.basepath,
+				verify.SignatureFile,
+			),
+			[]byte("signature"),
+			0644,
+		),
+	)
+	state, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+	addition, err := AddCabinets(
+		suite.v,
+		state,
+		suite.cabinets(),
+	)
+	suite.Require().NoError(err)
+
+	// A signed manifest is not left with a signature that no longer matches.
+	manifest, err := os.ReadFile(
+		filepath.Join(
+			suite.basepath,
+			verify.ManifestFile,
+		),
+	)
+	suite.Require().NoError(err)
+	suite.ErrorContains(
+		addition.Write(suite.basepath),
+		"manifest.json is signed",
+	)
+	written, err := os.ReadFile(
+		filepath.Join(
+			suite.basepath,
+			verify.ManifestFile,
+		),
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		manifest,
+		written,
+	)
+}
+
+func (suite *AddCabinetTestSuite) TestAddCabinets_Existing() {
+	state, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+	cabinets := suite.cabinets()
+	// The river cabinets come first, following sls.ValidCabinetTypes.
+	cabinets[0].CabinetDetails[0].ID = 3000
+	_, err = AddCabinets(
+		suite.v,
+		state,
+		cabinets,
+	)
+	suite.ErrorContains(
+		err,
+		"cabinet x3000 already exists",
+	)
+
+	cabinets = suite.cabinets()
+	cabinets[1].CabinetDetails[0].NMNVlanID = 2000
+	_, err = AddCabinets(
+		suite.v,
+		state,
+		cabinets,
+	)
+	suite.ErrorContains(
+		err,
+		"VLAN 2000 is already in use in the NMN_MTN network",
+	)
+
+	// The VLANs of the other networks are not handed out either.
+	cabinets = suite.cabinets()
+	cabinets[1].CabinetDetails[0].NMNVlanID = 3000
+	_, err = AddCabinets(
+		suite.v,
+		state,
+		cabinets,
+	)
+	suite.ErrorContains(
+		err,
+		"VLAN 3000 can not be used in the NMN_MTN network because VLAN 3000 is reserved (used by the HMN_MTN network)",
+	)
+}
+
+func (suite *AddCabinetTestSuite) TestAddCabinets_ReservedVLANs() {
+	state, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+	suite.v.Set(
+		"reserved-vlans",
+		[]string{
+			"2004-2005",
+		},
+	)
+	_, err = AddCabinets(
+		suite.v,
+		state,
+		suite.cabinets(),
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		int16(2006),
+		slsSubnets(
+			&suite.Suite,
+			state,
+		)["NMN_MTN/cabinet_1004"].VlanID,
+	)
+}
+
+func (suite *AddCabinetTestSuite) TestUpdateStaticRoutes() {
+	path := filepath.Join(
+		suite.basepath,
+		BasecampDataFile,
+	)
+	var data map[string]map[string]interface{}
+	suite.Require().NoError(
+		files.ReadJSONConfig(
+			path,
+			&data,
+		),
+	)
+	var ncn string
+	for name := range data {
+		if name != "Global" {
+			ncn = name
+			break
+		}
+	}
+	delete(
+		data[ncn]["user-data"].(map[string]interface{}),
+		"write_files",
+	)
+	suite.Require().NoError(
+		files.WriteJSONConfig(
+			path,
+			data,
+		),
+	)
+
+	// An NCN without the ifroute files is given them.
+	routes := []networking.WriteFiles{
+		{
+			Content: "10.100.16.0/22 10.252.0.1 - bond0.nmn0\n",
+			Path:    "/etc/sysconfig/network/ifroute-bond0.nmn0",
+		},
+	}
+	tree := make(files.Tree)
+	suite.Require().NoError(
+		updateStaticRoutes(
+			tree,
+			suite.basepath,
+			routes,
+		),
+	)
+	suite.Require().NoError(
+		json.Unmarshal(
+			tree[BasecampDataFile],
+			&data,
+		),
+	)
+	suite.Len(
+		data[ncn]["user-data"].(map[string]interface{})["write_files"],
+		1,
+	)
+	suite.NotContains(
+		data["Global"]["user-data"],
+		"write_files",
+	)
+}
+
+func TestAddCabinetTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(AddCabinetTestSuite),
+	)
+}
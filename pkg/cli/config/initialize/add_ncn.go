@@ -0,0 +1,923 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/Cray-HPE/hms-xname/xnames"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli"
+	slsInit "github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/networking"
+)
+
+// firstManagementNID is the NID config init gives the first management NCN.
+const firstManagementNID = 100001
+
+// ncnHostnamePrefixes are the hostname prefixes of the management NCN subroles, their hostnames are numbered from 001.
+var ncnHostnamePrefixes = map[string]string{
+	"Master":        "ncn-m",
+	"Worker":        "ncn-w",
+	"Storage":       "ncn-s",
+	"FabricManager": "fmn",
+}
+
+// NCNAddition holds the SLS state and networks of a system after a management NCN was added to it.
+type NCNAddition struct {
+	SLSState *slsCommon.SLSState
+	Networks map[string]*networking.IPNetwork
+	// NCN is the added NCN, with its hostname, aliases, and IP addresses.
+	NCN *LogicalNCN
+	// NTPPeers is the ntp-peers list of the system, including the added NCN.
+	NTPPeers []string
+	// BasecampConfig is the cloud-init data of the added NCN, keyed by its MACs.
+	BasecampConfig map[string]networking.CloudInit
+	// HostRecords are the /etc/hosts entries of the added NCN.
+	HostRecords []BasecampHostRecord
+	bican       string
+	dhcpBackend string
+	console     conmanEntry
+	signingKey  string
+}
+
+// NewAddNCNCommand represents the add-ncn command.
+func NewAddNCNCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "add-ncn SYSTEM_DIR",
+		Short: "Adds a management NCN to an existing system configuration",
+		Long: `Adds a management NCN to a system directory generated by 'csi config init'.
+
+	The NCN is given by the same fields as a row of ncn_metadata.csv. The sls_input_file.json and system_config.yaml of
+	the system directory are loaded, and the NCN is added to them:
+	1. The NCN is named after the last NCN of its subrole (e.g. ncn-s004 after ncn-s003) and numbered after the last
+	   management NID
+	2. Its addresses are allocated in the bootstrap_dhcp subnets of the existing SLS networks, and their DHCP ranges
+	   are moved past them
+	3. Its SLS node hardware is added
+	4. Its cloud-init data is added to basecamp/data.json, and it is added to the ntp-peers of every NCN. The
+	   runcmd-yaml of the system config, if any, is read from the system directory
+	5. Its DHCP and host entries are added to dnsmasq.d/statics.conf, and its console to conman.conf. The BMC
+	   credentials are read from the system config, a redacted password must be given again with
+	   --bootstrap-ncn-bmc-pass. When the pit-dhcp-backend is kea, its DHCP reservations are added to
+	   kea-dhcp4.conf instead
+	6. manifest.json is updated with the new sha256 of every file. A signed manifest is signed again with
+	   --manifest-signing-key, which is then required
+
+	Every existing subnet and IP reservation is left where it is, only the new addresses are added.
+	`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(c *cobra.Command, args []string) {
+			basepath := args[0]
+			v := viper.New()
+			v.SetConfigFile(
+				filepath.Join(
+					basepath,
+					defaultConfigFilename,
+				),
+			)
+			err := v.ReadInConfig()
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: Unable to read the system config because %v",
+					err,
+				)
+			}
+
+			for _, flag := range []string{
+				"bootstrap-ncn-bmc-user",
+				"bootstrap-ncn-bmc-pass",
+				"sealed-secret-key-file",
+				"manifest-signing-key",
+			} {
+				if c.Flags().Changed(flag) {
+					value, err := c.Flags().GetString(flag)
+					if err != nil {
+						log.Fatalln(err)
+					}
+					v.Set(
+						flag,
+						value,
+					)
+				}
+			}
+
+			ncn := &LogicalNCN{}
+			for flag, value := range map[string]*string{
+				"xname":         &ncn.Xname,
+				"role":          &ncn.Role,
+				"subrole":       &ncn.Subrole,
+				"bmc-mac":       &ncn.BmcMac,
+				"bootstrap-mac": &ncn.NmnMac,
+				"bond0-mac0":    &ncn.Bond0Mac0,
+				"bond0-mac1":    &ncn.Bond0Mac1,
+			} {
+				*value, err = c.Flags().GetString(flag)
+				if err != nil {
+					log.Fatalln(err)
+				}
+			}
+
+			state, err := LoadPreviousSLS(basepath)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: %v",
+					err,
+				)
+			}
+
+			addition, err := AddNCN(
+				v,
+				state,
+				ncn,
+			)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: %v",
+					err,
+				)
+			}
+			err = addition.Write(basepath)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: %v",
+					err,
+				)
+			}
+
+			log.Printf(
+				"Added %s (%s)\n",
+				addition.NCN.Hostname,
+				addition.NCN.Xname,
+			)
+			for _, ncnNetwork := range addition.NCN.Networks {
+				log.Printf(
+					"\t%s: %s\n",
+					ncnNetwork.NetworkName,
+					ncnNetwork.IPv4Address,
+				)
+			}
+		},
+	}
+	c.Flags().String(
+		"xname",
+		"",
+		"Xname of the new NCN",
+	)
+	c.Flags().String(
+		"role",
+		"Management",
+		"Role of the new NCN",
+	)
+	c.Flags().String(
+		"subrole",
+		"",
+		"Subrole of the new NCN (Master, Worker, Storage, or FabricManager)",
+	)
+	c.Flags().String(
+		"bmc-mac",
+		"",
+		"MAC address of the new NCN's BMC",
+	)
+	c.Flags().String(
+		"bootstrap-mac",
+		"",
+		"MAC address the new NCN boots from",
+	)
+	c.Flags().String(
+		"bond0-mac0",
+		"",
+		"MAC address of the first member of the new NCN's bond0",
+	)
+	c.Flags().String(
+		"bond0-mac1",
+		"",
+		"MAC address of the second member of the new NCN's bond0",
+	)
+	c.Flags().String(
+		"bootstrap-ncn-bmc-user",
+		"",
+		"Username for connecting to the new NCN's BMC, or a secret reference (defaults to the system config's)",
+	)
+	c.Flags().String(
+		"bootstrap-ncn-bmc-pass",
+		"",
+		"Password for connecting to the new NCN's BMC, or a secret reference (defaults to the system config's)",
+	)
+	c.Flags().String(
+		"sealed-secret-key-file",
+		"",
+		"Path to the sealed secrets/shasta-cfg private key (defaults to the system config's)",
+	)
+	c.Flags().String(
+		"manifest-signing-key",
+		"",
+		"Path to the ed25519 private key (PEM) to sign the updated manifest.json with",
+	)
+	for _, flag := range []string{
+		"xname",
+		"subrole",
+		"bmc-mac",
+		"bootstrap-mac",
+	} {
+		_ = c.MarkFlagRequired(flag)
+	}
+	return c
+}
+
+/*
+AddNCN adds a management NCN to an SLS state. The NCN is given the next hostname of its subrole and the next management
+NID, and an IP reservation in the bootstrap_dhcp subnet of every network that has one. The existing hardware and IP
+reservations of the SLS state are left untouched, an NCN that already exists is an error.
+*/
+func AddNCN(v *viper.Viper, state *slsCommon.SLSState, ncn *LogicalNCN) (addition *NCNAddition, err error) {
+	err = ncn.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	err = ncn.Validate()
+	if err != nil {
+		return nil, err
+	}
+	credential, err := BMCCredential(v)
+	if err != nil {
+		return nil, err
+	}
+	if ncn.Role != "Management" {
+		return nil, fmt.Errorf(
+			"only Management NCNs can be added, not %s",
+			ncn.Role,
+		)
+	}
+	if ncn.Subrole == "FabricManager" {
+		if _, oneSevenCSM := csm.CompareMajorMinorWith(
+			v,
+			"1.7",
+		); oneSevenCSM == -1 {
+			return nil, fmt.Errorf("FabricManager nodes require CSM 1.7 or later")
+		}
+	}
+	if _, exists := state.Hardware[ncn.Xname]; exists {
+		return nil, fmt.Errorf(
+			"NCN %s already exists in the SLS state",
+			ncn.Xname,
+		)
+	}
+	cabinet, err := CabinetForXname(ncn.Xname)
+	if err != nil {
+		return nil, err
+	}
+	cabinetHardware, exists := state.Hardware[cabinet]
+	if !exists {
+		return nil, fmt.Errorf(
+			"cabinet %s of NCN %s does not exist in the SLS state",
+			cabinet,
+			ncn.Xname,
+		)
+	}
+
+	ncn.Hostname, err = nextNCNHostname(
+		state,
+		ncn.Subrole,
+	)
+	if err != nil {
+		return nil, err
+	}
+	nid, err := nextManagementNID(state)
+	if err != nil {
+		return nil, err
+	}
+
+	shastaNetworks, extraProperties, err := networksFromSLS(state)
+	if err != nil {
+		return nil, err
+	}
+	err = AllocateIPs(
+		[]*LogicalNCN{ncn},
+		shastaNetworks,
+		networking.NewAddressAllocator(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if v.GetBool("reproducible") {
+		ncn.InstanceID = DeriveInstanceID(
+			reproducibleSeed(v),
+			ncn.Xname,
+		)
+	}
+	err = updateBootstrapSubnets(
+		v,
+		shastaNetworks,
+		[]*LogicalNCN{ncn},
+	)
+	if err != nil {
+		return nil, err
+	}
+	setNetworkIPs(ncn)
+
+	// Only the bootstrap_dhcp subnets are given a reservation, the other subnets are written back as they were.
+	for _, ncnNetwork := range ncn.Networks {
+		name := ncnNetwork.NetworkName
+		bootstrapSubnet, err := shastaNetworks[name].LookUpSubnet("bootstrap_dhcp")
+		if err != nil {
+			return nil, err
+		}
+		networkExtraProperties := extraProperties[name]
+		networkExtraProperties.Subnets = slices.Clone(networkExtraProperties.Subnets)
+		for i, subnet := range networkExtraProperties.Subnets {
+			if subnet.Name == bootstrapSubnet.Name {
+				networkExtraProperties.Subnets[i] = *bootstrapSubnet
+			}
+		}
+		network := state.Networks[name]
+		network.ExtraPropertiesRaw = networkExtraProperties
+		state.Networks[name] = network
+	}
+
+	ncn.Aliases = []string{ncn.Hostname}
+	xname := xnames.FromString(ncn.Xname)
+	state.Hardware[ncn.Xname] = slsCommon.GenericHardware{
+		Parent:     xname.ParentInterface().String(),
+		Xname:      ncn.Xname,
+		Type:       slsCommon.HMSTypeToHMSStringType(xname.Type()),
+		Class:      cabinetHardware.Class,
+		TypeString: xname.Type(),
+		ExtraPropertiesRaw: slsCommon.ComptypeNode{
+			NID:     nid,
+			Role:    ncn.Role,
+			SubRole: ncn.Subrole,
+			Aliases: ncn.Aliases,
+		},
+	}
+
+	ntpPeers := v.GetStringSlice("ntp-peers")
+	if !slices.Contains(
+		ntpPeers,
+		ncn.Hostname,
+	) {
+		ntpPeers = append(
+			ntpPeers,
+			ncn.Hostname,
+		)
+		v.Set(
+			"ntp-peers",
+			ntpPeers,
+		)
+	}
+	basecampConfig, err := MakeBaseCampfromNCNs(
+		v,
+		[]LogicalNCN{*ncn},
+		shastaNetworks,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NCNAddition{
+		SLSState:       state,
+		Networks:       shastaNetworks,
+		NCN:            ncn,
+		NTPPeers:       ntpPeers,
+		BasecampConfig: basecampConfig,
+		HostRecords: MakeNCNHostRecords(
+			[]LogicalNCN{*ncn},
+			shastaNetworks,
+		),
+		bican:       v.GetString("bican-user-network-name"),
+		dhcpBackend: v.GetString("pit-dhcp-backend"),
+		console: conmanEntry{
+			Hostname: ncn.Hostname,
+			User:     credential.Username,
+			Pass:     credential.Password,
+			IP:       ncn.BmcIP,
+		},
+		signingKey: v.GetString("manifest-signing-key"),
+	}, nil
+}
+
+/*
+Render renders the files of the given system directory the NCN is added to, from their current contents: the SLS state,
+cloud-init data, dnsmasq, Kea, conman, ntp-peers, and the manifest. Nothing is written, an NCN that can not be added to one of them
+leaves the system directory as it was.
+*/
+func (addition *NCNAddition) Render(basepath string) (files.Tree, error) {
+	tree := make(files.Tree)
+	// The cloud-init data is rendered first, it refuses an NCN whose MACs are already in use.
+	err := addition.updateBasecampData(
+		tree,
+		basepath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = tree.JSON(
+		slsInit.OutputFile,
+		addition.SLSState,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to encode SLS state because %v",
+			err,
+		)
+	}
+	err = addition.updateDNSMasqConfig(
+		tree,
+		basepath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if addition.dhcpBackend == PITDHCPBackendKea {
+		err = addition.updateKeaConfig(
+			tree,
+			basepath,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = addition.updateConmanConfig(
+		tree,
+		basepath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = updateNTPPeers(
+		tree,
+		basepath,
+		addition.NTPPeers,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// The manifest is rendered last, it hashes every other file of the system directory.
+	err = renderManifest(
+		tree,
+		basepath,
+		addition.signingKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Write adds the NCN to the SLS state, cloud-init data, dnsmasq, Kea, conman, ntp-peers, and manifest of the given
+// system directory. The files are only replaced once every one of them was rendered and written next to it.
+func (addition *NCNAddition) Write(basepath string) error {
+	tree, err := addition.Render(basepath)
+	if err != nil {
+		return err
+	}
+	return tree.Replace(
+		basepath,
+		"conman.conf",
+	)
+}
+
+// updateBasecampData renders data.json with the cloud-init data and host records of the NCN added, and the ntp-peers of
+// every NCN set.
+func (addition *NCNAddition) updateBasecampData(tree files.Tree, basepath string) error {
+	path := filepath.Join(
+		basepath,
+		BasecampDataFile,
+	)
+	var data map[string]map[string]interface{}
+	err := files.ReadJSONConfig(
+		path,
+		&data,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to read %s because %v",
+			path,
+			err,
+		)
+	}
+
+	for _, mac := range slices.Sorted(maps.Keys(addition.BasecampConfig)) {
+		if _, exists := data[mac]; exists {
+			return fmt.Errorf(
+				"MAC %s is already in %s",
+				mac,
+				path,
+			)
+		}
+		data[mac], err = jsonObject(addition.BasecampConfig[mac])
+		if err != nil {
+			return err
+		}
+	}
+	for name, cloudInit := range data {
+		if name == "Global" {
+			continue
+		}
+		userData, ok := cloudInit["user-data"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ntp, ok := userData["ntp"].(map[string]interface{}); ok {
+			ntp["peers"] = addition.NTPPeers
+		}
+	}
+
+	if metaData, ok := data["Global"]["meta-data"].(map[string]interface{}); ok {
+		hostRecords, _ := metaData["host_records"].([]interface{})
+		for _, hostRecord := range addition.HostRecords {
+			hostRecords = append(
+				hostRecords,
+				hostRecord,
+			)
+		}
+		metaData["host_records"] = hostRecords
+		if addition.NCN.Subrole == "Storage" {
+			storageNodeCount, _ := metaData["num_storage_nodes"].(float64)
+			metaData["num_storage_nodes"] = int(storageNodeCount) + 1
+		}
+	}
+
+	err = tree.JSON(
+		BasecampDataFile,
+		data,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to encode data.json because %v",
+			err,
+		)
+	}
+	return nil
+}
+
+// updateDNSMasqConfig renders statics.conf with the static entries of the NCN added, and the network configurations
+// with their DHCP ranges moved past its reservations.
+func (addition *NCNAddition) updateDNSMasqConfig(tree files.Tree, basepath string) error {
+	staticsName := "dnsmasq.d/statics.conf"
+	staticsPath := filepath.Join(
+		basepath,
+		staticsName,
+	)
+	statics, err := os.ReadFile(staticsPath)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to read %s because %v",
+			staticsPath,
+			err,
+		)
+	}
+	// The NCN entries end where the virtual IP entries start.
+	end := bytes.Index(
+		statics,
+		[]byte("\n# Virtual IP Addresses"),
+	)
+	if end == -1 {
+		return fmt.Errorf(
+			"failed to find the end of the NCN entries in %s",
+			staticsPath,
+		)
+	}
+	var entries bytes.Buffer
+	ncnStatics := NewDNSMasqNCNStatics(
+		*addition.NCN,
+		addition.bican,
+	)
+	ncnStatics.DNSOnly = addition.dhcpBackend == PITDHCPBackendKea
+	tpl := template.Must(template.New("statics").Parse(string(StaticConfigTemplate)))
+	err = tpl.ExecuteTemplate(
+		&entries,
+		"ncn",
+		ncnStatics,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to execute template because %v",
+			err,
+		)
+	}
+	tree[staticsName] = slices.Concat(
+		statics[:end],
+		entries.Bytes(),
+		statics[end:],
+	)
+
+	for _, ncnNetwork := range addition.NCN.Networks {
+		name := fmt.Sprintf(
+			"dnsmasq.d/%v.conf",
+			ncnNetwork.NetworkName,
+		)
+		path := filepath.Join(
+			basepath,
+			name,
+		)
+		config, err := os.ReadFile(path)
+		if errors.Is(
+			err,
+			fs.ErrNotExist,
+		) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf(
+				"failed to read %s because %v",
+				path,
+				err,
+			)
+		}
+		subnet, err := addition.Networks[ncnNetwork.NetworkName].LookUpSubnet("bootstrap_dhcp")
+		if err != nil {
+			return err
+		}
+		// dhcp-range=interface:<interface>,<start>,<end>,<lease time>
+		lines := strings.Split(
+			string(config),
+			"\n",
+		)
+		for i, line := range lines {
+			fields := strings.Split(
+				line,
+				",",
+			)
+			if !strings.HasPrefix(
+				line,
+				"dhcp-range=",
+			) || len(fields) != 4 {
+				continue
+			}
+			fields[1] = subnet.DHCPStart.String()
+			fields[2] = subnet.DHCPEnd.String()
+			lines[i] = strings.Join(
+				fields,
+				",",
+			)
+		}
+		tree[name] = []byte(strings.Join(
+			lines,
+			"\n",
+		))
+	}
+	return nil
+}
+
+// updateKeaConfig renders kea-dhcp4.conf with the reservations of the NCN added, and the pools of its subnets moved past
+// them.
+func (addition *NCNAddition) updateKeaConfig(tree files.Tree, basepath string) error {
+	config, err := ReadKeaConfig(
+		filepath.Join(
+			basepath,
+			KeaConfigFile,
+		),
+	)
+	if err != nil {
+		return err
+	}
+	for _, ncnNetwork := range addition.NCN.Networks {
+		subnet, err := addition.Networks[ncnNetwork.NetworkName].LookUpSubnet("bootstrap_dhcp")
+		if err != nil || subnet.DHCPStart == nil || subnet.DHCPEnd == nil {
+			continue
+		}
+		start, _ := netip.AddrFromSlice(subnet.DHCPStart.To4())
+		for i, keaSubnet := range config.Dhcp4.Subnet4 {
+			prefix, err := netip.ParsePrefix(keaSubnet.Subnet)
+			if err != nil || !prefix.Contains(start) {
+				continue
+			}
+			config.Dhcp4.Subnet4[i].Pools = []KeaPool{
+				{
+					Pool: subnet.DHCPStart.String() + " - " + subnet.DHCPEnd.String(),
+				},
+			}
+		}
+	}
+	config.AddNCN(
+		NewDNSMasqNCNStatics(
+			*addition.NCN,
+			addition.bican,
+		),
+	)
+	return config.Render(
+		tree,
+		KeaConfigFile,
+		cli.Runtime,
+	)
+}
+
+// updateConmanConfig renders conman.conf with the console of the NCN's BMC added.
+func (addition *NCNAddition) updateConmanConfig(tree files.Tree, basepath string) error {
+	path := filepath.Join(
+		basepath,
+		"conman.conf",
+	)
+	config, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to read %s because %v",
+			path,
+			err,
+		)
+	}
+	console := bytes.NewBuffer(config)
+	tpl := template.Must(template.New("conmanconfig").Parse(string(ConmanConfigTemplate)))
+	err = tpl.ExecuteTemplate(
+		console,
+		"console",
+		addition.console,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to execute template because %v",
+			err,
+		)
+	}
+	console.WriteString("\n")
+	tree["conman.conf"] = console.Bytes()
+	return nil
+}
+
+// updateNTPPeers renders the system config file with its ntp-peers set, leaving the rest of it as it was written.
+func updateNTPPeers(tree files.Tree, basepath string, ntpPeers []string) error {
+	path := filepath.Join(
+		basepath,
+		defaultConfigFilename,
+	)
+	config, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to read %s because %v",
+			path,
+			err,
+		)
+	}
+	var document yaml.Node
+	err = yaml.Unmarshal(
+		config,
+		&document,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to parse %s because %v",
+			path,
+			err,
+		)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf(
+			"%s is not a system config",
+			path,
+		)
+	}
+	var peers yaml.Node
+	err = peers.Encode(ntpPeers)
+	if err != nil {
+		return err
+	}
+	settings := document.Content[0]
+	found := false
+	for i := 0; i+1 < len(settings.Content); i += 2 {
+		if settings.Content[i].Value == "ntp-peers" {
+			settings.Content[i+1] = &peers
+			found = true
+		}
+	}
+	if !found {
+		settings.Content = append(
+			settings.Content,
+			&yaml.Node{
+				Kind:  yaml.ScalarNode,
+				Value: "ntp-peers",
+			},
+			&peers,
+		)
+	}
+	tree[defaultConfigFilename], err = yaml.Marshal(&document)
+	return err
+}
+
+// nextNCNHostname returns the hostname after the last management NCN of the given subrole.
+func nextNCNHostname(state *slsCommon.SLSState, subrole string) (hostname string, err error) {
+	prefix, ok := ncnHostnamePrefixes[subrole]
+	if !ok {
+		return "", fmt.Errorf(
+			"unknown NCN subrole %s, expected one of %v",
+			subrole,
+			slices.Sorted(maps.Keys(ncnHostnamePrefixes)),
+		)
+	}
+	index := 0
+	ncns, err := managementNodes(state)
+	if err != nil {
+		return "", err
+	}
+	for _, ncn := range ncns {
+		if ncn.SubRole != subrole || len(ncn.Aliases) == 0 {
+			continue
+		}
+		ncnIndex, err := strconv.Atoi(
+			strings.TrimPrefix(
+				ncn.Aliases[0],
+				prefix,
+			),
+		)
+		if err == nil && ncnIndex > index {
+			index = ncnIndex
+		}
+	}
+	return fmt.Sprintf(
+		"%s%03d",
+		prefix,
+		index+1,
+	), nil
+}
+
+// nextManagementNID returns the NID after the last management NCN of the SLS state.
+func nextManagementNID(state *slsCommon.SLSState) (nid int, err error) {
+	ncns, err := managementNodes(state)
+	if err != nil {
+		return 0, err
+	}
+	nid = firstManagementNID
+	for _, ncn := range ncns {
+		if ncn.NID >= nid {
+			nid = ncn.NID + 1
+		}
+	}
+	return nid, nil
+}
+
+// managementNodes returns the node extra properties of the management NCNs of the SLS state.
+func managementNodes(state *slsCommon.SLSState) (ncns []slsCommon.ComptypeNode, err error) {
+	for _, xname := range slices.Sorted(maps.Keys(state.Hardware)) {
+		hardware := state.Hardware[xname]
+		if hardware.Type != slsCommon.Node {
+			continue
+		}
+		extraProperties, err := sls.UnmarshalComptypeNode(&hardware)
+		if err != nil {
+			return nil, err
+		}
+		if extraProperties.Role != "Management" {
+			continue
+		}
+		ncns = append(
+			ncns,
+			extraProperties,
+		)
+	}
+	return ncns, nil
+}
+
+// jsonObject returns the JSON object an object encodes to.
+func jsonObject(object interface{}) (jsonObject map[string]interface{}, err error) {
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(
+		encoded,
+		&jsonObject,
+	)
+	return jsonObject, err
+}
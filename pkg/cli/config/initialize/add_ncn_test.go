@@ -0,0 +1,320 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	slsInit "github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/verify"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/secrets"
+)
+
+type AddNCNTestSuite struct {
+	suite.Suite
+	basepath string
+	v        *viper.Viper
+}
+
+func (suite *AddNCNTestSuite) SetupTest() {
+	suite.v, suite.basepath = generateSystemDir(&suite.Suite)
+}
+
+func (suite *AddNCNTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+func (suite *AddNCNTestSuite) ncn() *LogicalNCN {
+	return &LogicalNCN{
+		Xname:     "x3000c0s30b0n0",
+		Role:      "Management",
+		Subrole:   "Storage",
+		BmcMac:    "94:40:c9:00:00:01",
+		NmnMac:    "14:02:ec:00:00:01",
+		Bond0Mac0: "14:02:ec:00:00:01",
+		Bond0Mac1: "94:40:c9:00:00:02",
+	}
+}
+
+// readFile returns the contents of a file in the system directory.
+func (suite *AddNCNTestSuite) readFile(name string) string {
+	contents, err := os.ReadFile(
+		filepath.Join(
+			suite.basepath,
+			name,
+		),
+	)
+	suite.Require().NoError(err)
+	return string(contents)
+}
+
+func (suite *AddNCNTestSuite) TestAddNCN() {
+	previous, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+	state, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+
+	addition, err := AddNCN(
+		suite.v,
+		state,
+		suite.ncn(),
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"ncn-s004",
+		addition.NCN.Hostname,
+	)
+
+	// Existing reservations keep their addresses, the NCN is given the first free one.
+	nmn := slsSubnets(
+		&suite.Suite,
+		state,
+	)["NMN/bootstrap_dhcp"]
+	suite.Equal(
+		slsSubnets(
+			&suite.Suite,
+			previous,
+		)["NMN/bootstrap_dhcp"].IPReservations,
+		nmn.IPReservations[:len(nmn.IPReservations)-1],
+	)
+	reservation := nmn.IPReservations[len(nmn.IPReservations)-1]
+	suite.Equal(
+		"ncn-s004",
+		reservation.Name,
+	)
+	suite.Equal(
+		"10.252.1.12",
+		reservation.IPAddress.String(),
+	)
+	suite.Contains(
+		reservation.Aliases,
+		"x3000c0s30b0n0",
+	)
+	suite.Equal(
+		"10.252.1.15",
+		nmn.DHCPStart.String(),
+	)
+	suite.Equal(
+		"10.254.1.20",
+		addition.NCN.BmcIP,
+	)
+
+	hardware := state.Hardware["x3000c0s30b0n0"]
+	node, err := sls.UnmarshalComptypeNode(&hardware)
+	suite.Require().NoError(err)
+	suite.Equal(
+		100011,
+		node.NID,
+	)
+	suite.Equal(
+		[]string{"ncn-s004"},
+		node.Aliases,
+	)
+
+	suite.Require().NoError(addition.Write(suite.basepath))
+	data := suite.readFile(BasecampDataFile)
+	suite.Contains(
+		data,
+		`"14:02:ec:00:00:01"`,
+	)
+	suite.Contains(
+		data,
+		`"num_storage_nodes": 4`,
+	)
+	suite.Contains(
+		suite.readFile("dnsmasq.d/statics.conf"),
+		"dhcp-host=94:40:c9:00:00:01,10.254.1.20,ncn-s004-mgmt,20m #HMN\n",
+	)
+	suite.Contains(
+		suite.readFile("dnsmasq.d/NMN.conf"),
+		"dhcp-range=interface:bond0.nmn0,10.252.1.15,10.252.1.215,10m\n",
+	)
+	suite.Contains(
+		suite.readFile("conman.conf"),
+		`console name="ncn-s004-mgmt" ipmiopts="U:root,P:changeme,W:solpayloadsize" dev="ipmi:10.254.1.20"`,
+	)
+
+	config := viper.New()
+	config.SetConfigFile(
+		filepath.Join(
+			suite.basepath,
+			defaultConfigFilename,
+		),
+	)
+	suite.Require().NoError(config.ReadInConfig())
+	suite.Contains(
+		config.GetStringSlice("ntp-peers"),
+		"ncn-s004",
+	)
+
+	// The manifest follows every file the NCN was added to.
+	report, err := verify.Verify(
+		suite.basepath,
+		generateFixtureDir,
+	)
+	suite.Require().NoError(err)
+	suite.True(
+		report.Empty(),
+		report,
+	)
+}
+
+func (suite *AddNCNTestSuite) TestAddNCN_Existing() {
+	state, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+	ncn := suite.ncn()
+	ncn.Xname = "x3000c0s9b0n0"
+	_, err = AddNCN(
+		suite.v,
+		state,
+		ncn,
+	)
+	suite.ErrorContains(
+		err,
+		"NCN x3000c0s9b0n0 already exists",
+	)
+
+	ncn = suite.ncn()
+	ncn.Subrole = "Compute"
+	_, err = AddNCN(
+		suite.v,
+		state,
+		ncn,
+	)
+	suite.ErrorContains(
+		err,
+		"unknown NCN subrole Compute",
+	)
+}
+
+func (suite *AddNCNTestSuite) TestAddNCN_SecretReference() {
+	state, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+
+	// The system config only holds the redacted password, it must be given again.
+	suite.v.Set(
+		"bootstrap-ncn-bmc-pass",
+		secrets.Redacted,
+	)
+	_, err = AddNCN(
+		suite.v,
+		state,
+		suite.ncn(),
+	)
+	suite.ErrorContains(
+		err,
+		"unable to resolve bootstrap-ncn-bmc-pass",
+	)
+
+	suite.T().Setenv(
+		"CSI_TEST_BMC_PASS",
+		"from-env",
+	)
+	suite.v.Set(
+		"bootstrap-ncn-bmc-pass",
+		"env:CSI_TEST_BMC_PASS",
+	)
+	addition, err := AddNCN(
+		suite.v,
+		state,
+		suite.ncn(),
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(addition.Write(suite.basepath))
+	suite.Contains(
+		suite.readFile("conman.conf"),
+		`ipmiopts="U:root,P:from-env,W:solpayloadsize" dev="ipmi:10.254.1.20"`,
+	)
+	info, err := os.Stat(
+		filepath.Join(
+			suite.basepath,
+			"conman.conf",
+		),
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		os.FileMode(0600),
+		info.Mode().Perm(),
+	)
+}
+
+func (suite *AddNCNTestSuite) TestAddNCN_WriteFailure() {
+	state, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+	addition, err := AddNCN(
+		suite.v,
+		state,
+		suite.ncn(),
+	)
+	suite.Require().NoError(err)
+
+	// A file that can not be rendered leaves every file of the system directory as it was.
+	data := suite.readFile(BasecampDataFile)
+	slsState := suite.readFile(slsInit.OutputFile)
+	suite.Require().NoError(
+		os.Remove(
+			filepath.Join(
+				suite.basepath,
+				"conman.conf",
+			),
+		),
+	)
+	suite.ErrorContains(
+		addition.Write(suite.basepath),
+		"conman.conf",
+	)
+	suite.Equal(
+		data,
+		suite.readFile(BasecampDataFile),
+	)
+	suite.Equal(
+		slsState,
+		suite.readFile(slsInit.OutputFile),
+	)
+	entries, err := os.ReadDir(
+		filepath.Join(
+			suite.basepath,
+			"basecamp",
+		),
+	)
+	suite.Require().NoError(err)
+	suite.Len(
+		entries,
+		1,
+	)
+}
+
+func TestAddNCNTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(AddNCNTestSuite),
+	)
+}
@@ -0,0 +1,314 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CRDSchema is the part of an OpenAPI v3 schema, as given by the openAPIV3Schema of a CustomResourceDefinition, that
+// resources are validated against.
+type CRDSchema struct {
+	Type                  string                `yaml:"type"`
+	Properties            map[string]*CRDSchema `yaml:"properties"`
+	Items                 *CRDSchema            `yaml:"items"`
+	Required              []string              `yaml:"required"`
+	Enum                  []interface{}         `yaml:"enum"`
+	Minimum               *float64              `yaml:"minimum"`
+	Maximum               *float64              `yaml:"maximum"`
+	PreserveUnknownFields bool                  `yaml:"x-kubernetes-preserve-unknown-fields"`
+	IntOrString           bool                  `yaml:"x-kubernetes-int-or-string"`
+}
+
+// CustomResourceDefinition is the part of a CustomResourceDefinition that is needed to validate its resources.
+type CustomResourceDefinition struct {
+	Spec struct {
+		Group string `yaml:"group"`
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+		Versions []struct {
+			Name   string `yaml:"name"`
+			Schema struct {
+				OpenAPIV3Schema *CRDSchema `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// CRDSchemas are the schemas of the versions of CustomResourceDefinitions, keyed by the apiVersion and kind of their
+// resources (e.g. "metallb.io/v1beta1 IPAddressPool").
+type CRDSchemas map[string]*CRDSchema
+
+// LoadCRDSchemas reads the schemas of every version of the CustomResourceDefinitions in a multi-document YAML.
+func LoadCRDSchemas(data []byte) (schemas CRDSchemas, err error) {
+	schemas = make(CRDSchemas)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var crd CustomResourceDefinition
+		err = decoder.Decode(&crd)
+		if errors.Is(
+			err,
+			io.EOF,
+		) {
+			return schemas, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to decode CustomResourceDefinition because %v",
+				err,
+			)
+		}
+		for _, version := range crd.Spec.Versions {
+			schemas[crd.Spec.Group+"/"+version.Name+" "+crd.Spec.Names.Kind] = version.Schema.OpenAPIV3Schema
+		}
+	}
+}
+
+/*
+Validate checks every document of a multi-document YAML against the schema of its apiVersion and kind. A document
+without a schema is an error, as is every field that is not in the schema, since the API server would prune it.
+*/
+func (schemas CRDSchemas) Validate(data []byte) (errs []error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var resource map[string]interface{}
+		err := decoder.Decode(&resource)
+		if errors.Is(
+			err,
+			io.EOF,
+		) {
+			return errs
+		}
+		if err != nil {
+			return append(
+				errs,
+				err,
+			)
+		}
+		if resource == nil {
+			continue
+		}
+		metadata, _ := resource["metadata"].(map[string]interface{})
+		name := fmt.Sprintf(
+			"%v %v",
+			resource["kind"],
+			metadata["name"],
+		)
+		schema, ok := schemas[fmt.Sprintf(
+			"%v %v",
+			resource["apiVersion"],
+			resource["kind"],
+		)]
+		if !ok {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"%s: there is no schema for %v %v",
+					name,
+					resource["apiVersion"],
+					resource["kind"],
+				),
+			)
+			continue
+		}
+		for _, err := range schema.Validate(
+			name,
+			resource,
+		) {
+			errs = append(
+				errs,
+				err,
+			)
+		}
+	}
+}
+
+// Validate checks value against the schema, path names value in the errors.
+func (schema *CRDSchema) Validate(path string, value interface{}) (errs []error) {
+	if len(schema.Enum) > 0 && !slices.Contains(
+		schema.Enum,
+		value,
+	) {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"%s: %v is not one of %v",
+				path,
+				value,
+				schema.Enum,
+			),
+		)
+	}
+	if schema.IntOrString {
+		switch value.(type) {
+		case int, string:
+			return errs
+		}
+		return append(
+			errs,
+			fmt.Errorf(
+				"%s: must be an integer or a string",
+				path,
+			),
+		)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(
+				errs,
+				fmt.Errorf(
+					"%s: must be an object",
+					path,
+				),
+			)
+		}
+		for _, key := range schema.Required {
+			if _, ok := object[key]; !ok {
+				errs = append(
+					errs,
+					fmt.Errorf(
+						"%s.%s: is required",
+						path,
+						key,
+					),
+				)
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(object)) {
+			property, ok := schema.Properties[key]
+			if !ok {
+				if !schema.PreserveUnknownFields {
+					errs = append(
+						errs,
+						fmt.Errorf(
+							"%s.%s: is not a known field",
+							path,
+							key,
+						),
+					)
+				}
+				continue
+			}
+			errs = append(
+				errs,
+				property.Validate(
+					path+"."+key,
+					object[key],
+				)...,
+			)
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return append(
+				errs,
+				fmt.Errorf(
+					"%s: must be an array",
+					path,
+				),
+			)
+		}
+		if schema.Items != nil {
+			for i, item := range array {
+				errs = append(
+					errs,
+					schema.Items.Validate(
+						fmt.Sprintf(
+							"%s[%d]",
+							path,
+							i,
+						),
+						item,
+					)...,
+				)
+			}
+		}
+	case "integer":
+		integer, ok := value.(int)
+		if !ok {
+			return append(
+				errs,
+				fmt.Errorf(
+					"%s: must be an integer",
+					path,
+				),
+			)
+		}
+		if schema.Minimum != nil && float64(integer) < *schema.Minimum {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"%s: %d is less than %v",
+					path,
+					integer,
+					*schema.Minimum,
+				),
+			)
+		}
+		if schema.Maximum != nil && float64(integer) > *schema.Maximum {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"%s: %d is greater than %v",
+					path,
+					integer,
+					*schema.Maximum,
+				),
+			)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"%s: must be a string",
+					path,
+				),
+			)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"%s: must be a boolean",
+					path,
+				),
+			)
+		}
+	}
+	return errs
+}
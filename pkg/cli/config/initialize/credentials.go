@@ -24,9 +24,81 @@
 
 package initialize
 
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/secrets"
+)
+
 // PasswordCredential is a struct for holding username/password credentials
 type PasswordCredential struct {
 	Username   string `form:"username" json:"username"`
 	Password   string `form:"password" json:"password"`
 	ServiceURL string `form:"service_url" json:"service_url" binding:"omitempty"`
 }
+
+// BMCCredential returns the bootstrap-ncn-bmc-user and bootstrap-ncn-bmc-pass of a config, resolving them if they are
+// secret references.
+func BMCCredential(v *viper.Viper) (credential PasswordCredential, err error) {
+	for key, value := range map[string]*string{
+		"bootstrap-ncn-bmc-user": &credential.Username,
+		"bootstrap-ncn-bmc-pass": &credential.Password,
+	} {
+		*value, err = resolveSecret(
+			v,
+			key,
+		)
+		if err != nil {
+			return credential, err
+		}
+	}
+	return credential, nil
+}
+
+// runSecrets are the secrets of a config init run. They are resolved once, a secret reference (e.g. a cmd: reference) is
+// not resolved again for every file it is rendered to.
+type runSecrets struct {
+	bmc             PasswordCredential
+	metalLBPassword string
+}
+
+// resolveRunSecrets resolves the secrets of a config, returning an error for every one that can not be resolved.
+func resolveRunSecrets(v *viper.Viper) (resolved runSecrets, errs []error) {
+	var err error
+	resolved.bmc, err = BMCCredential(v)
+	if err != nil {
+		errs = append(
+			errs,
+			err,
+		)
+	}
+	resolved.metalLBPassword, err = resolveSecret(
+		v,
+		"metallb-bgp-password",
+	)
+	if err != nil {
+		errs = append(
+			errs,
+			err,
+		)
+	}
+	return resolved, errs
+}
+
+// resolveSecret returns the value of a key of a config, resolving it if it is a secret reference.
+func resolveSecret(v *viper.Viper, key string) (secret string, err error) {
+	resolver := secrets.Resolver{
+		SealedSecretKeyFile: v.GetString("sealed-secret-key-file"),
+	}
+	secret, err = resolver.Resolve(v.GetString(key))
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to resolve %s because %v",
+			key,
+			err,
+		)
+	}
+	return secret, nil
+}
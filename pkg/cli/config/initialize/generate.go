@@ -0,0 +1,751 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cray-HPE/hms-bss/pkg/bssTypes"
+	shcdParser "github.com/Cray-HPE/hms-shcd-parser/pkg/shcd-parser"
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/spf13/viper"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+	slsInit "github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/networking"
+)
+
+// defaultConfigFilename is the name given to the written system config when the CLI did not resolve one.
+const defaultConfigFilename = "system_config.yaml"
+
+// VLANsFile is the report of the VLANs used by the system, and the networks and subnets using them.
+const VLANsFile = "vlans.json"
+
+// privateFiles are the rendered files that hold secrets (conman.conf needs the BMC password itself, and the MetalLB
+// BGPPeers the BGP password), only their owner may read them.
+var privateFiles = []string{
+	"conman.conf",
+	"metallb-resources.yaml",
+}
+
+// SourceDateEpochEnv is the environment variable (see https://reproducible-builds.org/specs/source-date-epoch/) that
+// pins the generated timestamps to the given UNIX time, and implies --reproducible.
+const SourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// Inputs holds everything Generate needs to build a system's configuration payload.
+type Inputs struct {
+	// Flags are config init settings keyed by flag name (e.g. "system-name"). Every call of Generate layers them on
+	// top of the init command's flag defaults in a viper of its own, CollectInputs fills them with the settings that
+	// were set in its viper.
+	Flags                 map[string]interface{}
+	LogicalNCNs           []*LogicalNCN
+	Switches              []*networking.ManagementSwitch
+	Cabinets              []sls.CabinetGroupDetail
+	HMNRows               []shcdParser.HMNRow
+	ApplicationNodeConfig slsInit.GeneratorApplicationNodeConfig
+	// ExtraNetworks are site-specific networks that are built alongside the CSM networks.
+	ExtraNetworks []slsInit.ExtraNetwork
+	// PreviousSLS is the SLS state of a previous run. When set, its subnets, VLANs, and IP reservations are kept and
+	// only new hardware is given new addresses.
+	PreviousSLS *slsCommon.SLSState
+	// InputFiles are the paths of the seed files the inputs were read from, keyed by the flag that names them (e.g.
+	// "ncn-metadata"). Their sha256 is recorded in the payload's manifest.
+	InputFiles map[string]string
+	// SkipFiles leaves Outputs.Files empty, skipping the rendering of the payload's files.
+	SkipFiles bool
+}
+
+// Outputs holds the generated configuration payload for a system.
+type Outputs struct {
+	SystemName             string
+	SLSState               slsCommon.SLSState
+	Networks               map[string]*networking.IPNetwork
+	LogicalNCNs            []LogicalNCN
+	UANs                   []LogicalUAN
+	Switches               []*networking.ManagementSwitch
+	BasecampGlobalMetaData BasecampGlobalMetaData
+	Basecamp               bssTypes.CloudDataType
+	Customizations         CustomizationsYaml
+	// VLANs are the VLANs allocated and reserved during the run, along with the networks and subnets using them.
+	VLANs *networking.VLANAllocator
+	// InputFiles are the seed files of Inputs.InputFiles.
+	InputFiles map[string]string
+	// Runtime is the time of the run, the rendered files are stamped with it. Reproducible runs pin it.
+	Runtime time.Time
+	// Files are the rendered files of the payload keyed by their path relative to the system directory.
+	Files   files.Tree
+	secrets runSecrets
+}
+
+// CollectInputs reads the seed files (hmn_connections.json, ncn_metadata.csv, switch_metadata.csv, and the optional
+// application node, cabinet, and previous SLS files) referenced by the given viper into Inputs, along with every
+// setting that was set in it.
+func CollectInputs(v *viper.Viper) (inputs Inputs, err error) {
+	hmnRows, logicalNCNs, switches, applicationNodeConfig, cabinetDetailList, errs := collectInput(v)
+	if errs != nil {
+		return inputs, errors.Join(errs...)
+	}
+	inputs = Inputs{
+		LogicalNCNs:           logicalNCNs,
+		Switches:              switches,
+		Cabinets:              cabinetDetailList,
+		HMNRows:               hmnRows,
+		ApplicationNodeConfig: applicationNodeConfig,
+		InputFiles:            inputFiles(v),
+		Flags:                 make(map[string]interface{}),
+	}
+	for _, key := range v.AllKeys() {
+		if _, alias := AliasKeys[key]; alias || !v.IsSet(key) {
+			continue
+		}
+		inputs.Flags[key] = v.Get(key)
+	}
+	inputs.ExtraNetworks, err = collectExtraNetworks(v)
+	if err != nil {
+		return inputs, err
+	}
+	if v.GetString("previous-sls") != "" {
+		inputs.PreviousSLS, err = LoadPreviousSLS(v.GetString("previous-sls"))
+		if err != nil {
+			return inputs, err
+		}
+	}
+	return inputs, nil
+}
+
+/*
+Generate runs the config init pipeline against the given inputs and returns the resulting payload. Nothing is
+written to the system directory, the payload's files are rendered in memory and can be written with Outputs.Write.
+
+Generate never exits the process; all failures are returned as errors.
+*/
+func Generate(ctx context.Context, inputs Inputs) (*Outputs, error) {
+	// Every call has settings of its own, nothing is read from or left behind in the global viper.
+	v := viper.New()
+	err := v.BindPFlags(initFlags())
+	if err != nil {
+		return nil, err
+	}
+	registerAliases(
+		v,
+		AliasKeys,
+	)
+	for key, value := range inputs.Flags {
+		v.Set(
+			key,
+			copySettings(value),
+		)
+	}
+
+	secrets, err := validateInputFlags(v)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime, reproducible, err := reproducibleRuntime(v)
+	if err != nil {
+		return nil, err
+	}
+	if !reproducible {
+		runtime = time.Now().UTC()
+	}
+
+	// Every run allocates its VLANs and addresses, and seeds its reservations from scratch.
+	vlans := networking.NewVLANAllocator()
+	addresses := networking.NewAddressAllocator()
+	for _, reserved := range v.GetStringSlice("reserved-vlans") {
+		start, end, err := networking.ParseVLANRange(reserved)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to reserve VLANs because %v",
+				err,
+			)
+		}
+		err = vlans.Reserve(
+			start,
+			end,
+			"reserved-vlans",
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy the NCNs, the pipeline fills in their hostnames, aliases, and networks.
+	logicalNCNs := make(
+		[]*LogicalNCN,
+		0,
+		len(inputs.LogicalNCNs),
+	)
+	for _, ncn := range inputs.LogicalNCNs {
+		logicalNCN := *ncn
+		logicalNCNs = append(
+			logicalNCNs,
+			&logicalNCN,
+		)
+	}
+
+	defaultNetConfigs := GenerateDefaultNetworkConfigs(
+		v,
+		inputs.Switches,
+		logicalNCNs,
+		inputs.Cabinets,
+	)
+	extraNetworks, err := addExtraNetworkConfigs(
+		defaultNetConfigs,
+		inputs.ExtraNetworks,
+		inputs.Cabinets,
+	)
+	if err != nil {
+		return nil, err
+	}
+	internalNetConfigs, err := GenerateNetworkConfigs(
+		v,
+		defaultNetConfigs,
+		vlans,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// Build a set of networks we can use
+	shastaNetworks, err := slsInit.BuildCSMNetworks(
+		v,
+		internalNetConfigs,
+		inputs.Cabinets,
+		inputs.Switches,
+		vlans,
+		addresses,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = checkExtraNetworkOverlap(
+		shastaNetworks,
+		extraNetworks,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if inputs.PreviousSLS != nil {
+		err = seedNetworks(
+			shastaNetworks,
+			inputs.PreviousSLS,
+			addresses,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Use our new networks and our list of logicalNCNs to distribute ips
+	err = AllocateIPs(
+		logicalNCNs,
+		shastaNetworks,
+		addresses,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if reproducible {
+		for _, ncn := range logicalNCNs {
+			ncn.InstanceID = DeriveInstanceID(
+				reproducibleSeed(v),
+				ncn.Xname,
+			)
+		}
+	}
+
+	// Now we can finally generate the slsState
+	slsState, err := prepareAndGenerateSLS(
+		v,
+		inputs.Cabinets,
+		shastaNetworks,
+		inputs.HMNRows,
+		inputs.Switches,
+		inputs.ApplicationNodeConfig,
+		v.GetInt("starting-mountain-nid"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// SLS can tell us which NCNs match with which Xnames, we need to update the IP Reservations
+	slsNcns, err := ExtractSLSNCNs(&slsState)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge the SLS NCN list with the NCN list we got at the beginning
+	logicalNCNs, err = mergeNCNs(
+		v,
+		logicalNCNs,
+		slsNcns,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pull UANs from the completed slsState to assign CAN addresses
+	slsUans, err := ExtractUANs(&slsState)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only add UANs if there actually is a CAN network
+	if v.GetString("bican-user-network-name") == "CAN" || v.GetBool("retain-unused-user-network") {
+		canSubnet, _ := shastaNetworks["CAN"].LookUpSubnet("bootstrap_dhcp")
+		for _, uan := range slsUans {
+			_, err := addresses.AddReservation(
+				canSubnet,
+				uan.Hostname,
+				uan.Xname,
+			)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"failed to reserve a CAN address for %s because %v",
+					uan.Xname,
+					err,
+				)
+			}
+		}
+	}
+	// Only add UANs if there actually is a CHN network
+	if v.GetString("bican-user-network-name") == "CHN" || v.GetBool("retain-unused-user-network") {
+		chnSubnet, _ := shastaNetworks["CHN"].LookUpSubnet("bootstrap_dhcp")
+		for _, uan := range slsUans {
+			_, err := addresses.AddReservation(
+				chnSubnet,
+				uan.Hostname,
+				uan.Xname,
+			)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"failed to reserve a CHN address for %s because %v",
+					uan.Xname,
+					err,
+				)
+			}
+		}
+	}
+	if conflicts := addresses.SeedConflicts(); len(conflicts) > 0 {
+		return nil, &ReservationConflictError{
+			Conflicts: conflicts,
+		}
+	}
+
+	err = updateBootstrapSubnets(
+		v,
+		shastaNetworks,
+		logicalNCNs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the SLSState with the updated network information
+	_, slsState.Networks = prepareNetworkSLS(shastaNetworks)
+
+	// Switch from a list of pointers to a list of things before we write it out
+	var ncns []LogicalNCN
+	for _, ncn := range logicalNCNs {
+		ncns = append(
+			ncns,
+			*ncn,
+		)
+	}
+	globalMetaData, err := MakeBasecampGlobalMetaData(
+		v,
+		ncns,
+		shastaNetworks,
+		"NMN",
+		"bootstrap_dhcp",
+		v.GetString("install-ncn"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to generate basecamp globalMetaData because %v",
+			err,
+		)
+	}
+	basecamp, err := MakeBasecampData(
+		v,
+		ncns,
+		shastaNetworks,
+		globalMetaData,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to generate basecamp data because %v",
+			err,
+		)
+	}
+	customizations, err := GenCustomizationsYaml(
+		v,
+		ncns,
+		shastaNetworks,
+		inputs.Switches,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to generate customizations because %v",
+			err,
+		)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	outputs := &Outputs{
+		SystemName:             v.GetString("system-name"),
+		SLSState:               slsState,
+		Networks:               shastaNetworks,
+		LogicalNCNs:            ncns,
+		UANs:                   slsUans,
+		Switches:               inputs.Switches,
+		BasecampGlobalMetaData: globalMetaData,
+		Basecamp:               basecamp,
+		Customizations:         customizations,
+		VLANs:                  vlans,
+		InputFiles:             inputs.InputFiles,
+		Runtime:                runtime,
+		secrets:                secrets,
+	}
+	if inputs.SkipFiles {
+		return outputs, nil
+	}
+	outputs.Files, err = renderOutput(
+		v,
+		outputs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to render one or more output files because %v",
+			err,
+		)
+	}
+	return outputs, nil
+}
+
+// Write writes the rendered files to the given system directory, creating it if it does not exist.
+func (outputs *Outputs) Write(basepath string) error {
+	_, err := setupDirectories(basepath)
+	if err != nil && !errors.Is(
+		err,
+		fs.ErrExist,
+	) {
+		return err
+	}
+	return outputs.Files.Write(
+		basepath,
+		privateFiles...,
+	)
+}
+
+// reproducibleRuntime returns whether this is a reproducible run, and if so the time its timestamps are pinned to.
+func reproducibleRuntime(v *viper.Viper) (runtime time.Time, reproducible bool, err error) {
+	epoch := os.Getenv(SourceDateEpochEnv)
+	if epoch == "" {
+		return time.Unix(
+			0,
+			0,
+		).UTC(), v.GetBool("reproducible"), nil
+	}
+	seconds, err := strconv.ParseInt(
+		epoch,
+		10,
+		64,
+	)
+	if err != nil {
+		return runtime, false, fmt.Errorf(
+			"unable to parse %s=%s as a UNIX timestamp because %v",
+			SourceDateEpochEnv,
+			epoch,
+			err,
+		)
+	}
+	return time.Unix(
+		seconds,
+		0,
+	).UTC(), true, nil
+}
+
+/*
+validateInputFlags checks the flags that config init can not proceed without, and returns the secrets of the run. The
+secrets are resolved before anything is rendered, a secret reference that cannot be resolved would otherwise leave a
+partial system directory behind.
+*/
+func validateInputFlags(v *viper.Viper) (secrets runSecrets, err error) {
+	flagErrors := validateFlags(v)
+	secrets, secretErrors := resolveRunSecrets(v)
+	flagErrors = append(
+		flagErrors,
+		secretErrors...,
+	)
+
+	if len(
+		strings.Split(
+			v.GetString("site-ip"),
+			"/",
+		),
+	) != 2 {
+		flagErrors = append(
+			flagErrors,
+			fmt.Errorf(
+				"unable to parse %s as --site-ip. Must be in the format \"192.168.0.1/24\"",
+				v.GetString("site-ip"),
+			),
+		)
+	}
+
+	// Validate that the BGP ASN is within the private range
+	for _, network := range []string{
+		"bgp-asn",
+		"bgp-chn-asn",
+		"bgp-cmn-asn",
+		"bgp-nmn-asn",
+	} {
+		asn := v.GetInt(network)
+		if asn > 65534 || asn < 64512 {
+			flagErrors = append(
+				flagErrors,
+				fmt.Errorf(
+					"BGP ASNs must be within the private range 64512-65534, fix the value for: %s",
+					network,
+				),
+			)
+		}
+	}
+
+	if len(flagErrors) > 0 {
+		return secrets, fmt.Errorf(
+			"one or more flags had invalid values:\n%v",
+			errors.Join(flagErrors...),
+		)
+	}
+	return secrets, nil
+}
+
+// reproducibleSeed returns the seed that reproducible runs derive their NCN instance IDs from.
+func reproducibleSeed(v *viper.Viper) string {
+	seed := v.GetString("reproducible-seed")
+	if seed == "" {
+		seed = v.GetString("system-name")
+	}
+	return seed
+}
+
+// updateBootstrapSubnets cycles through the main networks and updates the reservations, masks and dhcp ranges as necessary.
+func updateBootstrapSubnets(v *viper.Viper, shastaNetworks map[string]*networking.IPNetwork, logicalNCNs []*LogicalNCN) error {
+	for _, network := range slices.Sorted(maps.Keys(shastaNetworks)) {
+		// Loop the reservations and update the NCN reservations with hostnames
+		// we likely didn't have when we registered the reservation
+		subnet, err := updateReservations(
+			shastaNetworks[network],
+			"bootstrap_dhcp",
+			logicalNCNs,
+		)
+		if err != nil {
+			continue
+		}
+		if network == "CAN" || network == "CMN" || network == "CHN" {
+			err := updateUserNetworkDHCPRange(
+				v,
+				network,
+				subnet,
+			)
+			if err != nil {
+				return err
+			}
+		} else {
+			err := networking.UpdateDHCPRange(
+				subnet,
+				v.GetBool("supernet"),
+			)
+			if err != nil {
+				return fmt.Errorf(
+					"error updating DHCP range: %v",
+					err,
+				)
+			}
+		}
+
+		// We expect a bootstrap_dhcp in every net, but uai_macvlan is only in
+		// the NMN range for today
+		if strings.ToUpper(network) == "NMN" {
+			subnet, err := updateReservations(
+				shastaNetworks[network],
+				"uai_macvlan",
+				logicalNCNs,
+			)
+			if err != nil {
+				continue
+			}
+			err = networking.UpdateDHCPRange(
+				subnet,
+				false,
+			)
+			if err != nil {
+				return fmt.Errorf(
+					"error updating DHCP range for %s: %v",
+					subnet.Name,
+					err,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// updateUserNetworkDHCPRange sets the DHCP range of a CAN, CMN, or CHN bootstrap subnet so it ends before the
+// network's MetalLB pools.
+func updateUserNetworkDHCPRange(v *viper.Viper, network string, subnet *slsCommon.IPSubnet) error {
+	netNameLower := strings.ToLower(network)
+
+	// Do not use supernet hack for the CAN/CMN/CHN, these should reflect the abstracted subnets.
+	err := networking.UpdateDHCPRange(
+		subnet,
+		false,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"error updating DHCP range: %v",
+			err,
+		)
+	}
+
+	cidr4Key := fmt.Sprintf(
+		"%s-cidr4",
+		netNameLower,
+	)
+	cidrKey := fmt.Sprintf(
+		"%s-cidr",
+		netNameLower,
+	)
+
+	// Handle IPv4 CIDRs, networks with IPv6 will use a different key for their cidr4.
+	var cidr4 string
+	if v.IsSet(cidr4Key) {
+		cidr4 = v.GetString(cidr4Key)
+	} else if v.IsSet(cidrKey) {
+		cidr4 = v.GetString(cidrKey)
+	}
+
+	if cidr4 == "" {
+		return nil
+	}
+	myPrefix, err := netip.ParsePrefix(cidr4)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to parse CIDR '%s': %v",
+			cidr4,
+			err,
+		)
+	}
+
+	// If neither static nor dynamic pool is defined we can use the last available IP in the subnet
+	poolStartIP, err := networking.Broadcast(myPrefix)
+	if err != nil {
+		return err
+	}
+
+	// Do not overlap the static or dynamic pools
+	myStaticPoolName := fmt.Sprintf(
+		"%s-static-pool",
+		netNameLower,
+	)
+	myDynPoolName := fmt.Sprintf(
+		"%s-dynamic-pool",
+		netNameLower,
+	)
+
+	myStaticPoolCIDR := v.GetString(myStaticPoolName)
+	myDynPoolCIDR := v.GetString(myDynPoolName)
+
+	if len(myStaticPoolCIDR) > 0 && len(myDynPoolCIDR) > 0 {
+		// Both pools are defined so find the start of whichever pool comes first
+		_, myStaticPool, _ := net.ParseCIDR(myStaticPoolCIDR)
+		_, myDynamicPool, _ := net.ParseCIDR(myDynPoolCIDR)
+		myStaticPoolPrefix, parseErr := netip.ParsePrefix(myStaticPoolCIDR)
+		myDynamicPoolPrefix, parseDynamicErr := netip.ParsePrefix(myDynPoolCIDR)
+		if parseErr != nil || parseDynamicErr != nil {
+			return errors.Join(
+				parseErr,
+				parseDynamicErr,
+			)
+		}
+		if myStaticPoolPrefix.Addr().Compare(myDynamicPoolPrefix.Addr()) == -1 {
+			poolStartIP, err = netip.ParseAddr(myStaticPool.IP.String())
+		} else {
+			poolStartIP, err = netip.ParseAddr(myDynamicPool.IP.String())
+		}
+	} else if len(myStaticPoolCIDR) > 0 && len(myDynPoolCIDR) == 0 {
+		// Only the static pool is defined so use the first IP of that pool
+		_, myStaticPool, _ := net.ParseCIDR(myStaticPoolCIDR)
+		poolStartIP, err = netip.ParseAddr(myStaticPool.IP.String())
+	} else if len(myStaticPoolCIDR) == 0 && len(myDynPoolCIDR) > 0 {
+		// Only the dynamic pool is defined so use the first IP of that pool
+		_, myDynamicPool, _ := net.ParseCIDR(myDynPoolCIDR)
+		poolStartIP, err = netip.ParseAddr(myDynamicPool.IP.String())
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"failed to parse a static or dynamic pool because %v",
+			err,
+		)
+	}
+
+	// Guidance has changed on whether the CAN gw should be at the start or end of the
+	// range. Here we account for it being at the end of the range.
+	// Leaving this check in place for CMN because it is harmless to do so.
+	subnetGateway, err := netip.ParseAddr(subnet.Gateway.String())
+	if err != nil {
+		return fmt.Errorf(
+			"failed to parse subnet gateway because %v",
+			err,
+		)
+	}
+	if subnetGateway == poolStartIP.Prev() {
+		// The gw *is* at the end, so shorten the range to accommodate
+		subnet.DHCPEnd = poolStartIP.Prev().Prev().AsSlice()
+	} else {
+		// The gw is not at the end
+		subnet.DHCPEnd = poolStartIP.Prev().AsSlice()
+	}
+	return nil
+}
@@ -0,0 +1,842 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	slsInit "github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/verify"
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+)
+
+const generateFixtureDir = "../../../../testdata/fixtures/init"
+
+type GenerateTestSuite struct {
+	suite.Suite
+	inputs Inputs
+}
+
+func (suite *GenerateTestSuite) SetupTest() {
+	viper.Reset()
+	v := viper.GetViper()
+	suite.Require().NoError(v.BindPFlags(NewCommand().Flags()))
+	v.SetConfigFile(
+		filepath.Join(
+			generateFixtureDir,
+			"system_config.yaml",
+		),
+	)
+	suite.Require().NoError(v.ReadInConfig())
+
+	inputs, err := CollectInputs(v)
+	suite.Require().NoError(err)
+	suite.inputs = inputs
+}
+
+func (suite *GenerateTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// withFlags returns the inputs of the suite with the given settings layered on top of its flags.
+func (suite *GenerateTestSuite) withFlags(flags map[string]interface{}) Inputs {
+	inputs := suite.inputs
+	inputs.Flags = maps.Clone(suite.inputs.Flags)
+	maps.Copy(
+		inputs.Flags,
+		flags,
+	)
+	return inputs
+}
+
+func (suite *GenerateTestSuite) TestGenerate_HappyPath() {
+	outputs, err := Generate(
+		context.Background(),
+		suite.inputs,
+	)
+	suite.Require().NoError(err)
+
+	suite.Equal(
+		"eniac",
+		outputs.SystemName,
+	)
+	suite.Contains(
+		outputs.Networks,
+		"NMN",
+	)
+	suite.Len(
+		outputs.LogicalNCNs,
+		len(suite.inputs.LogicalNCNs),
+	)
+	suite.Len(
+		outputs.UANs,
+		2,
+	)
+	suite.NotEmpty(outputs.SLSState.Hardware)
+	suite.NotEmpty(outputs.SLSState.Networks)
+	suite.NotEmpty(outputs.Customizations.Networking.NetStaticIps.NmnTftp)
+	for _, name := range []string{
+		"sls_input_file.json",
+		"customizations.yaml",
+		"basecamp/data.json",
+		"dnsmasq.d/NMN.conf",
+		"pit-files/ifcfg-bond0.nmn0",
+	} {
+		suite.Contains(
+			outputs.Files,
+			name,
+		)
+	}
+}
+
+func (suite *GenerateTestSuite) TestGenerate_Flags() {
+	_, err := Generate(
+		context.Background(),
+		Inputs{
+			Flags: map[string]interface{}{
+				"bgp-asn": 1,
+			},
+			LogicalNCNs: suite.inputs.LogicalNCNs,
+			Switches:    suite.inputs.Switches,
+			Cabinets:    suite.inputs.Cabinets,
+			HMNRows:     suite.inputs.HMNRows,
+		},
+	)
+	suite.ErrorContains(
+		err,
+		"bgp-asn",
+	)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_Canceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Generate(
+		ctx,
+		suite.inputs,
+	)
+	suite.ErrorIs(
+		err,
+		context.Canceled,
+	)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_SecretsResolvedOnce() {
+	runs := filepath.Join(
+		suite.T().TempDir(),
+		"runs",
+	)
+	outputs, err := Generate(
+		context.Background(),
+		suite.withFlags(
+			map[string]interface{}{
+				"bootstrap-ncn-bmc-pass": fmt.Sprintf(
+					"cmd:echo >> %s; echo from-cmd",
+					runs,
+				),
+			},
+		),
+	)
+	suite.Require().NoError(err)
+	suite.Contains(
+		string(outputs.Files["conman.conf"]),
+		"P:from-cmd,",
+	)
+	data, err := os.ReadFile(runs)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"\n",
+		string(data),
+	)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_Concurrent() {
+	inputs := suite.withFlags(
+		map[string]interface{}{
+			"reproducible": true,
+			// Nested settings are lower-cased when the system config is rendered, the inputs must keep their case.
+			"site-labels": []interface{}{
+				map[string]interface{}{
+					"Name": "eniac",
+				},
+			},
+		},
+	)
+	flags := copySettings(inputs.Flags)
+
+	var wg sync.WaitGroup
+	outputs := make(
+		[]*Outputs,
+		2,
+	)
+	errs := make(
+		[]error,
+		len(outputs),
+	)
+	for i := range outputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outputs[i], errs[i] = Generate(
+				context.Background(),
+				inputs,
+			)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		suite.Require().NoError(err)
+	}
+	suite.Equal(
+		outputs[0].Files,
+		outputs[1].Files,
+	)
+	suite.Equal(
+		flags,
+		inputs.Flags,
+	)
+}
+
+func (suite *GenerateTestSuite) TestOutputsWrite() {
+	outputs, err := Generate(
+		context.Background(),
+		suite.inputs,
+	)
+	suite.Require().NoError(err)
+
+	basepath := filepath.Join(
+		suite.T().TempDir(),
+		outputs.SystemName,
+	)
+	suite.Require().NoError(outputs.Write(basepath))
+	for name, contents := range outputs.Files {
+		written, err := os.ReadFile(
+			filepath.Join(
+				basepath,
+				name,
+			),
+		)
+		suite.Require().NoError(err)
+		suite.Equal(
+			contents,
+			written,
+		)
+	}
+
+	// The manifest covers every written file and the fixture's input files.
+	report, err := verify.Verify(
+		basepath,
+		generateFixtureDir,
+	)
+	suite.Require().NoError(err)
+	suite.True(
+		report.Empty(),
+		report,
+	)
+	manifest, err := verify.LoadManifest(basepath)
+	suite.Require().NoError(err)
+	suite.Contains(
+		manifest.Inputs,
+		"ncn-metadata",
+	)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_Reproducible() {
+	inputs := suite.withFlags(
+		map[string]interface{}{
+			"reproducible": true,
+		},
+	)
+	first, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+	second, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+
+	suite.Equal(
+		first.Files,
+		second.Files,
+	)
+	suite.Contains(
+		string(first.Files["dnsmasq.d/NMN.conf"]),
+		"Generated time: 1970-01-01T00:00:00Z",
+	)
+	suite.Equal(
+		DeriveInstanceID(
+			"eniac",
+			first.LogicalNCNs[0].Xname,
+		),
+		first.LogicalNCNs[0].InstanceID,
+	)
+
+	// Neither the flags nor the pinned time of a call are left behind for the next one.
+	suite.False(viper.GetBool("reproducible"))
+	third, err := Generate(
+		context.Background(),
+		suite.inputs,
+	)
+	suite.Require().NoError(err)
+	suite.NotContains(
+		string(third.Files["dnsmasq.d/NMN.conf"]),
+		"Generated time: 1970-01-01T00:00:00Z",
+	)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_SourceDateEpoch() {
+	suite.T().Setenv(
+		SourceDateEpochEnv,
+		"1700000000",
+	)
+	outputs, err := Generate(
+		context.Background(),
+		suite.inputs,
+	)
+	suite.Require().NoError(err)
+	suite.Contains(
+		string(outputs.Files["dnsmasq.d/NMN.conf"]),
+		"Generated time: 2023-11-14T22:13:20Z",
+	)
+
+	suite.T().Setenv(
+		SourceDateEpochEnv,
+		"yesterday",
+	)
+	_, err = Generate(
+		context.Background(),
+		suite.inputs,
+	)
+	suite.ErrorContains(
+		err,
+		SourceDateEpochEnv,
+	)
+}
+
+func (suite *GenerateTestSuite) TestNewPlan() {
+	inputs := suite.inputs
+	inputs.SkipFiles = true
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+	suite.Empty(outputs.Files)
+
+	plan := NewPlan(outputs)
+	suite.Len(
+		plan.Networks,
+		len(outputs.Networks),
+	)
+	owners := make(map[string]string)
+	for _, network := range plan.Networks {
+		if network.Name != "NMN" {
+			continue
+		}
+		for _, subnet := range network.Subnets {
+			for _, reservation := range subnet.Reservations {
+				if reservation.Owner != nil {
+					owners[reservation.Owner.Xname] = reservation.Owner.Kind
+				}
+			}
+		}
+	}
+	suite.Equal(
+		PlanOwnerNCN,
+		owners["x3000c0s1b0n0"],
+	)
+	suite.Equal(
+		PlanOwnerSwitch,
+		owners["x3000c0h37s1"],
+	)
+
+	for _, format := range []string{
+		"json",
+		"yaml",
+	} {
+		document, err := plan.Marshal(format)
+		suite.NoError(err)
+		suite.Contains(
+			string(document),
+			"bootstrap_dhcp",
+		)
+	}
+	_, err = plan.Marshal("xml")
+	suite.Error(err)
+}
+
+func (suite *GenerateTestSuite) TestNewSummary() {
+	inputs := suite.inputs
+	inputs.SkipFiles = true
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+
+	summary := NewSummary(
+		viper.GetViper(),
+		outputs,
+	)
+	suite.Equal(
+		"eniac",
+		summary.SystemName,
+	)
+	suite.Equal(
+		len(outputs.LogicalNCNs),
+		summary.NCNs,
+	)
+	suite.Equal(
+		map[slsCommon.CabinetType]int{
+			slsCommon.ClassMountain: 4,
+			slsCommon.ClassHill:     0,
+			slsCommon.ClassRiver:    1,
+		},
+		summary.Cabinets,
+	)
+	var nmn SummaryNetwork
+	for _, network := range summary.Networks {
+		if network.Name == "NMN" {
+			nmn = network
+		}
+	}
+	suite.Equal(
+		outputs.Networks["NMN"].CIDR4,
+		nmn.CIDR4,
+	)
+	suite.Len(
+		nmn.Subnets4,
+		len(outputs.Networks["NMN"].AllocatedIPv4Subnets()),
+	)
+	suite.Contains(
+		nmn.Subnets4,
+		"10.252.1.0/17",
+	)
+
+	for _, format := range []string{
+		"json",
+		"yaml",
+	} {
+		document, err := summary.Marshal(format)
+		suite.NoError(err)
+		suite.Contains(
+			string(document),
+			"subnets4",
+		)
+	}
+	_, err = summary.Marshal("text")
+	suite.Error(err)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_ExtraNetworks() {
+	inputs := suite.inputs
+	inputs.ExtraNetworks = []slsInit.ExtraNetwork{
+		{
+			Name:                 "stor",
+			FullName:             "Storage Network",
+			CIDR4:                "10.110.0.0/22",
+			BaseVlan:             20,
+			BootstrapDHCP:        true,
+			ReservationHostnames: []string{"nas-01"},
+		},
+		{
+			Name:               "OOB",
+			CIDR4:              "10.111.0.0/16",
+			BaseVlan:           1400,
+			SubdivideByCabinet: true,
+		},
+	}
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+
+	network := outputs.SLSState.Networks["STOR"]
+	stor, err := sls.UnmarshalNetworkExtraProperties(&network)
+	suite.Require().NoError(err)
+	suite.Len(
+		stor.Subnets,
+		1,
+	)
+	suite.Equal(
+		int16(20),
+		stor.Subnets[0].VlanID,
+	)
+	reservations := make(map[string]string)
+	for _, reservation := range stor.Subnets[0].IPReservations {
+		reservations[reservation.Name] = reservation.IPAddress.String()
+	}
+	suite.Equal(
+		"10.110.0.2",
+		reservations["nas-01"],
+	)
+	suite.Contains(
+		reservations,
+		"ncn-m001",
+	)
+	suite.NotNil(stor.Subnets[0].DHCPStart)
+
+	// Every cabinet is given the next VLAN of the network.
+	network = outputs.SLSState.Networks["OOB"]
+	oob, err := sls.UnmarshalNetworkExtraProperties(&network)
+	suite.Require().NoError(err)
+	suite.Len(
+		oob.Subnets,
+		5,
+	)
+	for i, subnet := range oob.Subnets {
+		suite.Equal(
+			int16(1400+i),
+			subnet.VlanID,
+		)
+	}
+	suite.Equal(
+		[]int16{
+			1400,
+			1404,
+		},
+		oob.VlanRange,
+	)
+
+	suite.Contains(
+		outputs.Files,
+		"pit-files/ifcfg-bond0.stor0",
+	)
+	suite.Contains(
+		string(outputs.Files["basecamp/data.json"]),
+		"ncn-m002.stor",
+	)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_IPv6() {
+	inputs := suite.withFlags(
+		map[string]interface{}{
+			"nmn-cidr6":     "fd00:252::/64",
+			"hmn-cidr6":     "fd00:254::/64",
+			"mtl-cidr6":     "fd00:1::/64",
+			"nmn-mtn-cidr6": "fd00:100::/64",
+		},
+	)
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+
+	// Every cabinet is given its own IPv6 subnet, as large as its IPv4 subnet.
+	network := outputs.SLSState.Networks["NMN_MTN"]
+	nmnMTN, err := sls.UnmarshalNetworkExtraProperties(&network)
+	suite.Require().NoError(err)
+	var cidrs, gateways []string
+	for _, subnet := range nmnMTN.Subnets {
+		cidrs = append(
+			cidrs,
+			subnet.CIDR6,
+		)
+		gateways = append(
+			gateways,
+			subnet.Gateway6.String(),
+		)
+	}
+	suite.Equal(
+		[]string{
+			"fd00:100::/118",
+			"fd00:100::400/118",
+			"fd00:100::800/118",
+			"fd00:100::c00/118",
+		},
+		cidrs,
+	)
+	suite.Equal(
+		[]string{
+			"fd00:100::1",
+			"fd00:100::401",
+			"fd00:100::801",
+			"fd00:100::c01",
+		},
+		gateways,
+	)
+
+	for _, ncn := range outputs.LogicalNCNs {
+		if ncn.Hostname != "ncn-m001" {
+			continue
+		}
+		addresses := make(map[string]string)
+		for _, ncnNetwork := range ncn.Networks {
+			if ncnNetwork.CIDR6.IsValid() {
+				addresses[ncnNetwork.NetworkName] = ncnNetwork.CIDR6.String()
+				suite.Equal(
+					ncnNetwork.CIDR6.Masked().Addr().Next(),
+					ncnNetwork.Gateway6,
+				)
+			}
+		}
+		suite.Equal(
+			map[string]string{
+				"HMN": "fd00:254::101/64",
+				"MTL": "fd00:1::100/64",
+				"NMN": "fd00:252::100/64",
+			},
+			addresses,
+		)
+	}
+	suite.Contains(
+		string(outputs.Files["pit-files/ifcfg-bond0.nmn0"]),
+		"IPADDR_6='fd00:252::100/64'\n",
+	)
+	suite.Contains(
+		string(outputs.Files["pit-files/ifcfg-bond0"]),
+		"IPADDR_6='fd00:1::100/64'\n",
+	)
+	suite.Contains(
+		string(outputs.Files["dnsmasq.d/NMN.conf"]),
+		"dhcp-range=interface:bond0.nmn0,fd00:252::,static,64,10m\n",
+	)
+	suite.Contains(
+		string(outputs.Files["dnsmasq.d/statics.conf"]),
+		"host-record=ncn-m001,ncn-m001.nmn,10.252.1.0,fd00:252::100\n",
+	)
+	suite.Contains(
+		string(outputs.Files["basecamp/data.json"]),
+		`"ip6": "fd00:252::100/64"`,
+	)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_ExtraNetworksInvalid() {
+	for _, test := range []struct {
+		extraNetwork slsInit.ExtraNetwork
+		expected     string
+	}{
+		{
+			extraNetwork: slsInit.ExtraNetwork{
+				Name:     "NMN",
+				CIDR4:    "10.110.0.0/22",
+				BaseVlan: 20,
+			},
+			expected: "can not replace the CSM network",
+		},
+		{
+			extraNetwork: slsInit.ExtraNetwork{
+				Name:     "STOR",
+				CIDR4:    "10.252.5.0/24",
+				BaseVlan: 20,
+			},
+			expected: "extra network STOR [10.252.5.0/24] overlaps the NMN network",
+		},
+		{
+			extraNetwork: slsInit.ExtraNetwork{
+				Name:     "STOR",
+				CIDR4:    "10.110.0.0/22",
+				BaseVlan: 2,
+			},
+			expected: "unable to allocate single VLAN 2",
+		},
+		{
+			extraNetwork: slsInit.ExtraNetwork{
+				Name:          "STORAGE_NET",
+				CIDR4:         "10.110.0.0/22",
+				BaseVlan:      20,
+				BootstrapDHCP: true,
+			},
+			expected: "longer than 15 bytes",
+		},
+	} {
+		inputs := suite.inputs
+		inputs.SkipFiles = true
+		inputs.ExtraNetworks = []slsInit.ExtraNetwork{test.extraNetwork}
+		_, err := Generate(
+			context.Background(),
+			inputs,
+		)
+		suite.ErrorContains(
+			err,
+			test.expected,
+		)
+	}
+}
+
+func (suite *GenerateTestSuite) TestGenerate_PreviousSLS() {
+	inputs := suite.inputs
+	inputs.SkipFiles = true
+	previous, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+
+	// Reordering the NCNs would hand out their addresses in a different order, unless they are seeded.
+	inputs.LogicalNCNs = slices.Clone(inputs.LogicalNCNs)
+	slices.Reverse(inputs.LogicalNCNs)
+	inputs.PreviousSLS = &previous.SLSState
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		suite.reservedAddresses(previous.SLSState),
+		suite.reservedAddresses(outputs.SLSState),
+	)
+
+	// A previous SLS state with a different NMN can not be kept.
+	nmn := previous.SLSState.Networks["NMN"]
+	nmn.IPRanges = []string{"10.1.0.0/16"}
+	extraProperties, err := sls.UnmarshalNetworkExtraProperties(&nmn)
+	suite.Require().NoError(err)
+	extraProperties.CIDR = "10.1.0.0/16"
+	nmn.ExtraPropertiesRaw = extraProperties
+	inputs.PreviousSLS = &slsCommon.SLSState{
+		Networks: map[string]slsCommon.Network{
+			"NMN": nmn,
+		},
+	}
+	_, err = Generate(
+		context.Background(),
+		inputs,
+	)
+	var conflictErr *ReservationConflictError
+	suite.Require().ErrorAs(
+		err,
+		&conflictErr,
+	)
+	suite.Equal(
+		"10.1.0.0/16",
+		conflictErr.Conflicts[0].Previous,
+	)
+}
+
+func (suite *GenerateTestSuite) TestGenerate_ReservedVLANs() {
+	inputs := suite.withFlags(
+		map[string]interface{}{
+			"reserved-vlans": []string{
+				"100",
+				"1000-1099",
+			},
+		},
+	)
+	inputs.SkipFiles = true
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+	report := outputs.VLANs.Report()
+	reserved := make(map[uint16]string)
+	owners := make(map[string]uint16)
+	for _, ownership := range report {
+		if ownership.Reserved != "" {
+			reserved[ownership.VLAN] = ownership.Reserved
+		}
+		for _, owner := range ownership.Owners {
+			if owner.Subnet != "" {
+				owners[owner.Network+"/"+owner.Subnet] = ownership.VLAN
+			}
+		}
+	}
+	suite.Equal(
+		"reserved-vlans",
+		reserved[100],
+	)
+	suite.Equal(
+		"reserved-vlans",
+		reserved[1050],
+	)
+	suite.Equal(
+		"IEEE 802.1Q",
+		reserved[4095],
+	)
+	suite.Equal(
+		uint16(2),
+		owners["NMN/bootstrap_dhcp"],
+	)
+	suite.Equal(
+		uint16(2),
+		owners["NMNLB/nmn_metallb_address_pool"],
+	)
+
+	// Reserving a VLAN the system allocates fails the run.
+	inputs.Flags["reserved-vlans"] = []string{"2"}
+	_, err = Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.ErrorContains(
+		err,
+		"VLAN 2 is reserved (reserved-vlans)",
+	)
+
+	inputs.Flags["reserved-vlans"] = []string{"4000-4096"}
+	_, err = Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.ErrorContains(
+		err,
+		`"4000-4096" is not a range of VLAN IDs`,
+	)
+}
+
+// reservedAddresses returns the address of every IP reservation in the SLS state, keyed by network, subnet, and name.
+func (suite *GenerateTestSuite) reservedAddresses(state slsCommon.SLSState) map[string]string {
+	addresses := make(map[string]string)
+	for name, network := range state.Networks {
+		extraProperties, err := sls.UnmarshalNetworkExtraProperties(&network)
+		suite.Require().NoError(err)
+		for _, subnet := range extraProperties.Subnets {
+			for _, reservation := range subnet.IPReservations {
+				addresses[fmt.Sprintf(
+					"%s/%s/%s",
+					name,
+					subnet.Name,
+					reservation.Name,
+				)] = reservation.IPAddress.String()
+			}
+		}
+	}
+	return addresses
+}
+
+func TestGenerateTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(GenerateTestSuite),
+	)
+}
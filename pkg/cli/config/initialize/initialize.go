@@ -27,17 +27,19 @@ package initialize
 import (
 	"fmt"
 	"log"
+	"maps"
 	"net"
 	"net/netip"
 	"os"
 	"path"
 	"path/filepath"
 	"slices"
-	"sort"
 	"strings"
+	"sync"
 
 	"github.com/Cray-HPE/cray-site-init/pkg/cli"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"golang.org/x/mod/semver"
 
@@ -46,14 +48,45 @@ import (
 
 	"github.com/Cray-HPE/cray-site-init/internal/files"
 	slsInit "github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/verify"
 	"github.com/Cray-HPE/cray-site-init/pkg/csm"
 	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
 	"github.com/Cray-HPE/cray-site-init/pkg/networking"
 	"github.com/Cray-HPE/cray-site-init/pkg/version"
 )
 
-// NewCommand represents the init command
+// NewCommand represents the init command, the AliasKeys are registered on the global viper it reads its settings from.
 func NewCommand() *cobra.Command {
+	c := newCommand()
+	registerAliases(
+		viper.GetViper(),
+		AliasKeys,
+	)
+	return c
+}
+
+var (
+	initFlagSet     *pflag.FlagSet
+	initFlagSetOnce sync.Once
+)
+
+/*
+initFlags returns the flags of the init command. The set is built once and only read afterwards, every Generate call
+binds a viper of its own to it.
+*/
+func initFlags() *pflag.FlagSet {
+	initFlagSetOnce.Do(
+		func() {
+			initFlagSet = newCommand().Flags()
+			// VisitAll caches the sorted flags on its first call, unsorted it never writes to the set.
+			initFlagSet.SortFlags = false
+		},
+	)
+	return initFlagSet
+}
+
+// newCommand builds the init command and its flags.
+func newCommand() *cobra.Command {
 	c := &cobra.Command{
 		Use:   "init",
 		Short: "Generates a Shasta configuration payload",
@@ -79,6 +112,63 @@ func NewCommand() *cobra.Command {
 	3. Specify Application node Aliases
 	** NB **
 
+	** NB **
+	Site-specific networks (e.g. a storage backend VLAN) can be built alongside the CSM networks by listing them in a file
+	given to the --extra-networks-yaml flag:
+
+	networks:
+	  - name: STOR                      # network name in SLS, the NCN interface is bond0.stor0
+	    full-name: Storage Network
+	    cidr4: 10.110.0.0/22
+	    cidr6: fd00:110::/64            # optional
+	    base-vlan: 20
+	    bootstrap-dhcp: true            # give every NCN an address on the network
+	    subdivide-by-cabinet: false     # give every cabinet its own subnet and VLAN, starting at base-vlan
+	    reservation-hostnames: [nas-01] # addresses to reserve in the bootstrap-dhcp subnet
+	** NB **
+
+	** NB **
+	The cloud-init runcmd of the NCNs can be changed by a file given to the --runcmd-yaml flag. It is keyed by the NCN
+	role (Management, every NCN) or subrole (Master, Worker, Storage, FabricManager), the role is applied first.
+	Entries are removed, replaced, prepended, and appended in that order:
+
+	Management:
+	  prepend:
+	    - /srv/site/scripts/harden.sh
+	Storage:
+	  remove:
+	    - /srv/cray/scripts/common/pre-load-images.sh
+	  replace:
+	    /srv/cray/scripts/metal/install.sh: /srv/site/scripts/install.sh
+	  append:
+	    - /srv/site/scripts/storage-done.sh
+	** NB **
+
+	** NB **
+	The --bootstrap-ncn-bmc-user, --bootstrap-ncn-bmc-pass, and --metallb-bgp-password flags take either the value
+	itself or a secret reference, which is resolved when conman.conf and metallb-resources.yaml are written:
+
+	file:/root/bmc-pass                                            the contents of a file
+	env:BMC_PASS                                                   an environment variable
+	cmd:pass show bmc                                              the output of a shell command
+	sealed:customizations.yaml#cray_reds_credentials/bmc_password  a sealed secret, decrypted with --sealed-secret-key-file
+
+	A password that is not a reference is written to system_config.yaml as <redacted>, and must be given again when
+	the system is re-initialized from it.
+	** NB **
+
+	** NB **
+	The IPv6 subnets of the cabinets (--nmn-mtn-cidr6, --nmn-rvr-cidr6, --hmn-mtn-cidr6, and --hmn-rvr-cidr6) are not
+	/64s, each cabinet is given as many IPv6 addresses as IPv4 addresses: a /118 for its /22. They have no SLAAC, their
+	addresses are assigned by DHCPv6 or statically, alongside the IPv4 ones.
+	** NB **
+
+	** NB **
+	VLANs used upstream of the system can be kept out of allocation with the --reserved-vlans flag (e.g. 100,3000-3099),
+	a network or subnet given a reserved VLAN fails the run. The VLANs in use, and the networks and subnets using them,
+	are written to vlans.json.
+	** NB **
+
 	In addition, there are many flags to impact the layout of the system. The defaults are generally fine except for the networking flags.
 	`,
 		DisableAutoGenTag: true,
@@ -90,341 +180,59 @@ func NewCommand() *cobra.Command {
 			if err != nil {
 				log.Fatalln(err)
 			}
-			flagErrors := validateFlags()
-			if len(flagErrors) > 0 {
-				fmt.Println("Errors:")
-				for _, e := range flagErrors {
-					fmt.Println(e)
-				}
-				log.Fatalln("The program could not continue, one or more flags had invalid values.")
-			}
 
-			if len(
-				strings.Split(
-					v.GetString("site-ip"),
-					"/",
-				),
-			) != 2 {
-				_ = c.Usage()
+			planFormat := v.GetString("plan")
+			if planFormat != "" && planFormat != "json" && planFormat != "yaml" {
 				log.Fatalf(
-					"FATAL ERROR: Unable to parse %s as --site-ip. Must be in the format \"192.168.0.1/24\"",
-					v.GetString("site-ip"),
+					"FATAL ERROR: Unsupported --plan format %q, must be json or yaml",
+					planFormat,
 				)
-
 			}
 
-			// Validate that the BGP ASN is within the private range
-			var bgp = map[string]int{
-				"bgp-asn":     v.GetInt("bgp-asn"),
-				"bgp-chn-asn": v.GetInt("bgp-chn-asn"),
-				"bgp-cmn-asn": v.GetInt("bgp-cmn-asn"),
-				"bgp-nmn-asn": v.GetInt("bgp-nmn-asn"),
-			}
-
-			for network, asn := range bgp {
-				if asn > 65534 || asn < 64512 {
-					_ = c.Usage()
-					log.Fatalln(
-						"FATAL ERROR: BGP ASNs must be within the private range 64512-65534, fix the value for:",
-						network,
-					)
-				}
+			outputFormat := v.GetString("output")
+			if outputFormat != "text" && outputFormat != "json" && outputFormat != "yaml" {
+				log.Fatalf(
+					"FATAL ERROR: Unsupported --output format %q, must be text, json, or yaml",
+					outputFormat,
+				)
 			}
 
 			// Read and validate our three input files
-			hmnRows, logicalNCNs, switches, applicationNodeConfig, cabinetDetailList, errs := collectInput(v)
-			if errs != nil {
+			inputs, err := CollectInputs(v)
+			if err != nil {
 				log.Fatalf(
 					"FATAL ERROR: Failed to collect one or more input files: %v",
-					errs,
+					err,
 				)
 			}
+			inputs.SkipFiles = planFormat != ""
 
-			defaultNetConfigs := GenerateDefaultNetworkConfigs(
-				switches,
-				logicalNCNs,
-				cabinetDetailList,
-			)
-			internalNetConfigs, err := GenerateNetworkConfigs(defaultNetConfigs)
-			if err != nil {
-				log.Fatal(err)
-			}
-			// Build a set of networks we can use
-			shastaNetworks, err := slsInit.BuildCSMNetworks(
-				internalNetConfigs,
-				cabinetDetailList,
-				switches,
+			outputs, err := Generate(
+				c.Context(),
+				inputs,
 			)
 			if err != nil {
-				log.Fatal(err)
-			}
-
-			// Use our new networks and our list of logicalNCNs to distribute ips
-			AllocateIPs(
-				logicalNCNs,
-				shastaNetworks,
-			)
-
-			// Now we can finally generate the slsState
-			slsState := prepareAndGenerateSLS(
-				cabinetDetailList,
-				shastaNetworks,
-				hmnRows,
-				switches,
-				applicationNodeConfig,
-				v.GetInt("starting-mountain-nid"),
-			)
-			// SLS can tell us which NCNs match with which Xnames, we need to update the IP Reservations
-			slsNcns, err := ExtractSLSNCNs(&slsState)
-			if err != nil {
-				log.Panic(err) // This should never happen. I can't really imagine how it would.
-			}
-
-			// Merge the SLS NCN list with the NCN list we got at the beginning
-			logicalNCNs, err = mergeNCNs(
-				logicalNCNs,
-				slsNcns,
-			)
-			if err != nil {
-				log.Fatalln(err)
-			}
-
-			// Pull UANs from the completed slsState to assign CAN addresses
-			slsUans, err := ExtractUANs(&slsState)
-			if err != nil {
-				log.Panic(err) // This should never happen. I can't really imagine how it would.
-			}
-
-			// Only add UANs if there actually is a CAN network
-			if v.GetString("bican-user-network-name") == "CAN" || v.GetBool("retain-unused-user-network") {
-				canSubnet, _ := shastaNetworks["CAN"].LookUpSubnet("bootstrap_dhcp")
-				for _, uan := range slsUans {
-					_, err := networking.AddReservation(
-						canSubnet,
-						uan.Hostname,
-						uan.Xname,
-					)
-					if err != nil {
-						return
-					}
-				}
-			}
-			// Only add UANs if there actually is a CHN network
-			if v.GetString("bican-user-network-name") == "CHN" || v.GetBool("retain-unused-user-network") {
-				chnSubnet, _ := shastaNetworks["CHN"].LookUpSubnet("bootstrap_dhcp")
-				for _, uan := range slsUans {
-					_, err := networking.AddReservation(
-						chnSubnet,
-						uan.Hostname,
-						uan.Xname,
-					)
-					if err != nil {
-						return
-					}
-				}
+				log.Fatalf(
+					"FATAL ERROR: %v",
+					err,
+				)
 			}
 
-			// Cycle through the main networks and update the reservations, masks and dhcp ranges as necessary
-			for _, network := range networking.ValidNetNames {
-				if shastaNetworks[network] != nil {
-					// Grab the supernet details for use in HACK substitution
-					if err == nil {
-						// Loop the reservations and update the NCN reservations with hostnames
-						// we likely didn't have when we registered the reservation
-						subnet, err := updateReservations(
-							shastaNetworks[network],
-							"bootstrap_dhcp",
-							logicalNCNs,
-						)
-						if err != nil {
-							continue
-						}
-						if network == "CAN" || network == "CMN" || network == "CHN" {
-							netNameLower := strings.ToLower(network)
-
-							// Do not use supernet hack for the CAN/CMN/CHN, these should reflect the abstracted subnets.
-							err := networking.UpdateDHCPRange(
-								subnet,
-								false,
-							)
-							if err != nil {
-								log.Fatalf(
-									"Error updating DHCP range: %v\n",
-									err,
-								)
-							}
-
-							cidr4Key := fmt.Sprintf(
-								"%s-cidr4",
-								netNameLower,
-							)
-							cidrKey := fmt.Sprintf(
-								"%s-cidr",
-								netNameLower,
-							)
-
-							// Handle IPv4 CIDRs, networks with IPv6 will use a different key for their cidr4.
-							var cidr4 string
-							if v.IsSet(cidr4Key) {
-								cidr4 = v.GetString(cidr4Key)
-							} else if v.IsSet(cidrKey) {
-								cidr4 = v.GetString(cidrKey)
-							}
-
-							if cidr4 == "" {
-								continue
-							}
-							myPrefix, err := netip.ParsePrefix(cidr4)
-							if err != nil {
-								log.Fatalf(
-									"Unable to parse CIDR '%s': %v",
-									cidr4,
-									err,
-								)
-							}
-
-							// If neither static nor dynamic pool is defined we can use the last available IP in the subnet
-							poolStartIP, err := networking.Broadcast(myPrefix)
-							if err != nil {
-								log.Fatal(err)
-							}
-
-							// Do not overlap the static or dynamic pools
-							myStaticPoolName := fmt.Sprintf(
-								"%s-static-pool",
-								netNameLower,
-							)
-							myDynPoolName := fmt.Sprintf(
-								"%s-dynamic-pool",
-								netNameLower,
-							)
-
-							myStaticPoolCIDR := v.GetString(myStaticPoolName)
-							myDynPoolCIDR := v.GetString(myDynPoolName)
-
-							if len(myStaticPoolCIDR) > 0 && len(myDynPoolCIDR) > 0 {
-								// Both pools are defined so find the start of whichever pool comes first
-								_, myStaticPool, _ := net.ParseCIDR(myStaticPoolCIDR)
-								_, myDynamicPool, _ := net.ParseCIDR(myDynPoolCIDR)
-								myStaticPoolPrefix, parseErr := netip.ParsePrefix(myStaticPoolCIDR)
-								myDynamicPoolPrefix, parseDynamicErr := netip.ParsePrefix(myDynPoolCIDR)
-								if parseErr != nil || parseDynamicErr != nil {
-									log.Fatalln(
-										parseErr,
-										parseDynamicErr,
-									)
-								}
-								if myStaticPoolPrefix.Addr().Compare(myDynamicPoolPrefix.Addr()) == -1 {
-									poolStartIP, err = netip.ParseAddr(myStaticPool.IP.String())
-								} else {
-									poolStartIP, err = netip.ParseAddr(myDynamicPool.IP.String())
-								}
-							} else if len(myStaticPoolCIDR) > 0 && len(myDynPoolCIDR) == 0 {
-								// Only the static pool is defined so use the first IP of that pool
-								_, myStaticPool, _ := net.ParseCIDR(myStaticPoolCIDR)
-								poolStartIP, err = netip.ParseAddr(myStaticPool.IP.String())
-							} else if len(myStaticPoolCIDR) == 0 && len(myDynPoolCIDR) > 0 {
-								// Only the dynamic pool is defined so use the first IP of that pool
-								_, myDynamicPool, _ := net.ParseCIDR(myDynPoolCIDR)
-								poolStartIP, err = netip.ParseAddr(myDynamicPool.IP.String())
-							}
-							if err != nil {
-								log.Fatalf(
-									"Failed to parse a static or dynamic pool because %v\n",
-									err,
-								)
-							}
-
-							// Guidance has changed on whether the CAN gw should be at the start or end of the
-							// range. Here we account for it being at the end of the range.
-							// Leaving this check in place for CMN because it is harmless to do so.
-							subnetGateway, err := netip.ParseAddr(subnet.Gateway.String())
-							if err != nil {
-								log.Fatalf(
-									"Failed to parse subnet gateway because %v\n",
-									err,
-								)
-							}
-							if subnetGateway == poolStartIP.Prev() {
-								// The gw *is* at the end, so shorten the range to accommodate
-								subnet.DHCPEnd = poolStartIP.Prev().Prev().AsSlice()
-							} else {
-								// The gw is not at the end
-								subnet.DHCPEnd = poolStartIP.Prev().AsSlice()
-							}
-						} else {
-							err := networking.UpdateDHCPRange(
-								subnet,
-								v.GetBool("supernet"),
-							)
-							if err != nil {
-								log.Fatalf(
-									"Error updating DHCP range: %v\n",
-									err,
-								)
-							}
-						}
-					}
-
-					// We expect a bootstrap_dhcp in every net, but uai_macvlan is only in
-					// the NMN range for today
-					if strings.ToUpper(network) == "NMN" {
-						subnet, err := updateReservations(
-							shastaNetworks[network],
-							"uai_macvlan",
-							logicalNCNs,
-						)
-						if err != nil {
-							continue
-						}
-						err = networking.UpdateDHCPRange(
-							subnet,
-							false,
-						)
-						if err != nil {
-							log.Fatalf(
-								"Error updating DHCP range: %v\n",
-								subnet.Name,
-							)
-						}
-					}
-
+			// Plans are printed for review instead of writing anything to the system directory.
+			if planFormat != "" {
+				plan, err := NewPlan(outputs).Marshal(planFormat)
+				if err != nil {
+					log.Fatalln(err)
 				}
+				fmt.Println(string(plan))
+				return
 			}
 
-			// Update the SLSState with the updated network information
-			_, slsState.Networks = prepareNetworkSLS(shastaNetworks)
-
-			// Switch from a list of pointers to a list of things before we write it out
-			var ncns []LogicalNCN
-			for _, ncn := range logicalNCNs {
-				ncns = append(
-					ncns,
-					*ncn,
-				)
-			}
-			globalMetaData, err := MakeBasecampGlobalMetaData(
-				v,
-				ncns,
-				shastaNetworks,
-				"NMN",
-				"bootstrap_dhcp",
-				v.GetString("install-ncn"),
-			)
+			basepath, err := filepath.Abs(filepath.Clean(v.GetString("system-name")))
 			if err != nil {
-				log.Fatalln(
-					"unable to generate basecamp globalMetaData: ",
-					err,
-				)
+				log.Fatalln(err)
 			}
-			err = writeOutput(
-				v,
-				shastaNetworks,
-				slsState,
-				ncns,
-				switches,
-				globalMetaData,
-			)
+			err = outputs.Write(basepath)
 			if err != nil {
 				log.Fatalf(
 					"unable to write one or more output files: %v",
@@ -432,180 +240,21 @@ func NewCommand() *cobra.Command {
 				)
 			}
 
-			// Gather SLS information for summary
-			slsMountainCabinets := GetSLSCabinets(
-				slsState,
-				slsCommon.ClassMountain,
-			)
-			slsHillCabinets := GetSLSCabinets(
-				slsState,
-				slsCommon.ClassHill,
-			)
-			slsRiverCabinets := GetSLSCabinets(
-				slsState,
-				slsCommon.ClassRiver,
-			)
-
-			if v.IsSet("cabinets-yaml") && (v.GetString("cabinets-yaml") != "") && (v.IsSet("mountain-cabinets") ||
-				v.IsSet("starting-mountain-cabinet") ||
-				v.IsSet("river-cabinets") ||
-				v.IsSet("starting-river-cabinet") ||
-				v.IsSet("hill-cabinets") ||
-				v.IsSet("starting-hill-cabinet")) {
-				fmt.Printf("\nWARNING: cabinet flags are not honored when a cabinets-yaml file is provided\n")
-			}
-
-			// Print Summary
-			fmt.Printf(
-				"\n===== %s Installation Summary =====\n\n",
-				v.GetString("system-name"),
-			)
-			fmt.Printf(
-				"%-20s: %s\n",
-				"Installation Node",
-				v.GetString("install-ncn"),
-			)
-
-			fmt.Printf(
-				"%-20s: %s\n",
-				"Upstream DNS",
-				v.GetString("site-dns"),
-			)
-			fmt.Printf(
-				"%-20s: %v\n",
-				"MetalLB Peers",
-				v.GetStringSlice("bgp-peer-types"),
-			)
-			fmt.Printf(
-				"\n----- %s Network Summary -----\n\n",
-				v.GetString("system-name"),
-			)
-			fmt.Printf(
-				"%-20s: %s\n",
-				"BICAN user network",
-				v.GetString("bican-user-network-name"),
-			)
-			fmt.Printf(
-				"%-20s: ",
-				"Supernet",
-			)
-			if v.GetBool("supernet") {
-				fmt.Printf("Enabled (Network gateway used for all SLS subnets)\n")
-			} else {
-				fmt.Printf("Disabled (SLS subnets use their own gateway)\n")
-			}
-
-			fmt.Printf("\nDefined networks:\n\n")
-			sortedNetworks := make(
-				networking.IPNetworks,
-				0,
-				len(shastaNetworks),
+			summary := NewSummary(
+				v,
+				outputs,
 			)
-			for shastaNetwork := range shastaNetworks {
-				sortedNetworks = append(
-					sortedNetworks,
-					shastaNetworks[shastaNetwork],
-				)
-			}
-			sort.Sort(sortedNetworks)
-			for _, network := range sortedNetworks {
-				prefix4, err := netip.ParsePrefix(network.CIDR4)
-				if err != nil || prefix4.Addr().IsUnspecified() {
-					continue
-				}
-				fmt.Printf(
-					"    * %-65s (subnets: %3d) %s\n",
-					network.FullName,
-					len(network.AllocatedIPv4Subnets()),
-					network.CIDR4,
-				)
-				if network.CIDR6 != "" {
-					prefix6, err := netip.ParsePrefix(network.CIDR4)
-					if err != nil || prefix6.Addr().IsUnspecified() {
-						continue
-					}
-					fmt.Printf(
-						"    * %-65s (subnets: %3d) %s\n",
-						fmt.Sprintf(
-							"%s (IPv6)",
-							network.FullName,
-						),
-						len(network.AllocatedIPv6Subnets()),
-						network.CIDR6,
-					)
+			if outputFormat != "text" {
+				b, err := summary.Marshal(outputFormat)
+				if err != nil {
+					log.Fatalln(err)
 				}
+				fmt.Println(string(b))
+				return
 			}
-			fmt.Printf(
-				"\n----- %s System Summary -----\n\n",
-				v.GetString("system-name"),
-			)
-			fmt.Printf(
-				"%-30s: %-3d\n",
-				"NCNs",
-				len(ncns),
-			)
-			fmt.Printf(
-				"%-30s: %-3d\n",
-				"UANs",
-				len(slsUans),
-			)
-			fmt.Printf(
-				"%-30s: %-3d\n",
-				"Switches",
-				len(switches),
-			)
-			fmt.Printf(
-				"%-30s: %-3d\n",
-				"Mountain Compute Cabinets",
-				len(slsMountainCabinets),
-			)
-			fmt.Printf(
-				"%-30s: %-3d\n",
-				"Hill Compute Cabinets",
-				len(slsHillCabinets),
-			)
-			fmt.Printf(
-				"%-30s: %-3d\n",
-				"River Compute Cabinets",
-				len(slsRiverCabinets),
-			)
-			fmt.Printf(
-				"%-30s: %s\n",
-				"CSI Version Information",
-				version.Get(),
-			)
-			fmt.Printf(
-				"\n%s\n********** CONFIG INITIALIZED **********\n%s\n",
-				strings.Repeat(
-					"*",
-					40,
-				),
-				strings.Repeat(
-					"*",
-					40,
-				),
-			)
-			fmt.Printf(
-				"\nNEW %s file!\n\nReplace any offline copies\nof %s with:\n\n%s\n\n",
-				cli.ConfigFilename,
-				cli.ConfigFilename,
-				path.Join(
-					"./",
-					v.GetString("system-name"),
-					cli.ConfigFilename,
-				),
-			)
-			fmt.Println(
-				strings.Repeat(
-					"*",
-					40,
-				),
-			)
-			fmt.Println(
-				strings.Repeat(
-					"*",
-					40,
-				),
+			printSummary(
+				v,
+				summary,
 			)
 		},
 	}
@@ -645,6 +294,16 @@ func NewCommand() *cobra.Command {
 		"p1p1,p1p2",
 		"List of devices to use to form a bond on the install ncn",
 	)
+	c.Flags().String(
+		"pit-dhcp-backend",
+		PITDHCPBackendDNSMasq,
+		"DHCP server of the install ncn (PIT), dnsmasq or kea (kea-dhcp4.conf is written, dnsmasq then only serves DNS)",
+	)
+	c.Flags().String(
+		"pit-network-renderer",
+		PITNetworkRendererIfcfg,
+		"Format of the install ncn (PIT) network configuration in pit-files, ifcfg (wicked), nmstate or networkd (systemd-networkd)",
+	)
 	_ = c.MarkFlagRequired("system-name")
 
 	// NTP
@@ -726,6 +385,11 @@ func NewCommand() *cobra.Command {
 		networking.DefaultNMNString,
 		"Overall IPv4 CIDR for all Node Management subnets",
 	)
+	c.Flags().String(
+		"nmn-cidr6",
+		"",
+		"Overall IPv6 CIDR for all Node Management subnets",
+	)
 	c.Flags().String(
 		"nmn-static-pool",
 		"",
@@ -746,6 +410,16 @@ func NewCommand() *cobra.Command {
 		networking.DefaultNMNRVRString,
 		"IPv4 CIDR for grouped River Node Management subnets",
 	)
+	c.Flags().String(
+		"nmn-mtn-cidr6",
+		"",
+		"IPv6 CIDR for grouped Mountain Node Management subnets, each cabinet is given a /118 (as many addresses as its IPv4 /22)",
+	)
+	c.Flags().String(
+		"nmn-rvr-cidr6",
+		"",
+		"IPv6 CIDR for grouped River Node Management subnets, each cabinet is given a /118 (as many addresses as its IPv4 /22)",
+	)
 	_ = c.MarkFlagRequired("nmn-cidr")
 
 	// Hardware management network.
@@ -754,6 +428,11 @@ func NewCommand() *cobra.Command {
 		networking.DefaultHMNString,
 		"Overall IPv4 CIDR for all Hardware Management subnets",
 	)
+	c.Flags().String(
+		"hmn-cidr6",
+		"",
+		"Overall IPv6 CIDR for all Hardware Management subnets",
+	)
 	c.Flags().String(
 		"hmn-static-pool",
 		"",
@@ -774,6 +453,16 @@ func NewCommand() *cobra.Command {
 		networking.DefaultHMNRVRString,
 		"IPv4 CIDR for grouped River Hardware Management subnets",
 	)
+	c.Flags().String(
+		"hmn-mtn-cidr6",
+		"",
+		"IPv6 CIDR for grouped Mountain Hardware Management subnets, each cabinet is given a /118 (as many addresses as its IPv4 /22)",
+	)
+	c.Flags().String(
+		"hmn-rvr-cidr6",
+		"",
+		"IPv6 CIDR for grouped River Hardware Management subnets, each cabinet is given a /118 (as many addresses as its IPv4 /22)",
+	)
 	_ = c.MarkFlagRequired("hmn-cidr")
 
 	// Customer access network.
@@ -881,14 +570,6 @@ func NewCommand() *cobra.Command {
 		"chn-cidr6",
 		"chn-gateway6",
 	)
-	RegisterAlias(
-		"chn-cidr",
-		"chn-cidr4",
-	)
-	RegisterAlias(
-		"chn-gateway",
-		"chn-gateway4",
-	)
 	c.MarkFlagsMutuallyExclusive(
 		"chn-gateway",
 		"chn-gateway4",
@@ -897,14 +578,6 @@ func NewCommand() *cobra.Command {
 		"chn-cidr",
 		"chn-cidr4",
 	)
-	RegisterAlias(
-		"chn-cidr",
-		"chn-cidr4",
-	)
-	RegisterAlias(
-		"chn-gateway",
-		"chn-gateway4",
-	)
 	c.MarkFlagsRequiredTogether(
 		"chn-static-pool",
 		"chn-dynamic-pool",
@@ -990,14 +663,6 @@ func NewCommand() *cobra.Command {
 		"cmn-gateway6",
 	)
 
-	RegisterAlias(
-		"cmn-cidr",
-		"cmn-cidr4",
-	)
-	RegisterAlias(
-		"cmn-gateway",
-		"cmn-gateway4",
-	)
 	c.MarkFlagsMutuallyExclusive(
 		"cmn-cidr",
 		"cmn-cidr4",
@@ -1019,6 +684,11 @@ func NewCommand() *cobra.Command {
 		networking.DefaultMTLString,
 		"Overall IPv4 CIDR for all Provisioning subnets",
 	)
+	c.Flags().String(
+		"mtl-cidr6",
+		"",
+		"Overall IPv6 CIDR for all Provisioning subnets",
+	)
 
 	// High-speed network.
 	c.Flags().String(
@@ -1060,6 +730,11 @@ func NewCommand() *cobra.Command {
 		networking.DefaultNMNVlan,
 		"Bootstrap VLAN for the NMN",
 	)
+	c.Flags().StringSlice(
+		"reserved-vlans",
+		[]string{},
+		"Comma-separated list of VLANs or VLAN ranges used upstream that must not be allocated (e.g. 100,3000-3099)",
+	)
 
 	// Hardware Details
 	c.Flags().Int(
@@ -1148,6 +823,16 @@ func NewCommand() *cobra.Command {
 		[]string{"spine"},
 		"Comma-separated list of which set of switches to use as metallb peers: spine (default), leaf and/or edge",
 	)
+	c.Flags().String(
+		"metallb-bgp-password",
+		"",
+		"Password of the BGP sessions with the metallb peers (CSM 1.7 and above), or a secret reference (file:, env:, cmd:, or sealed:)",
+	)
+	c.Flags().String(
+		"metallb-bfd-profile",
+		"",
+		"Name of the metallb BFDProfile used by the BGP sessions with the metallb peers, it is generated with metallb's default timers (CSM 1.7 and above)",
+	)
 
 	// Kubernetes.
 	c.Flags().Bool(
@@ -1165,12 +850,17 @@ func NewCommand() *cobra.Command {
 	c.Flags().String(
 		"bootstrap-ncn-bmc-pass",
 		"",
-		"Password for connecting to the BMC on the initial NCNs",
+		"Password for connecting to the BMC on the initial NCNs, or a secret reference (file:, env:, cmd:, or sealed:)",
 	)
 	c.Flags().String(
 		"bootstrap-ncn-bmc-user",
 		"",
-		"Username for connecting to the BMC on the initial NCNs",
+		"Username for connecting to the BMC on the initial NCNs, or a secret reference (file:, env:, cmd:, or sealed:)",
+	)
+	c.Flags().String(
+		"sealed-secret-key-file",
+		"",
+		"Path to the sealed secrets/shasta-cfg private key that sealed: secret references are decrypted with",
 	)
 	flagErr = c.MarkFlagRequired("bootstrap-ncn-bmc-pass")
 	if flagErr != nil {
@@ -1204,6 +894,11 @@ func NewCommand() *cobra.Command {
 		"",
 		"YAML file listing the ids for all cabinets by type",
 	)
+	c.Flags().String(
+		"extra-networks-yaml",
+		"",
+		"YAML file describing site-specific networks to build alongside the CSM networks",
+	)
 	c.Flags().String(
 		"hmn-connections",
 		"hmn_connections.json",
@@ -1214,6 +909,11 @@ func NewCommand() *cobra.Command {
 		"ncn_metadata.csv",
 		"CSV for mapping the mac addresses of the NCNs to their xnames",
 	)
+	c.Flags().String(
+		"runcmd-yaml",
+		"",
+		"YAML file of changes to the cloud-init runcmd of the NCNs, keyed by NCN role or subrole",
+	)
 	c.Flags().String(
 		"switch-metadata",
 		"switch_metadata.csv",
@@ -1236,12 +936,195 @@ func NewCommand() *cobra.Command {
 		"",
 		"Comma-separated list of the zones to be allowed transfer",
 	)
+
+	// Plan.
+	c.Flags().String(
+		"plan",
+		"",
+		"Print the network allocation plan as json or yaml instead of writing the system directory (--plan defaults to json)",
+	)
+	c.Flags().Lookup("plan").NoOptDefVal = "json"
+
+	// Summary.
+	c.Flags().StringP(
+		"output",
+		"o",
+		"text",
+		"Format of the installation summary printed once the system directory is written: text, json, or yaml",
+	)
+
+	// Reproducibility.
+	c.Flags().Bool(
+		"reproducible",
+		false,
+		"Generate byte-for-byte identical output for identical inputs by pinning timestamps (to $SOURCE_DATE_EPOCH, or the UNIX epoch) and deriving instance IDs from each xname (implied when $SOURCE_DATE_EPOCH is set)",
+	)
+	c.Flags().String(
+		"reproducible-seed",
+		"",
+		"Seed for deriving instance IDs in reproducible mode (defaults to the system name)",
+	)
+	c.Flags().String(
+		"previous-sls",
+		"",
+		"SLS file (or the system directory containing it) of a previous run whose subnets, VLANs, and IP reservations must be kept; only new hardware is given new addresses",
+	)
+
+	// Manifest.
+	c.Flags().String(
+		"manifest-signing-key",
+		"",
+		"PEM encoded ed25519 private key to sign manifest.json with, writing the detached signature to manifest.json.sig (see csi config verify)",
+	)
 	c.AddCommand(emptyCommand())
 
 	return c
 }
 
-func setupDirectories(systemName string, v *viper.Viper) (string, error) {
+// printSummary prints a human-readable summary of a completed config init to stdout.
+func printSummary(v *viper.Viper, summary Summary) {
+	for _, warning := range summary.Warnings {
+		fmt.Printf(
+			"\nWARNING: %s\n",
+			warning,
+		)
+	}
+
+	// Print Summary
+	fmt.Printf(
+		"\n===== %s Installation Summary =====\n\n",
+		summary.SystemName,
+	)
+	fmt.Printf(
+		"%-20s: %s\n",
+		"Installation Node",
+		summary.InstallationNode,
+	)
+
+	fmt.Printf(
+		"%-20s: %s\n",
+		"Upstream DNS",
+		summary.UpstreamDNS,
+	)
+	fmt.Printf(
+		"%-20s: %v\n",
+		"MetalLB Peers",
+		summary.MetalLBPeers,
+	)
+	fmt.Printf(
+		"\n----- %s Network Summary -----\n\n",
+		summary.SystemName,
+	)
+	fmt.Printf(
+		"%-20s: %s\n",
+		"BICAN user network",
+		summary.BICANUserNetwork,
+	)
+	fmt.Printf(
+		"%-20s: ",
+		"Supernet",
+	)
+	if summary.Supernet {
+		fmt.Printf("Enabled (Network gateway used for all SLS subnets)\n")
+	} else {
+		fmt.Printf("Disabled (SLS subnets use their own gateway)\n")
+	}
+
+	fmt.Printf("\nDefined networks:\n\n")
+	for _, network := range summary.Networks {
+		fmt.Printf(
+			"    * %-65s (subnets: %3d) %s\n",
+			network.FullName,
+			len(network.Subnets4),
+			network.CIDR4,
+		)
+		if network.CIDR6 != "" {
+			fmt.Printf(
+				"    * %-65s (subnets: %3d) %s\n",
+				fmt.Sprintf(
+					"%s (IPv6)",
+					network.FullName,
+				),
+				len(network.Subnets6),
+				network.CIDR6,
+			)
+		}
+	}
+	fmt.Printf(
+		"\n----- %s System Summary -----\n\n",
+		summary.SystemName,
+	)
+	fmt.Printf(
+		"%-30s: %-3d\n",
+		"NCNs",
+		summary.NCNs,
+	)
+	fmt.Printf(
+		"%-30s: %-3d\n",
+		"UANs",
+		summary.UANs,
+	)
+	fmt.Printf(
+		"%-30s: %-3d\n",
+		"Switches",
+		summary.Switches,
+	)
+	fmt.Printf(
+		"%-30s: %-3d\n",
+		"Mountain Compute Cabinets",
+		summary.Cabinets[slsCommon.ClassMountain],
+	)
+	fmt.Printf(
+		"%-30s: %-3d\n",
+		"Hill Compute Cabinets",
+		summary.Cabinets[slsCommon.ClassHill],
+	)
+	fmt.Printf(
+		"%-30s: %-3d\n",
+		"River Compute Cabinets",
+		summary.Cabinets[slsCommon.ClassRiver],
+	)
+	fmt.Printf(
+		"%-30s: %s\n",
+		"CSI Version Information",
+		summary.CSIVersion,
+	)
+	fmt.Printf(
+		"\n%s\n********** CONFIG INITIALIZED **********\n%s\n",
+		strings.Repeat(
+			"*",
+			40,
+		),
+		strings.Repeat(
+			"*",
+			40,
+		),
+	)
+	fmt.Printf(
+		"\nNEW %s file!\n\nReplace any offline copies\nof %s with:\n\n%s\n\n",
+		cli.ConfigFilename,
+		cli.ConfigFilename,
+		path.Join(
+			"./",
+			v.GetString("system-name"),
+			cli.ConfigFilename,
+		),
+	)
+	fmt.Println(
+		strings.Repeat(
+			"*",
+			40,
+		),
+	)
+	fmt.Println(
+		strings.Repeat(
+			"*",
+			40,
+		),
+	)
+}
+
+func setupDirectories(systemName string) (string, error) {
 	// Set up the path for our base directory using our system name.
 	basepath, err := filepath.Abs(filepath.Clean(systemName))
 	if err != nil {
@@ -1289,9 +1172,12 @@ func setupDirectories(systemName string, v *viper.Viper) (string, error) {
 	return basepath, nil
 }
 
-func mergeNCNs(logicalNcns []*LogicalNCN, slsNCNs []LogicalNCN) ([]*LogicalNCN, error) {
+func mergeNCNs(v *viper.Viper, logicalNcns []*LogicalNCN, slsNCNs []LogicalNCN) ([]*LogicalNCN, error) {
 	// Check CSM version for FabricManager node filtering
-	_, oneSevenCSM := csm.CompareMajorMinor("1.7")
+	_, oneSevenCSM := csm.CompareMajorMinorWith(
+		v,
+		"1.7",
+	)
 	
 	// First, filter out FabricManager nodes if CSM < 1.7
 	filteredNCNs := []*LogicalNCN{}
@@ -1352,18 +1238,21 @@ func prepareNetworkSLS(shastaNetworks map[string]*networking.IPNetwork) (
 }
 
 func prepareAndGenerateSLS(
+	v *viper.Viper,
 	cd []sls.CabinetGroupDetail,
 	shastaNetworks map[string]*networking.IPNetwork,
 	hmnRows []shcdParser.HMNRow,
 	inputSwitches []*networking.ManagementSwitch,
 	applicationNodeConfig slsInit.GeneratorApplicationNodeConfig,
 	startingNid int,
-) slsCommon.SLSState {
+) (
+	slsCommon.SLSState, error,
+) {
 	// Management Switch Information is included in the IP Reservations for each subnet
 	switchNet, err := shastaNetworks["HMN"].LookUpSubnet("network_hardware")
 	if err != nil {
-		log.Fatalln(
-			"Couldn't find subnet for management switches in the HMN:",
+		return slsCommon.SLSState{}, fmt.Errorf(
+			"couldn't find subnet for management switches in the HMN because %v",
 			err,
 		)
 	}
@@ -1380,8 +1269,8 @@ func prepareAndGenerateSLS(
 			}
 		}
 		if mySwitch.Brand == "" {
-			log.Fatalln(
-				"Couldn't determine switch brand for:",
+			return slsCommon.SLSState{}, fmt.Errorf(
+				"couldn't determine switch brand for %s",
 				xname,
 			)
 		}
@@ -1389,8 +1278,8 @@ func prepareAndGenerateSLS(
 		// Create SLS version of the switch
 		slsSwitches[mySwitch.Xname], err = slsInit.ConvertManagementSwitchToSLS(&mySwitch)
 		if err != nil {
-			log.Fatalln(
-				"Couldn't get SLS management switch representation:",
+			return slsCommon.SLSState{}, fmt.Errorf(
+				"couldn't get SLS management switch representation because %v",
 				err,
 			)
 		}
@@ -1435,13 +1324,14 @@ func prepareAndGenerateSLS(
 		MountainCabinets:      slsCabinetMap[slsCommon.ClassMountain],
 		MountainStartingNid:   startingNid,
 		Networks:              slsNetworks,
+		CSMVersion:            v.GetString(csm.APIKeyName),
 	}
 
 	slsState := slsInit.GenerateSLSState(
 		inputState,
 		hmnRows,
 	)
-	return slsState
+	return slsState, nil
 }
 
 func updateReservations(network *networking.IPNetwork, subnetName string, logicalNcns []*LogicalNCN) (subnet *slsCommon.IPSubnet, err error) {
@@ -1507,13 +1397,18 @@ func updateReservations(network *networking.IPNetwork, subnetName string, logica
 				subnet.IPReservations[index] = reservation
 			}
 		}
-		if strings.ToLower(network.Name) == "nmn" {
+		// Reservations updated by an earlier run already have their .local alias
+		localAlias := fmt.Sprintf(
+			"%v.local",
+			reservation.Name,
+		)
+		if strings.ToLower(network.Name) == "nmn" && !slices.Contains(
+			reservation.Aliases,
+			localAlias,
+		) {
 			reservation.Aliases = append(
 				reservation.Aliases,
-				fmt.Sprintf(
-					"%v.local",
-					reservation.Name,
-				),
+				localAlias,
 			)
 			subnet.IPReservations[index] = reservation
 		}
@@ -1521,70 +1416,70 @@ func updateReservations(network *networking.IPNetwork, subnetName string, logica
 	return subnet, err
 }
 
-func writeOutput(
+// renderOutput renders every file of the system directory of outputs, the manifest (and its signature) included.
+func renderOutput(
 	v *viper.Viper,
-	shastaNetworks map[string]*networking.IPNetwork,
-	slsState slsCommon.SLSState,
-	logicalNCNs []LogicalNCN,
-	switches []*networking.ManagementSwitch,
-	globalMetaData interface{},
-) (err error) {
-	basepath, _ := setupDirectories(
-		v.GetString("system-name"),
-		v,
-	)
-
-	err = files.WriteJSONConfig(
-		filepath.Join(
-			basepath,
-			slsInit.OutputFile,
-		),
-		&slsState,
+	outputs *Outputs,
+) (tree files.Tree, err error) {
+	tree = make(files.Tree)
+	err = tree.JSON(
+		slsInit.OutputFile,
+		&outputs.SLSState,
 	)
 	if err != nil {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"failed to encode SLS state because %v",
 			err,
 		)
 	}
+	if outputs.VLANs != nil {
+		err = tree.JSON(
+			VLANsFile,
+			outputs.VLANs,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to encode VLAN report because %v",
+				err,
+			)
+		}
+	}
 	v.Set(
 		"VersionInfo",
 		version.Get(),
 	)
 
-	err = WriteConfigAs(
-		filepath.Join(
-			basepath,
-			cli.ConfigFilename,
+	configFilename := cli.ConfigFilename
+	if configFilename == "" {
+		configFilename = defaultConfigFilename
+	}
+	tree[configFilename], err = renderSettings(
+		v.AllSettings(),
+		strings.TrimPrefix(
+			filepath.Ext(configFilename),
+			".",
 		),
 	)
 	if err != nil {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"failed to create %s because %v",
-			cli.ConfigFilename,
+			configFilename,
 			err,
 		)
 	}
 
-	err = files.WriteYAMLConfig(
-		filepath.Join(
-			basepath,
-			"customizations.yaml",
-		),
-		GenCustomizationsYaml(
-			logicalNCNs,
-			shastaNetworks,
-			switches,
-		),
+	err = tree.YAML(
+		"customizations.yaml",
+		outputs.Customizations,
 	)
 	if err != nil {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"failed to create customizations YAML because %v",
 			err,
 		)
 	}
 	var pit LogicalNCN
-	for _, ncn := range logicalNCNs {
+	for _, ncn := range outputs.LogicalNCNs {
 		// log.Println("Checking to see if we need PIT files for ", ncn.Hostname)
 		if strings.HasPrefix(
 			ncn.Hostname,
@@ -1598,65 +1493,127 @@ func writeOutput(
 			break
 		}
 	}
-	err = WriteCPTNetworkConfig(
-		filepath.Join(
-			basepath,
-			"pit-files",
-		),
+	pitFiles, err := RenderCPTNetworkConfig(
 		v,
 		pit,
-		shastaNetworks,
+		outputs.Networks,
+		outputs.Runtime,
 	)
 	if err != nil {
-		return
+		return nil, err
 	}
-	err = WriteDNSMasqConfig(
-		basepath,
+	tree.Merge(
+		"pit-files",
+		pitFiles,
+	)
+	err = RenderDNSMasqConfig(
+		tree,
 		v,
-		logicalNCNs,
-		shastaNetworks,
+		outputs.LogicalNCNs,
+		outputs.Networks,
+		outputs.Runtime,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = WriteConmanConfig(
-		filepath.Join(
-			basepath,
-			"conman.conf",
-		),
-		logicalNCNs,
+	if v.GetString("pit-dhcp-backend") == PITDHCPBackendKea {
+		err = RenderKeaConfig(
+			tree,
+			v,
+			outputs.LogicalNCNs,
+			outputs.Networks,
+			outputs.Runtime,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = RenderConmanConfig(
+		tree,
+		outputs.secrets.bmc,
+		outputs.LogicalNCNs,
+		outputs.Runtime,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	// The MetalLB ConfigMap is no longer used in CSM 1.7
-	_, eval := csm.CompareMajorMinor("1.7")
+	// The MetalLB ConfigMap is no longer used in CSM 1.7, MetalLB is configured by custom resources instead.
+	_, eval := csm.CompareMajorMinorWith(
+		v,
+		"1.7",
+	)
 	if eval == -1 {
-		err := WriteMetalLBConfigMap(
-			basepath,
+		err := RenderMetalLBConfigMap(
+			tree,
+			v,
+			outputs.Networks,
+			outputs.Switches,
+			outputs.Runtime,
+		)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err := RenderMetalLBResources(
+			tree,
 			v,
-			shastaNetworks,
-			switches,
+			outputs.Networks,
+			outputs.Switches,
+			outputs.secrets.metalLBPassword,
+			outputs.Runtime,
 		)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	WriteBasecampData(
-		filepath.Join(
-			basepath,
-			"basecamp/data.json",
-		),
-		logicalNCNs,
-		shastaNetworks,
-		globalMetaData,
+	err = tree.JSON(
+		"basecamp/data.json",
+		outputs.Basecamp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to encode data.json because %v",
+			err,
+		)
+	}
+
+	// The manifest is rendered last, it hashes every other file of the system directory.
+	manifest, err := verify.NewTreeManifest(
+		tree,
+		outputs.InputFiles,
 	)
-	return err
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to hash the system directory because %v",
+			err,
+		)
+	}
+	err = manifest.Render(tree)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to encode %s because %v",
+			verify.ManifestFile,
+			err,
+		)
+	}
+	if v.GetString("manifest-signing-key") != "" {
+		err = verify.SignTree(
+			tree,
+			v.GetString("manifest-signing-key"),
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to sign %s because %v",
+				verify.ManifestFile,
+				err,
+			)
+		}
+	}
+	return tree, nil
 }
 
-func validateFlags() []error {
+func validateFlags(v *viper.Viper) []error {
 	var errors []error
-	v := viper.GetViper()
 
 	var cidrFlags = []string{
 		"can-cidr",
@@ -1671,7 +1628,14 @@ func validateFlags() []error {
 		"cmn-dynamic-pool",
 		"cmn-static-pool",
 		"hmn-cidr",
+		"hmn-cidr6",
+		"hmn-mtn-cidr6",
+		"hmn-rvr-cidr6",
+		"mtl-cidr6",
 		"nmn-cidr",
+		"nmn-cidr6",
+		"nmn-mtn-cidr6",
+		"nmn-rvr-cidr6",
 		"site-ip",
 	}
 
@@ -1687,26 +1651,35 @@ func validateFlags() []error {
 	} else {
 		// The currentVersion needs to be canonical for semver to compare it.
 		// Exit and complain if the version of the inputs does not match the environment. It is not clear whether the inputs or the environment are wrong.
-		currentVersion, eval := csm.CompareMajorMinor(detectedVersion)
+		currentVersion, eval := csm.CompareMajorMinorWith(
+			v,
+			detectedVersion,
+		)
 		if eval != 0 {
-			log.Fatalf(
-				"Detected a potential mismatch of parameters and CSM environment!\n\n%15s=%-20s (a.k.a. %s)\n%15s=%-20s (a.k.a. %s)\n\nERROR: [%s != %s]\n\n- Both values must have matching <major>.<minor> versions\n- %s must be greater than this CSI's minimum allowed CSM version of: [%s]\n\nSince these values did not match one another it is possible that other inputs are also wrong.\nIf inputs from a prior release are being used, please double-check and/or refresh all other inputs and try again.\n",
-				csm.APIKeyName,
-				currentVersion,
-				semver.MajorMinor(currentVersion),
-				csm.APIEnvName,
-				detectedVersion,
-				semver.MajorMinor(detectedVersion),
-				semver.MajorMinor(detectedVersion),
-				semver.MajorMinor(currentVersion),
-				csm.APIKeyName,
-				csm.MinimumVersion,
+			errors = append(
+				errors,
+				fmt.Errorf(
+					"Detected a potential mismatch of parameters and CSM environment!\n\n%15s=%-20s (a.k.a. %s)\n%15s=%-20s (a.k.a. %s)\n\nERROR: [%s != %s]\n\n- Both values must have matching <major>.<minor> versions\n- %s must be greater than this CSI's minimum allowed CSM version of: [%s]\n\nSince these values did not match one another it is possible that other inputs are also wrong.\nIf inputs from a prior release are being used, please double-check and/or refresh all other inputs and try again.\n",
+					csm.APIKeyName,
+					currentVersion,
+					semver.MajorMinor(currentVersion),
+					csm.APIEnvName,
+					detectedVersion,
+					semver.MajorMinor(detectedVersion),
+					semver.MajorMinor(detectedVersion),
+					semver.MajorMinor(currentVersion),
+					csm.APIKeyName,
+					csm.MinimumVersion,
+				),
 			)
 		}
 	}
-	currentVersion, versionError := csm.IsCompatible()
+	currentVersion, versionError := csm.IsCompatibleWith(v)
 	if versionError != nil {
-		log.Fatal(versionError)
+		return append(
+			errors,
+			versionError,
+		)
 	}
 	log.Printf(
 		"[%s] was set to [%s]; All inputs are targeted for CSM %s",
@@ -1821,6 +1794,51 @@ func validateFlags() []error {
 		)
 	}
 
+	if !slices.Contains(
+		PITDHCPBackends,
+		v.GetString("pit-dhcp-backend"),
+	) {
+		errors = append(
+			errors,
+			fmt.Errorf(
+				"pit-dhcp-backend must be set to one of [%s]",
+				strings.Join(
+					PITDHCPBackends,
+					",",
+				),
+			),
+		)
+	}
+
+	for _, reserved := range v.GetStringSlice("reserved-vlans") {
+		_, _, err := networking.ParseVLANRange(reserved)
+		if err != nil {
+			errors = append(
+				errors,
+				fmt.Errorf(
+					"reserved-vlans: %v",
+					err,
+				),
+			)
+		}
+	}
+
+	if !slices.Contains(
+		PITNetworkRenderers,
+		v.GetString("pit-network-renderer"),
+	) {
+		errors = append(
+			errors,
+			fmt.Errorf(
+				"pit-network-renderer must be set to one of [%s]",
+				strings.Join(
+					PITNetworkRenderers,
+					",",
+				),
+			),
+		)
+	}
+
 	if v.IsSet("cilium-kube-proxy-replacement") {
 		validFlag := false
 		for _, value := range [3]string{
@@ -1852,7 +1870,10 @@ func validateFlags() []error {
 
 	if v.IsSet("k8s-primary-cni") {
 		validFlag := false
-		_, eval := csm.CompareMajorMinor("1.7")
+		_, eval := csm.CompareMajorMinorWith(
+			v,
+			"1.7",
+		)
 		var allowedValues []string
 		if eval != -1 {
 			allowedValues = []string{"cilium"}
@@ -1878,7 +1899,10 @@ func validateFlags() []error {
 			)
 		}
 	} else {
-		currentVersion, eval := csm.CompareMajorMinor("1.7")
+		currentVersion, eval := csm.CompareMajorMinorWith(
+			v,
+			"1.7",
+		)
 		if eval != -1 {
 			log.Printf(
 				"Detected CSM %s, setting k8s-primary-cni to Cilium",
@@ -2044,7 +2068,9 @@ func validateFlags() []error {
 }
 
 // AllocateIPs distributes IP reservations for each of the NCNs within the networks
-func AllocateIPs(ncns []*LogicalNCN, networks map[string]*networking.IPNetwork) {
+func AllocateIPs(
+	ncns []*LogicalNCN, networks map[string]*networking.IPNetwork, addresses *networking.AddressAllocator,
+) error {
 	lookup := func(name string, subnetName string, networks map[string]*networking.IPNetwork) (
 		subnet *slsCommon.IPSubnet, err error,
 	) {
@@ -2076,12 +2102,13 @@ func AllocateIPs(ncns []*LogicalNCN, networks map[string]*networking.IPNetwork)
 	// Loop through the NCNs and then run through the networks to add reservations and assign ip addresses
 	for _, ncn := range ncns {
 		ncn.InstanceID = GenerateInstanceID()
-		for netName, subnet := range subnets {
+		for _, netName := range slices.Sorted(maps.Keys(subnets)) {
+			subnet := subnets[netName]
 			// reserve the bmc ip
 			if strings.ToLower(netName) == "hmn" {
 				// The bmc xname is the ncn xname without the final two characters
 				// NCN Xname = x3000c0s9b0n0  BMC Xname = x3000c0s9b0
-				reservation, err := networking.AddReservation(
+				reservation, err := addresses.AddReservation(
 					subnet,
 					fmt.Sprintf(
 						"%v",
@@ -2096,7 +2123,7 @@ func AllocateIPs(ncns []*LogicalNCN, networks map[string]*networking.IPNetwork)
 					),
 				)
 				if err != nil {
-					log.Fatalf(
+					return fmt.Errorf(
 						"failed to allocate hmn reservation %v because %v",
 						ncn.Xname,
 						err,
@@ -2104,14 +2131,14 @@ func AllocateIPs(ncns []*LogicalNCN, networks map[string]*networking.IPNetwork)
 				}
 				ncn.BmcIP = reservation.IPAddress.String()
 			}
-			reservation, err := networking.AddReservation(
+			reservation, err := addresses.AddReservation(
 				subnet,
 				ncn.Xname,
 				ncn.Xname,
 			)
 			if err != nil {
-				log.Fatalf(
-					"Failed to allocate reservation for %s: %v",
+				return fmt.Errorf(
+					"failed to allocate reservation for %s: %v",
 					ncn.Xname,
 					err,
 				)
@@ -2121,24 +2148,24 @@ func AllocateIPs(ncns []*LogicalNCN, networks map[string]*networking.IPNetwork)
 
 			interfaceName, err := networks[netName].GenInterfaceName(subnet)
 			if err != nil {
-				log.Fatalf(
-					"Couldn't generate interface name for %v on subnet %v\n",
+				return fmt.Errorf(
+					"couldn't generate interface name for %v on subnet %v",
 					ncn.Xname,
 					subnet.CIDR,
 				)
 			}
 			addr4, err := netip.ParseAddr(reservation.IPAddress.String())
 			if err != nil {
-				log.Fatalf(
-					"Failed to parse network IP address %v because %v",
+				return fmt.Errorf(
+					"failed to parse network IP address %v because %v",
 					reservation.IPAddress.String(),
 					err,
 				)
 			}
 			gw4, err := netip.ParseAddr(subnet.Gateway.String())
 			if err != nil {
-				log.Fatalf(
-					"Failed to parse gateway IP address %v because %v",
+				return fmt.Errorf(
+					"failed to parse gateway IP address %v because %v",
 					subnet.Gateway.String(),
 					err,
 				)
@@ -2160,8 +2187,8 @@ func AllocateIPs(ncns []*LogicalNCN, networks map[string]*networking.IPNetwork)
 			if reservation.IPAddress6 != nil {
 				addr6, err := netip.ParseAddr(reservation.IPAddress6.String())
 				if err != nil {
-					log.Fatalf(
-						"Host %s had an unparseable address for IPv6: %v",
+					return fmt.Errorf(
+						"host %s had an unparseable address for IPv6: %v",
 						ncn.Hostname,
 						reservation.IPAddress6.String(),
 					)
@@ -2169,8 +2196,8 @@ func AllocateIPs(ncns []*LogicalNCN, networks map[string]*networking.IPNetwork)
 
 				prefix6, err := netip.ParsePrefix(subnet.CIDR6)
 				if err != nil {
-					log.Fatalf(
-						"Unparseable subnet IPv6 CIDR for %s: %v",
+					return fmt.Errorf(
+						"unparseable subnet IPv6 CIDR for %s: %v",
 						subnet.Name,
 						subnet.CIDR6,
 					)
@@ -2178,8 +2205,8 @@ func AllocateIPs(ncns []*LogicalNCN, networks map[string]*networking.IPNetwork)
 
 				gw6, err := netip.ParseAddr(subnet.Gateway6.String())
 				if err != nil {
-					log.Fatalf(
-						"Unparseable subnet Gateway IPv6 CIDR for %s: %v",
+					return fmt.Errorf(
+						"unparseable subnet Gateway IPv6 CIDR for %s: %v",
 						subnet.Name,
 						subnet.Gateway6,
 					)
@@ -2200,4 +2227,5 @@ func AllocateIPs(ncns []*LogicalNCN, networks map[string]*networking.IPNetwork)
 
 		}
 	}
+	return nil
 }
@@ -28,6 +28,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/Cray-HPE/cray-site-init/pkg/networking"
@@ -325,6 +326,7 @@ func (suite *InitCmdTestSuite) TestMergeNCNs_HappyPath() {
 	}
 
 	mergedNCNs, err := mergeNCNs(
+		viper.GetViper(),
 		ncns,
 		slsNCNs,
 	)
@@ -371,6 +373,7 @@ func (suite *InitCmdTestSuite) TestMergeNCNs_MissingXnameInSLS() {
 	}
 
 	_, err := mergeNCNs(
+		viper.GetViper(),
 		ncns,
 		slsNCNs,
 	)
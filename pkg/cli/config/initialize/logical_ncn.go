@@ -26,6 +26,7 @@ package initialize
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"log"
@@ -183,6 +184,17 @@ func GenerateInstanceID() (id string) {
 	return id
 }
 
+// DeriveInstanceID creates an instance-id like GenerateInstanceID, but derived from the given seed and xname so the same
+// inputs always give the same instance-id.
+func DeriveInstanceID(seed string, xname string) (id string) {
+	sum := sha256.Sum256([]byte(seed + "/" + xname))
+	id = fmt.Sprintf(
+		"i-%X",
+		sum[:4],
+	)
+	return id
+}
+
 // ReadNodeCSV parses a CSV file into a list of NCN_bootstrap nodes for use by the installer
 func ReadNodeCSV(filename string) (
 	[]*LogicalNCN, error,
@@ -70,8 +70,7 @@ type cabinetDefinition struct {
 	startingID int
 }
 
-func getFile(name string) (path string, err error) {
-	v := viper.GetViper()
+func getFile(v *viper.Viper, name string) (path string, err error) {
 	inputDir := v.GetString("input-dir")
 	if inputDir == "" {
 		path = fmt.Sprintf(
@@ -99,7 +98,10 @@ func collectHMNRows(v *viper.Viper) (hmnRows []shcdParser.HMNRow, err error) {
 	} else {
 		HMNConnectionsFile = hmnConnectionsFile
 	}
-	seedFileHmnConnections, err := getFile(HMNConnectionsFile)
+	seedFileHmnConnections, err := getFile(
+		v,
+		HMNConnectionsFile,
+	)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"error reading hmn-connections: %w",
@@ -123,7 +125,10 @@ func collectNCNMeta(v *viper.Viper) (ncns []*LogicalNCN, err error) {
 	} else {
 		NCNMetadataFile = ncnFile
 	}
-	seedFileNcnMetadata, err := getFile(NCNMetadataFile)
+	seedFileNcnMetadata, err := getFile(
+		v,
+		NCNMetadataFile,
+	)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"error reading ncn-metadata file because %v",
@@ -166,7 +171,10 @@ func collectSwitches(v *viper.Viper) (switches []*networking.ManagementSwitch, e
 	} else {
 		SwitchMetadataFile = switchMetadataFile
 	}
-	seedFileSwitchMetadata, err := getFile(SwitchMetadataFile)
+	seedFileSwitchMetadata, err := getFile(
+		v,
+		SwitchMetadataFile,
+	)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"error reading switch-metadata file because %v",
@@ -221,7 +229,10 @@ func collectApplicationNodeConfig(v *viper.Viper) (applicationNodeConfig slsInit
 	} else {
 		ApplicationNodeConfigFile = applicationNodeConfigFile
 	}
-	seedFileAppNodeConfig, err := getFile(ApplicationNodeConfigFile)
+	seedFileAppNodeConfig, err := getFile(
+		v,
+		ApplicationNodeConfigFile,
+	)
 	if err != nil {
 		return applicationNodeConfig, fmt.Errorf(
 			"error reading application-node-config-yaml because %v",
@@ -272,7 +283,10 @@ func collectCabinets(v *viper.Viper) (cabinetDetailList []sls.CabinetGroupDetail
 			CabinetsFile = DefaultCabinetsFilename
 		} else {
 			CabinetsFile = cabinetsFile
-			seedFileCabinets, err := getFile(v.GetString("cabinets-yaml"))
+			seedFileCabinets, err := getFile(
+				v,
+				v.GetString("cabinets-yaml"),
+			)
 			if err != nil {
 				return nil, fmt.Errorf(
 					"error reading cabinets-yaml file because %v",
@@ -333,6 +347,109 @@ func collectCabinets(v *viper.Viper) (cabinetDetailList []sls.CabinetGroupDetail
 	return cabinetDetailList, err
 }
 
+// collectExtraNetworks reads the optional extra-networks-yaml file.
+func collectExtraNetworks(v *viper.Viper) (extraNetworks []slsInit.ExtraNetwork, err error) {
+	if v.GetString("extra-networks-yaml") == "" {
+		return nil, nil
+	}
+	seedFileExtraNetworks, err := getFile(
+		v,
+		v.GetString("extra-networks-yaml"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error reading extra-networks-yaml file because %v",
+			err,
+		)
+	}
+	var extraNetworkFile slsInit.ExtraNetworkFile
+	err = files.ReadYAMLConfig(
+		seedFileExtraNetworks,
+		&extraNetworkFile,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to parse extra-networks-yaml file [%s] because %v",
+			seedFileExtraNetworks,
+			err,
+		)
+	}
+	return extraNetworkFile.Networks, nil
+}
+
+// collectRunCMDOverlays reads the optional runcmd-yaml file.
+func collectRunCMDOverlays(v *viper.Viper) (overlays RunCMDOverlayFile, err error) {
+	if v.GetString("runcmd-yaml") == "" {
+		return nil, nil
+	}
+	seedFileRunCMD, err := getFile(
+		v,
+		v.GetString("runcmd-yaml"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error reading runcmd-yaml file because %v",
+			err,
+		)
+	}
+	err = files.ReadYAMLConfig(
+		seedFileRunCMD,
+		&overlays,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to parse runcmd-yaml file [%s] because %v",
+			seedFileRunCMD,
+			err,
+		)
+	}
+	err = overlays.Validate()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"invalid runcmd-yaml file [%s] because %v",
+			seedFileRunCMD,
+			err,
+		)
+	}
+	return overlays, nil
+}
+
+// inputFiles returns the paths of the seed files collectInput read, keyed by the flag that names them.
+func inputFiles(v *viper.Viper) map[string]string {
+	names := map[string]string{
+		"hmn-connections": HMNConnectionsFile,
+		"ncn-metadata":    NCNMetadataFile,
+		"switch-metadata": SwitchMetadataFile,
+	}
+	if v.IsSet("application-node-config-yaml") {
+		names["application-node-config-yaml"] = ApplicationNodeConfigFile
+	}
+	if v.GetString("cabinets-yaml") != "" {
+		names["cabinets-yaml"] = v.GetString("cabinets-yaml")
+	}
+	if v.GetString("extra-networks-yaml") != "" {
+		names["extra-networks-yaml"] = v.GetString("extra-networks-yaml")
+	}
+	if v.GetString("runcmd-yaml") != "" {
+		names["runcmd-yaml"] = v.GetString("runcmd-yaml")
+	}
+	paths := make(map[string]string)
+	for flag, name := range names {
+		if name == "" {
+			continue
+		}
+		path, err := getFile(
+			v,
+			name,
+		)
+		if err != nil {
+			continue
+		}
+		paths[flag] = path
+	}
+	return paths
+}
+
 func collectInput(v *viper.Viper) (
 	hmnRows []shcdParser.HMNRow,
 	ncns []*LogicalNCN,
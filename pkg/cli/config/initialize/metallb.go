@@ -25,14 +25,20 @@
 package initialize
 
 import (
+	"bytes"
+	_ "embed"
+	"errors"
 	"fmt"
 	"log"
-	"path/filepath"
+	"maps"
 	"regexp"
+	"slices"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 
 	"github.com/Cray-HPE/cray-site-init/internal/files"
 
@@ -87,6 +93,18 @@ type AddressPoolDetail struct {
 	Name      string   `yaml:"name" valid:"_,required"`
 	Protocol  string   `yaml:"protocol" valid:"_,required"`
 	Addresses []string `yaml:"addresses" valid:"required"`
+	// Network is the PeerDetail.Network of the peers the pool is advertised to, like the PeerDetail fields it is only
+	// set for the Custom Resource Definitions of CSM 1.7 and above.
+	Network string `yaml:"network,omitempty" valid:"_"`
+}
+
+// metalLBPeerNetworks maps the networks of the MetalLB address pools to the network of the peers they are advertised
+// to, when that is not the network itself. The load balancer networks are routed by the NMN peers, and the CAN by the
+// CMN peers.
+var metalLBPeerNetworks = map[string]string{
+	"CAN":   "cmn",
+	"HMNLB": "nmn",
+	"NMNLB": "nmn",
 }
 
 // MetalLBConfigMap holds information needed by the MetalLBConfigMapTemplate
@@ -101,7 +119,9 @@ type MetalLBConfigMap struct {
 // GetMetalLBConfig gathers the information for the metallb config map
 func GetMetalLBConfig(
 	v *viper.Viper, networks map[string]*networking.IPNetwork, switches []*networking.ManagementSwitch,
-) MetalLBConfigMap {
+) (
+	MetalLBConfigMap, error,
+) {
 
 	var configStruct MetalLBConfigMap
 
@@ -111,9 +131,13 @@ func GetMetalLBConfig(
 	leafSwitchNameRegexp := regexp.MustCompile(`sw-leaf-\d{3}`)
 	edgeSwitchNameRegexp := regexp.MustCompile(`chn-switch-\d`)
 	// Populate extra PeerDetail fields if CSM 1.7 and above
-	_, useNewMetalLB := csm.CompareMajorMinor("1.7")
+	_, useNewMetalLB := csm.CompareMajorMinorWith(
+		v,
+		"1.7",
+	)
 
-	for name, network := range networks {
+	for _, name := range slices.Sorted(maps.Keys(networks)) {
+		network := networks[name]
 		for _, subnet := range network.Subnets {
 			// This is a v1.4 HACK related to the supernet.
 			if (name == "NMN" || name == "CMN") && subnet.Name == "network_hardware" {
@@ -170,6 +194,12 @@ func GetMetalLBConfig(
 					tmpAddPool.Addresses,
 					subnet.CIDR,
 				)
+				if useNewMetalLB != -1 {
+					tmpAddPool.Network = strings.ToLower(name)
+					if peerNetwork, ok := metalLBPeerNetworks[name]; ok {
+						tmpAddPool.Network = peerNetwork
+					}
+				}
 				configStruct.Networks = append(
 					configStruct.Networks,
 					tmpAddPool,
@@ -178,39 +208,46 @@ func GetMetalLBConfig(
 		}
 	}
 
-	configStruct.PeerSwitches = getMetalLBPeerSwitches(
+	peerSwitches, err := getMetalLBPeerSwitches(
 		bgpPeers,
 		configStruct,
 	)
+	if err != nil {
+		return configStruct, err
+	}
+	configStruct.PeerSwitches = peerSwitches
 
-	return configStruct
+	return configStruct, nil
 }
 
-// WriteMetalLBConfigMap creates the yaml configmap
-func WriteMetalLBConfigMap(
-	path string, v *viper.Viper, networks map[string]*networking.IPNetwork, switches []*networking.ManagementSwitch,
+// RenderMetalLBConfigMap renders the yaml configmap to tree, stamped with the given runtime
+func RenderMetalLBConfigMap(
+	tree files.Tree, v *viper.Viper, networks map[string]*networking.IPNetwork, switches []*networking.ManagementSwitch,
+	runtime time.Time,
 ) (err error) {
 
 	tpl := template.Must(template.New("mtllbconfigmap").Parse(string(MetalLBConfigMapTemplate)))
-	configStruct := GetMetalLBConfig(
+	configStruct, err := GetMetalLBConfig(
 		v,
 		networks,
 		switches,
 	)
-	configData := MakeTemplateData(configStruct)
+	if err != nil {
+		return err
+	}
+	configData := MakeTemplateData(
+		configStruct,
+		runtime,
+	)
 
-	err = files.WriteTemplate(
-		filepath.Join(
-			path,
-			"metallb.yaml",
-		),
+	err = tree.Template(
+		"metallb.yaml",
 		tpl,
 		configData,
 	)
 	if err != nil {
 		return fmt.Errorf(
-			"failed to write metallb configmap to %s because %v",
-			path,
+			"failed to render metallb configmap because %v",
 			err,
 		)
 	}
@@ -220,7 +257,9 @@ func WriteMetalLBConfigMap(
 // getMetalLBPeerSwitches returns a list of switches  that should be used as metallb peers
 func getMetalLBPeerSwitches(
 	bgpPeers []string, configStruct MetalLBConfigMap,
-) []PeerDetail {
+) (
+	[]PeerDetail, error,
+) {
 
 	switchTypeMap := map[string][]PeerDetail{
 		"spine": configStruct.SpineSwitches,
@@ -231,8 +270,8 @@ func getMetalLBPeerSwitches(
 	for _, peerType := range bgpPeers {
 		if peerSwitches, ok := switchTypeMap[peerType]; ok {
 			if len(peerSwitches) == 0 {
-				log.Fatalf(
-					"bgp-peer-types: %s specified but none defined in switch_metadata.csv\n",
+				return nil, fmt.Errorf(
+					"bgp-peer-types: %s specified but none defined in switch_metadata.csv",
 					peerType,
 				)
 			}
@@ -241,12 +280,249 @@ func getMetalLBPeerSwitches(
 				peerSwitches...,
 			)
 		} else {
-			log.Fatalf(
-				"bgp-peer-types: unrecognized option: %s\n",
+			return nil, fmt.Errorf(
+				"bgp-peer-types: unrecognized option: %s",
 				peerType,
 			)
 		}
 	}
 
-	return configStruct.PeerSwitches
+	return configStruct.PeerSwitches, nil
+}
+
+// MetalLBResourcesTemplate manages the MetalLB custom resources of CSM 1.7 and above, which replace the ConfigMap.
+var MetalLBResourcesTemplate = []byte(`
+{{- /* remove leading whitespace */ -}}
+#
+## This file was generated by cray-site-init.
+## Version: {{ .Version }}
+## Generated time: {{ .Timestamp }}
+#
+---
+{{ .Data }}`)
+
+// metalLBCRDs are the MetalLB CustomResourceDefinitions the MetalLB resources are validated against.
+//
+//go:embed crds/metallb.yaml
+var metalLBCRDs []byte
+
+// MetalLBAPIVersion is the apiVersion of the MetalLB resources.
+const MetalLBAPIVersion = "metallb.io/v1beta1"
+
+/*
+MetalLBResource is a MetalLB custom resource, its Spec is an IPAddressPoolSpec, BFDProfileSpec, BGPPeerSpec, or
+BGPAdvertisementSpec.
+*/
+type MetalLBResource struct {
+	APIVersion string          `yaml:"apiVersion"`
+	Kind       string          `yaml:"kind"`
+	Metadata   MetalLBMetadata `yaml:"metadata"`
+	Spec       interface{}     `yaml:"spec"`
+}
+
+// MetalLBMetadata is the metadata of a MetalLB resource.
+type MetalLBMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// IPAddressPoolSpec is the spec of an IPAddressPool, made from an AddressPoolDetail.
+type IPAddressPoolSpec struct {
+	Addresses []string `yaml:"addresses"`
+}
+
+// BFDProfileSpec is the spec of the BFDProfile of the BGP peers, it is empty so that MetalLB's default timers are used.
+type BFDProfileSpec struct{}
+
+// BGPPeerSpec is the spec of a BGPPeer, made from a PeerDetail.
+type BGPPeerSpec struct {
+	MyASN       int    `yaml:"myASN"`
+	PeerASN     int    `yaml:"peerASN"`
+	PeerAddress string `yaml:"peerAddress"`
+	Password    string `yaml:"password,omitempty"`
+	BFDProfile  string `yaml:"bfdProfile,omitempty"`
+}
+
+// BGPAdvertisementSpec is the spec of a BGPAdvertisement, it advertises the address pools of a network to its peers.
+type BGPAdvertisementSpec struct {
+	IPAddressPools []string `yaml:"ipAddressPools"`
+	Peers          []string `yaml:"peers"`
+}
+
+/*
+GetMetalLBResources turns the MetalLB config of CSM 1.7 and above into MetalLB custom resources:
+ 1. An IPAddressPool for every address pool
+ 2. A BFDProfile named after metallb-bfd-profile, if it is set, for the BGP peers to refer to
+ 3. A BGPPeer for every peer switch and network it peers on (e.g. sw-spine-001-nmn), with the ASNs of that network and
+    the given BGP password (the resolved metallb-bgp-password), if any, and the BFDProfile, if any
+ 4. A BGPAdvertisement for every network (e.g. nmn), advertising the pools of the network to its peers. The pools of a
+    network without peers are not advertised, an empty list of peers would advertise them to every peer
+*/
+func GetMetalLBResources(
+	v *viper.Viper, networks map[string]*networking.IPNetwork, switches []*networking.ManagementSwitch, password string,
+) (
+	resources []MetalLBResource, err error,
+) {
+	configStruct, err := GetMetalLBConfig(
+		v,
+		networks,
+		switches,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	newResource := func(kind string, name string, spec interface{}) MetalLBResource {
+		return MetalLBResource{
+			APIVersion: MetalLBAPIVersion,
+			Kind:       kind,
+			Metadata: MetalLBMetadata{
+				Name:      name,
+				Namespace: "metallb-system",
+			},
+			Spec: spec,
+		}
+	}
+	poolsByNetwork := make(map[string][]string)
+	for _, pool := range configStruct.Networks {
+		poolsByNetwork[pool.Network] = append(
+			poolsByNetwork[pool.Network],
+			pool.Name,
+		)
+		resources = append(
+			resources,
+			newResource(
+				"IPAddressPool",
+				pool.Name,
+				IPAddressPoolSpec{
+					Addresses: pool.Addresses,
+				},
+			),
+		)
+	}
+	bfdProfile := v.GetString("metallb-bfd-profile")
+	if bfdProfile != "" {
+		resources = append(
+			resources,
+			newResource(
+				"BFDProfile",
+				bfdProfile,
+				BFDProfileSpec{},
+			),
+		)
+	}
+	peersByNetwork := make(map[string][]string)
+	for _, peer := range configStruct.PeerSwitches {
+		name := fmt.Sprintf(
+			"%s-%s",
+			peer.Name,
+			peer.Network,
+		)
+		peersByNetwork[peer.Network] = append(
+			peersByNetwork[peer.Network],
+			name,
+		)
+		resources = append(
+			resources,
+			newResource(
+				"BGPPeer",
+				name,
+				BGPPeerSpec{
+					MyASN:       peer.MyASN,
+					PeerASN:     peer.PeerASN,
+					PeerAddress: peer.IPAddress,
+					Password:    password,
+					BFDProfile:  bfdProfile,
+				},
+			),
+		)
+	}
+	for _, network := range slices.Sorted(maps.Keys(poolsByNetwork)) {
+		peers := peersByNetwork[network]
+		if len(peers) == 0 {
+			log.Printf(
+				"WARNING (Not Fatal): The %s address pools %v are not advertised, there are no %s peers in bgp-peer-types",
+				network,
+				poolsByNetwork[network],
+				network,
+			)
+			continue
+		}
+		resources = append(
+			resources,
+			newResource(
+				"BGPAdvertisement",
+				network,
+				BGPAdvertisementSpec{
+					IPAddressPools: poolsByNetwork[network],
+					Peers:          peers,
+				},
+			),
+		)
+	}
+	return resources, nil
+}
+
+// RenderMetalLBResources renders the MetalLB resources to metallb-resources.yaml stamped with the given runtime, after
+// validating them against the bundled MetalLB CustomResourceDefinitions.
+func RenderMetalLBResources(
+	tree files.Tree, v *viper.Viper, networks map[string]*networking.IPNetwork, switches []*networking.ManagementSwitch,
+	password string, runtime time.Time,
+) (err error) {
+	resources, err := GetMetalLBResources(
+		v,
+		networks,
+		switches,
+		password,
+	)
+	if err != nil {
+		return err
+	}
+	var manifest bytes.Buffer
+	encoder := yaml.NewEncoder(&manifest)
+	encoder.SetIndent(2)
+	for _, resource := range resources {
+		err = encoder.Encode(resource)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to encode MetalLB %s %s because %v",
+				resource.Kind,
+				resource.Metadata.Name,
+				err,
+			)
+		}
+	}
+	err = encoder.Close()
+	if err != nil {
+		return err
+	}
+
+	schemas, err := LoadCRDSchemas(metalLBCRDs)
+	if err != nil {
+		return err
+	}
+	err = errors.Join(schemas.Validate(manifest.Bytes())...)
+	if err != nil {
+		return fmt.Errorf(
+			"the MetalLB resources do not match the MetalLB CustomResourceDefinitions because %v",
+			err,
+		)
+	}
+
+	tpl := template.Must(template.New("metallbresources").Parse(string(MetalLBResourcesTemplate)))
+	err = tree.Template(
+		"metallb-resources.yaml",
+		tpl,
+		MakeTemplateData(
+			manifest.String(),
+			runtime,
+		),
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to render metallb resources because %v",
+			err,
+		)
+	}
+	return nil
 }
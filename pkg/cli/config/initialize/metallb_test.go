@@ -0,0 +1,208 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetalLBTestSuite struct {
+	suite.Suite
+	v *viper.Viper
+}
+
+func (suite *MetalLBTestSuite) SetupTest() {
+	viper.Reset()
+	suite.v = viper.GetViper()
+	suite.Require().NoError(suite.v.BindPFlags(NewCommand().Flags()))
+	suite.v.SetConfigFile(
+		filepath.Join(
+			generateFixtureDir,
+			"system_config.yaml",
+		),
+	)
+	suite.Require().NoError(suite.v.ReadInConfig())
+	suite.v.Set(
+		"csm-version",
+		"1.7",
+	)
+}
+
+func (suite *MetalLBTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+func (suite *MetalLBTestSuite) generate() *Outputs {
+	inputs, err := CollectInputs(suite.v)
+	suite.Require().NoError(err)
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+	return outputs
+}
+
+func (suite *MetalLBTestSuite) TestGetMetalLBResources() {
+	suite.T().Setenv(
+		"CSI_TEST_BGP_PASS",
+		"from-env",
+	)
+	suite.v.Set(
+		"metallb-bgp-password",
+		"env:CSI_TEST_BGP_PASS",
+	)
+	suite.v.Set(
+		"metallb-bfd-profile",
+		"fast",
+	)
+	outputs := suite.generate()
+	resources, err := GetMetalLBResources(
+		suite.v,
+		outputs.Networks,
+		outputs.Switches,
+		outputs.secrets.metalLBPassword,
+	)
+	suite.Require().NoError(err)
+
+	byName := make(map[string]MetalLBResource)
+	for _, resource := range resources {
+		suite.Equal(
+			MetalLBAPIVersion,
+			resource.APIVersion,
+		)
+		byName[resource.Kind+"/"+resource.Metadata.Name] = resource
+	}
+	suite.Equal(
+		IPAddressPoolSpec{
+			Addresses: []string{"10.92.100.0/24"},
+		},
+		byName["IPAddressPool/node-management"].Spec,
+	)
+	// The BGP peers refer to a BFDProfile of their own.
+	suite.Equal(
+		BFDProfileSpec{},
+		byName["BFDProfile/fast"].Spec,
+	)
+	// Every network has its own ASN.
+	suite.Equal(
+		BGPPeerSpec{
+			MyASN:       65531,
+			PeerASN:     65533,
+			PeerAddress: "10.252.0.2",
+			Password:    "from-env",
+			BFDProfile:  "fast",
+		},
+		byName["BGPPeer/sw-spine-001-nmn"].Spec,
+	)
+	suite.Equal(
+		65532,
+		byName["BGPPeer/sw-spine-001-cmn"].Spec.(BGPPeerSpec).MyASN,
+	)
+	suite.Equal(
+		BGPAdvertisementSpec{
+			IPAddressPools: []string{
+				"hardware-management",
+				"node-management",
+			},
+			Peers: []string{
+				"sw-spine-001-nmn",
+				"sw-spine-002-nmn",
+			},
+		},
+		byName["BGPAdvertisement/nmn"].Spec,
+	)
+	suite.Contains(
+		byName["BGPAdvertisement/cmn"].Spec.(BGPAdvertisementSpec).IPAddressPools,
+		"customer-access",
+	)
+
+	manifest := string(outputs.Files["metallb-resources.yaml"])
+	suite.Contains(
+		manifest,
+		"kind: BGPAdvertisement\n",
+	)
+	suite.Contains(
+		manifest,
+		"kind: BFDProfile\nmetadata:\n  name: fast\n  namespace: metallb-system\nspec: {}\n",
+	)
+	suite.NotContains(
+		outputs.Files,
+		"metallb.yaml",
+	)
+}
+
+func (suite *MetalLBTestSuite) TestCRDSchemasValidate() {
+	schemas, err := LoadCRDSchemas(metalLBCRDs)
+	suite.Require().NoError(err)
+	suite.Contains(
+		schemas,
+		"metallb.io/v1beta1 BGPPeer",
+	)
+
+	errs := schemas.Validate(
+		[]byte(strings.Join(
+			[]string{
+				"apiVersion: metallb.io/v1beta1\nkind: BGPPeer\nmetadata:\n  name: sw-spine-001-nmn\nspec:\n  myASN: -1\n  peerASN: 65533\n  peerAddress: 10.252.0.2\n",
+				"apiVersion: metallb.io/v1beta1\nkind: IPAddressPool\nmetadata:\n  name: node-management\nspec:\n  address: 10.92.100.0/24\n",
+				"apiVersion: metallb.io/v1beta2\nkind: BGPPeer\nmetadata:\n  name: sw-spine-002-nmn\n",
+				"apiVersion: metallb.io/v1beta1\nkind: BGPAdvertisement\nmetadata:\n  name: nmn\nspec:\n  peers: sw-spine-001-nmn\n",
+				"apiVersion: metallb.io/v1beta1\nkind: BFDProfile\nmetadata:\n  name: fast\nspec:\n  detectMultiplier: 1\n",
+			},
+			"---\n",
+		)),
+	)
+	var messages []string
+	for _, err := range errs {
+		messages = append(
+			messages,
+			err.Error(),
+		)
+	}
+	suite.Equal(
+		[]string{
+			"BGPPeer sw-spine-001-nmn.spec.myASN: -1 is less than 0",
+			"IPAddressPool node-management.spec.addresses: is required",
+			"IPAddressPool node-management.spec.address: is not a known field",
+			"BGPPeer sw-spine-002-nmn: there is no schema for metallb.io/v1beta2 BGPPeer",
+			"BGPAdvertisement nmn.spec.peers: must be an array",
+			"BFDProfile fast.spec.detectMultiplier: 1 is less than 2",
+		},
+		messages,
+	)
+}
+
+func TestMetalLBTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(MetalLBTestSuite),
+	)
+}
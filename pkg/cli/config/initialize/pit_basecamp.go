@@ -29,8 +29,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
 	"net/netip"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/Cray-HPE/hms-bss/pkg/bssTypes"
@@ -71,7 +73,7 @@ type BasecampGlobalMetaData struct {
 // Basecamp Defaults
 // See disks.go for disk layout, filesystems, and mounts
 
-// We should try to make these customizable by the user at some point
+// The runcmd lists can be changed by the user with the --runcmd-yaml overlay, see runcmd.go
 // k8sRunCMD has the list of scripts to run on NCN boot for
 // all members of the kubernetes cluster
 var k8sRunCMD = []string{
@@ -163,10 +165,8 @@ type BasecampHostRecord struct {
 	Aliases []string `json:"aliases"`
 }
 
-// MakeBasecampHostRecords uses the ncns to generate a list of host ips and their names for use in /etc/hosts
-func MakeBasecampHostRecords(
-	ncns []LogicalNCN, shastaNetworks map[string]*networking.IPNetwork, installNCN string,
-) interface{} {
+// MakeNCNHostRecords returns the /etc/hosts entries of the ncns, one per network they are on and one for their BMC
+func MakeNCNHostRecords(ncns []LogicalNCN, shastaNetworks map[string]*networking.IPNetwork) []BasecampHostRecord {
 	var hostrecords []BasecampHostRecord
 	hmnNetwork, _ := shastaNetworks["HMN"].LookUpSubnet("bootstrap_dhcp")
 	for _, ncn := range ncns {
@@ -222,6 +222,17 @@ func MakeBasecampHostRecords(
 			}
 		}
 	}
+	return hostrecords
+}
+
+// MakeBasecampHostRecords uses the ncns to generate a list of host ips and their names for use in /etc/hosts
+func MakeBasecampHostRecords(
+	ncns []LogicalNCN, shastaNetworks map[string]*networking.IPNetwork, installNCN string,
+) interface{} {
+	hostrecords := MakeNCNHostRecords(
+		ncns,
+		shastaNetworks,
+	)
 	nmnNetwork, _ := shastaNetworks["NMN"].LookUpSubnet("bootstrap_dhcp")
 	nmnLbNetwork, _ := shastaNetworks["NMNLB"].LookUpSubnet("nmn_metallb_address_pool")
 	k8sres := nmnNetwork.ReservationsByName()["kubeapi-vip"]
@@ -342,13 +353,9 @@ func MakeBasecampGlobalMetaData(
 
 	// First loop through and see if there's a viper flag
 	// We register a few aliases because flags don't necessarily match data.json keys
-	RegisterAlias(
-		"can-gw",
-		"can-gateway",
-	)
-	RegisterAlias(
-		"cmn-gw",
-		"cmn-gateway4",
+	registerAliases(
+		v,
+		basecampAliases,
 	)
 	allSettings, _ := json.Marshal(v.AllSettings())
 	_ = json.Unmarshal(
@@ -358,8 +365,8 @@ func MakeBasecampGlobalMetaData(
 
 	tempSubnet := shastaNetworks[installNetwork].SubnetByName(installSubnet)
 	if tempSubnet.Name == "" {
-		log.Fatalf(
-			"Couldn't find a '%v' subnet in the %v network for generating basecamp's data.json. Install is doomed.",
+		return global, fmt.Errorf(
+			"couldn't find a '%v' subnet in the %v network for generating basecamp's data.json",
 			installSubnet,
 			installNetwork,
 		)
@@ -381,7 +388,10 @@ func MakeBasecampGlobalMetaData(
 	global.RGWVirtualIP = reservations["rgw-vip"].IPAddress.String()
 
 	// "Set k8s-primary-cni" to Cilium if CSM 1.7 or later
-	currentVersion, eval := csm.CompareMajorMinor("1.7")
+	currentVersion, eval := csm.CompareMajorMinorWith(
+		v,
+		"1.7",
+	)
 	if eval != -1 {
 		log.Printf(
 			"Detected CSM %s, setting k8s-primary-cni to Cilium",
@@ -414,7 +424,9 @@ func MakeBasecampGlobalMetaData(
 // Format for ifroute-<interface> files
 func getNCNStaticRoutes(
 	v *viper.Viper, shastaNetworks map[string]*networking.IPNetwork,
-) []networking.WriteFiles {
+) (
+	[]networking.WriteFiles, error,
+) {
 	var nmnGateway string
 	var hmnGateway string
 	var ifrouteNMN bytes.Buffer
@@ -480,7 +492,7 @@ func getNCNStaticRoutes(
 
 	// we should always have routes at this point
 	if ifrouteNMN.Len() == 0 || ifrouteHMN.Len() == 0 {
-		log.Panic("Error generating routes")
+		return nil, fmt.Errorf("failed to generate the NMN and HMN static routes for the NCNs")
 	}
 
 	// add k8s routes
@@ -513,7 +525,7 @@ func getNCNStaticRoutes(
 			Permissions: "0644",
 		},
 	}
-	return writeFiles
+	return writeFiles, nil
 }
 
 // MakeBaseCampfromNCNs uses ncns and networks to create the basecamp config
@@ -525,15 +537,28 @@ func MakeBaseCampfromNCNs(
 	basecampConfig := make(map[string]networking.CloudInit)
 	uaiMacvlanSubnet, err := shastaNetworks["NMN"].LookUpSubnet("uai_macvlan")
 	if err != nil {
-		log.Fatal("basecamp_gen: Couldn't find the macvlan subnet in the NMN")
+		return nil, fmt.Errorf("basecamp_gen: couldn't find the macvlan subnet in the NMN")
 	}
 	uaiReservations := uaiMacvlanSubnet.ReservationsByName()
-	writeFiles := getNCNStaticRoutes(
+	writeFiles, err := getNCNStaticRoutes(
 		v,
 		shastaNetworks,
 	)
-	_, oneSixCloudInit := csm.CompareMajorMinor("1.6")
-	_, oneSevenCSM := csm.CompareMajorMinor("1.7")
+	if err != nil {
+		return nil, err
+	}
+	runCMDOverlays, err := collectRunCMDOverlays(v)
+	if err != nil {
+		return nil, err
+	}
+	_, oneSixCloudInit := csm.CompareMajorMinorWith(
+		v,
+		"1.6",
+	)
+	_, oneSevenCSM := csm.CompareMajorMinorWith(
+		v,
+		"1.7",
+	)
 	for _, ncn := range ncns {
 		mac0Interface := networking.MAC0Interface{}
 		mac0Interface.IP = uaiReservations[ncn.Hostname].IPAddress
@@ -646,6 +671,11 @@ func MakeBaseCampfromNCNs(
 			}
 		}
 
+		userData.RunCMD = runCMDOverlays.Apply(
+			ncn.Role,
+			ncn.Subrole,
+			userData.RunCMD,
+		)
 		userData.Hostname = ncn.Hostname
 		userData.LocalHostname = ncn.Hostname
 		userData.MAC0Interface = mac0Interface
@@ -672,7 +702,8 @@ func MakeBaseCampfromNCNs(
 		var nmnNets []string
 
 		// Need to exclude the BICAN toggle network and the NMNLB/HMNLB networks.
-		for _, netNetwork := range shastaNetworks {
+		for _, netName := range slices.Sorted(maps.Keys(shastaNetworks)) {
+			netNetwork := shastaNetworks[netName]
 			if (strings.Contains(
 				netNetwork.Name,
 				"HMN",
@@ -689,6 +720,12 @@ func MakeBaseCampfromNCNs(
 					nmnNets,
 					netNetwork.CIDR4,
 				)
+				if netNetwork.CIDR6 != "" {
+					nmnNets = append(
+						nmnNets,
+						netNetwork.CIDR6,
+					)
+				}
 			}
 		}
 
@@ -707,14 +744,14 @@ func MakeBaseCampfromNCNs(
 		// FIXME: Incompatible with IPv6
 		re, err := regexp.Compile(`^[0-9A-Za-z](?:(?:[0-9A-Za-z]|-){0,61}[0-9A-Za-z])?(?:\.[0-9A-Za-z](?:(?:[0-9A-Za-z]|-){0,61}[0-9A-Za-z])?)*\.?$`)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
 		// validate ntp domains
 		for _, d := range v.GetStringSlice("ntp-peers") {
 			_, err := netip.ParseAddr(d)
 			if !re.Match([]byte(d)) && err != nil {
-				log.Fatalf(
+				return nil, fmt.Errorf(
 					"invalid ntp peer: %s",
 					d,
 				)
@@ -723,7 +760,7 @@ func MakeBaseCampfromNCNs(
 		for _, d := range v.GetStringSlice("ntp-servers") {
 			_, err := netip.ParseAddr(d)
 			if !re.Match([]byte(d)) && err != nil {
-				log.Fatalf(
+				return nil, fmt.Errorf(
 					"invalid ntp server: %s",
 					d,
 				)
@@ -732,7 +769,7 @@ func MakeBaseCampfromNCNs(
 		for _, d := range v.GetStringSlice("ntp-pools") {
 			_, err := netip.ParseAddr(d)
 			if !re.Match([]byte(d)) && err != nil {
-				log.Fatalf(
+				return nil, fmt.Errorf(
 					"invalid ntp pool: %s",
 					d,
 				)
@@ -759,27 +796,29 @@ func MakeBaseCampfromNCNs(
 	return basecampConfig, nil
 }
 
-// WriteBasecampData writes basecamp data.json for the installer
-func WriteBasecampData(
-	path string, ncns []LogicalNCN, shastaNetworks map[string]*networking.IPNetwork, globalMetaData interface{},
+// MakeBasecampData assembles the contents of basecamp's data.json, the per-NCN cloud-init data along with the
+// Global meta-data shared by every NCN.
+func MakeBasecampData(
+	v *viper.Viper, ncns []LogicalNCN, shastaNetworks map[string]*networking.IPNetwork, globalMetaData interface{},
+) (
+	bssTypes.CloudDataType, error,
 ) {
-	v := viper.GetViper()
 	basecampConfig, err := MakeBaseCampfromNCNs(
 		v,
 		ncns,
 		shastaNetworks,
 	)
 	if err != nil {
-		log.Printf(
-			"Error extracting NCNs: %v",
+		return nil, fmt.Errorf(
+			"failed to extract NCNs because %v",
 			err,
 		)
 	}
 	// To write this the way we want to consume it, we need to convert it to a map of strings and interfaces
 	globalMetaDataJSON, err := json.Marshal(globalMetaData)
 	if err != nil {
-		log.Fatalf(
-			"Failed to marshal global data because %v",
+		return nil, fmt.Errorf(
+			"failed to marshal global data because %v",
 			err,
 		)
 	}
@@ -791,8 +830,8 @@ func WriteBasecampData(
 		&global.MetaData,
 	)
 	if err != nil {
-		log.Fatalf(
-			"Failed to unmarshal global data into BSS object because %v",
+		return nil, fmt.Errorf(
+			"failed to unmarshal global data into BSS object because %v",
 			err,
 		)
 	}
@@ -801,16 +840,31 @@ func WriteBasecampData(
 	for k, v := range basecampConfig {
 		data[k] = v
 	}
+	return data, nil
+}
 
+// WriteBasecampData writes basecamp data.json for the installer
+func WriteBasecampData(
+	path string, ncns []LogicalNCN, shastaNetworks map[string]*networking.IPNetwork, globalMetaData interface{},
+) error {
+	data, err := MakeBasecampData(
+		viper.GetViper(),
+		ncns,
+		shastaNetworks,
+		globalMetaData,
+	)
+	if err != nil {
+		return err
+	}
 	err = files.WriteJSONConfig(
 		path,
 		data,
 	)
 	if err != nil {
-		log.Printf(
-			"Error writing data.json: %v",
+		return fmt.Errorf(
+			"failed to write data.json because %v",
 			err,
 		)
 	}
-
+	return nil
 }
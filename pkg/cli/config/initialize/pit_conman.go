@@ -27,13 +27,13 @@ package initialize
 import (
 	"fmt"
 	"text/template"
-
-	"github.com/spf13/viper"
+	"time"
 
 	"github.com/Cray-HPE/cray-site-init/internal/files"
 )
 
 // ConmanConfigTemplate manages the Conman Configuration
+// The line of each NCN is the "console" template, which renders a conmanEntry.
 var ConmanConfigTemplate = []byte(`
 {{- /* remove leading whitespace */ -}}
 #
@@ -52,8 +52,9 @@ GLOBAL seropts="115200,8n1"
 GLOBAL log="console.%N"
 GLOBAL logopts="sanitize,timestamp"
 {{range .Data}}
-console name="{{.Hostname}}-mgmt" ipmiopts="U:{{.User}},P:{{.Pass}},W:solpayloadsize" dev="ipmi:{{.IP}}"
+{{template "console" .}}
 {{- end}}
+{{define "console"}}console name="{{.Hostname}}-mgmt" ipmiopts="U:{{.User}},P:{{.Pass}},W:solpayloadsize" dev="ipmi:{{.IP}}"{{end -}}
 `)
 
 type conmanEntry struct {
@@ -63,14 +64,11 @@ type conmanEntry struct {
 	Pass     string
 }
 
-// WriteConmanConfig provides conman configuration for the installer
-func WriteConmanConfig(
-	path string, ncns []LogicalNCN,
+// RenderConmanConfig renders the conman configuration for the installer to tree, connecting to the BMCs with the given
+// credential, stamped with the given runtime.
+func RenderConmanConfig(
+	tree files.Tree, credential PasswordCredential, ncns []LogicalNCN, runtime time.Time,
 ) (err error) {
-	v := viper.GetViper()
-	ncnBMCUser := v.GetString("bootstrap-ncn-bmc-user")
-	ncnBMCPass := v.GetString("bootstrap-ncn-bmc-pass")
-
 	var conmanNCNs []conmanEntry
 
 	for _, k := range ncns {
@@ -78,24 +76,26 @@ func WriteConmanConfig(
 			conmanNCNs,
 			conmanEntry{
 				Hostname: k.Hostname,
-				User:     ncnBMCUser,
-				Pass:     ncnBMCPass,
+				User:     credential.Username,
+				Pass:     credential.Password,
 				IP:       k.BmcIP,
 			},
 		)
 	}
 
-	conmanData := MakeTemplateData(conmanNCNs)
+	conmanData := MakeTemplateData(
+		conmanNCNs,
+		runtime,
+	)
 	tpl6 := template.Must(template.New("conmanconfig").Parse(string(ConmanConfigTemplate)))
-	err = files.WriteTemplate(
-		path,
+	err = tree.Template(
+		"conman.conf",
 		tpl6,
 		conmanData,
 	)
 	if err != nil {
 		err = fmt.Errorf(
-			"failed to write conman to config to %s because %v",
-			path,
+			"failed to render conman config because %v",
 			err,
 		)
 	}
@@ -26,8 +26,10 @@ package initialize
 
 import (
 	"fmt"
+	"maps"
 	"net"
 	"regexp"
+	"slices"
 	"strings"
 
 	valid "github.com/asaskevich/govalidator"
@@ -115,9 +117,13 @@ func (c *CustomizationsYaml) IsValid() bool {
 
 // GenCustomizationsYaml generates our configurations.yaml nested struct
 func GenCustomizationsYaml(
-	ncns []LogicalNCN, shastaNetworks map[string]*networking.IPNetwork, switches []*networking.ManagementSwitch,
-) CustomizationsYaml {
-	v := viper.GetViper()
+	v *viper.Viper,
+	ncns []LogicalNCN,
+	shastaNetworks map[string]*networking.IPNetwork,
+	switches []*networking.ManagementSwitch,
+) (
+	CustomizationsYaml, error,
+) {
 	systemName := v.GetString("system-name")
 	siteDomain := v.GetString("site-domain")
 
@@ -156,11 +162,14 @@ func GenCustomizationsYaml(
 		}
 	}
 
-	metallb = GetMetalLBConfig(
+	metallb, err := GetMetalLBConfig(
 		v,
 		shastaNetworks,
 		switches,
 	)
+	if err != nil {
+		return output, err
+	}
 
 	nmnLBs, _ := shastaNetworks["NMNLB"].LookUpSubnet("nmn_metallb_address_pool")
 	hmnLBs, _ := shastaNetworks["HMNLB"].LookUpSubnet("hmn_metallb_address_pool")
@@ -290,7 +299,8 @@ func GenCustomizationsYaml(
 			NMNMacVlanReservationEnd:   uaiNet.ReservationEnd,
 		},
 	}
-	for netName, network := range shastaNetworks {
+	for _, netName := range slices.Sorted(maps.Keys(shastaNetworks)) {
+		network := shastaNetworks[netName]
 		switch netName {
 
 		case "NMNLB", "HMN", "HMNLB":
@@ -317,7 +327,7 @@ func GenCustomizationsYaml(
 			)
 		}
 	}
-	return output
+	return output, nil
 }
 
 func init() {
@@ -28,9 +28,9 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
-	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
 
@@ -51,13 +51,17 @@ var SubnetConfigDHCPOnlyTemplate = []byte(`
 # {{.Data.Network.Name}}:
 server=/{{.Data.Network.Name | lower}}/
 address=/{{.Data.Network.Name | lower}}/
+{{ if not .Data.DNSOnly -}}
 dhcp-option=interface:{{.Data.Interface}},option:domain-search,{{.Data.Network.Name | lower}}
+{{ end -}}
 interface-name=pit.{{.Data.Network.Name | lower}},{{.Data.Interface}}
 interface={{.Data.Interface}}
 cname=packages.{{.Data.Network.Name | lower}},pit.{{.Data.Network.Name | lower}}
 cname=registry.{{.Data.Network.Name | lower}},pit.{{.Data.Network.Name | lower}}
+{{ if not .Data.DNSOnly -}}
 dhcp-option=interface:{{.Data.Interface}},option:router,{{.Data.Subnet.Gateway}}
 dhcp-range=interface:{{.Data.Interface}},{{.Data.Subnet.DHCPStart}},{{.Data.Subnet.DHCPEnd}},10m
+{{ end -}}
 `)
 
 // SubnetConfigTemplate handles subnet definitions for DNSMasq.
@@ -73,21 +77,34 @@ server=/{{.Data.Network.Name | lower}}/
 address=/{{.Data.Network.Name | lower}}/
 domain=nmn,{{.Data.NetworkStart}},{{.Data.NetworkEnd}},local
 interface-name=pit.{{.Data.Network.Name | lower}},{{.Data.Interface}}
+{{ if not .Data.DNSOnly -}}
 dhcp-option=interface:{{.Data.Interface}},option:domain-search,{{.Data.Network.Name | lower}}
+{{ end -}}
 interface={{.Data.Interface}}
 cname=packages.{{.Data.Network.Name | lower}},pit.{{.Data.Network.Name | lower}}
 {{ if eq .Data.Network.Name "MTL" -}}
 cname=packages.local,pit.{{.Data.Network.Name | lower}}
 {{ end -}}
 cname=registry.{{.Data.Network.Name | lower}},pit.{{.Data.Network.Name | lower}}
+{{ if not .Data.DNSOnly -}}
 dhcp-option=interface:{{.Data.Interface}},option:dns-server,{{.Data.Network.PITServer}}
 dhcp-option=interface:{{.Data.Interface}},option:ntp-server,{{.Data.Network.PITServer}}
 dhcp-option=interface:{{.Data.Interface}},option:router,{{.Data.Subnet.Gateway}}
 dhcp-range=interface:{{.Data.Interface}},{{.Data.Subnet.DHCPStart}},{{.Data.Subnet.DHCPEnd}},10m
+{{ end -}}
+{{ if and .Data.Prefix6.IsValid (not .Data.DNSOnly) -}}
+{{ if .Data.PITServer6.IsValid -}}
+dhcp-option=interface:{{.Data.Interface}},option6:dns-server,[{{.Data.PITServer6}}]
+dhcp-option=interface:{{.Data.Interface}},option6:ntp-server,[{{.Data.PITServer6}}]
+{{ end -}}
+dhcp-range=interface:{{.Data.Interface}},{{.Data.Prefix6.Addr}},static,{{.Data.Prefix6.Bits}},10m
+{{ end -}}
 `)
 
 // StaticConfigTemplate manages the static portion of the DNSMasq configuration
 // Systems with onboard NICs will have a MTL MAC. Others will also use the NMN
+// The entries of each NCN are the "ncn" template, which renders a DNSMasqNCNStatics.
+// The DHCP entries are left out when another DHCP server (Kea) serves the PIT.
 var StaticConfigTemplate = []byte(`
 {{- /* remove leading whitespace */ -}}
 #
@@ -96,33 +113,48 @@ var StaticConfigTemplate = []byte(`
 ## Generated time: {{ .Timestamp }}
 #
 # Static Configurations
-{{range .Data.NCNS}}
+{{range .Data.NCNS}}{{template "ncn" .}}{{end}}
+# Virtual IP Addresses for k8s and the rados gateway
+host-record=kubeapi-vip,kubeapi-vip.nmn,{{.Data.KUBEVIP}}{{with .Data.KUBEVIP6}},{{.}}{{end}} # k8s-virtual-ip
+host-record=rgw-vip,rgw-vip.nmn,{{.Data.RGWVIP}}{{with .Data.RGWVIP6}},{{.}}{{end}} # rgw-virtual-ip
+host-record={{.Data.APIGWALIASES}},{{.Data.APIGWIP}} # api gateway
+
+cname=kubernetes-api.vshasta.io,ncn-m001
+{{define "ncn"}}
+{{- if not .DNSOnly }}
 # DHCP Entries for {{.Hostname}}
 dhcp-host=id:{{.Xname}},set:{{.Hostname}},{{.Bond0Mac0}},{{.Bond0Mac1}},{{.MtlIP}},{{.Hostname}},20m # MTL
 dhcp-host=id:{{.Xname}},set:{{.Hostname}},{{.Bond0Mac0}},{{.Bond0Mac1}},{{.NmnIP}},{{.Hostname}},20m # Bond0 Mac0/Mac1
 dhcp-host=id:{{.Xname}},set:{{.Hostname}},{{.Bond0Mac0}},{{.Bond0Mac1}},{{.HmnIP}},{{.Hostname}},20m # HMN
-{{ if eq $.Data.BICAN "CAN" -}}
+{{ if eq .BICAN "CAN" -}}
 dhcp-host=id:{{.Xname}},set:{{.Hostname}},{{.Bond0Mac0}},{{.Bond0Mac1}},{{.CanIP}},{{.Hostname}},20m # CAN
 {{ end -}}
 dhcp-host={{.BmcMac}},{{.BmcIP}},{{.Hostname}}-mgmt,20m #HMN
+{{ if .MtlIP6 -}}
+dhcp-host={{.Bond0Mac0}},{{.Bond0Mac1}},[{{.MtlIP6}}],{{.Hostname}},20m # MTL IPv6
+{{ end -}}
+{{ if .NmnIP6 -}}
+dhcp-host={{.Bond0Mac0}},{{.Bond0Mac1}},[{{.NmnIP6}}],{{.Hostname}},20m # Bond0 Mac0/Mac1 IPv6
+{{ end -}}
+{{ if .HmnIP6 -}}
+dhcp-host={{.Bond0Mac0}},{{.Bond0Mac1}},[{{.HmnIP6}}],{{.Hostname}},20m # HMN IPv6
+{{ end -}}
+{{- else }}
+{{ end -}}
 # Host Record Entries for {{.Hostname}}
-{{ if eq $.Data.BICAN "CAN" -}}
+{{ if eq .BICAN "CAN" -}}
 host-record={{.Hostname}},{{.Hostname}}.can,{{.CanIP}}
 {{ end -}}
-host-record={{.Hostname}},{{.Hostname}}.hmn,{{.HmnIP}}
-host-record={{.Hostname}},{{.Hostname}}.nmn,{{.NmnIP}}
-host-record={{.Hostname}},{{.Hostname}}.mtl,{{.MtlIP}}
-host-record={{.Xname}},{{.Hostname}}.nmn,{{.NmnIP}}
+host-record={{.Hostname}},{{.Hostname}}.hmn,{{.HmnIP}}{{with .HmnIP6}},{{.}}{{end}}
+host-record={{.Hostname}},{{.Hostname}}.nmn,{{.NmnIP}}{{with .NmnIP6}},{{.}}{{end}}
+host-record={{.Hostname}},{{.Hostname}}.mtl,{{.MtlIP}}{{with .MtlIP6}},{{.}}{{end}}
+host-record={{.Xname}},{{.Hostname}}.nmn,{{.NmnIP}}{{with .NmnIP6}},{{.}}{{end}}
 host-record={{.Hostname}}-mgmt,{{.Hostname}}-mgmt.hmn,{{.BmcIP}}
+{{ if not .DNSOnly -}}
 # Override root-path with {{.Hostname}}'s xname
 dhcp-option-force=tag:{{.Hostname}},17,{{.Xname}}
-{{end}}
-# Virtual IP Addresses for k8s and the rados gateway
-host-record=kubeapi-vip,kubeapi-vip.nmn,{{.Data.KUBEVIP}} # k8s-virtual-ip
-host-record=rgw-vip,rgw-vip.nmn,{{.Data.RGWVIP}} # rgw-virtual-ip
-host-record={{.Data.APIGWALIASES}},{{.Data.APIGWIP}} # api gateway
-
-cname=kubernetes-api.vshasta.io,ncn-m001
+{{ end -}}
+{{end -}}
 `)
 
 // DNSMasqBootstrapNetwork holds information for configuring DNSMasq on the LiveCD
@@ -132,38 +164,67 @@ type DNSMasqBootstrapNetwork struct {
 	Network      networking.IPNetwork
 	Subnet       slsCommon.IPSubnet
 	Interface    string
+	// Prefix6 is the IPv6 prefix of the subnet, it is only valid when the subnet has IPv6.
+	Prefix6    netip.Prefix
+	PITServer6 netip.Addr
+	// DNSOnly leaves out the DHCP options and ranges, for when Kea is the DHCP server of the PIT.
+	DNSOnly bool
+}
+
+// DNSMasqNCNStatics holds the static DNSMasq entries of a single NCN
+type DNSMasqNCNStatics struct {
+	LogicalNCN
+	BICAN  string
+	NmnIP6 string
+	MtlIP6 string
+	HmnIP6 string
+	// DNSOnly leaves out the DHCP entries, for when Kea is the DHCP server of the PIT.
+	DNSOnly bool
 }
 
 type DNSMasqStatics struct {
-	NCNS         []LogicalNCN
+	NCNS         []DNSMasqNCNStatics
 	KUBEVIP      string
+	KUBEVIP6     string
 	RGWVIP       string
+	RGWVIP6      string
 	APIGWALIASES string
 	APIGWIP      string
 	BICAN        string
 }
 
-// WriteDNSMasqConfig writes the dnsmasq configuration files necessary for installation
-func WriteDNSMasqConfig(
-	path string, v *viper.Viper, bootstrap []LogicalNCN, networks map[string]*networking.IPNetwork,
-) (err error) {
-	for i, tmpNcn := range bootstrap {
-		for _, tmpNet := range tmpNcn.Networks {
-			if strings.ToUpper(tmpNet.NetworkName) == "NMN" {
-				tmpNcn.NmnIP = tmpNet.IPv4Address.String()
-			}
-			if strings.ToUpper(tmpNet.NetworkName) == "CAN" {
-				tmpNcn.CanIP = tmpNet.IPv4Address.String()
-			}
-			if strings.ToUpper(tmpNet.NetworkName) == "MTL" {
-				tmpNcn.MtlIP = tmpNet.IPv4Address.String()
-			}
-			if strings.ToUpper(tmpNet.NetworkName) == "HMN" {
-				tmpNcn.HmnIP = tmpNet.IPv4Address.String()
-			}
+// NewDNSMasqNCNStatics returns the static DNSMasq entries of an NCN, including its IPv6 addresses.
+func NewDNSMasqNCNStatics(ncn LogicalNCN, bican string) DNSMasqNCNStatics {
+	statics := DNSMasqNCNStatics{
+		LogicalNCN: ncn,
+		BICAN:      bican,
+	}
+	for _, tmpNet := range ncn.Networks {
+		if !tmpNet.IPv6Address.IsValid() {
+			continue
+		}
+		switch strings.ToUpper(tmpNet.NetworkName) {
+		case "NMN":
+			statics.NmnIP6 = tmpNet.IPv6Address.String()
+		case "MTL":
+			statics.MtlIP6 = tmpNet.IPv6Address.String()
+		case "HMN":
+			statics.HmnIP6 = tmpNet.IPv6Address.String()
 		}
-		bootstrap[i] = tmpNcn
 	}
+	return statics
+}
+
+// RenderDNSMasqConfig renders the dnsmasq configuration files necessary for installation to tree, stamped with the
+// given runtime. Their DHCP entries are left out when Kea is the pit-dhcp-backend, dnsmasq then only serves DNS.
+func RenderDNSMasqConfig(
+	tree files.Tree, v *viper.Viper, bootstrap []LogicalNCN, networks map[string]*networking.IPNetwork,
+	runtime time.Time,
+) (err error) {
+	for i := range bootstrap {
+		setNetworkIPs(&bootstrap[i])
+	}
+	dnsOnly := v.GetString("pit-dhcp-backend") == PITDHCPBackendKea
 	funcMap := template.FuncMap{
 		// The name "title" is what the function will be called in the template text.
 		"lower": strings.ToLower,
@@ -174,64 +235,135 @@ func WriteDNSMasqConfig(
 	netHMN := template.Must(template.New("hmnconfig").Funcs(funcMap).Parse(string(SubnetConfigTemplate)))
 	netNMN := template.Must(template.New("nmnconfig").Funcs(funcMap).Parse(string(SubnetConfigTemplate)))
 	netMTL := template.Must(template.New("mtlconfig").Funcs(funcMap).Parse(string(SubnetConfigTemplate)))
-	err = writeConfig(
+	err = renderConfig(
+		tree,
+		v,
 		"CMN",
-		path,
 		*netCMN,
 		networks,
+		dnsOnly,
+		runtime,
 	)
 	if err != nil {
 		return fmt.Errorf(
-			"failed to write CMN DNSMasq config because %v",
+			"failed to render CMN DNSMasq config because %v",
 			err,
 		)
 	}
-	err = writeConfig(
+	err = renderConfig(
+		tree,
+		v,
 		"HMN",
-		path,
 		*netHMN,
 		networks,
+		dnsOnly,
+		runtime,
 	)
 	if err != nil {
 		return fmt.Errorf(
-			"failed to write HMN DNSMasq config because %v",
+			"failed to render HMN DNSMasq config because %v",
 			err,
 		)
 	}
-	err = writeConfig(
+	err = renderConfig(
+		tree,
+		v,
 		"NMN",
-		path,
 		*netNMN,
 		networks,
+		dnsOnly,
+		runtime,
 	)
 	if err != nil {
 		return fmt.Errorf(
-			"failed to write NMN DNSMasq config because %v",
+			"failed to render NMN DNSMasq config because %v",
 			err,
 		)
 	}
-	err = writeConfig(
+	err = renderConfig(
+		tree,
+		v,
 		"MTL",
-		path,
 		*netMTL,
 		networks,
+		dnsOnly,
+		runtime,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to render MTL DNSMasq config because %v",
+			err,
+		)
+	}
+
+	bicanNetworkName := v.GetString("bican-user-network-name")
+	if bicanNetworkName == "CAN" || v.GetBool("retain-unused-user-network") {
+		netCAN := template.Must(template.New("canconfig").Funcs(funcMap).Parse(string(SubnetConfigDHCPOnlyTemplate)))
+		err := renderConfig(
+			tree,
+			v,
+			bicanNetworkName,
+			*netCAN,
+			networks,
+			dnsOnly,
+			runtime,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to render CAN DNSMasq config because %v",
+				err,
+			)
+		}
+	}
+
+	statics := NewDNSMasqStatics(
+		v,
+		bootstrap,
+		networks,
+	)
+	for i := range statics.NCNS {
+		statics.NCNS[i].DNSOnly = dnsOnly
+	}
+	data := MakeTemplateData(
+		statics,
+		runtime,
+	)
+	// Expected NCNs (and other devices) reserved DHCP leases:
+	netIPAM := template.Must(template.New("statics").Parse(string(StaticConfigTemplate)))
+	err = tree.Template(
+		"dnsmasq.d/statics.conf",
+		netIPAM,
+		data,
 	)
 	if err != nil {
 		return fmt.Errorf(
-			"failed to write MTL DNSMasq config because %v",
+			"failed to render DNSMasq statics.conf because %v",
 			err,
 		)
 	}
+	return err
+}
 
+// NewDNSMasqStatics returns the static entries of the bootstrap NCNs, along with the virtual IPs of the NMN and the
+// API gateway.
+func NewDNSMasqStatics(
+	v *viper.Viper, bootstrap []LogicalNCN, networks map[string]*networking.IPNetwork,
+) DNSMasqStatics {
 	bicanNetworkName := v.GetString("bican-user-network-name")
-	var kubevip, rgwvip string
+	var kubevip, kubevip6, rgwvip, rgwvip6 string
 	nmnSubnet, _ := networks["NMN"].LookUpSubnet("bootstrap_dhcp")
 	for _, reservation := range nmnSubnet.IPReservations {
 		if reservation.Name == "kubeapi-vip" {
 			kubevip = reservation.IPAddress.String()
+			if reservation.IPAddress6 != nil {
+				kubevip6 = reservation.IPAddress6.String()
+			}
 		}
 		if reservation.Name == "rgw-vip" {
 			rgwvip = reservation.IPAddress.String()
+			if reservation.IPAddress6 != nil {
+				rgwvip6 = reservation.IPAddress6.String()
+			}
 		}
 	}
 
@@ -244,68 +376,103 @@ func WriteDNSMasqConfig(
 	)
 	apigwIP = apigw.IPAddress.String()
 
-	if bicanNetworkName == "CAN" || v.GetBool("retain-unused-user-network") {
-		netCAN := template.Must(template.New("canconfig").Funcs(funcMap).Parse(string(SubnetConfigDHCPOnlyTemplate)))
-		err := writeConfig(
-			bicanNetworkName,
-			path,
-			*netCAN,
-			networks,
+	var ncnStatics []DNSMasqNCNStatics
+	for _, tmpNcn := range bootstrap {
+		setNetworkIPs(&tmpNcn)
+		ncnStatics = append(
+			ncnStatics,
+			NewDNSMasqNCNStatics(
+				tmpNcn,
+				bicanNetworkName,
+			),
 		)
-		if err != nil {
-			return fmt.Errorf(
-				"failed to write CAN DNSMasq config because %v",
-				err,
-			)
+	}
+	return DNSMasqStatics{
+		NCNS:         ncnStatics,
+		KUBEVIP:      kubevip,
+		KUBEVIP6:     kubevip6,
+		RGWVIP:       rgwvip,
+		RGWVIP6:      rgwvip6,
+		APIGWALIASES: apigwAliases,
+		APIGWIP:      apigwIP,
+		BICAN:        bicanNetworkName,
+	}
+}
+
+// setNetworkIPs sets the NMN, CAN, MTL, and HMN addresses of an NCN from its networks
+func setNetworkIPs(ncn *LogicalNCN) {
+	for _, tmpNet := range ncn.Networks {
+		if strings.ToUpper(tmpNet.NetworkName) == "NMN" {
+			ncn.NmnIP = tmpNet.IPv4Address.String()
+		}
+		if strings.ToUpper(tmpNet.NetworkName) == "CAN" {
+			ncn.CanIP = tmpNet.IPv4Address.String()
+		}
+		if strings.ToUpper(tmpNet.NetworkName) == "MTL" {
+			ncn.MtlIP = tmpNet.IPv4Address.String()
+		}
+		if strings.ToUpper(tmpNet.NetworkName) == "HMN" {
+			ncn.HmnIP = tmpNet.IPv4Address.String()
 		}
 	}
+}
 
-	data := MakeTemplateData(
-		DNSMasqStatics{
-			NCNS:         bootstrap,
-			KUBEVIP:      kubevip,
-			RGWVIP:       rgwvip,
-			APIGWALIASES: apigwAliases,
-			APIGWIP:      apigwIP,
-			BICAN:        bicanNetworkName,
-		},
+func renderConfig(
+	tree files.Tree, v *viper.Viper, name string, tpl template.Template, networks map[string]*networking.IPNetwork, dnsOnly bool,
+	runtime time.Time,
+) (err error) {
+	bootstrapNetwork, err := NewDNSMasqBootstrapNetwork(
+		v,
+		name,
+		networks,
 	)
-	// Expected NCNs (and other devices) reserved DHCP leases:
-	netIPAM := template.Must(template.New("statics").Parse(string(StaticConfigTemplate)))
-	err = files.WriteTemplate(
-		filepath.Join(
-			path,
-			"dnsmasq.d/statics.conf",
+	if err != nil {
+		return err
+	}
+	bootstrapNetwork.DNSOnly = dnsOnly
+
+	err = tree.Template(
+		fmt.Sprintf(
+			"dnsmasq.d/%v.conf",
+			name,
+		),
+		&tpl,
+		MakeTemplateData(
+			bootstrapNetwork,
+			runtime,
 		),
-		netIPAM,
-		data,
 	)
 	if err != nil {
-		return fmt.Errorf(
-			"failed to write DNSMasq statics.conf because %v",
+		err = fmt.Errorf(
+			"failed to render dnsmasq config for %s because %v",
+			name,
 			err,
 		)
 	}
 	return err
 }
 
-func writeConfig(
-	name, path string, tpl template.Template, networks map[string]*networking.IPNetwork,
-) (err error) {
+// NewDNSMasqBootstrapNetwork returns the bootstrap_dhcp subnet of a network with the gateway, PIT server, and interface
+// it is served with.
+func NewDNSMasqBootstrapNetwork(
+	v *viper.Viper, name string, networks map[string]*networking.IPNetwork,
+) (bootstrapNetwork DNSMasqBootstrapNetwork, err error) {
 	// Pointer to the IPNetwork
 	tempNet := networks[name]
 
-	v := viper.GetViper()
-
 	// Pointer to the subnet
 	bootstrapSubnet, _ := tempNet.LookUpSubnet("bootstrap_dhcp")
 	// Create a subnet copy (avoid modifying the base Data with dnsmasq overrides)
 	tempSubnet := *bootstrapSubnet
 
 	// Look up the PIT IP for the network
+	var pitServer6 netip.Addr
 	for _, reservation := range tempSubnet.IPReservations {
 		if reservation.Name == v.GetString("install-ncn") {
 			tempNet.PITServer = reservation.IPAddress.String()
+			if reservation.IPAddress6 != nil {
+				pitServer6, _ = netip.AddrFromSlice(reservation.IPAddress6)
+			}
 		}
 	}
 	if strings.ToLower(tempNet.Name) == "can" {
@@ -321,7 +488,7 @@ func writeConfig(
 	interfaceName, _ := tempNet.GenInterfaceName(&tempSubnet)
 	prefix4, err := netip.ParsePrefix(tempNet.CIDR4)
 	if err != nil {
-		return fmt.Errorf(
+		return bootstrapNetwork, fmt.Errorf(
 			"failed to parse %s CIDR because %v ",
 			tempNet.CIDR4,
 			err,
@@ -329,39 +496,31 @@ func writeConfig(
 	}
 	networkEnd, err := networking.Broadcast(prefix4)
 	if err != nil {
-		return fmt.Errorf(
+		return bootstrapNetwork, fmt.Errorf(
 			"failed to find broadcast address for %s because %v ",
 			tempNet.CIDR4,
 			err,
 		)
 	}
-	bootstrapNetwork := MakeTemplateData(
-		DNSMasqBootstrapNetwork{
-			NetworkStart: prefix4.Addr(),
-			NetworkEnd:   networkEnd,
-			Network:      *tempNet,
-			Interface:    interfaceName,
-			Subnet:       tempSubnet,
-		},
-	)
-
-	err = files.WriteTemplate(
-		filepath.Join(
-			path,
-			fmt.Sprintf(
-				"dnsmasq.d/%v.conf",
-				name,
-			),
-		),
-		&tpl,
-		bootstrapNetwork,
-	)
-	if err != nil {
-		err = fmt.Errorf(
-			"failed to write dnsmasq config for %s because %v",
-			path,
-			err,
-		)
+	var prefix6 netip.Prefix
+	if tempSubnet.CIDR6 != "" {
+		prefix6, err = netip.ParsePrefix(tempSubnet.CIDR6)
+		if err != nil {
+			return bootstrapNetwork, fmt.Errorf(
+				"failed to parse %s CIDR6 because %v ",
+				tempSubnet.CIDR6,
+				err,
+			)
+		}
+		prefix6 = prefix6.Masked()
 	}
-	return err
+	return DNSMasqBootstrapNetwork{
+		NetworkStart: prefix4.Addr(),
+		NetworkEnd:   networkEnd,
+		Network:      *tempNet,
+		Interface:    interfaceName,
+		Subnet:       tempSubnet,
+		Prefix6:      prefix6,
+		PITServer6:   pitServer6.Unmap(),
+	}, nil
 }
@@ -0,0 +1,675 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+	"github.com/Cray-HPE/cray-site-init/pkg/networking"
+)
+
+const (
+	// PITDHCPBackendDNSMasq serves DHCP on the PIT with dnsmasq.
+	PITDHCPBackendDNSMasq = "dnsmasq"
+
+	// PITDHCPBackendKea serves DHCP on the PIT with Kea, dnsmasq then only serves DNS.
+	PITDHCPBackendKea = "kea"
+)
+
+// PITDHCPBackends are the valid values of pit-dhcp-backend.
+var PITDHCPBackends = []string{
+	PITDHCPBackendDNSMasq,
+	PITDHCPBackendKea,
+}
+
+// KeaDHCP4Template is a kea-dhcp4.conf, Kea allows shell comments in its JSON configuration.
+var KeaDHCP4Template = []byte(`
+{{- /* remove leading whitespace */ -}}
+#
+## This file was generated by cray-site-init.
+## Version: {{ .Version }}
+## Generated time: {{ .Timestamp }}
+#
+{{ .Data }}
+`)
+
+// KeaConfigFile is the Kea DHCPv4 configuration in a system directory.
+const KeaConfigFile = "kea-dhcp4.conf"
+
+// keaLeaseLifetime is the lifetime of the leases, in seconds. It is the 10m of the dnsmasq ranges.
+const keaLeaseLifetime = 600
+
+// KeaConfig is the configuration of the Kea DHCPv4 server.
+type KeaConfig struct {
+	Dhcp4 KeaDHCP4 `json:"Dhcp4"`
+}
+
+// KeaDHCP4 is the Dhcp4 object of a KeaConfig. The NCNs have a reservation for both of their bond MACs with the same
+// address, so IPReservationsUnique is false.
+type KeaDHCP4 struct {
+	InterfacesConfig     KeaInterfacesConfig `json:"interfaces-config"`
+	LeaseDatabase        KeaLeaseDatabase    `json:"lease-database"`
+	ValidLifetime        int                 `json:"valid-lifetime"`
+	IPReservationsUnique bool                `json:"ip-reservations-unique"`
+	Subnet4              []KeaSubnet4        `json:"subnet4"`
+}
+
+// KeaInterfacesConfig are the interfaces Kea listens on.
+type KeaInterfacesConfig struct {
+	Interfaces []string `json:"interfaces"`
+}
+
+// KeaLeaseDatabase is where Kea stores its leases.
+type KeaLeaseDatabase struct {
+	Type    string `json:"type"`
+	Persist bool   `json:"persist"`
+	Name    string `json:"name"`
+}
+
+// KeaSubnet4 is a subnet served by Kea, its pools are "<start> - <end>" ranges.
+type KeaSubnet4 struct {
+	ID           int              `json:"id"`
+	Subnet       string           `json:"subnet"`
+	Interface    string           `json:"interface"`
+	Pools        []KeaPool        `json:"pools,omitempty"`
+	OptionData   []KeaOptionData  `json:"option-data,omitempty"`
+	Reservations []KeaReservation `json:"reservations,omitempty"`
+}
+
+// KeaPool is a range of dynamically leased addresses.
+type KeaPool struct {
+	Pool string `json:"pool"`
+}
+
+// KeaOptionData is a DHCP option, given by name or by code.
+type KeaOptionData struct {
+	Name       string `json:"name,omitempty"`
+	Code       int    `json:"code,omitempty"`
+	Data       string `json:"data"`
+	AlwaysSend bool   `json:"always-send,omitempty"`
+}
+
+// KeaReservation is a host reservation of a subnet.
+type KeaReservation struct {
+	HWAddress  string          `json:"hw-address"`
+	IPAddress  string          `json:"ip-address"`
+	Hostname   string          `json:"hostname"`
+	OptionData []KeaOptionData `json:"option-data,omitempty"`
+}
+
+/*
+NewKeaConfig returns the Kea DHCPv4 configuration of the PIT, made from the same bootstrap networks and statics as the
+dnsmasq configuration:
+
+ 1. A subnet for the bootstrap_dhcp subnet of the MTL, NMN, HMN, CMN, and CAN (when it is the BICAN network or
+    retained), with a pool from its DHCPStart to its DHCPEnd
+ 2. The router and domain-search options of every subnet, and the DNS and NTP server options of the MTL, NMN, and
+    HMN, which are the PIT
+ 3. A reservation of every NCN address for each of its bond MACs, overriding the root-path (option 17) with the
+    xname of the NCN, and a reservation of the address of every NCN BMC
+*/
+func NewKeaConfig(
+	v *viper.Viper, bootstrap []LogicalNCN, networks map[string]*networking.IPNetwork,
+) (config KeaConfig, err error) {
+	config.Dhcp4 = KeaDHCP4{
+		LeaseDatabase: KeaLeaseDatabase{
+			Type:    "memfile",
+			Persist: true,
+			Name:    "/var/lib/kea/kea-leases4.csv",
+		},
+		ValidLifetime: keaLeaseLifetime,
+	}
+	networkNames := []string{
+		"MTL",
+		"NMN",
+		"HMN",
+		"CMN",
+	}
+	bicanNetworkName := v.GetString("bican-user-network-name")
+	if bicanNetworkName == "CAN" || v.GetBool("retain-unused-user-network") {
+		networkNames = append(
+			networkNames,
+			"CAN",
+		)
+	}
+	for i, name := range networkNames {
+		if _, ok := networks[name]; !ok {
+			return config, fmt.Errorf(
+				"there is no %s network to serve DHCP on",
+				name,
+			)
+		}
+		bootstrapNetwork, err := NewDNSMasqBootstrapNetwork(
+			v,
+			name,
+			networks,
+		)
+		if err != nil {
+			return config, err
+		}
+		prefix, err := netip.ParsePrefix(bootstrapNetwork.Subnet.CIDR)
+		if err != nil {
+			return config, fmt.Errorf(
+				"failed to parse the %s bootstrap_dhcp CIDR %s because %v",
+				name,
+				bootstrapNetwork.Subnet.CIDR,
+				err,
+			)
+		}
+		subnet := KeaSubnet4{
+			ID:        i + 1,
+			Subnet:    prefix.Masked().String(),
+			Interface: bootstrapNetwork.Interface,
+			OptionData: []KeaOptionData{
+				{
+					Name: "routers",
+					Data: bootstrapNetwork.Subnet.Gateway.String(),
+				},
+				{
+					Name: "domain-search",
+					Data: strings.ToLower(name),
+				},
+			},
+		}
+		if bootstrapNetwork.Subnet.DHCPStart != nil && bootstrapNetwork.Subnet.DHCPEnd != nil {
+			subnet.Pools = []KeaPool{
+				{
+					Pool: bootstrapNetwork.Subnet.DHCPStart.String() + " - " + bootstrapNetwork.Subnet.DHCPEnd.String(),
+				},
+			}
+		}
+		// The CMN and CAN only hand out addresses, like their DHCP only dnsmasq configuration.
+		if name != "CMN" && name != "CAN" {
+			subnet.OptionData = append(
+				subnet.OptionData,
+				KeaOptionData{
+					Name: "domain-name-servers",
+					Data: bootstrapNetwork.Network.PITServer,
+				},
+				KeaOptionData{
+					Name: "ntp-servers",
+					Data: bootstrapNetwork.Network.PITServer,
+				},
+			)
+		}
+		config.Dhcp4.Subnet4 = append(
+			config.Dhcp4.Subnet4,
+			subnet,
+		)
+		if !slices.Contains(
+			config.Dhcp4.InterfacesConfig.Interfaces,
+			subnet.Interface,
+		) {
+			config.Dhcp4.InterfacesConfig.Interfaces = append(
+				config.Dhcp4.InterfacesConfig.Interfaces,
+				subnet.Interface,
+			)
+		}
+	}
+
+	statics := NewDNSMasqStatics(
+		v,
+		bootstrap,
+		networks,
+	)
+	for _, ncn := range statics.NCNS {
+		config.AddNCN(ncn)
+	}
+	return config, nil
+}
+
+// AddNCN reserves the addresses of an NCN for each of its bond MACs, and the address of its BMC. Each reservation is
+// added to the subnet containing its address, addresses outside of every subnet are left to the validation.
+func (config *KeaConfig) AddNCN(ncn DNSMasqNCNStatics) {
+	reserve := func(address string, reservation KeaReservation) {
+		ip, err := netip.ParseAddr(address)
+		if err != nil {
+			return
+		}
+		reservation.IPAddress = ip.String()
+		for i, subnet := range config.Dhcp4.Subnet4 {
+			prefix, err := netip.ParsePrefix(subnet.Subnet)
+			if err == nil && prefix.Contains(ip) {
+				config.Dhcp4.Subnet4[i].Reservations = append(
+					config.Dhcp4.Subnet4[i].Reservations,
+					reservation,
+				)
+				return
+			}
+		}
+	}
+	addresses := []string{
+		ncn.MtlIP,
+		ncn.NmnIP,
+		ncn.HmnIP,
+	}
+	if ncn.BICAN == "CAN" {
+		addresses = append(
+			addresses,
+			ncn.CanIP,
+		)
+	}
+	var macs []string
+	for _, mac := range []string{
+		ncn.Bond0Mac0,
+		ncn.Bond0Mac1,
+	} {
+		if mac != "" && !slices.Contains(
+			macs,
+			mac,
+		) {
+			macs = append(
+				macs,
+				mac,
+			)
+		}
+	}
+	for _, address := range addresses {
+		for _, mac := range macs {
+			reserve(
+				address,
+				KeaReservation{
+					HWAddress: mac,
+					Hostname:  ncn.Hostname,
+					OptionData: []KeaOptionData{
+						{
+							Name:       "root-path",
+							Code:       17,
+							Data:       ncn.Xname,
+							AlwaysSend: true,
+						},
+					},
+				},
+			)
+		}
+	}
+	if ncn.BmcMac != "" {
+		reserve(
+			ncn.BmcIP,
+			KeaReservation{
+				HWAddress: ncn.BmcMac,
+				Hostname:  ncn.Hostname + "-mgmt",
+			},
+		)
+	}
+}
+
+/*
+Validate checks that the subnets, pools, and reservations of the configuration are consistent:
+
+ 1. Every subnet is a valid, masked CIDR with a unique ID and an interface, and does not overlap another subnet
+ 2. Every pool is a range within its subnet, that does not overlap another pool or contain the router
+ 3. Every reservation has a valid MAC, an address within its subnet and outside of its pools, and neither its MAC nor
+    its address is reserved for another host of the subnet
+*/
+func (config KeaConfig) Validate() (errs []error) {
+	ids := make(map[int]string)
+	var prefixes []netip.Prefix
+	for _, subnet := range config.Dhcp4.Subnet4 {
+		prefix, err := netip.ParsePrefix(subnet.Subnet)
+		if err != nil || prefix != prefix.Masked() || !prefix.Addr().Is4() {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s is not an IPv4 network CIDR",
+					subnet.Subnet,
+				),
+			)
+			continue
+		}
+		if other, ok := ids[subnet.ID]; ok || subnet.ID <= 0 {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s has the invalid or duplicate ID %d of %s",
+					subnet.Subnet,
+					subnet.ID,
+					other,
+				),
+			)
+		}
+		ids[subnet.ID] = subnet.Subnet
+		if subnet.Interface == "" {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s has no interface",
+					subnet.Subnet,
+				),
+			)
+		}
+		for _, other := range prefixes {
+			if other.Overlaps(prefix) {
+				errs = append(
+					errs,
+					fmt.Errorf(
+						"subnet %s overlaps subnet %s",
+						subnet.Subnet,
+						other,
+					),
+				)
+			}
+		}
+		prefixes = append(
+			prefixes,
+			prefix,
+		)
+		errs = append(
+			errs,
+			subnet.validate(prefix)...,
+		)
+	}
+	return errs
+}
+
+// validate checks the pools and reservations of a subnet with the given prefix.
+func (subnet KeaSubnet4) validate(prefix netip.Prefix) (errs []error) {
+	var pools [][2]netip.Addr
+	for _, pool := range subnet.Pools {
+		start, end, err := parseKeaPool(pool.Pool)
+		if err != nil {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s pool %q is not a range because %v",
+					subnet.Subnet,
+					pool.Pool,
+					err,
+				),
+			)
+			continue
+		}
+		if !prefix.Contains(start) || !prefix.Contains(end) || end.Less(start) {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s pool %s is not a range within the subnet",
+					subnet.Subnet,
+					pool.Pool,
+				),
+			)
+			continue
+		}
+		for _, other := range pools {
+			if !end.Less(other[0]) && !other[1].Less(start) {
+				errs = append(
+					errs,
+					fmt.Errorf(
+						"subnet %s pool %s overlaps pool %s - %s",
+						subnet.Subnet,
+						pool.Pool,
+						other[0],
+						other[1],
+					),
+				)
+			}
+		}
+		pools = append(
+			pools,
+			[2]netip.Addr{
+				start,
+				end,
+			},
+		)
+	}
+	inPool := func(address netip.Addr) bool {
+		for _, pool := range pools {
+			if !address.Less(pool[0]) && !pool[1].Less(address) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, option := range subnet.OptionData {
+		if option.Name != "routers" {
+			continue
+		}
+		if router, err := netip.ParseAddr(option.Data); err == nil && inPool(router) {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s router %s is in a pool",
+					subnet.Subnet,
+					router,
+				),
+			)
+		}
+	}
+
+	hosts := make(map[string]string)
+	macs := make(map[string]string)
+	for _, reservation := range subnet.Reservations {
+		mac, err := net.ParseMAC(reservation.HWAddress)
+		if err != nil {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s reservation of %s has the invalid MAC %q",
+					subnet.Subnet,
+					reservation.Hostname,
+					reservation.HWAddress,
+				),
+			)
+		} else if other, ok := macs[mac.String()]; ok {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s reservation of %s has MAC %s, which is already reserved for %s",
+					subnet.Subnet,
+					reservation.Hostname,
+					mac,
+					other,
+				),
+			)
+		} else {
+			macs[mac.String()] = reservation.Hostname
+		}
+
+		address, err := netip.ParseAddr(reservation.IPAddress)
+		if err != nil || !prefix.Contains(address) {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s reservation of %s has the address %q, which is not in the subnet",
+					subnet.Subnet,
+					reservation.Hostname,
+					reservation.IPAddress,
+				),
+			)
+			continue
+		}
+		if inPool(address) {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s reservation of %s has the address %s, which is in a pool",
+					subnet.Subnet,
+					reservation.Hostname,
+					address,
+				),
+			)
+		}
+		// The MACs of a host share its address.
+		if other, ok := hosts[address.String()]; ok && other != reservation.Hostname {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"subnet %s reservation of %s has the address %s, which is already reserved for %s",
+					subnet.Subnet,
+					reservation.Hostname,
+					address,
+					other,
+				),
+			)
+		}
+		hosts[address.String()] = reservation.Hostname
+	}
+	return errs
+}
+
+// parseKeaPool returns the start and end of a "<start> - <end>" pool.
+func parseKeaPool(pool string) (start netip.Addr, end netip.Addr, err error) {
+	first, last, found := strings.Cut(
+		pool,
+		"-",
+	)
+	if !found {
+		return start, end, fmt.Errorf("there is no - between its start and end")
+	}
+	start, err = netip.ParseAddr(strings.TrimSpace(first))
+	if err != nil {
+		return start, end, err
+	}
+	end, err = netip.ParseAddr(strings.TrimSpace(last))
+	return start, end, err
+}
+
+// ReadKeaConfig reads a kea-dhcp4.conf written by WriteKeaConfig, skipping its comments.
+func ReadKeaConfig(path string) (config KeaConfig, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf(
+			"failed to read %s because %v",
+			path,
+			err,
+		)
+	}
+	var lines []string
+	for _, line := range strings.Split(
+		string(data),
+		"\n",
+	) {
+		if !strings.HasPrefix(
+			line,
+			"#",
+		) {
+			lines = append(
+				lines,
+				line,
+			)
+		}
+	}
+	err = json.Unmarshal(
+		[]byte(strings.Join(
+			lines,
+			"\n",
+		)),
+		&config,
+	)
+	if err != nil {
+		return config, fmt.Errorf(
+			"failed to decode %s because %v",
+			path,
+			err,
+		)
+	}
+	return config, nil
+}
+
+// RenderKeaConfig renders the kea-dhcp4.conf of the PIT to tree, stamped with the given runtime.
+func RenderKeaConfig(
+	tree files.Tree, v *viper.Viper, bootstrap []LogicalNCN, networks map[string]*networking.IPNetwork,
+	runtime time.Time,
+) error {
+	config, err := NewKeaConfig(
+		v,
+		bootstrap,
+		networks,
+	)
+	if err != nil {
+		return err
+	}
+	return config.Render(
+		tree,
+		KeaConfigFile,
+		runtime,
+	)
+}
+
+// Write writes the configuration to a file stamped with the given runtime, after checking its consistency.
+func (config KeaConfig) Write(path string, runtime time.Time) error {
+	tree := make(files.Tree)
+	err := config.Render(
+		tree,
+		filepath.Base(path),
+		runtime,
+	)
+	if err != nil {
+		return err
+	}
+	return tree.Write(filepath.Dir(path))
+}
+
+// Render renders the configuration to tree as name stamped with the given runtime, after checking its consistency.
+func (config KeaConfig) Render(tree files.Tree, name string, runtime time.Time) error {
+	err := errors.Join(config.Validate()...)
+	if err != nil {
+		return fmt.Errorf(
+			"the Kea DHCP configuration is inconsistent because %v",
+			err,
+		)
+	}
+	data, err := json.MarshalIndent(
+		config,
+		"",
+		"  ",
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to encode the Kea DHCP configuration because %v",
+			err,
+		)
+	}
+	tpl := template.Must(template.New("keadhcp4").Parse(string(KeaDHCP4Template)))
+	err = tree.Template(
+		name,
+		tpl,
+		MakeTemplateData(
+			string(data),
+			runtime,
+		),
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to render %s because %v",
+			name,
+			err,
+		)
+	}
+	return nil
+}
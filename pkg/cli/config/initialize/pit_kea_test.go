@@ -0,0 +1,359 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+type KeaTestSuite struct {
+	suite.Suite
+	basepath string
+	v        *viper.Viper
+}
+
+func (suite *KeaTestSuite) SetupTest() {
+	viper.Reset()
+	suite.v = viper.GetViper()
+	suite.Require().NoError(suite.v.BindPFlags(NewCommand().Flags()))
+	suite.v.SetConfigFile(
+		filepath.Join(
+			generateFixtureDir,
+			"system_config.yaml",
+		),
+	)
+	suite.Require().NoError(suite.v.ReadInConfig())
+	suite.v.Set(
+		"pit-dhcp-backend",
+		PITDHCPBackendKea,
+	)
+
+	inputs, err := CollectInputs(suite.v)
+	suite.Require().NoError(err)
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+	suite.basepath = filepath.Join(
+		suite.T().TempDir(),
+		outputs.SystemName,
+	)
+	suite.Require().NoError(outputs.Write(suite.basepath))
+}
+
+func (suite *KeaTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// readConfig returns the kea-dhcp4.conf of the system directory, keyed by interface.
+func (suite *KeaTestSuite) readConfig() map[string]KeaSubnet4 {
+	config, err := ReadKeaConfig(
+		filepath.Join(
+			suite.basepath,
+			KeaConfigFile,
+		),
+	)
+	suite.Require().NoError(err)
+	suite.Empty(config.Validate())
+	subnets := make(map[string]KeaSubnet4)
+	for _, subnet := range config.Dhcp4.Subnet4 {
+		subnets[subnet.Interface] = subnet
+	}
+	return subnets
+}
+
+// reservation returns the reservation of a MAC in a subnet.
+func (suite *KeaTestSuite) reservation(subnet KeaSubnet4, mac string) KeaReservation {
+	for _, reservation := range subnet.Reservations {
+		if reservation.HWAddress == mac {
+			return reservation
+		}
+	}
+	suite.Failf(
+		"missing reservation",
+		"%s has no reservation for %s",
+		subnet.Subnet,
+		mac,
+	)
+	return KeaReservation{}
+}
+
+func (suite *KeaTestSuite) TestGenerate() {
+	subnets := suite.readConfig()
+	suite.Len(
+		subnets,
+		5,
+	)
+	nmn := subnets["bond0.nmn0"]
+	suite.Equal(
+		"10.252.0.0/17",
+		nmn.Subnet,
+	)
+	suite.Equal(
+		[]KeaPool{
+			{
+				Pool: "10.252.1.14 - 10.252.1.214",
+			},
+		},
+		nmn.Pools,
+	)
+	suite.Contains(
+		nmn.OptionData,
+		KeaOptionData{
+			Name: "routers",
+			Data: "10.252.0.1",
+		},
+	)
+	suite.Equal(
+		KeaReservation{
+			HWAddress: "14:02:ec:d9:76:88",
+			IPAddress: "10.252.1.0",
+			Hostname:  "ncn-m001",
+			OptionData: []KeaOptionData{
+				{
+					Name:       "root-path",
+					Code:       17,
+					Data:       "x3000c0s1b0n0",
+					AlwaysSend: true,
+				},
+			},
+		},
+		suite.reservation(
+			nmn,
+			"14:02:ec:d9:76:88",
+		),
+	)
+	suite.Empty(subnets["bond0.cmn0"].Reservations)
+
+	// dnsmasq only serves DNS.
+	config, err := os.ReadFile(
+		filepath.Join(
+			suite.basepath,
+			"dnsmasq.d/NMN.conf",
+		),
+	)
+	suite.Require().NoError(err)
+	suite.Contains(
+		string(config),
+		"domain=nmn,10.252.0.0,10.252.127.255,local\n",
+	)
+	suite.NotContains(
+		string(config),
+		"dhcp-",
+	)
+	statics, err := os.ReadFile(
+		filepath.Join(
+			suite.basepath,
+			"dnsmasq.d/statics.conf",
+		),
+	)
+	suite.Require().NoError(err)
+	suite.Contains(
+		string(statics),
+		"host-record=ncn-m001,ncn-m001.nmn,10.252.1.0\n",
+	)
+	suite.NotContains(
+		string(statics),
+		"dhcp-",
+	)
+}
+
+func (suite *KeaTestSuite) TestAddNCN() {
+	state, err := LoadPreviousSLS(suite.basepath)
+	suite.Require().NoError(err)
+	addition, err := AddNCN(
+		suite.v,
+		state,
+		&LogicalNCN{
+			Xname:     "x3000c0s30b0n0",
+			Role:      "Management",
+			Subrole:   "Storage",
+			BmcMac:    "94:40:c9:00:00:01",
+			NmnMac:    "14:02:ec:00:00:01",
+			Bond0Mac0: "14:02:ec:00:00:01",
+			Bond0Mac1: "94:40:c9:00:00:02",
+		},
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(addition.Write(suite.basepath))
+
+	subnets := suite.readConfig()
+	nmn := subnets["bond0.nmn0"]
+	suite.Equal(
+		"10.252.1.12",
+		suite.reservation(
+			nmn,
+			"94:40:c9:00:00:02",
+		).IPAddress,
+	)
+	suite.Equal(
+		[]KeaPool{
+			{
+				Pool: "10.252.1.15 - 10.252.1.215",
+			},
+		},
+		nmn.Pools,
+	)
+	suite.Equal(
+		KeaReservation{
+			HWAddress: "94:40:c9:00:00:01",
+			IPAddress: "10.254.1.20",
+			Hostname:  "ncn-s004-mgmt",
+		},
+		suite.reservation(
+			subnets["bond0.hmn0"],
+			"94:40:c9:00:00:01",
+		),
+	)
+
+	statics, err := os.ReadFile(
+		filepath.Join(
+			suite.basepath,
+			"dnsmasq.d/statics.conf",
+		),
+	)
+	suite.Require().NoError(err)
+	suite.NotContains(
+		string(statics),
+		"dhcp-",
+	)
+}
+
+func (suite *KeaTestSuite) TestValidate() {
+	reservation := func(mac string, address string, hostname string) KeaReservation {
+		return KeaReservation{
+			HWAddress: mac,
+			IPAddress: address,
+			Hostname:  hostname,
+		}
+	}
+	config := KeaConfig{
+		Dhcp4: KeaDHCP4{
+			Subnet4: []KeaSubnet4{
+				{
+					ID:        1,
+					Subnet:    "10.252.0.0/17",
+					Interface: "bond0.nmn0",
+					Pools: []KeaPool{
+						{
+							Pool: "10.252.1.10 - 10.252.1.100",
+						},
+						{
+							Pool: "10.253.0.10 - 10.253.0.100",
+						},
+					},
+					OptionData: []KeaOptionData{
+						{
+							Name: "routers",
+							Data: "10.252.1.20",
+						},
+					},
+					Reservations: []KeaReservation{
+						reservation(
+							"14:02:ec:00:00:01",
+							"10.252.1.5",
+							"ncn-m001",
+						),
+						reservation(
+							"14:02:ec:00:00:01",
+							"10.252.1.6",
+							"ncn-m002",
+						),
+						reservation(
+							"14:02:ec:00:00:02",
+							"10.252.1.5",
+							"ncn-m003",
+						),
+						reservation(
+							"14:02:ec:00:00:03",
+							"10.252.1.50",
+							"ncn-m004",
+						),
+						reservation(
+							"not-a-mac",
+							"10.254.1.5",
+							"ncn-m005",
+						),
+					},
+				},
+				{
+					ID:        1,
+					Subnet:    "10.252.1.0/24",
+					Interface: "",
+				},
+			},
+		},
+	}
+	var messages []string
+	for _, err := range config.Validate() {
+		messages = append(
+			messages,
+			err.Error(),
+		)
+	}
+	for _, message := range []string{
+		"subnet 10.252.1.0/24 has the invalid or duplicate ID 1 of 10.252.0.0/17",
+		"subnet 10.252.1.0/24 has no interface",
+		"subnet 10.252.1.0/24 overlaps subnet 10.252.0.0/17",
+		"subnet 10.252.0.0/17 pool 10.253.0.10 - 10.253.0.100 is not a range within the subnet",
+		"subnet 10.252.0.0/17 router 10.252.1.20 is in a pool",
+		"subnet 10.252.0.0/17 reservation of ncn-m002 has MAC 14:02:ec:00:00:01, which is already reserved for ncn-m001",
+		"subnet 10.252.0.0/17 reservation of ncn-m003 has the address 10.252.1.5, which is already reserved for ncn-m001",
+		"subnet 10.252.0.0/17 reservation of ncn-m004 has the address 10.252.1.50, which is in a pool",
+		"subnet 10.252.0.0/17 reservation of ncn-m005 has the invalid MAC \"not-a-mac\"",
+		"subnet 10.252.0.0/17 reservation of ncn-m005 has the address \"10.254.1.5\", which is not in the subnet",
+	} {
+		suite.Contains(
+			messages,
+			message,
+		)
+	}
+
+	suite.ErrorContains(
+		config.Write(
+			filepath.Join(
+				suite.T().TempDir(),
+				KeaConfigFile,
+			),
+			time.Now(),
+		),
+		"the Kea DHCP configuration is inconsistent",
+	)
+}
+
+func TestKeaTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(KeaTestSuite),
+	)
+}
@@ -26,15 +26,17 @@ package initialize
 
 import (
 	"fmt"
+	"maps"
+	"net"
 	"net/netip"
-	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/spf13/viper"
 
 	"github.com/Cray-HPE/cray-site-init/internal/files"
-	"github.com/Cray-HPE/cray-site-init/pkg/cli"
 	"github.com/Cray-HPE/cray-site-init/pkg/networking"
 )
 
@@ -44,6 +46,33 @@ type Route struct {
 	NextHop     string
 }
 
+// Prefix returns the destination of the route as a CIDR, the default route is 0.0.0.0/0.
+func (route Route) Prefix() (string, error) {
+	if route.Destination == "default" {
+		return "0.0.0.0/0", nil
+	}
+	destination, err := netip.ParseAddr(route.Destination)
+	if err != nil {
+		return "", fmt.Errorf(
+			"invalid route destination [%s] because %v",
+			route.Destination,
+			err,
+		)
+	}
+	mask := net.ParseIP(route.Mask).To4()
+	if mask == nil {
+		return "", fmt.Errorf(
+			"invalid route mask [%s]",
+			route.Mask,
+		)
+	}
+	bits, _ := net.IPMask(mask).Size()
+	return netip.PrefixFrom(
+		destination,
+		bits,
+	).String(), nil
+}
+
 // Routes is a list of Route structs.
 type Routes []Route
 
@@ -51,6 +80,7 @@ type LACPBond struct {
 	Members   []string
 	PrefixLen int
 	CIDR      string
+	CIDR6     string
 }
 
 type NetworkInterface struct {
@@ -64,180 +94,296 @@ type SysConfig struct {
 	SiteDNS string
 }
 
-// WriteCPTNetworkConfig writes the Network Configuration details for the installation node (PIT)
-func WriteCPTNetworkConfig(
-	path string, v *viper.Viper, ncn LogicalNCN, shastaNetworks map[string]*networking.IPNetwork,
-) (err error) {
+const (
+	// PITNetworkRendererIfcfg writes SUSE ifcfg-* and ifroute-* files for wicked.
+	PITNetworkRendererIfcfg = "ifcfg"
+
+	// PITNetworkRendererNMState writes an nmstate YAML state.
+	PITNetworkRendererNMState = "nmstate"
+
+	// PITNetworkRendererNetworkd writes systemd-networkd .netdev and .network files.
+	PITNetworkRendererNetworkd = "networkd"
+)
+
+// PITNetworkRenderers are the valid values of pit-network-renderer.
+var PITNetworkRenderers = []string{
+	PITNetworkRendererIfcfg,
+	PITNetworkRendererNMState,
+	PITNetworkRendererNetworkd,
+}
+
+// PITNetworkConfig is the network configuration of the installation node (PIT), independent of how it is written.
+type PITNetworkConfig struct {
+	// Bond is bond0 on the MTL network, it is nil if the PIT has no MTL address.
+	Bond *LACPBond
+
+	// Site is the lan0 bridge of the external site link.
+	Site NetworkInterface
+
+	// SiteRoutes are the routes of lan0.
+	SiteRoutes Routes
+
+	// SiteDNS is the DNS server of the site network.
+	SiteDNS string
+
+	// VLANs are the VLAN interfaces on bond0.
+	VLANs []NCNNetwork
+
+	// Routes are the static routes of the VLAN interfaces, keyed by interface name.
+	Routes map[string]Routes
+
+	// Runtime is the time the configuration was generated at, the rendered files are stamped with it.
+	Runtime time.Time
+}
+
+// PITNetworkRenderer renders a PITNetworkConfig in the format of a network manager.
+type PITNetworkRenderer interface {
+	Render(config PITNetworkConfig) (files.Tree, error)
+}
 
+// NewPITNetworkRenderer returns the PITNetworkRenderer of one of the PITNetworkRenderers.
+func NewPITNetworkRenderer(name string) (PITNetworkRenderer, error) {
+	switch name {
+	case PITNetworkRendererIfcfg:
+		return IfcfgRenderer{}, nil
+	case PITNetworkRendererNMState:
+		return NMStateRenderer{}, nil
+	case PITNetworkRendererNetworkd:
+		return NetworkdRenderer{}, nil
+	}
+	return nil, fmt.Errorf(
+		"unsupported PIT network renderer %q, must be one of %s",
+		name,
+		strings.Join(
+			PITNetworkRenderers,
+			",",
+		),
+	)
+}
+
+// NewPITNetworkConfig returns the network configuration of the PIT from its NCN networks and the site flags.
+func NewPITNetworkConfig(
+	v *viper.Viper, ncn LogicalNCN, shastaNetworks map[string]*networking.IPNetwork,
+) (config PITNetworkConfig, err error) {
 	for _, network := range ncn.Networks {
 		if network.NetworkName != "MTL" {
 			continue
 		}
-		data := MakeTemplateData(
-			LACPBond{
-				Members: strings.Split(
-					v.GetString("install-ncn-bond-members"),
-					",",
-				),
-				CIDR:      network.CIDR4.String(),
-				PrefixLen: network.CIDR4.Bits(),
-			},
-		)
-		err = files.WriteTemplate(
-			filepath.Join(
-				path,
-				"ifcfg-bond0",
+		config.Bond = &LACPBond{
+			Members: strings.Split(
+				v.GetString("install-ncn-bond-members"),
+				",",
 			),
-			template.Must(template.New("bond0").Parse(string(BondConfigTemplate))),
-			data,
-		)
-		if err != nil {
-			return fmt.Errorf(
-				"failed to write ifcfg-bond0 because %v",
-				err,
-			)
+			CIDR:      network.CIDR4.String(),
+			PrefixLen: network.CIDR4.Bits(),
+		}
+		if network.CIDR6.IsValid() {
+			config.Bond.CIDR6 = network.CIDR6.String()
 		}
 		break
 	}
 
 	sitePrefix, err := netip.ParsePrefix(v.GetString("site-ip"))
 	if err != nil {
-		return fmt.Errorf(
+		return config, fmt.Errorf(
 			"invalid site-ip, cannot continue because %v",
 			err,
 		)
 	}
-	lan0ConfigData := MakeTemplateData(
-		NetworkInterface{
-			NIC:       v.GetString("site-nic"),
-			IP:        sitePrefix.Addr().String(),
-			PrefixLen: sitePrefix.Bits(),
+	config.Site = NetworkInterface{
+		NIC:       v.GetString("site-nic"),
+		IP:        sitePrefix.Addr().String(),
+		PrefixLen: sitePrefix.Bits(),
+	}
+
+	siteGw, err := netip.ParseAddr(v.GetString("site-gw"))
+	if err != nil {
+		return config, fmt.Errorf(
+			"invalide site-gw, cannot continue because %v",
+			err,
+		)
+	}
+	config.SiteRoutes = Routes{
+		{
+			Destination: "default",
+			Mask:        "-",
+			NextHop:     siteGw.String(),
 		},
+	}
+	config.SiteDNS = v.GetString("site-dns")
+
+	config.Routes = make(map[string]Routes)
+	for _, network := range ncn.Networks {
+		// Besides the CSM networks, this includes any extra network the NCN was given an address on.
+		if shastaNetworks[network.NetworkName] == nil || network.ParentInterfaceName == "" {
+			continue
+		}
+		if network.Vlan != networking.DefaultMTLVlan && network.NetworkName != "CHN" {
+			config.VLANs = append(
+				config.VLANs,
+				network,
+			)
+		}
+		if network.NetworkName == "NMN" {
+			_, routes, err := genMetalLBTemplates(
+				shastaNetworks,
+			)
+			if err != nil {
+				return config, fmt.Errorf(
+					"failed to generate metallb routes because %v",
+					err,
+				)
+			}
+			config.Routes[strings.ToLower(network.InterfaceName)] = routes
+		}
+	}
+	return config, nil
+}
+
+// RenderCPTNetworkConfig renders the Network Configuration details for the installation node (PIT), in the format of
+// the pit-network-renderer, stamped with the given runtime.
+func RenderCPTNetworkConfig(
+	v *viper.Viper, ncn LogicalNCN, shastaNetworks map[string]*networking.IPNetwork, runtime time.Time,
+) (files.Tree, error) {
+	renderer, err := NewPITNetworkRenderer(v.GetString("pit-network-renderer"))
+	if err != nil {
+		return nil, err
+	}
+	config, err := NewPITNetworkConfig(
+		v,
+		ncn,
+		shastaNetworks,
 	)
-	err = files.WriteTemplate(
-		filepath.Join(
-			path,
-			"ifcfg-lan0",
+	if err != nil {
+		return nil, err
+	}
+	config.Runtime = runtime
+	return renderer.Render(config)
+}
+
+// siteDNSServers returns the servers of site-dns, which may separate them with commas or spaces.
+func siteDNSServers(siteDNS string) []string {
+	return strings.Fields(
+		strings.ReplaceAll(
+			siteDNS,
+			",",
+			" ",
 		),
-		template.Must(template.New("lan0").Parse(string(Lan0ConfigTemplate))),
-		lan0ConfigData,
 	)
-	if err != nil {
-		return fmt.Errorf(
-			"failed to write ifcfg-lan0 because %v",
-			err,
+}
+
+// IfcfgRenderer renders the SUSE ifcfg-* and ifroute-* files of the PIT, and its sysconfig network config.
+type IfcfgRenderer struct{}
+
+// Render renders the ifcfg files of the PIT.
+func (IfcfgRenderer) Render(config PITNetworkConfig) (tree files.Tree, err error) {
+	tree = make(files.Tree)
+	if config.Bond != nil {
+		err = tree.Template(
+			"ifcfg-bond0",
+			template.Must(template.New("bond0").Parse(string(BondConfigTemplate))),
+			MakeTemplateData(
+				*config.Bond,
+				config.Runtime,
+			),
 		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to render ifcfg-bond0 because %v",
+				err,
+			)
+		}
 	}
 
-	siteGw, err := netip.ParseAddr(v.GetString("site-gw"))
+	err = tree.Template(
+		"ifcfg-lan0",
+		template.Must(template.New("lan0").Parse(string(Lan0ConfigTemplate))),
+		MakeTemplateData(
+			config.Site,
+			config.Runtime,
+		),
+	)
 	if err != nil {
-		return fmt.Errorf(
-			"invalide site-gw, cannot continue because %v",
+		return nil, fmt.Errorf(
+			"failed to render ifcfg-lan0 because %v",
 			err,
 		)
 	}
-	lan0Route := Route{
-		Destination: "default",
-		Mask:        "-",
-		NextHop:     siteGw.String(),
-	}
-	lan0RouteData := MakeTemplateData(Routes{lan0Route})
-	err = files.WriteTemplate(
-		filepath.Join(
-			path,
-			"ifroute-lan0",
-		),
+
+	err = tree.Template(
+		"ifroute-lan0",
 		template.Must(template.New("vlan").Parse(string(VlanRouteTemplate))),
-		lan0RouteData,
+		MakeTemplateData(
+			config.SiteRoutes,
+			config.Runtime,
+		),
 	)
 	if err != nil {
-		return fmt.Errorf(
-			"failed to write ifroute-lan0 because %v",
+		return nil, fmt.Errorf(
+			"failed to render ifroute-lan0 because %v",
 			err,
 		)
 	}
 
-	sysconfigData := MakeTemplateData(SysConfig{SiteDNS: v.GetString("site-dns")})
-	err = files.WriteTemplate(
-		filepath.Join(
-			path,
-			"config",
-		),
+	sysconfigData := MakeTemplateData(
+		SysConfig{SiteDNS: config.SiteDNS},
+		config.Runtime,
+	)
+	err = tree.Template(
+		"config",
 		template.Must(template.New("netcofig").Parse(string(sysconfigNetworkConfigTemplate))),
 		sysconfigData,
 	)
 	if err != nil {
-		return fmt.Errorf(
-			"failed to write netcofig because %v",
+		return nil, fmt.Errorf(
+			"failed to render netcofig because %v",
 			err,
 		)
 	}
 
-	for _, network := range ncn.Networks {
-		if cli.StringInSlice(
-			network.NetworkName,
-			networking.ValidNetNames,
-		) {
-			if network.ParentInterfaceName == "" {
-				continue
-			}
-			ifcfgFilename := fmt.Sprintf(
-				"ifcfg-%s",
-				strings.ToLower(network.InterfaceName),
+	for _, network := range config.VLANs {
+		ifcfgFilename := fmt.Sprintf(
+			"ifcfg-%s",
+			strings.ToLower(network.InterfaceName),
+		)
+		err = tree.Template(
+			ifcfgFilename,
+			template.Must(template.New("vlan").Parse(string(VlanConfigTemplate))),
+			MakeTemplateData(
+				network,
+				config.Runtime,
+			),
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to render %s because %v",
+				ifcfgFilename,
+				err,
 			)
-			ifrouteFilename := fmt.Sprintf(
-				"ifroute-%s",
-				strings.ToLower(network.InterfaceName),
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.Routes)) {
+		ifrouteFilename := fmt.Sprintf(
+			"ifroute-%s",
+			name,
+		)
+		err = tree.Template(
+			ifrouteFilename,
+			template.Must(template.New("vlan").Parse(string(VlanRouteTemplate))),
+			MakeTemplateData(
+				config.Routes[name],
+				config.Runtime,
+			),
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to render %s because %v",
+				ifrouteFilename,
+				err,
 			)
-			data := MakeTemplateData(network)
-			if network.Vlan != networking.DefaultMTLVlan && network.NetworkName != "CHN" {
-				err = files.WriteTemplate(
-					filepath.Join(
-						path,
-						ifcfgFilename,
-					),
-					template.Must(template.New("vlan").Parse(string(VlanConfigTemplate))),
-					data,
-				)
-				if err != nil {
-					return fmt.Errorf(
-						"failed to write %s because %v",
-						ifcfgFilename,
-						err,
-					)
-				}
-			}
-			if network.NetworkName == "NMN" {
-
-				t, routes, err := genMetalLBTemplates(
-					shastaNetworks,
-				)
-				if err != nil {
-					return fmt.Errorf(
-						"failed to generate metallb routes because %v",
-						err,
-					)
-				}
-				data := MakeTemplateData(routes)
-				err = files.WriteTemplate(
-					filepath.Join(
-						path,
-						ifrouteFilename,
-					),
-					t,
-					data,
-				)
-				if err != nil {
-					return fmt.Errorf(
-						"failed to write %s because %v",
-						ifrouteFilename,
-						err,
-					)
-				}
-			}
 		}
 	}
-	return err
+	return tree, nil
 }
 
 func genMetalLBTemplates(networks map[string]*networking.IPNetwork) (t *template.Template, routes Routes, err error) {
@@ -296,7 +442,9 @@ NAME='{{.Data.FullName}}'
 BOOTPROTO='static'
 IPADDR='{{.Data.CIDR4}}'
 PREFIXLEN='{{.Data.CIDR4.Bits}}'
-
+{{ if .Data.CIDR6.IsValid -}}
+IPADDR_6='{{.Data.CIDR6}}'
+{{ end }}
 # CHANGE AT OWN RISK:
 ETHERDEVICE='{{.Data.ParentInterfaceName}}'
 
@@ -335,7 +483,9 @@ NAME='Internal Interface'
 BOOTPROTO='static'
 IPADDR='{{.Data.CIDR}}'
 PREFIXLEN='{{.Data.PrefixLen}}'
-
+{{ if .Data.CIDR6 -}}
+IPADDR_6='{{.Data.CIDR6}}'
+{{ end }}
 # CHANGE AT OWN RISK:
 BONDING_MODULE_OPTS='mode=802.3ad miimon=100 lacp_rate=fast xmit_hash_policy=layer2+3'
 
@@ -0,0 +1,270 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+)
+
+type PITNetworkTestSuite struct {
+	suite.Suite
+	v *viper.Viper
+}
+
+func (suite *PITNetworkTestSuite) SetupTest() {
+	viper.Reset()
+	suite.v = viper.GetViper()
+	suite.Require().NoError(suite.v.BindPFlags(NewCommand().Flags()))
+	suite.v.SetConfigFile(
+		filepath.Join(
+			generateFixtureDir,
+			"system_config.yaml",
+		),
+	)
+	suite.Require().NoError(suite.v.ReadInConfig())
+}
+
+func (suite *PITNetworkTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// generate returns the pit-files of the fixtures, written by a renderer.
+func (suite *PITNetworkTestSuite) generate(renderer string) map[string]string {
+	suite.v.Set(
+		"pit-network-renderer",
+		renderer,
+	)
+	inputs, err := CollectInputs(suite.v)
+	suite.Require().NoError(err)
+	outputs, err := Generate(
+		context.Background(),
+		inputs,
+	)
+	suite.Require().NoError(err)
+	pitFiles := make(map[string]string)
+	for name, contents := range outputs.Files {
+		if filepath.Dir(name) == "pit-files" {
+			pitFiles[filepath.Base(name)] = string(contents)
+		}
+	}
+	return pitFiles
+}
+
+func (suite *PITNetworkTestSuite) TestRender_Ifcfg() {
+	pitFiles := suite.generate(PITNetworkRendererIfcfg)
+	for _, name := range []string{
+		"ifcfg-bond0",
+		"ifcfg-bond0.nmn0",
+		"ifcfg-lan0",
+		"ifroute-bond0.nmn0",
+		"ifroute-lan0",
+		"config",
+	} {
+		suite.Contains(
+			pitFiles,
+			name,
+		)
+	}
+	suite.Contains(
+		pitFiles["ifroute-bond0.nmn0"],
+		"10.92.100.0 10.252.0.1 255.255.255.0 -\n",
+	)
+	suite.NotContains(
+		pitFiles,
+		NMStateFile,
+	)
+}
+
+func (suite *PITNetworkTestSuite) TestRender_NMState() {
+	pitFiles := suite.generate(PITNetworkRendererNMState)
+	suite.Len(
+		pitFiles,
+		1,
+	)
+	var state NMState
+	suite.Require().NoError(
+		yaml.Unmarshal(
+			[]byte(pitFiles[NMStateFile]),
+			&state,
+		),
+	)
+
+	interfaces := make(map[string]NMStateInterface)
+	for _, iface := range state.Interfaces {
+		interfaces[iface.Name] = iface
+	}
+	suite.Equal(
+		[]string{
+			"p1p1",
+			"p1p2",
+		},
+		interfaces["bond0"].LinkAggregation.Port,
+	)
+	suite.Equal(
+		[]NMStateIPAddress{
+			{
+				IP:           "10.1.1.0",
+				PrefixLength: 16,
+			},
+		},
+		interfaces["bond0"].IPv4.Address,
+	)
+	suite.Equal(
+		&NMStateVLAN{
+			BaseIface: "bond0",
+			ID:        2,
+		},
+		interfaces["bond0.nmn0"].VLAN,
+	)
+	suite.Equal(
+		[]NMStateBridgePort{
+			{
+				Name: "em1",
+			},
+		},
+		interfaces["lan0"].Bridge.Port,
+	)
+	suite.Equal(
+		[]NMStateRoute{
+			{
+				Destination:      "10.92.100.0/24",
+				NextHopAddress:   "10.252.0.1",
+				NextHopInterface: "bond0.nmn0",
+			},
+			{
+				Destination:      "0.0.0.0/0",
+				NextHopAddress:   "172.30.48.1",
+				NextHopInterface: "lan0",
+			},
+		},
+		state.Routes.Config,
+	)
+	suite.Equal(
+		[]string{
+			"172.30.84.40",
+		},
+		state.DNSResolver.Config.Server,
+	)
+}
+
+func (suite *PITNetworkTestSuite) TestRender_Networkd() {
+	pitFiles := suite.generate(PITNetworkRendererNetworkd)
+	suite.Contains(
+		pitFiles["10-bond0.netdev"],
+		"[NetDev]\nName=bond0\nDescription=Internal Interface\nKind=bond\n\n[Bond]\nMode=802.3ad\n",
+	)
+	suite.Contains(
+		pitFiles["10-bond0-members.network"],
+		"[Match]\nName=p1p1 p1p2\n\n[Network]\nBond=bond0\n",
+	)
+	suite.Contains(
+		pitFiles["10-bond0.network"],
+		"[Network]\nAddress=10.1.1.0/16\nVLAN=bond0.can0\nVLAN=bond0.cmn0\nVLAN=bond0.hmn0\nVLAN=bond0.nmn0\n",
+	)
+	suite.Contains(
+		pitFiles["20-bond0.nmn0.netdev"],
+		"[NetDev]\nName=bond0.nmn0\nDescription=NMN Bootstrap DHCP Subnet\nKind=vlan\n\n[VLAN]\nId=2\n",
+	)
+	suite.Contains(
+		pitFiles["20-bond0.nmn0.network"],
+		"[Network]\nAddress=10.252.1.0/17\n\n[Route]\nDestination=10.92.100.0/24\nGateway=10.252.0.1\n",
+	)
+	suite.Contains(
+		pitFiles["30-lan0-members.network"],
+		"[Match]\nName=em1\n\n[Network]\nBridge=lan0\n",
+	)
+	suite.Contains(
+		pitFiles["30-lan0.network"],
+		"[Network]\nAddress=172.30.53.79/20\nDNS=172.30.84.40\nDomains=nmn mtl hmn\n\n[Route]\nDestination=0.0.0.0/0\nGateway=172.30.48.1\n",
+	)
+	suite.NotContains(
+		pitFiles,
+		"ifcfg-bond0",
+	)
+}
+
+func (suite *PITNetworkTestSuite) TestNewPITNetworkRenderer() {
+	for _, name := range PITNetworkRenderers {
+		renderer, err := NewPITNetworkRenderer(name)
+		suite.NoError(err)
+		suite.NotNil(renderer)
+	}
+	_, err := NewPITNetworkRenderer("netplan")
+	suite.ErrorContains(
+		err,
+		`unsupported PIT network renderer "netplan", must be one of ifcfg,nmstate,networkd`,
+	)
+}
+
+func (suite *PITNetworkTestSuite) TestRoutePrefix() {
+	for _, test := range []struct {
+		route  Route
+		prefix string
+	}{
+		{
+			route: Route{
+				Destination: "default",
+				Mask:        "-",
+			},
+			prefix: "0.0.0.0/0",
+		},
+		{
+			route: Route{
+				Destination: "10.92.100.0",
+				Mask:        "255.255.255.0",
+			},
+			prefix: "10.92.100.0/24",
+		},
+	} {
+		prefix, err := test.route.Prefix()
+		suite.NoError(err)
+		suite.Equal(
+			test.prefix,
+			prefix,
+		)
+	}
+
+	_, err := Route{
+		Destination: "10.92.100.0",
+		Mask:        "24",
+	}.Prefix()
+	suite.ErrorContains(
+		err,
+		"invalid route mask [24]",
+	)
+}
+
+func TestPITNetworkTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(PITNetworkTestSuite),
+	)
+}
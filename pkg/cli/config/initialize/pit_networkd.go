@@ -0,0 +1,313 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+)
+
+// NetworkdUnitTemplate is a systemd-networkd .netdev or .network file.
+var NetworkdUnitTemplate = []byte(`
+{{- /* remove leading whitespace */ -}}
+#
+## This file was generated by cray-site-init.
+## Version: {{ .Version }}
+## Generated time: {{ .Timestamp }}
+#
+{{- range .Data }}
+
+[{{ .Name }}]
+{{- range .Entries }}
+{{ .Key }}={{ .Value }}
+{{- end }}
+{{- end }}
+`)
+
+// NetworkdUnit is a systemd-networkd .netdev or .network file, a list of sections.
+type NetworkdUnit []NetworkdSection
+
+// NetworkdSection is a section of a NetworkdUnit, its keys may repeat.
+type NetworkdSection struct {
+	Name    string
+	Entries []NetworkdEntry
+}
+
+// NetworkdEntry is a key of a NetworkdSection.
+type NetworkdEntry struct {
+	Key   string
+	Value string
+}
+
+// NetworkdRenderer renders the systemd-networkd .netdev and .network files of the PIT.
+type NetworkdRenderer struct{}
+
+// Render renders the systemd-networkd files of the PIT.
+func (NetworkdRenderer) Render(config PITNetworkConfig) (files.Tree, error) {
+	units, err := NewNetworkdUnits(config)
+	if err != nil {
+		return nil, err
+	}
+	tree := make(files.Tree)
+	tpl := template.Must(template.New("networkd").Parse(string(NetworkdUnitTemplate)))
+	for _, name := range slices.Sorted(maps.Keys(units)) {
+		err = tree.Template(
+			name,
+			tpl,
+			MakeTemplateData(
+				units[name],
+				config.Runtime,
+			),
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to render %s because %v",
+				name,
+				err,
+			)
+		}
+	}
+	return tree, nil
+}
+
+// NewNetworkdUnits returns the systemd-networkd files of a PITNetworkConfig, keyed by file name. The bond is numbered
+// 10, its VLANs 20, and the site link 30, so that networkd reads them in that order.
+func NewNetworkdUnits(config PITNetworkConfig) (units map[string]NetworkdUnit, err error) {
+	units = make(map[string]NetworkdUnit)
+	if config.Bond != nil {
+		units["10-bond0.netdev"] = NetworkdUnit{
+			{
+				Name: "NetDev",
+				Entries: []NetworkdEntry{
+					{"Name", "bond0"},
+					{"Description", "Internal Interface"},
+					{"Kind", "bond"},
+				},
+			},
+			{
+				Name: "Bond",
+				Entries: []NetworkdEntry{
+					{"Mode", "802.3ad"},
+					{"MIIMonitorSec", "100ms"},
+					{"LACPTransmitRate", "fast"},
+					{"TransmitHashPolicy", "layer2+3"},
+				},
+			},
+		}
+		units["10-bond0-members.network"] = NetworkdUnit{
+			{
+				Name: "Match",
+				Entries: []NetworkdEntry{
+					{
+						"Name",
+						strings.Join(
+							config.Bond.Members,
+							" ",
+						),
+					},
+				},
+			},
+			{
+				Name: "Network",
+				Entries: []NetworkdEntry{
+					{"Bond", "bond0"},
+				},
+			},
+		}
+		network := NetworkdSection{
+			Name: "Network",
+			Entries: []NetworkdEntry{
+				{"Address", config.Bond.CIDR},
+			},
+		}
+		if config.Bond.CIDR6 != "" {
+			network.Entries = append(
+				network.Entries,
+				NetworkdEntry{"Address", config.Bond.CIDR6},
+			)
+		}
+		for _, vlan := range config.VLANs {
+			if vlan.ParentInterfaceName == "bond0" {
+				network.Entries = append(
+					network.Entries,
+					NetworkdEntry{"VLAN", strings.ToLower(vlan.InterfaceName)},
+				)
+			}
+		}
+		units["10-bond0.network"] = NetworkdUnit{
+			{
+				Name: "Match",
+				Entries: []NetworkdEntry{
+					{"Name", "bond0"},
+				},
+			},
+			network,
+		}
+	}
+
+	for _, vlan := range config.VLANs {
+		name := strings.ToLower(vlan.InterfaceName)
+		units[fmt.Sprintf("20-%s.netdev", name)] = NetworkdUnit{
+			{
+				Name: "NetDev",
+				Entries: []NetworkdEntry{
+					{"Name", name},
+					{"Description", vlan.FullName},
+					{"Kind", "vlan"},
+				},
+			},
+			{
+				Name: "VLAN",
+				Entries: []NetworkdEntry{
+					{"Id", strconv.Itoa(vlan.Vlan)},
+				},
+			},
+		}
+		network := NetworkdSection{
+			Name: "Network",
+			Entries: []NetworkdEntry{
+				{"Address", vlan.CIDR4.String()},
+			},
+		}
+		if vlan.CIDR6.IsValid() {
+			network.Entries = append(
+				network.Entries,
+				NetworkdEntry{"Address", vlan.CIDR6.String()},
+			)
+		}
+		unit := NetworkdUnit{
+			{
+				Name: "Match",
+				Entries: []NetworkdEntry{
+					{"Name", name},
+				},
+			},
+			network,
+		}
+		unit, err = unit.withRoutes(config.Routes[name])
+		if err != nil {
+			return nil, err
+		}
+		units[fmt.Sprintf("20-%s.network", name)] = unit
+	}
+
+	units["30-lan0.netdev"] = NetworkdUnit{
+		{
+			Name: "NetDev",
+			Entries: []NetworkdEntry{
+				{"Name", "lan0"},
+				{"Description", "External Site-Link"},
+				{"Kind", "bridge"},
+			},
+		},
+		{
+			Name: "Bridge",
+			Entries: []NetworkdEntry{
+				{"STP", "no"},
+			},
+		},
+	}
+	units["30-lan0-members.network"] = NetworkdUnit{
+		{
+			Name: "Match",
+			Entries: []NetworkdEntry{
+				{"Name", config.Site.NIC},
+			},
+		},
+		{
+			Name: "Network",
+			Entries: []NetworkdEntry{
+				{"Bridge", "lan0"},
+			},
+		},
+	}
+	network := NetworkdSection{
+		Name: "Network",
+		Entries: []NetworkdEntry{
+			{
+				"Address",
+				fmt.Sprintf(
+					"%s/%d",
+					config.Site.IP,
+					config.Site.PrefixLen,
+				),
+			},
+		},
+	}
+	for _, server := range siteDNSServers(config.SiteDNS) {
+		network.Entries = append(
+			network.Entries,
+			NetworkdEntry{"DNS", server},
+		)
+	}
+	if config.SiteDNS != "" {
+		network.Entries = append(
+			network.Entries,
+			NetworkdEntry{"Domains", "nmn mtl hmn"},
+		)
+	}
+	unit := NetworkdUnit{
+		{
+			Name: "Match",
+			Entries: []NetworkdEntry{
+				{"Name", "lan0"},
+			},
+		},
+		network,
+	}
+	unit, err = unit.withRoutes(config.SiteRoutes)
+	if err != nil {
+		return nil, err
+	}
+	units["30-lan0.network"] = unit
+	return units, nil
+}
+
+// withRoutes returns the unit with a Route section for each route.
+func (unit NetworkdUnit) withRoutes(routes Routes) (NetworkdUnit, error) {
+	for _, route := range routes {
+		destination, err := route.Prefix()
+		if err != nil {
+			return unit, err
+		}
+		unit = append(
+			unit,
+			NetworkdSection{
+				Name: "Route",
+				Entries: []NetworkdEntry{
+					{"Destination", destination},
+					{"Gateway", route.NextHop},
+				},
+			},
+		)
+	}
+	return unit, nil
+}
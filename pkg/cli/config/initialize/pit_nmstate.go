@@ -0,0 +1,338 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"strings"
+	"text/template"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+)
+
+// NMStateTemplate is an nmstate YAML state of the PIT.
+var NMStateTemplate = []byte(`
+{{- /* remove leading whitespace */ -}}
+#
+## This file was generated by cray-site-init.
+## Version: {{ .Version }}
+## Generated time: {{ .Timestamp }}
+#
+{{ .Data }}`)
+
+// NMStateFile is the nmstate state of the PIT, nmstate.service applies the states in /etc/nmstate.
+const NMStateFile = "pit.yml"
+
+// NMState is the desired network state of a host, as read by nmstatectl apply.
+type NMState struct {
+	Interfaces  []NMStateInterface  `yaml:"interfaces"`
+	Routes      NMStateRoutes       `yaml:"routes,omitempty"`
+	DNSResolver *NMStateDNSResolver `yaml:"dns-resolver,omitempty"`
+}
+
+// NMStateInterface is an interface of an NMState.
+type NMStateInterface struct {
+	Name            string                  `yaml:"name"`
+	Description     string                  `yaml:"description,omitempty"`
+	Type            string                  `yaml:"type"`
+	State           string                  `yaml:"state"`
+	IPv4            NMStateIP               `yaml:"ipv4"`
+	IPv6            NMStateIP               `yaml:"ipv6"`
+	LinkAggregation *NMStateLinkAggregation `yaml:"link-aggregation,omitempty"`
+	VLAN            *NMStateVLAN            `yaml:"vlan,omitempty"`
+	Bridge          *NMStateBridge          `yaml:"bridge,omitempty"`
+}
+
+// NMStateIP is the static IPv4 or IPv6 configuration of an NMStateInterface.
+type NMStateIP struct {
+	Enabled bool               `yaml:"enabled"`
+	DHCP    *bool              `yaml:"dhcp,omitempty"`
+	Address []NMStateIPAddress `yaml:"address,omitempty"`
+}
+
+// NMStateIPAddress is an address of an NMStateIP.
+type NMStateIPAddress struct {
+	IP           string `yaml:"ip"`
+	PrefixLength int    `yaml:"prefix-length"`
+}
+
+// NMStateLinkAggregation is the bond of an NMStateInterface.
+type NMStateLinkAggregation struct {
+	Mode    string         `yaml:"mode"`
+	Options map[string]any `yaml:"options,omitempty"`
+	Port    []string       `yaml:"port"`
+}
+
+// NMStateVLAN is the VLAN of an NMStateInterface.
+type NMStateVLAN struct {
+	BaseIface string `yaml:"base-iface"`
+	ID        int    `yaml:"id"`
+}
+
+// NMStateBridge is the Linux bridge of an NMStateInterface.
+type NMStateBridge struct {
+	Options NMStateBridgeOptions `yaml:"options"`
+	Port    []NMStateBridgePort  `yaml:"port"`
+}
+
+// NMStateBridgeOptions are the options of an NMStateBridge.
+type NMStateBridgeOptions struct {
+	STP NMStateSTP `yaml:"stp"`
+}
+
+// NMStateSTP is the spanning tree configuration of an NMStateBridge.
+type NMStateSTP struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// NMStateBridgePort is a port of an NMStateBridge.
+type NMStateBridgePort struct {
+	Name string `yaml:"name"`
+}
+
+// NMStateRoutes are the static routes of an NMState.
+type NMStateRoutes struct {
+	Config []NMStateRoute `yaml:"config,omitempty"`
+}
+
+// NMStateRoute is a static route of an NMState.
+type NMStateRoute struct {
+	Destination      string `yaml:"destination"`
+	NextHopAddress   string `yaml:"next-hop-address"`
+	NextHopInterface string `yaml:"next-hop-interface"`
+}
+
+// NMStateDNSResolver is the DNS configuration of an NMState.
+type NMStateDNSResolver struct {
+	Config NMStateDNSConfig `yaml:"config"`
+}
+
+// NMStateDNSConfig is the static DNS configuration of an NMStateDNSResolver.
+type NMStateDNSConfig struct {
+	Search []string `yaml:"search,omitempty"`
+	Server []string `yaml:"server,omitempty"`
+}
+
+// NMStateRenderer renders the nmstate YAML state of the PIT.
+type NMStateRenderer struct{}
+
+// Render renders the nmstate state of the PIT.
+func (NMStateRenderer) Render(config PITNetworkConfig) (files.Tree, error) {
+	state, err := NewNMState(config)
+	if err != nil {
+		return nil, err
+	}
+	var yaml bytes.Buffer
+	err = files.EncodeYAML(
+		&yaml,
+		state,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to encode the nmstate state because %v",
+			err,
+		)
+	}
+	tree := make(files.Tree)
+	err = tree.Template(
+		NMStateFile,
+		template.Must(template.New("nmstate").Parse(string(NMStateTemplate))),
+		MakeTemplateData(
+			yaml.String(),
+			config.Runtime,
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to render %s because %v",
+			NMStateFile,
+			err,
+		)
+	}
+	return tree, nil
+}
+
+// NewNMState returns the nmstate state of a PITNetworkConfig.
+func NewNMState(config PITNetworkConfig) (state NMState, err error) {
+	if config.Bond != nil {
+		bond := NMStateInterface{
+			Name:        "bond0",
+			Description: "Internal Interface",
+			Type:        "bond",
+			State:       "up",
+			LinkAggregation: &NMStateLinkAggregation{
+				Mode: "802.3ad",
+				Options: map[string]any{
+					"miimon":           100,
+					"lacp_rate":        "fast",
+					"xmit_hash_policy": "layer2+3",
+				},
+				Port: config.Bond.Members,
+			},
+		}
+		bond.IPv4, err = newNMStateIP(config.Bond.CIDR)
+		if err != nil {
+			return state, err
+		}
+		bond.IPv6, err = newNMStateIP(config.Bond.CIDR6)
+		if err != nil {
+			return state, err
+		}
+		state.Interfaces = append(
+			state.Interfaces,
+			bond,
+		)
+	}
+
+	for _, network := range config.VLANs {
+		vlan := NMStateInterface{
+			Name:        strings.ToLower(network.InterfaceName),
+			Description: network.FullName,
+			Type:        "vlan",
+			State:       "up",
+			VLAN: &NMStateVLAN{
+				BaseIface: network.ParentInterfaceName,
+				ID:        network.Vlan,
+			},
+		}
+		vlan.IPv4, err = newNMStateIP(network.CIDR4.String())
+		if err != nil {
+			return state, err
+		}
+		if network.CIDR6.IsValid() {
+			vlan.IPv6, err = newNMStateIP(network.CIDR6.String())
+			if err != nil {
+				return state, err
+			}
+		}
+		state.Interfaces = append(
+			state.Interfaces,
+			vlan,
+		)
+		err = state.addRoutes(
+			vlan.Name,
+			config.Routes[vlan.Name],
+		)
+		if err != nil {
+			return state, err
+		}
+	}
+
+	site := NMStateInterface{
+		Name:        "lan0",
+		Description: "External Site-Link",
+		Type:        "linux-bridge",
+		State:       "up",
+		Bridge: &NMStateBridge{
+			Port: []NMStateBridgePort{
+				{
+					Name: config.Site.NIC,
+				},
+			},
+		},
+	}
+	site.IPv4, err = newNMStateIP(
+		fmt.Sprintf(
+			"%s/%d",
+			config.Site.IP,
+			config.Site.PrefixLen,
+		),
+	)
+	if err != nil {
+		return state, err
+	}
+	state.Interfaces = append(
+		state.Interfaces,
+		site,
+	)
+	err = state.addRoutes(
+		site.Name,
+		config.SiteRoutes,
+	)
+	if err != nil {
+		return state, err
+	}
+
+	if config.SiteDNS != "" {
+		state.DNSResolver = &NMStateDNSResolver{
+			Config: NMStateDNSConfig{
+				Search: []string{
+					"nmn",
+					"mtl",
+					"hmn",
+				},
+				Server: siteDNSServers(config.SiteDNS),
+			},
+		}
+	}
+	return state, nil
+}
+
+// addRoutes adds the routes of an interface to the state.
+func (state *NMState) addRoutes(name string, routes Routes) error {
+	for _, route := range routes {
+		destination, err := route.Prefix()
+		if err != nil {
+			return err
+		}
+		state.Routes.Config = append(
+			state.Routes.Config,
+			NMStateRoute{
+				Destination:      destination,
+				NextHopAddress:   route.NextHop,
+				NextHopInterface: name,
+			},
+		)
+	}
+	return nil
+}
+
+// newNMStateIP returns the static configuration of an address in CIDR notation, an empty CIDR disables the family.
+func newNMStateIP(cidr string) (ip NMStateIP, err error) {
+	if cidr == "" {
+		return ip, nil
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return ip, fmt.Errorf(
+			"invalid interface address [%s] because %v",
+			cidr,
+			err,
+		)
+	}
+	dhcp := false
+	return NMStateIP{
+		Enabled: true,
+		DHCP:    &dhcp,
+		Address: []NMStateIPAddress{
+			{
+				IP:           prefix.Addr().String(),
+				PrefixLength: prefix.Bits(),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,218 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/Cray-HPE/hms-xname/xnametypes"
+)
+
+// Owner kinds annotate which piece of hardware an IP reservation belongs to.
+const (
+	PlanOwnerNCN    = "ncn"
+	PlanOwnerNCNBMC = "ncn-bmc"
+	PlanOwnerSwitch = "switch"
+	PlanOwnerUAN    = "uan"
+)
+
+// Plan is a reviewable document of every network, subnet, VLAN, DHCP range, and IP reservation config init would write.
+type Plan struct {
+	SystemName string        `json:"system-name" yaml:"system-name"`
+	Networks   []PlanNetwork `json:"networks" yaml:"networks"`
+}
+
+// PlanNetwork is a network within a Plan.
+type PlanNetwork struct {
+	Name      string       `json:"name" yaml:"name"`
+	FullName  string       `json:"full-name" yaml:"full-name"`
+	Type      string       `json:"type" yaml:"type"`
+	CIDR4     string       `json:"cidr4,omitempty" yaml:"cidr4,omitempty"`
+	CIDR6     string       `json:"cidr6,omitempty" yaml:"cidr6,omitempty"`
+	MTU       int16        `json:"mtu,omitempty" yaml:"mtu,omitempty"`
+	VlanRange []int16      `json:"vlan-range" yaml:"vlan-range,flow"`
+	Subnets   []PlanSubnet `json:"subnets" yaml:"subnets"`
+}
+
+// PlanSubnet is a subnet of a PlanNetwork.
+type PlanSubnet struct {
+	Name             string            `json:"name" yaml:"name"`
+	FullName         string            `json:"full-name" yaml:"full-name"`
+	CIDR             string            `json:"cidr,omitempty" yaml:"cidr,omitempty"`
+	CIDR6            string            `json:"cidr6,omitempty" yaml:"cidr6,omitempty"`
+	VlanID           int16             `json:"vlan" yaml:"vlan"`
+	Gateway          net.IP            `json:"gateway,omitempty" yaml:"gateway,omitempty"`
+	Gateway6         net.IP            `json:"gateway6,omitempty" yaml:"gateway6,omitempty"`
+	DHCPStart        net.IP            `json:"dhcp-start,omitempty" yaml:"dhcp-start,omitempty"`
+	DHCPEnd          net.IP            `json:"dhcp-end,omitempty" yaml:"dhcp-end,omitempty"`
+	ReservationStart net.IP            `json:"reservation-start,omitempty" yaml:"reservation-start,omitempty"`
+	ReservationEnd   net.IP            `json:"reservation-end,omitempty" yaml:"reservation-end,omitempty"`
+	MetalLBPoolName  string            `json:"metallb-pool,omitempty" yaml:"metallb-pool,omitempty"`
+	Reservations     []PlanReservation `json:"reservations,omitempty" yaml:"reservations,omitempty"`
+}
+
+// PlanReservation is an IP reservation within a PlanSubnet.
+type PlanReservation struct {
+	Name       string     `json:"name" yaml:"name"`
+	IPAddress  net.IP     `json:"ip,omitempty" yaml:"ip,omitempty"`
+	IPAddress6 net.IP     `json:"ip6,omitempty" yaml:"ip6,omitempty"`
+	Aliases    []string   `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Comment    string     `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Owner      *PlanOwner `json:"owner,omitempty" yaml:"owner,omitempty"`
+}
+
+// PlanOwner identifies the NCN, UAN, or switch a PlanReservation was made for.
+type PlanOwner struct {
+	Kind     string `json:"kind" yaml:"kind"`
+	Xname    string `json:"xname" yaml:"xname"`
+	Hostname string `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	Role     string `json:"role,omitempty" yaml:"role,omitempty"`
+}
+
+// NewPlan builds a Plan from the outputs of Generate.
+func NewPlan(outputs *Outputs) Plan {
+	owners := make(map[string]*PlanOwner)
+	for _, ncn := range outputs.LogicalNCNs {
+		owners[ncn.Xname] = &PlanOwner{
+			Kind:     PlanOwnerNCN,
+			Xname:    ncn.Xname,
+			Hostname: ncn.Hostname,
+			Role:     ncn.Subrole,
+		}
+		bmcXname := xnametypes.GetHMSCompParent(ncn.Xname)
+		owners[bmcXname] = &PlanOwner{
+			Kind:  PlanOwnerNCNBMC,
+			Xname: bmcXname,
+			Hostname: fmt.Sprintf(
+				"%s-mgmt",
+				ncn.Hostname,
+			),
+			Role: ncn.Subrole,
+		}
+	}
+	for _, uan := range outputs.UANs {
+		owners[uan.Xname] = &PlanOwner{
+			Kind:     PlanOwnerUAN,
+			Xname:    uan.Xname,
+			Hostname: uan.Hostname,
+			Role:     uan.Subrole,
+		}
+	}
+	for _, mySwitch := range outputs.Switches {
+		owners[mySwitch.Xname] = &PlanOwner{
+			Kind:     PlanOwnerSwitch,
+			Xname:    mySwitch.Xname,
+			Hostname: mySwitch.Name,
+			Role:     mySwitch.SwitchType.String(),
+		}
+	}
+
+	names := make(
+		[]string,
+		0,
+		len(outputs.Networks),
+	)
+	for name := range outputs.Networks {
+		names = append(
+			names,
+			name,
+		)
+	}
+	sort.Strings(names)
+
+	plan := Plan{
+		SystemName: outputs.SystemName,
+		Networks: make(
+			[]PlanNetwork,
+			0,
+			len(names),
+		),
+	}
+	for _, name := range names {
+		network := outputs.Networks[name]
+		planNetwork := PlanNetwork{
+			Name:      network.Name,
+			FullName:  network.FullName,
+			Type:      string(network.NetType),
+			CIDR4:     network.CIDR4,
+			CIDR6:     network.CIDR6,
+			MTU:       network.MTU,
+			VlanRange: network.VlanRange,
+			Subnets: make(
+				[]PlanSubnet,
+				0,
+				len(network.Subnets),
+			),
+		}
+		for _, subnet := range network.Subnets {
+			planSubnet := PlanSubnet{
+				Name:             subnet.Name,
+				FullName:         subnet.FullName,
+				CIDR:             subnet.CIDR,
+				CIDR6:            subnet.CIDR6,
+				VlanID:           subnet.VlanID,
+				Gateway:          subnet.Gateway,
+				Gateway6:         subnet.Gateway6,
+				DHCPStart:        subnet.DHCPStart,
+				DHCPEnd:          subnet.DHCPEnd,
+				ReservationStart: subnet.ReservationStart,
+				ReservationEnd:   subnet.ReservationEnd,
+				MetalLBPoolName:  subnet.MetalLBPoolName,
+			}
+			for _, reservation := range subnet.IPReservations {
+				planSubnet.Reservations = append(
+					planSubnet.Reservations,
+					PlanReservation{
+						Name:       reservation.Name,
+						IPAddress:  reservation.IPAddress,
+						IPAddress6: reservation.IPAddress6,
+						Aliases:    reservation.Aliases,
+						Comment:    reservation.Comment,
+						Owner:      owners[reservation.Comment],
+					},
+				)
+			}
+			planNetwork.Subnets = append(
+				planNetwork.Subnets,
+				planSubnet,
+			)
+		}
+		plan.Networks = append(
+			plan.Networks,
+			planNetwork,
+		)
+	}
+	return plan
+}
+
+// Marshal encodes the plan in the given format, either json or yaml.
+func (plan Plan) Marshal(format string) ([]byte, error) {
+	return marshalDocument(
+		plan,
+		format,
+	)
+}
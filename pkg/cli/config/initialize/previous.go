@@ -0,0 +1,130 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+	"github.com/Cray-HPE/cray-site-init/pkg/networking"
+)
+
+// previousSLSFilename is the SLS file looked for when --previous-sls is given a system directory.
+const previousSLSFilename = "sls_input_file.json"
+
+// ReservationConflictError is returned by Generate when subnets or IP reservations of the previous SLS state can not be
+// kept.
+type ReservationConflictError struct {
+	Conflicts []networking.SeedConflict
+}
+
+func (e *ReservationConflictError) Error() string {
+	conflicts := make(
+		[]string,
+		0,
+		len(e.Conflicts),
+	)
+	for _, conflict := range e.Conflicts {
+		conflicts = append(
+			conflicts,
+			conflict.String(),
+		)
+	}
+	return fmt.Sprintf(
+		"%d subnet(s) or IP reservation(s) of the previous SLS state can not be kept:\n%s",
+		len(e.Conflicts),
+		strings.Join(
+			conflicts,
+			"\n",
+		),
+	)
+}
+
+// LoadPreviousSLS reads the SLS state of a previous run from an sls_input_file.json, or from the system directory
+// containing one.
+func LoadPreviousSLS(path string) (*slsCommon.SLSState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to read the previous SLS state because %v",
+			err,
+		)
+	}
+	if info.IsDir() {
+		path = filepath.Join(
+			path,
+			previousSLSFilename,
+		)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to read the previous SLS state because %v",
+			err,
+		)
+	}
+	var state slsCommon.SLSState
+	err = json.Unmarshal(
+		b,
+		&state,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to parse the previous SLS state in %s because %v",
+			path,
+			err,
+		)
+	}
+	return &state, nil
+}
+
+// seedNetworks seeds each network with the subnets and IP reservations of the same network in the previous SLS state.
+func seedNetworks(
+	shastaNetworks map[string]*networking.IPNetwork, previous *slsCommon.SLSState, addresses *networking.AddressAllocator,
+) error {
+	for _, name := range slices.Sorted(maps.Keys(shastaNetworks)) {
+		previousNetwork, ok := previous.Networks[name]
+		if !ok {
+			continue
+		}
+		extraProperties, err := sls.UnmarshalNetworkExtraProperties(&previousNetwork)
+		if err != nil {
+			return err
+		}
+		shastaNetworks[name].Seed(
+			extraProperties,
+			addresses,
+		)
+	}
+	return nil
+}
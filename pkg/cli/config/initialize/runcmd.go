@@ -0,0 +1,178 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// RunCMDOverlayFile is the layout of the file given to config init's --runcmd-yaml, the changes to the default
+// cloud-init runcmd lists keyed by NCN role (e.g. Management) or subrole (e.g. Storage).
+type RunCMDOverlayFile map[string]RunCMDOverlay
+
+// RunCMDOverlay describes the changes to make to a runcmd list, they are applied in the order of its fields.
+type RunCMDOverlay struct {
+	// Remove drops these entries.
+	Remove []string `yaml:"remove"`
+	// Replace swaps each entry for its value.
+	Replace map[string]string `yaml:"replace"`
+	// Prepend adds these entries before the first entry.
+	Prepend []string `yaml:"prepend"`
+	// Append adds these entries after the last entry.
+	Append []string `yaml:"append"`
+}
+
+// defaultRunCMDs are the runcmd lists every NCN subrole can be given.
+var defaultRunCMDs = map[string][][]string{
+	"Master": {
+		k8sRunCMD,
+	},
+	"Worker": {
+		k8sRunCMD,
+	},
+	"Storage": {
+		cephRunCMD,
+		cephWorkerRunCMD,
+	},
+	"FabricManager": {
+		fmnRunCMD,
+	},
+}
+
+// managementRole is the role of every NCN, its overlay is applied before the overlay of the NCN's subrole.
+const managementRole = "Management"
+
+// key returns the role or subrole the overlay file uses for name, regardless of case.
+func (overlays RunCMDOverlayFile) key(name string) (string, bool) {
+	for key := range overlays {
+		if strings.EqualFold(
+			key,
+			name,
+		) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Validate checks that every key is a known role or subrole, and that every entry to remove or replace is in one of
+// the runcmd lists the key applies to.
+func (overlays RunCMDOverlayFile) Validate() error {
+	for _, key := range slices.Sorted(maps.Keys(overlays)) {
+		var runCMDs [][]string
+		if strings.EqualFold(
+			key,
+			managementRole,
+		) {
+			for _, subrole := range slices.Sorted(maps.Keys(defaultRunCMDs)) {
+				runCMDs = append(
+					runCMDs,
+					defaultRunCMDs[subrole]...,
+				)
+			}
+		} else {
+			for subrole, subroleRunCMDs := range defaultRunCMDs {
+				if strings.EqualFold(
+					key,
+					subrole,
+				) {
+					runCMDs = subroleRunCMDs
+				}
+			}
+		}
+		if runCMDs == nil {
+			return fmt.Errorf(
+				"runcmd overlay %s is not %s or one of the NCN subroles %v",
+				key,
+				managementRole,
+				slices.Sorted(maps.Keys(defaultRunCMDs)),
+			)
+		}
+		overlay := overlays[key]
+		for _, entry := range slices.Concat(
+			overlay.Remove,
+			slices.Sorted(maps.Keys(overlay.Replace)),
+		) {
+			if !slices.ContainsFunc(
+				runCMDs,
+				func(runCMD []string) bool {
+					return slices.Contains(
+						runCMD,
+						entry,
+					)
+				},
+			) {
+				return fmt.Errorf(
+					"runcmd overlay %s changes %q which is not in the runcmd of %s",
+					key,
+					entry,
+					key,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply returns runCMD with the overlays of role and subrole applied to it, runCMD itself is left unchanged.
+func (overlays RunCMDOverlayFile) Apply(role string, subrole string, runCMD []string) []string {
+	runCMD = slices.Clone(runCMD)
+	for _, name := range []string{
+		role,
+		subrole,
+	} {
+		key, ok := overlays.key(name)
+		if !ok {
+			continue
+		}
+		runCMD = overlays[key].apply(runCMD)
+	}
+	return runCMD
+}
+
+func (overlay RunCMDOverlay) apply(runCMD []string) []string {
+	runCMD = slices.DeleteFunc(
+		runCMD,
+		func(entry string) bool {
+			return slices.Contains(
+				overlay.Remove,
+				entry,
+			)
+		},
+	)
+	for i, entry := range runCMD {
+		if replacement, ok := overlay.Replace[entry]; ok {
+			runCMD[i] = replacement
+		}
+	}
+	return slices.Concat(
+		overlay.Prepend,
+		runCMD,
+		overlay.Append,
+	)
+}
@@ -0,0 +1,125 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RunCMDTestSuite struct {
+	suite.Suite
+}
+
+func (suite *RunCMDTestSuite) TestApply() {
+	overlays := RunCMDOverlayFile{
+		"management": {
+			Prepend: []string{"/srv/site/scripts/harden.sh"},
+		},
+		"Storage": {
+			Remove: []string{"/srv/cray/scripts/common/pre-load-images.sh"},
+			Replace: map[string]string{
+				"/srv/cray/scripts/metal/install.sh": "/srv/site/scripts/install.sh",
+			},
+			Append: []string{"/srv/site/scripts/storage-done.sh"},
+		},
+	}
+	suite.Require().NoError(overlays.Validate())
+
+	suite.Equal(
+		[]string{
+			"/srv/site/scripts/harden.sh",
+			"/srv/cray/scripts/metal/net-init.sh",
+			"/srv/cray/scripts/common/update_ca_certs.py",
+			"/srv/site/scripts/install.sh",
+			"touch /etc/cloud/cloud-init.disabled",
+			"/srv/site/scripts/storage-done.sh",
+		},
+		overlays.Apply(
+			"Management",
+			"Storage",
+			cephWorkerRunCMD,
+		),
+	)
+	suite.Equal(
+		slices.Concat(
+			[]string{"/srv/site/scripts/harden.sh"},
+			k8sRunCMD,
+		),
+		overlays.Apply(
+			"Management",
+			"Master",
+			k8sRunCMD,
+		),
+	)
+	// The defaults are shared by every NCN, they must not be changed.
+	suite.Equal(
+		"/srv/cray/scripts/metal/install.sh",
+		cephWorkerRunCMD[2],
+	)
+	suite.Equal(
+		fmnRunCMD,
+		RunCMDOverlayFile(nil).Apply(
+			"Management",
+			"FabricManager",
+			fmnRunCMD,
+		),
+	)
+}
+
+func (suite *RunCMDTestSuite) TestValidate() {
+	suite.ErrorContains(
+		RunCMDOverlayFile{
+			"Compute": {
+				Append: []string{"/srv/site/scripts/harden.sh"},
+			},
+		}.Validate(),
+		"runcmd overlay Compute is not Management or one of the NCN subroles",
+	)
+	suite.ErrorContains(
+		RunCMDOverlayFile{
+			"Master": {
+				Remove: []string{"/srv/cray/scripts/common/storage-ceph-cloudinit.sh"},
+			},
+		}.Validate(),
+		`runcmd overlay Master changes "/srv/cray/scripts/common/storage-ceph-cloudinit.sh"`,
+	)
+	suite.NoError(
+		RunCMDOverlayFile{
+			"Management": {
+				Remove: []string{"/srv/cray/scripts/common/storage-ceph-cloudinit.sh"},
+			},
+		}.Validate(),
+	)
+}
+
+func TestRunCMDTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(RunCMDTestSuite),
+	)
+}
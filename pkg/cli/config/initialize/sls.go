@@ -39,7 +39,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"net/netip"
 	"regexp"
+	"slices"
 	"strings"
 
 	slsInit "github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize/sls"
@@ -57,7 +60,9 @@ func ExtractUANs(sls *slsCommon.SLSState) (
 ) {
 	var uans []LogicalUAN
 	uanIndex := int(1)
-	for key, node := range sls.Hardware {
+	// Walk the hardware in xname order so the UANs are always listed, and reserved, in the same order.
+	for _, key := range slices.Sorted(maps.Keys(sls.Hardware)) {
+		node := sls.Hardware[key]
 		if node.Type == slsCommon.Node {
 			var extra slsCommon.ComptypeNode
 			err := mapstructure.Decode(
@@ -69,7 +74,10 @@ func ExtractUANs(sls *slsCommon.SLSState) (
 			}
 			if extra.Role == "Application" && extra.SubRole == "UAN" {
 				if extra.Aliases == nil {
-					log.Fatal("ERROR: UANs must have at least one alias defined in the application-node-config-yaml file")
+					return uans, fmt.Errorf(
+						"UAN %s must have at least one alias defined in the application-node-config-yaml file",
+						key,
+					)
 				}
 				uans = append(
 					uans,
@@ -198,11 +206,11 @@ func GetSLSCabinets(
 // GenerateDefaultNetworkConfigs generates a map containing every network that could be used on the system, initialized
 // with default values.
 func GenerateDefaultNetworkConfigs(
+	v *viper.Viper,
 	switches []*networking.ManagementSwitch,
 	logicalNCNs []*LogicalNCN,
 	cabinetDetailList []sls.CabinetGroupDetail,
 ) (defaultNetConfigs map[string]slsInit.NetworkLayoutConfiguration) {
-	v := viper.GetViper()
 	var riverCabinetCount, mountainCabinetCount, hillCabinetCount int
 	for _, cab := range cabinetDetailList {
 		switch class, _ := cab.Kind.Class(); class {
@@ -318,9 +326,92 @@ func GenerateDefaultNetworkConfigs(
 	return defaultNetConfigs
 }
 
-// GenerateNetworkConfigs creates a network configuration map of all networks for the system.
-func GenerateNetworkConfigs(netconfig map[string]slsInit.NetworkLayoutConfiguration) (internalNetConfigs map[string]slsInit.NetworkLayoutConfiguration, err error) {
-	v := viper.GetViper()
+/*
+addExtraNetworkConfigs normalizes and validates the extra networks, and adds their layouts to the network
+configuration map. The normalized extra networks are returned.
+*/
+func addExtraNetworkConfigs(
+	netConfigs map[string]slsInit.NetworkLayoutConfiguration,
+	extraNetworks []slsInit.ExtraNetwork,
+	cabinetDetailList []sls.CabinetGroupDetail,
+) (normalized []slsInit.ExtraNetwork, err error) {
+	var cabinets int
+	for _, cab := range cabinetDetailList {
+		cabinets += len(cab.CabinetIDs())
+	}
+	for _, extraNetwork := range extraNetworks {
+		extraNetwork.Normalize()
+		err = extraNetwork.Validate()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := netConfigs[extraNetwork.Name]; exists {
+			return nil, fmt.Errorf(
+				"extra network %s is defined more than once",
+				extraNetwork.Name,
+			)
+		}
+		netConfigs[extraNetwork.Name] = extraNetwork.LayoutConfiguration(cabinets)
+		normalized = append(
+			normalized,
+			extraNetwork,
+		)
+	}
+	return normalized, nil
+}
+
+// checkExtraNetworkOverlap verifies that the CIDRs of the extra networks do not overlap any other network.
+func checkExtraNetworkOverlap(networks map[string]*networking.IPNetwork, extraNetworks []slsInit.ExtraNetwork) error {
+	var overlapErrors []error
+	for _, extraNetwork := range extraNetworks {
+		for _, name := range slices.Sorted(maps.Keys(networks)) {
+			if name == extraNetwork.Name {
+				continue
+			}
+			for _, cidrs := range [][2]string{
+				{
+					extraNetwork.CIDR4,
+					networks[name].CIDR4,
+				},
+				{
+					extraNetwork.CIDR6,
+					networks[name].CIDR6,
+				},
+			} {
+				extraPrefix, err := netip.ParsePrefix(cidrs[0])
+				if err != nil {
+					continue
+				}
+				prefix, err := netip.ParsePrefix(cidrs[1])
+				if err != nil || prefix.Addr().IsUnspecified() {
+					continue
+				}
+				if extraPrefix.Overlaps(prefix) {
+					overlapErrors = append(
+						overlapErrors,
+						fmt.Errorf(
+							"extra network %s [%s] overlaps the %s network [%s]",
+							extraNetwork.Name,
+							extraPrefix,
+							name,
+							prefix,
+						),
+					)
+				}
+			}
+		}
+	}
+	if len(overlapErrors) > 0 {
+		return fmt.Errorf(
+			"overlapping CIDRs in the extra networks, can not continue:\n%v",
+			errors.Join(overlapErrors...),
+		)
+	}
+	return nil
+}
+
+// GenerateNetworkConfigs creates a network configuration map of all networks for the system, allocating their VLANs.
+func GenerateNetworkConfigs(v *viper.Viper, netconfig map[string]slsInit.NetworkLayoutConfiguration, vlans *networking.VLANAllocator) (internalNetConfigs map[string]slsInit.NetworkLayoutConfiguration, err error) {
 	internalNetConfigs = make(map[string]slsInit.NetworkLayoutConfiguration)
 	for name, layout := range netconfig {
 		myLayout := layout
@@ -369,13 +460,14 @@ func GenerateNetworkConfigs(netconfig map[string]slsInit.NetworkLayoutConfigurat
 
 		// Check VLAN allocations for re-use and overlaps
 		if len(layout.Template.VlanRange) == 2 {
-			err := networking.AllocateVlanRange(
+			err := vlans.AllocateRange(
 				myLayout.Template.VlanRange[0],
 				myLayout.Template.VlanRange[1],
+				myLayout.Template.Name,
 			)
 			if err != nil {
-				log.Fatalln(
-					"Unable to allocate VLAN range for",
+				return nil, fmt.Errorf(
+					"unable to allocate VLAN range for %s because %v",
 					myLayout.Template.Name,
 					err,
 				)
@@ -387,15 +479,17 @@ func GenerateNetworkConfigs(netconfig map[string]slsInit.NetworkLayoutConfigurat
 					myLayout.Template.VlanRange[1],
 				)
 			}
-		} else {
-			err := networking.AllocateVLAN(
+		} else if myLayout.Template.VlanRange[0] != networking.MinVLAN {
+			// Networks on VLAN 0, e.g. the BICAN toggle, are untagged and have no VLAN to allocate.
+			err := vlans.Allocate(
 				uint16(myLayout.Template.VlanRange[0]),
+				myLayout.Template.Name,
 			)
 			if err != nil {
-				log.Fatalln(
-					"Unable to allocate single VLAN for",
-					myLayout.Template.Name,
+				return nil, fmt.Errorf(
+					"unable to allocate single VLAN %d for %s because %v",
 					myLayout.Template.VlanRange[0],
+					myLayout.Template.Name,
 					err,
 				)
 			} else {
@@ -407,12 +501,11 @@ func GenerateNetworkConfigs(netconfig map[string]slsInit.NetworkLayoutConfigurat
 			}
 		}
 
-		allocated, err := networking.IsVLANAllocated(uint16(myLayout.BaseVlan))
+		allocated, err := vlans.IsAllocated(uint16(myLayout.BaseVlan))
 		if !allocated {
-			log.Fatalln(
-				"VLAN for",
+			return nil, fmt.Errorf(
+				"VLAN for %s has not been initialized by defaults or input values: %v",
 				layout.Template.Name,
-				"has not been initialized by defaults or input values:",
 				err,
 			)
 		}
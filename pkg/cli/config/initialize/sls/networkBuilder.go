@@ -27,8 +27,10 @@ package sls
 import (
 	"fmt"
 	"log"
+	"maps"
 	"net"
 	"net/netip"
+	"slices"
 	"sort"
 	"strings"
 
@@ -60,6 +62,176 @@ type NetworkLayoutConfiguration struct {
 	HasFabricManagerNodes           bool
 }
 
+// ExtraNetworkFile is the layout of the file given to config init's --extra-networks-yaml.
+type ExtraNetworkFile struct {
+	Networks []ExtraNetwork `yaml:"networks"`
+}
+
+// ExtraNetwork is a site-specific network that is built alongside the CSM networks.
+type ExtraNetwork struct {
+	Name     string `yaml:"name"`
+	FullName string `yaml:"full-name"`
+	CIDR4    string `yaml:"cidr4"`
+	CIDR6    string `yaml:"cidr6,omitempty"`
+	// BaseVlan is the VLAN of the network, or the first of the per-cabinet VLANs when it is subdivided by cabinet.
+	BaseVlan int16 `yaml:"base-vlan"`
+	MTU      int16 `yaml:"mtu,omitempty"`
+	// ParentDevice is the NCN interface the network's VLAN interface is created on, it defaults to bond0.
+	ParentDevice string `yaml:"parent-device,omitempty"`
+	// SubdivideByCabinet gives every cabinet its own subnet and VLAN.
+	SubdivideByCabinet bool `yaml:"subdivide-by-cabinet"`
+	// CabinetPrefixLength is the size of the per-cabinet subnets, it defaults to a /22.
+	CabinetPrefixLength int `yaml:"cabinet-prefix-length,omitempty"`
+	// BootstrapDHCP adds a bootstrap_dhcp subnet, which gives every NCN an address on the network.
+	BootstrapDHCP bool `yaml:"bootstrap-dhcp"`
+	// ReservationHostnames are reserved an address in the bootstrap_dhcp subnet.
+	ReservationHostnames []string `yaml:"reservation-hostnames,omitempty"`
+}
+
+// reservedNetNames are the networks config init builds on its own, besides networking.ValidNetNames.
+var reservedNetNames = []string{
+	"HMNLB",
+	"HSN",
+	"NMNLB",
+}
+
+// Normalize upper-cases the network's name and fills in its defaults.
+func (network *ExtraNetwork) Normalize() {
+	network.Name = strings.ToUpper(network.Name)
+	if network.FullName == "" {
+		network.FullName = network.Name
+	}
+	if network.ParentDevice == "" {
+		network.ParentDevice = networking.DefaultNMN.ParentDevice
+	}
+	if network.MTU == 0 {
+		network.MTU = networking.DefaultNMN.MTU
+	}
+	if network.CabinetPrefixLength == 0 {
+		network.CabinetPrefixLength, _ = networking.DefaultCabinetMask.Size()
+	}
+}
+
+// Validate checks that the network can be built, and that it does not replace one of the CSM networks.
+func (network *ExtraNetwork) Validate() error {
+	if network.Name == "" {
+		return fmt.Errorf("extra network is missing a name")
+	}
+	for _, char := range network.Name {
+		if (char < 'A' || char > 'Z') && (char < '0' || char > '9') && char != '_' {
+			return fmt.Errorf(
+				"extra network name %s must only contain letters, digits, and underscores",
+				network.Name,
+			)
+		}
+	}
+	if slices.Contains(
+		networking.ValidNetNames,
+		network.Name,
+	) || slices.Contains(
+		reservedNetNames,
+		network.Name,
+	) {
+		return fmt.Errorf(
+			"extra network %s can not replace the CSM network of the same name",
+			network.Name,
+		)
+	}
+	// The VLAN interface is named <parent>.<name>0, which must fit the 15 byte limit of Linux interface names.
+	interfaceName := fmt.Sprintf(
+		"%s.%s0",
+		network.ParentDevice,
+		strings.ToLower(network.Name),
+	)
+	if network.BootstrapDHCP && len(interfaceName) > 15 {
+		return fmt.Errorf(
+			"extra network %s has an interface name %s longer than 15 bytes, shorten its name",
+			network.Name,
+			interfaceName,
+		)
+	}
+	prefix4, err := netip.ParsePrefix(network.CIDR4)
+	if err != nil || !prefix4.Addr().Is4() {
+		return fmt.Errorf(
+			"extra network %s has an invalid cidr4 %q",
+			network.Name,
+			network.CIDR4,
+		)
+	}
+	if network.CIDR6 != "" {
+		prefix6, err := netip.ParsePrefix(network.CIDR6)
+		if err != nil || !prefix6.Addr().Is6() {
+			return fmt.Errorf(
+				"extra network %s has an invalid cidr6 %q",
+				network.Name,
+				network.CIDR6,
+			)
+		}
+	}
+	if network.BaseVlan <= networking.MinVLAN || network.BaseVlan >= networking.MaxUsableVLAN {
+		return fmt.Errorf(
+			"extra network %s has an invalid base-vlan %d, it must be between %d and %d",
+			network.Name,
+			network.BaseVlan,
+			networking.MinVLAN+1,
+			networking.MaxUsableVLAN-1,
+		)
+	}
+	if network.SubdivideByCabinet && (network.CabinetPrefixLength < prefix4.Bits() || network.CabinetPrefixLength > networking.SmallestIPv4Block) {
+		return fmt.Errorf(
+			"extra network %s has an invalid cabinet-prefix-length %d for its cidr4 %s",
+			network.Name,
+			network.CabinetPrefixLength,
+			network.CIDR4,
+		)
+	}
+	if len(network.ReservationHostnames) > 0 && !network.BootstrapDHCP {
+		return fmt.Errorf(
+			"extra network %s has reservation-hostnames but no bootstrap-dhcp subnet to reserve them in",
+			network.Name,
+		)
+	}
+	return nil
+}
+
+/*
+LayoutConfiguration returns the layout of the network. Networks that are subdivided by cabinet are given a VLAN range
+starting at their BaseVlan, with one VLAN for each of the given number of cabinets (after the BaseVlan itself when the
+bootstrap_dhcp subnet uses it).
+*/
+func (network *ExtraNetwork) LayoutConfiguration(cabinets int) NetworkLayoutConfiguration {
+	vlanRange := []int16{network.BaseVlan}
+	if network.SubdivideByCabinet && cabinets > 0 {
+		last := network.BaseVlan + int16(cabinets) - 1
+		if network.BootstrapDHCP {
+			last++
+		}
+		vlanRange = []int16{
+			network.BaseVlan,
+			last,
+		}
+	}
+	return NetworkLayoutConfiguration{
+		Template: networking.IPNetwork{
+			FullName:     network.FullName,
+			CIDR4:        network.CIDR4,
+			CIDR6:        network.CIDR6,
+			Name:         network.Name,
+			VlanRange:    vlanRange,
+			MTU:          network.MTU,
+			NetType:      networking.DefaultNMN.NetType,
+			ParentDevice: network.ParentDevice,
+		},
+		ReservationHostnames: network.ReservationHostnames,
+		IncludeBootstrapDHCP: network.BootstrapDHCP,
+		SubdivideByCabinet:   network.SubdivideByCabinet && cabinets > 0,
+		CabinetCIDR: net.CIDRMask(
+			network.CabinetPrefixLength,
+			networking.IPv4Size,
+		),
+	}
+}
+
 // GenDefaultBICANConfig returns the set of defaults for mapping the BICAN toggle
 func GenDefaultBICANConfig(systemDefaultRoute string) NetworkLayoutConfiguration {
 
@@ -169,13 +341,17 @@ func GenDefaultMTLConfig() NetworkLayoutConfiguration {
 	}
 }
 
-// BuildCSMNetworks creates an array of IPNetworks based on the supplied system configuration
+// BuildCSMNetworks creates an array of IPNetworks based on the supplied system configuration and the settings of v,
+// recording the VLAN of every subnet in the VLANAllocator the network VLANs were allocated from, and reserving the
+// addresses of the networking hardware and services with the run's AddressAllocator.
 func BuildCSMNetworks(
+	v *viper.Viper,
 	internalNetConfigs map[string]NetworkLayoutConfiguration,
 	internalCabinetDetails []sls.CabinetGroupDetail,
 	switches []*networking.ManagementSwitch,
+	vlans *networking.VLANAllocator,
+	addresses *networking.AddressAllocator,
 ) (networkMap networking.NetworkMap, err error) {
-	v := viper.GetViper()
 	networkMap = make(networking.NetworkMap)
 
 	for name, layout := range internalNetConfigs {
@@ -191,7 +367,12 @@ func BuildCSMNetworks(
 		// Update with computed fields
 		myLayout.CabinetDetails = internalCabinetDetails
 		myLayout.ManagementSwitches = switches
-		netPtr, err := createNetFromLayoutConfig(myLayout)
+		netPtr, err := createNetFromLayoutConfig(
+			v,
+			myLayout,
+			vlans,
+			addresses,
+		)
 		if err != nil {
 			return nil, err
 		}
@@ -217,7 +398,16 @@ func BuildCSMNetworks(
 	}
 	pool.FullName = "NMN MetalLB"
 	pool.MetalLBPoolName = "node-management"
-	for nme, rsrv := range networking.PinnedMetalLBReservations {
+	err = vlans.Assign(
+		pool.VlanID,
+		tempNMNLoadBalancer.Name,
+		pool.Name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, nme := range slices.Sorted(maps.Keys(networking.PinnedMetalLBReservations)) {
+		rsrv := networking.PinnedMetalLBReservations[nme]
 		_, err = networking.AddReservationWithPin(
 			pool,
 			nme,
@@ -248,7 +438,16 @@ func BuildCSMNetworks(
 	)
 	pool.FullName = "HMN MetalLB"
 	pool.MetalLBPoolName = "hardware-management"
-	for nme, rsrv := range networking.PinnedMetalLBReservations {
+	err = vlans.Assign(
+		pool.VlanID,
+		tempHMNLoadBalancer.Name,
+		pool.Name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, nme := range slices.Sorted(maps.Keys(networking.PinnedMetalLBReservations)) {
+		rsrv := networking.PinnedMetalLBReservations[nme]
 		// // Because of the hack to pin ip addresses, we've got an overloaded datastructure in defaults.
 		// // We need to prune it here before we write it out. It's pretty ugly, but we plan to throw all of this code away when ip pinning is no longer necessary
 		if nme != "istio-ingressgateway-local" {
@@ -283,14 +482,17 @@ func BuildCSMNetworks(
 	return networkMap, err
 }
 
-func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networking.IPNetwork, err error) {
+func createNetFromLayoutConfig(
+	v *viper.Viper,
+	conf NetworkLayoutConfiguration,
+	vlans *networking.VLANAllocator,
+	addresses *networking.AddressAllocator,
+) (network *networking.IPNetwork, err error) {
 
 	var canCIDR netip.Prefix
 	var cmnCIDR netip.Prefix
 	var chnCIDR netip.Prefix
 
-	v := viper.GetViper()
-
 	tempNet := conf.Template
 	netNameLower := strings.ToLower(tempNet.Name)
 
@@ -361,7 +563,11 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 			)
 
 			if err != nil {
-				log.Fatal(err)
+				return nil, fmt.Errorf(
+					"couldn't reserve the external-dns address %v because %v",
+					v.GetString("cmn-external-dns"),
+					err,
+				)
 			}
 		}
 		cmnDynamicPool, err := netip.ParsePrefix(v.GetString("cmn-dynamic-pool"))
@@ -586,6 +792,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 			leafSwitches,
 			leafbmcSwitches,
 			cduSwitches,
+			addresses,
 		)
 		if err != nil {
 			return nil, fmt.Errorf(
@@ -624,6 +831,12 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 			cidr6 = v.GetString(cidr6Key)
 		}
 
+		// Networks without flags of their own (e.g. extra networks) use the CIDRs of their template.
+		if cidr4 == "" {
+			cidr4 = tempNet.CIDR4
+			cidr6 = tempNet.CIDR6
+		}
+
 		var mask4, mask6 net.IPMask
 		if cidr4 == "" {
 			return &tempNet, fmt.Errorf("failed to find a CIDR v4 IP for bootstrap_dhcp")
@@ -696,7 +909,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 					return nil, fmt.Errorf("chn-gateway4 was not a valid IPv4 address")
 				}
 				subnet.Gateway = canGateway
-				_, err := networking.AddReservation(
+				_, err := addresses.AddReservation(
 					subnet,
 					"can-switch-1",
 					"",
@@ -704,7 +917,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 				if err != nil {
 					return nil, err
 				}
-				_, err = networking.AddReservation(
+				_, err = addresses.AddReservation(
 					subnet,
 					"can-switch-2",
 					"",
@@ -723,6 +936,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 				err := networking.ReserveEdgeSwitchIPs(
 					subnet,
 					edgeSwitches,
+					addresses,
 				)
 				if err != nil {
 					return nil, err
@@ -734,6 +948,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 					[]string{},
 					[]string{},
 					[]string{},
+					addresses,
 				)
 				if err != nil {
 					return nil, fmt.Errorf(
@@ -744,7 +959,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 				}
 			}
 			if tempNet.Name == "NMN" {
-				_, err = networking.AddReservation(
+				_, err = addresses.AddReservation(
 					subnet,
 					"rgw-vip",
 					"rgw-virtual-ip",
@@ -752,7 +967,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 				if err != nil {
 					return nil, err
 				}
-				_, err = networking.AddReservation(
+				_, err = addresses.AddReservation(
 					subnet,
 					"kubeapi-vip",
 					"k8s-virtual-ip",
@@ -761,9 +976,12 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 					return nil, err
 				}
 				// FabricManager VIP is only supported in CSM 1.7 and later AND requires FabricManager nodes
-				_, oneSevenCSM := csm.CompareMajorMinor("1.7")
+				_, oneSevenCSM := csm.CompareMajorMinorWith(
+					v,
+					"1.7",
+				)
 				if oneSevenCSM != -1 && conf.HasFabricManagerNodes {
-					_, err = networking.AddReservation(
+					_, err = addresses.AddReservation(
 						subnet,
 						"fmn-vip",
 						"fmn-virtual-ip",
@@ -775,9 +993,12 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 			}
 			if tempNet.Name == "HMN" {
 				// FabricManager VIP is only supported in CSM 1.7 and later AND requires FabricManager nodes
-				_, oneSevenCSM := csm.CompareMajorMinor("1.7")
+				_, oneSevenCSM := csm.CompareMajorMinorWith(
+					v,
+					"1.7",
+				)
 				if oneSevenCSM != -1 && conf.HasFabricManagerNodes {
-					_, err = networking.AddReservation(
+					_, err = addresses.AddReservation(
 						subnet,
 						"fmn-vip",
 						"fmn-virtual-ip",
@@ -788,6 +1009,21 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 				}
 			}
 		}
+		for _, hostname := range conf.ReservationHostnames {
+			_, err = addresses.AddReservation(
+				subnet,
+				hostname,
+				"",
+			)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"could not reserve %s in the %s network because %v",
+					hostname,
+					tempNet.Name,
+					err,
+				)
+			}
+		}
 	}
 
 	// Set up the ASNs
@@ -842,7 +1078,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 
 		for _, reservationName := range keys {
 			var reservationComment = networking.DefaultUAISubnetReservations[reservationName]
-			reservation, err := networking.AddReservation(
+			reservation, err := addresses.AddReservation(
 				uaisubnet,
 				reservationName,
 				strings.Join(
@@ -884,6 +1120,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 						sls.CabinetAirCooledChassisCountFilter(1),
 					),
 				),
+				vlans,
 			)
 			if err != nil {
 				return nil, err
@@ -897,6 +1134,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 				conf.CabinetDetails,
 				conf.CabinetCIDR,
 				sls.CabinetClassFilter(slsCommon.ClassMountain),
+				vlans,
 			)
 			if err != nil {
 				return nil, err
@@ -905,6 +1143,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 				conf.CabinetDetails,
 				conf.CabinetCIDR,
 				sls.CabinetClassFilter(slsCommon.ClassHill),
+				vlans,
 			)
 			if err != nil {
 				return nil, err
@@ -913,10 +1152,12 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 		// Otherwise do both
 	}
 	if conf.SubdivideByCabinet && !conf.GroupNetworksByCabinetType {
+		// The VLANs given by GenSubnets are renumbered below, they are assigned once the subnets have their final VLAN.
 		err := tempNet.GenSubnets(
 			conf.CabinetDetails,
 			conf.CabinetCIDR,
 			sls.CabinetClassFilter(slsCommon.ClassRiver),
+			nil,
 		)
 		if err != nil {
 			return nil, err
@@ -925,6 +1166,7 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 			conf.CabinetDetails,
 			conf.CabinetCIDR,
 			sls.CabinetClassFilter(slsCommon.ClassHill),
+			nil,
 		)
 		if err != nil {
 			return nil, err
@@ -933,15 +1175,47 @@ func createNetFromLayoutConfig(conf NetworkLayoutConfiguration) (network *networ
 			conf.CabinetDetails,
 			conf.CabinetCIDR,
 			sls.CabinetClassFilter(slsCommon.ClassMountain),
+			nil,
 		)
 		if err != nil {
 			return nil, err
 		}
+		// GenSubnets numbers the VLANs within each cabinet group, number them across the whole network instead so
+		// they stay within the VLAN range that was allocated for it.
+		vlan := conf.BaseVlan
+		if conf.IncludeBootstrapDHCP {
+			vlan++
+		}
+		for _, subnet := range tempNet.Subnets {
+			if !strings.HasPrefix(
+				subnet.Name,
+				"cabinet_",
+			) {
+				continue
+			}
+			subnet.VlanID = vlan
+			vlan++
+		}
+		tempNet.VlanRange = []int16{
+			conf.BaseVlan,
+			vlan - 1,
+		}
 	}
 
 	// Apply the Supernet Hack
 	if conf.SuperNetHack {
-		tempNet.ApplySupernetHack()
+		tempNet.ApplySupernetHack(v)
+	}
+
+	for _, subnet := range tempNet.Subnets {
+		err = vlans.Assign(
+			subnet.VlanID,
+			tempNet.Name,
+			subnet.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	network = &tempNet
@@ -100,6 +100,10 @@ type GeneratorInputState struct {
 	MountainStartingNid int                                  `json:"MountainStartingNid"`
 
 	Networks map[string]slsCommon.Network `json:"Networks"`
+
+	// CSMVersion is the csm-version the state is generated for, FabricManager nodes are left out before CSM 1.7. An
+	// empty version is taken to be the latest.
+	CSMVersion string `json:"CSMVersion,omitempty"`
 }
 
 // GeneratorApplicationNodeConfig is given to the SLS config generator to control the application node generation in SLS
@@ -967,7 +971,10 @@ func (g *StateGenerator) getNodeHardwareFromRow(row shcdParser.HMNRow) (hardware
 		"fmn",
 	) {
 		// FabricManager nodes are only supported in CSM 1.7 and later
-		_, oneSevenCSM := csm.CompareMajorMinor("1.7")
+		oneSevenCSM := csm.CompareMajorMinorOf(
+			g.inputState.CSMVersion,
+			"1.7",
+		)
 		if oneSevenCSM == -1 {
 			logger.Info(
 				"Skipping FabricManager node - requires CSM 1.7 or later",
@@ -1595,7 +1602,7 @@ func GenerateSLSState(inputState GeneratorInputState, hmnRows []shcdParser.HMNRo
 	logger := zap.New(
 		zapcore.NewCore(
 			zapcore.NewJSONEncoder(encoderCfg),
-			zapcore.Lock(os.Stdout),
+			zapcore.Lock(os.Stderr),
 			atomicLevel,
 		),
 	)
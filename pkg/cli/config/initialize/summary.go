@@ -0,0 +1,186 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/networking"
+	"github.com/Cray-HPE/cray-site-init/pkg/version"
+)
+
+// Summary is the installation summary config init prints once the system directory is written.
+type Summary struct {
+	SystemName       string           `json:"system-name" yaml:"system-name"`
+	InstallationNode string           `json:"installation-node" yaml:"installation-node"`
+	UpstreamDNS      string           `json:"upstream-dns" yaml:"upstream-dns"`
+	MetalLBPeers     []string         `json:"metallb-peers" yaml:"metallb-peers,flow"`
+	BICANUserNetwork string           `json:"bican-user-network" yaml:"bican-user-network"`
+	Supernet         bool             `json:"supernet" yaml:"supernet"`
+	Networks         []SummaryNetwork `json:"networks" yaml:"networks"`
+	NCNs             int              `json:"ncns" yaml:"ncns"`
+	UANs             int              `json:"uans" yaml:"uans"`
+	Switches         int              `json:"switches" yaml:"switches"`
+	// Cabinets are the number of SLS cabinets keyed by their class (e.g. "River").
+	Cabinets   map[slsCommon.CabinetType]int `json:"cabinets" yaml:"cabinets"`
+	CSIVersion string                        `json:"csi-version" yaml:"csi-version"`
+	Warnings   []string                      `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// SummaryNetwork is a network within a Summary, along with the subnets allocated from it.
+type SummaryNetwork struct {
+	Name     string   `json:"name" yaml:"name"`
+	FullName string   `json:"full-name" yaml:"full-name"`
+	CIDR4    string   `json:"cidr4" yaml:"cidr4"`
+	CIDR6    string   `json:"cidr6,omitempty" yaml:"cidr6,omitempty"`
+	Subnets4 []string `json:"subnets4" yaml:"subnets4"`
+	Subnets6 []string `json:"subnets6,omitempty" yaml:"subnets6,omitempty"`
+}
+
+// NewSummary builds a Summary from the given viper and the outputs of Generate.
+func NewSummary(v *viper.Viper, outputs *Outputs) Summary {
+	summary := Summary{
+		SystemName:       v.GetString("system-name"),
+		InstallationNode: v.GetString("install-ncn"),
+		UpstreamDNS:      v.GetString("site-dns"),
+		MetalLBPeers:     v.GetStringSlice("bgp-peer-types"),
+		BICANUserNetwork: v.GetString("bican-user-network-name"),
+		Supernet:         v.GetBool("supernet"),
+		Networks:         []SummaryNetwork{},
+		NCNs:             len(outputs.LogicalNCNs),
+		UANs:             len(outputs.UANs),
+		Switches:         len(outputs.Switches),
+		Cabinets:         make(map[slsCommon.CabinetType]int),
+		CSIVersion:       version.Get().String(),
+	}
+
+	if v.IsSet("cabinets-yaml") && (v.GetString("cabinets-yaml") != "") && (v.IsSet("mountain-cabinets") ||
+		v.IsSet("starting-mountain-cabinet") ||
+		v.IsSet("river-cabinets") ||
+		v.IsSet("starting-river-cabinet") ||
+		v.IsSet("hill-cabinets") ||
+		v.IsSet("starting-hill-cabinet")) {
+		summary.Warnings = append(
+			summary.Warnings,
+			"cabinet flags are not honored when a cabinets-yaml file is provided",
+		)
+	}
+
+	for _, class := range []slsCommon.CabinetType{
+		slsCommon.ClassMountain,
+		slsCommon.ClassHill,
+		slsCommon.ClassRiver,
+	} {
+		summary.Cabinets[class] = len(
+			GetSLSCabinets(
+				outputs.SLSState,
+				class,
+			),
+		)
+	}
+
+	sortedNetworks := make(
+		networking.IPNetworks,
+		0,
+		len(outputs.Networks),
+	)
+	for shastaNetwork := range outputs.Networks {
+		sortedNetworks = append(
+			sortedNetworks,
+			outputs.Networks[shastaNetwork],
+		)
+	}
+	sort.Sort(sortedNetworks)
+	for _, network := range sortedNetworks {
+		prefix4, err := netip.ParsePrefix(network.CIDR4)
+		if err != nil || prefix4.Addr().IsUnspecified() {
+			continue
+		}
+		summaryNetwork := SummaryNetwork{
+			Name:     network.Name,
+			FullName: network.FullName,
+			CIDR4:    network.CIDR4,
+			CIDR6:    network.CIDR6,
+			Subnets4: prefixStrings(network.AllocatedIPv4Subnets()),
+		}
+		if network.CIDR6 != "" {
+			summaryNetwork.Subnets6 = prefixStrings(network.AllocatedIPv6Subnets())
+		}
+		summary.Networks = append(
+			summary.Networks,
+			summaryNetwork,
+		)
+	}
+	return summary
+}
+
+// Marshal encodes the summary as either json or yaml.
+func (summary Summary) Marshal(format string) ([]byte, error) {
+	return marshalDocument(
+		summary,
+		format,
+	)
+}
+
+// marshalDocument encodes a document config init prints or writes, e.g. its summary or plan, as either json or yaml.
+func marshalDocument(document interface{}, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(
+			document,
+			"",
+			"  ",
+		)
+	case "yaml":
+		return yaml.Marshal(document)
+	default:
+		return nil, fmt.Errorf(
+			"unsupported format %q, must be json or yaml",
+			format,
+		)
+	}
+}
+
+func prefixStrings(prefixes []netip.Prefix) []string {
+	formatted := make(
+		[]string,
+		0,
+		len(prefixes),
+	)
+	for _, prefix := range prefixes {
+		formatted = append(
+			formatted,
+			prefix.String(),
+		)
+	}
+	return formatted
+}
@@ -0,0 +1,450 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/csm"
+)
+
+// Kinds of config changes.
+const (
+	ConfigKeyAdded   = "added"
+	ConfigKeyChanged = "changed"
+	ConfigKeyRemoved = "removed"
+	ConfigKeyRenamed = "renamed"
+)
+
+// ConfigChange is a single change a migration made to a config.
+type ConfigChange struct {
+	// Version is the CSM version of the migration that made the change.
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// ConfigMigration upgrades a config to a CSM version, its changes are made in the order of its fields.
+type ConfigMigration struct {
+	// Version is the CSM version the migration upgrades to.
+	Version string
+	// Renames maps keys to the keys that replaced them.
+	Renames map[string]string
+	// Removes are keys that are no longer used.
+	Removes []string
+	// Defaults are keys the version requires, and the values they are given when they are not set.
+	Defaults map[string]interface{}
+	// Update makes the changes that are not a rename, removal, or default.
+	Update func(config map[string]interface{}) []ConfigChange
+}
+
+// ConfigMigrations upgrade a config from one CSM version to the next, ordered by version.
+var ConfigMigrations = []ConfigMigration{
+	{
+		Version: "1.5",
+		Renames: map[string]string{
+			"bgp-peers": "bgp-peer-types",
+		},
+	},
+	{
+		Version: "1.6",
+		Renames: map[string]string{
+			"chn-cidr":    "chn-cidr4",
+			"chn-gateway": "chn-gateway4",
+			"cmn-cidr":    "cmn-cidr4",
+			"cmn-gateway": "cmn-gateway4",
+		},
+	},
+	{
+		// CSM 1.7 dropped Weave for Cilium. It also replaced the MetalLB ConfigMap (metallb.yaml) with MetalLB resources,
+		// these are built from the same bgp-* keys so none of them change.
+		Version: "1.7",
+		Removes: []string{
+			"kubernetes-weave-mtu",
+		},
+		Defaults: map[string]interface{}{
+			"k8s-primary-cni": "cilium",
+		},
+		Update: func(config map[string]interface{}) []ConfigChange {
+			if config["k8s-primary-cni"] != "weave" {
+				return nil
+			}
+			config["k8s-primary-cni"] = "cilium"
+			return []ConfigChange{
+				{
+					Kind:    ConfigKeyChanged,
+					Key:     "k8s-primary-cni",
+					Message: "changed from weave to cilium, weave is no longer supported",
+				},
+			}
+		},
+	},
+}
+
+// NewUpgradeCommand represents the upgrade command.
+func NewUpgradeCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "upgrade CONFIG NEW_CONFIG",
+		Short: "Migrates a system_config.yaml to a newer CSM version",
+		Long: `Migrates a config file of 'csi config init' (e.g. system_config.yaml) from the CSM version in its csm-version
+	to a newer one, and writes it to NEW_CONFIG (which may be CONFIG).
+
+	Every CSM version between the two has a migration, they are applied in order:
+	1. Keys that were replaced are renamed, unless their replacement is already set
+	2. Keys that are no longer used are removed
+	3. Keys the version requires are added with their default
+	4. Values the version no longer supports are changed
+
+	Aliases are then renamed to their key, and the remaining deprecated keys are removed. Every change is printed.
+
+	Secrets (e.g. bootstrap-ncn-bmc-pass) are written as they are, NEW_CONFIG must be kept as private as CONFIG.
+	`,
+		Args:              cobra.ExactArgs(2),
+		DisableAutoGenTag: true,
+		Run: func(c *cobra.Command, args []string) {
+			target, err := c.Flags().GetString("target-csm-version")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: Unable to read %s because %v",
+					args[0],
+					err,
+				)
+			}
+			var config map[string]interface{}
+			err = yaml.Unmarshal(
+				data,
+				&config,
+			)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: Unable to parse %s because %v",
+					args[0],
+					err,
+				)
+			}
+
+			changes, err := UpgradeConfig(
+				config,
+				target,
+			)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: %v",
+					err,
+				)
+			}
+			err = writeUpgradedConfig(
+				config,
+				args[1],
+			)
+			if err != nil {
+				log.Fatalf(
+					"FATAL ERROR: Unable to write %s because %v",
+					args[1],
+					err,
+				)
+			}
+
+			for _, change := range changes {
+				fmt.Printf(
+					"%s: %s %s\n",
+					change.Version,
+					change.Key,
+					change.Message,
+				)
+			}
+			fmt.Printf(
+				"Wrote %s for CSM %s\n",
+				args[1],
+				target,
+			)
+		},
+	}
+	c.Flags().String(
+		"target-csm-version",
+		"",
+		"CSM version to upgrade the config to",
+	)
+	_ = c.MarkFlagRequired("target-csm-version")
+	return c
+}
+
+/*
+UpgradeConfig applies the ConfigMigrations between the csm-version of config and the target version to config, and
+returns the changes they made. Aliases are renamed to their key, and the deprecated flags of config init that are left
+are removed.
+*/
+func UpgradeConfig(config map[string]interface{}, target string) (changes []ConfigChange, err error) {
+	// Viper matches keys regardless of their case, and writes them in lower case.
+	for _, key := range slices.Collect(maps.Keys(config)) {
+		if lowerKey := strings.ToLower(key); lowerKey != key {
+			config[lowerKey] = config[key]
+			delete(
+				config,
+				key,
+			)
+		}
+	}
+	current, ok := config[csm.APIKeyName].(string)
+	if !ok || current == "" {
+		return nil, fmt.Errorf(
+			"the config has no %s to upgrade from",
+			csm.APIKeyName,
+		)
+	}
+
+	if err := isCompatibleVersion(target); err != nil {
+		return nil, fmt.Errorf(
+			"unable to upgrade to %s because %v",
+			target,
+			err,
+		)
+	}
+	if err := isCompatibleVersion(current); err != nil {
+		return nil, err
+	}
+	if csm.CompareMajorMinorOf(
+		current,
+		target,
+	) == 1 {
+		return nil, fmt.Errorf(
+			"the config is for CSM %s, it can not be downgraded to %s",
+			current,
+			target,
+		)
+	}
+	for _, migration := range ConfigMigrations {
+		if csm.CompareMajorMinorOf(
+			target,
+			migration.Version,
+		) == -1 {
+			break
+		}
+		if csm.CompareMajorMinorOf(
+			current,
+			migration.Version,
+		) != -1 {
+			continue
+		}
+		for _, change := range migration.apply(config) {
+			change.Version = migration.Version
+			changes = append(
+				changes,
+				change,
+			)
+		}
+		current = migration.Version
+	}
+
+	// Aliases and deprecated keys the migrations did not handle would be dropped when the config is written.
+	for _, change := range renameConfigKeys(
+		config,
+		AliasKeys,
+	) {
+		change.Version = target
+		changes = append(
+			changes,
+			change,
+		)
+	}
+	for _, key := range deprecatedKeys() {
+		if _, ok := config[key]; ok {
+			delete(
+				config,
+				key,
+			)
+			changes = append(
+				changes,
+				ConfigChange{
+					Version: target,
+					Kind:    ConfigKeyRemoved,
+					Key:     key,
+					Message: "removed, deprecated",
+				},
+			)
+		}
+	}
+
+	if config[csm.APIKeyName] != target {
+		changes = append(
+			changes,
+			ConfigChange{
+				Version: target,
+				Kind:    ConfigKeyChanged,
+				Key:     csm.APIKeyName,
+				Message: fmt.Sprintf(
+					"changed from %v to %s",
+					config[csm.APIKeyName],
+					target,
+				),
+			},
+		)
+		config[csm.APIKeyName] = target
+	}
+	return changes, nil
+}
+
+// writeUpgradedConfig writes an upgraded config to path. Unlike config init its secrets are kept, redacting them would
+// lose them when the config is written over itself.
+func writeUpgradedConfig(config map[string]interface{}, path string) error {
+	finalConfig, err := writableSettings(
+		config,
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	return finalConfig.WriteConfigAs(path)
+}
+
+// deprecatedKeys returns the deprecated flags of config init, sorted.
+func deprecatedKeys() (keys []string) {
+	initFlags().VisitAll(
+		func(flag *pflag.Flag) {
+			if flag.Deprecated != "" {
+				keys = append(
+					keys,
+					flag.Name,
+				)
+			}
+		},
+	)
+	slices.Sort(keys)
+	return keys
+}
+
+// isCompatibleVersion returns whether csi is compatible with the given CSM version, see csm.IsCompatible.
+func isCompatibleVersion(version string) error {
+	v := viper.New()
+	v.Set(
+		csm.APIKeyName,
+		version,
+	)
+	_, err := csm.IsCompatibleWith(v)
+	return err
+}
+
+func (migration ConfigMigration) apply(config map[string]interface{}) (changes []ConfigChange) {
+	changes = renameConfigKeys(
+		config,
+		migration.Renames,
+	)
+	for _, key := range migration.Removes {
+		if _, ok := config[key]; ok {
+			delete(
+				config,
+				key,
+			)
+			changes = append(
+				changes,
+				ConfigChange{
+					Kind:    ConfigKeyRemoved,
+					Key:     key,
+					Message: "removed, no longer used",
+				},
+			)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(migration.Defaults)) {
+		if _, ok := config[key]; !ok {
+			config[key] = migration.Defaults[key]
+			changes = append(
+				changes,
+				ConfigChange{
+					Kind: ConfigKeyAdded,
+					Key:  key,
+					Message: fmt.Sprintf(
+						"added as %v",
+						migration.Defaults[key],
+					),
+				},
+			)
+		}
+	}
+	if migration.Update != nil {
+		changes = append(
+			changes,
+			migration.Update(config)...,
+		)
+	}
+	return changes
+}
+
+// renameConfigKeys renames the keys of config, a key whose replacement is already set is removed instead.
+func renameConfigKeys(config map[string]interface{}, renames map[string]string) (changes []ConfigChange) {
+	for _, key := range slices.Sorted(maps.Keys(renames)) {
+		value, ok := config[key]
+		if !ok {
+			continue
+		}
+		delete(
+			config,
+			key,
+		)
+		newKey := renames[key]
+		if existing, ok := config[newKey]; ok && existing != nil && existing != "" {
+			changes = append(
+				changes,
+				ConfigChange{
+					Kind: ConfigKeyRemoved,
+					Key:  key,
+					Message: fmt.Sprintf(
+						"removed, replaced by %s which is already set",
+						newKey,
+					),
+				},
+			)
+			continue
+		}
+		config[newKey] = value
+		changes = append(
+			changes,
+			ConfigChange{
+				Kind: ConfigKeyRenamed,
+				Key:  key,
+				Message: fmt.Sprintf(
+					"renamed to %s",
+					newKey,
+				),
+			},
+		)
+	}
+	return changes
+}
@@ -0,0 +1,278 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+type UpgradeTestSuite struct {
+	suite.Suite
+}
+
+func (suite *UpgradeTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+func (suite *UpgradeTestSuite) TestUpgradeConfig() {
+	config := map[string]interface{}{
+		"csm-version":          "1.4",
+		"bgp-peers":            "spine",
+		"chn-cidr":             "10.103.9.0/25",
+		"CMN-CIDR":             "10.103.8.0/24",
+		"cmn-cidr4":            "10.103.6.0/24",
+		"k8s-primary-cni":      "weave",
+		"kubernetes-weave-mtu": 1376,
+	}
+	viper.Set(
+		"csm-version",
+		"1.6",
+	)
+	changes, err := UpgradeConfig(
+		config,
+		"1.7",
+	)
+	suite.Require().NoError(err)
+	// The global config is left as it was.
+	suite.Equal(
+		"1.6",
+		viper.GetString("csm-version"),
+	)
+	suite.Equal(
+		map[string]interface{}{
+			"csm-version":     "1.7",
+			"bgp-peer-types":  "spine",
+			"chn-cidr4":       "10.103.9.0/25",
+			"cmn-cidr4":       "10.103.6.0/24",
+			"k8s-primary-cni": "cilium",
+		},
+		config,
+	)
+	suite.Equal(
+		[]ConfigChange{
+			{
+				Version: "1.5",
+				Kind:    ConfigKeyRenamed,
+				Key:     "bgp-peers",
+				Message: "renamed to bgp-peer-types",
+			},
+			{
+				Version: "1.6",
+				Kind:    ConfigKeyRenamed,
+				Key:     "chn-cidr",
+				Message: "renamed to chn-cidr4",
+			},
+			{
+				Version: "1.6",
+				Kind:    ConfigKeyRemoved,
+				Key:     "cmn-cidr",
+				Message: "removed, replaced by cmn-cidr4 which is already set",
+			},
+			{
+				Version: "1.7",
+				Kind:    ConfigKeyRemoved,
+				Key:     "kubernetes-weave-mtu",
+				Message: "removed, no longer used",
+			},
+			{
+				Version: "1.7",
+				Kind:    ConfigKeyChanged,
+				Key:     "k8s-primary-cni",
+				Message: "changed from weave to cilium, weave is no longer supported",
+			},
+			{
+				Version: "1.7",
+				Kind:    ConfigKeyChanged,
+				Key:     "csm-version",
+				Message: "changed from 1.4 to 1.7",
+			},
+		},
+		changes,
+	)
+}
+
+func (suite *UpgradeTestSuite) TestUpgradeConfig_Partial() {
+	// Only the migrations after the csm-version of the config and up to the target are applied.
+	config := map[string]interface{}{
+		"csm-version":          "1.5",
+		"bgp-peers":            "spine",
+		"chn-cidr":             "10.103.9.0/25",
+		"kubernetes-weave-mtu": 1376,
+	}
+	changes, err := UpgradeConfig(
+		config,
+		"1.6",
+	)
+	suite.Require().NoError(err)
+	// The 1.5 rename of bgp-peers is not applied, it is removed as a deprecated key.
+	suite.Equal(
+		map[string]interface{}{
+			"csm-version":          "1.6",
+			"chn-cidr4":            "10.103.9.0/25",
+			"kubernetes-weave-mtu": 1376,
+		},
+		config,
+	)
+	suite.Len(
+		changes,
+		3,
+	)
+
+	// A config is given the defaults of the versions it is upgraded past.
+	config = map[string]interface{}{
+		"csm-version": "1.6",
+	}
+	_, err = UpgradeConfig(
+		config,
+		"1.7.1",
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		map[string]interface{}{
+			"csm-version":     "1.7.1",
+			"k8s-primary-cni": "cilium",
+		},
+		config,
+	)
+}
+
+func (suite *UpgradeTestSuite) TestUpgradeConfig_Deprecated() {
+	// Deprecated keys the migrations do not handle are removed, and an unchanged csm-version is not a change.
+	config := map[string]interface{}{
+		"csm-version":     "1.7",
+		"bgp-peers":       "spine",
+		"k8s-primary-cni": "cilium",
+	}
+	changes, err := UpgradeConfig(
+		config,
+		"1.7",
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		map[string]interface{}{
+			"csm-version":     "1.7",
+			"k8s-primary-cni": "cilium",
+		},
+		config,
+	)
+	suite.Equal(
+		[]ConfigChange{
+			{
+				Version: "1.7",
+				Kind:    ConfigKeyRemoved,
+				Key:     "bgp-peers",
+				Message: "removed, deprecated",
+			},
+		},
+		changes,
+	)
+	suite.Empty(DeprecatedKeys)
+}
+
+func (suite *UpgradeTestSuite) TestWriteUpgradedConfig() {
+	path := filepath.Join(
+		suite.T().TempDir(),
+		defaultConfigFilename,
+	)
+	suite.Require().NoError(
+		writeUpgradedConfig(
+			map[string]interface{}{
+				"bootstrap-ncn-bmc-pass": "changeme",
+				"csm-version":            "1.7",
+				"help":                   false,
+			},
+			path,
+		),
+	)
+	config, err := os.ReadFile(path)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"bootstrap-ncn-bmc-pass: changeme\ncsm-version: \"1.7\"\n",
+		string(config),
+	)
+}
+
+func (suite *UpgradeTestSuite) TestUpgradeConfig_Invalid() {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		target  string
+		message string
+	}{
+		{
+			name:    "missing version",
+			config:  map[string]interface{}{},
+			target:  "1.7",
+			message: "the config has no csm-version to upgrade from",
+		},
+		{
+			name: "downgrade",
+			config: map[string]interface{}{
+				"csm-version": "1.6",
+			},
+			target:  "1.5",
+			message: "the config is for CSM 1.6, it can not be downgraded to 1.5",
+		},
+		{
+			name: "unsupported version",
+			config: map[string]interface{}{
+				"csm-version": "1.3",
+			},
+			target:  "1.7",
+			message: "only compatible with CSM v1.4 and higher",
+		},
+		{
+			name: "unsupported target",
+			config: map[string]interface{}{
+				"csm-version": "1.6",
+			},
+			target:  "latest",
+			message: "unable to upgrade to latest",
+		},
+	}
+	for _, test := range tests {
+		_, err := UpgradeConfig(
+			test.config,
+			test.target,
+		)
+		suite.ErrorContains(
+			err,
+			test.message,
+			test.name,
+		)
+	}
+}
+
+func TestUpgradeTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(UpgradeTestSuite),
+	)
+}
@@ -25,7 +25,12 @@
 package initialize
 
 import (
-	"github.com/Cray-HPE/cray-site-init/pkg/cli"
+	"bytes"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/secrets"
 	"github.com/Cray-HPE/cray-site-init/pkg/version"
 	"github.com/spf13/viper"
 )
@@ -38,10 +43,30 @@ var UniqueCIDRKeys = []string{
 	"cmn-cidr4",
 	"cmn-cidr6",
 	"hmn-cidr",
+	"hmn-cidr6",
 	"hmn-mtn-cidr",
+	"hmn-mtn-cidr6",
 	"mtl-cidr",
+	"mtl-cidr6",
 	"nmn-cidr",
+	"nmn-cidr6",
 	"nmn-mtn-cidr",
+	"nmn-mtn-cidr6",
+}
+
+// ConfigKeys are keys that are only read from a config file, there is no flag for them.
+var ConfigKeys = []string{
+	"cilium-kube-proxy-replacement",
+	"cilium-operator-replicas",
+	"domain",
+	"internal-domain",
+	"k8s-primary-cni",
+	"kubernetes-max-pods-per-node",
+	"kubernetes-pods-cidr",
+	"kubernetes-services-cidr",
+	"kubernetes-weave-mtu",
+	"ncn-file",
+	"wipe-ceph-osds",
 }
 
 // DeprecatedKeys is a list of every key that is deprecated in Cobra.
@@ -55,9 +80,32 @@ var NoWriteKeys = []string{
 	"input-dir",
 	"k8s-namespace",
 	"k8s-secret-name",
+	"manifest-signing-key",
+	"output",
+	"plan",
+	"previous-sls",
+}
+
+// SecretKeys are keys whose values are secrets, they are redacted wherever they are written or shown unless they are
+// a secret reference (see the secrets package).
+var SecretKeys = []string{
+	"bootstrap-ncn-bmc-pass",
+	"metallb-bgp-password",
 }
 
-var Aliases []string
+// AliasKeys maps every alias of a config init flag to the key it stands for.
+var AliasKeys = map[string]string{
+	"chn-cidr":    "chn-cidr4",
+	"chn-gateway": "chn-gateway4",
+	"cmn-cidr":    "cmn-cidr4",
+	"cmn-gateway": "cmn-gateway4",
+}
+
+// basecampAliases map the data.json keys of the basecamp globals that differ from their flag to the flag.
+var basecampAliases = map[string]string{
+	"can-gw": "can-gateway",
+	"cmn-gw": "cmn-gateway4",
+}
 
 type TemplateData struct {
 	Data      interface{}
@@ -65,38 +113,82 @@ type TemplateData struct {
 	Version   string
 }
 
-// RegisterAlias a viper alias for a given key, and adds the alias to the Aliases slice.
-func RegisterAlias(alias string, key string) {
-	Aliases = append(
-		Aliases,
-		alias,
-	)
-	v := viper.GetViper()
-	v.RegisterAlias(
-		alias,
-		key,
-	)
+// registerAliases registers every alias of aliases on v.
+func registerAliases(v *viper.Viper, aliases map[string]string) {
+	for _, alias := range slices.Sorted(maps.Keys(aliases)) {
+		v.RegisterAlias(
+			alias,
+			aliases[alias],
+		)
+	}
 }
 
 /*
 MakeTemplateData creates a TemplateData struct using the given interface. The returned struct has useful runtime
-data such as the program version and its runtime timestamp. This information can be used in templates to identify
+data such as the program version and the timestamp of the given runtime. This information can be used in templates to identify
 where they came from.
 */
-func MakeTemplateData(data interface{}) TemplateData {
+func MakeTemplateData(data interface{}, runtime time.Time) TemplateData {
 	return TemplateData{
 		Data:      data,
-		Timestamp: cli.RuntimeTimestamp,
+		Timestamp: runtime.Format(time.RFC3339Nano),
 		Version:   version.Get().String(),
 	}
 }
 
 func WriteConfigAs(path string) (err error) {
-	delConfig := viper.New()
-	err = delConfig.MergeConfigMap(viper.AllSettings())
+	return writeSettingsAs(
+		viper.AllSettings(),
+		path,
+	)
+}
+
+// RedactSecrets replaces the values of the SecretKeys in settings with secrets.Redacted, secret references are kept.
+func RedactSecrets(settings map[string]interface{}) {
+	for _, key := range SecretKeys {
+		if value, ok := settings[key].(string); ok {
+			settings[key] = secrets.Redact(value)
+		}
+	}
+}
+
+// writeSettingsAs writes settings as a config file, without the NoWriteKeys, DeprecatedKeys, and Aliases, and with the
+// SecretKeys redacted.
+func writeSettingsAs(settings map[string]interface{}, path string) (err error) {
+	finalConfig, err := writableSettings(
+		settings,
+		true,
+	)
 	if err != nil {
 		return err
 	}
+	return finalConfig.WriteConfigAs(path)
+}
+
+// renderSettings renders settings like writeSettingsAs does, as a config file of the given type (e.g. "yaml").
+func renderSettings(settings map[string]interface{}, configType string) ([]byte, error) {
+	finalConfig, err := writableSettings(
+		settings,
+		true,
+	)
+	if err != nil {
+		return nil, err
+	}
+	finalConfig.SetConfigType(configType)
+	var bs bytes.Buffer
+	err = finalConfig.WriteConfigTo(&bs)
+	return bs.Bytes(), err
+}
+
+// writableSettings returns a viper of settings without the NoWriteKeys, DeprecatedKeys, and Aliases, and with the
+// SecretKeys redacted when redact is set.
+func writableSettings(settings map[string]interface{}, redact bool) (*viper.Viper, error) {
+	delConfig := viper.New()
+	// MergeConfigMap lower-cases the keys of the maps it is given in place, merge a copy to leave settings as they are.
+	err := delConfig.MergeConfigMap(copySettings(settings).(map[string]interface{}))
+	if err != nil {
+		return nil, err
+	}
 	delConfigMap := delConfig.AllSettings()
 	for _, key := range NoWriteKeys {
 		delConfig.Set(
@@ -119,7 +211,10 @@ func WriteConfigAs(path string) (err error) {
 		)
 	}
 
-	for _, key := range Aliases {
+	for _, key := range slices.Concat(
+		slices.Collect(maps.Keys(AliasKeys)),
+		slices.Collect(maps.Keys(basecampAliases)),
+	) {
 		delConfig.Set(
 			key,
 			struct{}{},
@@ -129,11 +224,47 @@ func WriteConfigAs(path string) (err error) {
 			key,
 		)
 	}
+	if redact {
+		RedactSecrets(delConfigMap)
+	}
 	finalConfig := viper.New()
 	err = finalConfig.MergeConfigMap(delConfigMap)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return finalConfig, nil
+}
+
+// copySettings returns a deep copy of the maps and slices of a settings value, other values are shared.
+func copySettings(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		settings := make(
+			map[string]interface{},
+			len(value),
+		)
+		for key, setting := range value {
+			settings[key] = copySettings(setting)
+		}
+		return settings
+	case map[interface{}]interface{}:
+		settings := make(
+			map[interface{}]interface{},
+			len(value),
+		)
+		for key, setting := range value {
+			settings[key] = copySettings(setting)
+		}
+		return settings
+	case []interface{}:
+		settings := make(
+			[]interface{},
+			len(value),
+		)
+		for i, setting := range value {
+			settings[i] = copySettings(setting)
+		}
+		return settings
 	}
-	err = finalConfig.WriteConfigAs(path)
-	return err
+	return value
 }
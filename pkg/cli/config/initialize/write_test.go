@@ -0,0 +1,86 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package initialize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/secrets"
+)
+
+type WriteTestSuite struct {
+	suite.Suite
+}
+
+func (suite *WriteTestSuite) TestWriteSettingsAs() {
+	path := filepath.Join(
+		suite.T().TempDir(),
+		defaultConfigFilename,
+	)
+	suite.Require().NoError(
+		writeSettingsAs(
+			map[string]interface{}{
+				"bootstrap-ncn-bmc-pass": "changeme",
+				"bootstrap-ncn-bmc-user": "root",
+				"help":                   false,
+				"system-name":            "eniac",
+			},
+			path,
+		),
+	)
+	config, err := os.ReadFile(path)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"bootstrap-ncn-bmc-pass: "+secrets.Redacted+"\nbootstrap-ncn-bmc-user: root\nsystem-name: eniac\n",
+		string(config),
+	)
+
+	// A secret reference is written as it is, it can be resolved again.
+	suite.Require().NoError(
+		writeSettingsAs(
+			map[string]interface{}{
+				"bootstrap-ncn-bmc-pass": "env:BMC_PASS",
+			},
+			path,
+		),
+	)
+	config, err = os.ReadFile(path)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"bootstrap-ncn-bmc-pass: env:BMC_PASS\n",
+		string(config),
+	)
+}
+
+func TestWriteTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(WriteTestSuite),
+	)
+}
@@ -25,6 +25,7 @@
 package cloudinit
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -33,6 +34,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var profilesFile string
+
 // NewCommand represents the 'cloud-init' sub-command.
 func NewCommand() *cobra.Command {
 	c := &cobra.Command{
@@ -51,49 +54,72 @@ func NewCommand() *cobra.Command {
 // DisksCommand represents the 'template disks' sub-command.
 func DisksCommand() *cobra.Command {
 	c := &cobra.Command{
-		Use:               "disks",
-		Short:             "Process cloud-init disk templates",
-		Long:              `Process cloud-iniit meta-data for disks. Includes bootcmd, fs_setup, and mounts cloud-init user-data`,
+		Use:   "disks",
+		Short: "Process cloud-init disk templates",
+		Long: `Process cloud-iniit meta-data for disks. Includes bootcmd, fs_setup, and mounts cloud-init user-data
+
+	The user-data of each role is written to ncn-<role>/cloud-init/user-data.json. The built-in disk profiles put every
+	role on the metalvg0 volume group of /dev/md/AUX, other layouts can be given by a YAML file to the --profiles flag.
+	Its profiles are added to the built-in master, worker, storage, and fabric profiles (or replace them), and are mapped
+	to roles or to individual NCNs. The user-data of an NCN is written to <xname>/cloud-init/user-data.json:
+
+	profiles:
+	  nvme-worker:
+	    volume-group: metalvg0
+	    raid-device: /dev/md/NVME
+	    logical-volumes:
+	      - label: CRAYS3CACHE
+	        size: 200GB                 # or a percentage of the PVs, e.g. 25%PVS
+	        filesystem: ext4
+	        mount-point: /var/lib/s3fs_cache
+	      - label: CONLIB
+	        size: 60%PVS
+	        filesystem: xfs
+	        mount-point: /var/lib/containerd
+	        mount-options: defaults,nofail  # the default, mount-type defaults to the filesystem
+	roles:
+	  worker: nvme-worker
+	xnames:
+	  x3000c0s4b0n0: worker
+	`,
 		Args:              cobra.NoArgs,
 		DisableAutoGenTag: true,
 		Run: func(cmd *cobra.Command, args []string) {
-			WriteDiskTemplates()
+			diskProfiles, err := LoadDiskProfiles(profilesFile)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			WriteDiskTemplates(diskProfiles)
 		},
 	}
+	c.Flags().StringVar(
+		&profilesFile,
+		"profiles",
+		"",
+		"YAML file of disk profiles and the roles and xnames they are used for",
+	)
 	return c
 }
 
-// WriteDiskTemplates Write cloud-init user-data for disks (bootcmd, fs_setup, mounts) to files
-func WriteDiskTemplates() {
-	userDataMaps := map[string]map[string]map[string]interface{}{
-		"ncn-master/cloud-init/user-data.json": {
-			"user-data": {
-				"bootcmd":  MasterBootCMD,
-				"fs_setup": MasterFileSystems,
-				"mounts":   MasterMounts,
-			},
-		},
-		"ncn-worker/cloud-init/user-data.json": {
-			"user-data": {
-				"bootcmd":  WorkerBootCMD,
-				"fs_setup": WorkerFileSystems,
-				"mounts":   WorkerMounts,
-			},
-		},
-		"ncn-storage/cloud-init/user-data.json": {
-			"user-data": {
-				"bootcmd":  CephBootCMD,
-				"fs_setup": CephFileSystems,
-				"mounts":   CephMounts,
-			},
-		},
-		"ncn-fabric/cloud-init/user-data.json": {
-			"user-data": {
-				"bootcmd":  FabricManagerBootCMD,
-				"fs_setup": FabricManagerFileSystems,
-				"mounts":   FabricManagerMounts,
-			},
-		},
+// WriteDiskTemplates Write cloud-init user-data for disks (bootcmd, fs_setup, mounts) to files, for every role and
+// for every xname given its own profile.
+func WriteDiskTemplates(diskProfiles DiskProfiles) {
+	userDataMaps := make(map[string]map[string]map[string]interface{})
+	for _, role := range DiskRoles {
+		userDataMaps[fmt.Sprintf(
+			"ncn-%s/cloud-init/user-data.json",
+			role,
+		)] = map[string]map[string]interface{}{
+			"user-data": diskProfiles.Profiles[diskProfiles.Roles[role]].UserData(),
+		}
+	}
+	for xname, profile := range diskProfiles.Xnames {
+		userDataMaps[fmt.Sprintf(
+			"%s/cloud-init/user-data.json",
+			xname,
+		)] = map[string]map[string]interface{}{
+			"user-data": diskProfiles.Profiles[profile].UserData(),
+		}
 	}
 
 	for path, data := range userDataMaps {
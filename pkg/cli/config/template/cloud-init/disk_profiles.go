@@ -0,0 +1,374 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package cloudinit
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+)
+
+// DiskRoles are the NCN roles given a disk profile, each is written to ncn-<role>/cloud-init/user-data.json.
+var DiskRoles = []string{
+	"master",
+	"worker",
+	"storage",
+	"fabric",
+}
+
+// DiskProfiles are the named disk layouts of the NCNs, and the roles and xnames they are used for.
+type DiskProfiles struct {
+	Profiles map[string]DiskProfile `yaml:"profiles"`
+	// Roles maps each of the DiskRoles to a profile.
+	Roles map[string]string `yaml:"roles"`
+	// Xnames maps an NCN xname to a profile, taking precedence over the profile of its role.
+	Xnames map[string]string `yaml:"xnames"`
+}
+
+// DiskProfile is an LVM volume group on a RAID device, and the logical volumes created in it.
+type DiskProfile struct {
+	VolumeGroup    string          `yaml:"volume-group"`
+	RAIDDevice     string          `yaml:"raid-device"`
+	LogicalVolumes []LogicalVolume `yaml:"logical-volumes"`
+}
+
+// LogicalVolume is a logical volume, the filesystem made on it, and where it is mounted.
+type LogicalVolume struct {
+	// Label names both the logical volume and its filesystem.
+	Label string `yaml:"label"`
+	// Size is given to lvcreate, either as an absolute size (e.g. 200GB) or a percentage of the PVs (e.g. 25%PVS).
+	Size       string `yaml:"size"`
+	Filesystem string `yaml:"filesystem"`
+	MountPoint string `yaml:"mount-point"`
+	// MountType is the filesystem type in fstab, it defaults to Filesystem.
+	MountType string `yaml:"mount-type"`
+	// MountOptions defaults to defaults,nofail.
+	MountOptions string `yaml:"mount-options"`
+	// Overwrite lets cloud-init replace an existing filesystem, it defaults to true.
+	Overwrite *bool `yaml:"overwrite"`
+}
+
+// maxLabelLength is the longest filesystem label, xfs labels are at most 12 bytes.
+var maxLabelLength = map[string]int{
+	"ext4": 16,
+	"xfs":  12,
+}
+
+// BootCMD returns the cloud-init bootcmd creating the profile's volume group and logical volumes.
+func (profile DiskProfile) BootCMD() [][]string {
+	bootCMD := [][]string{
+		{
+			"cloud-init-per",
+			"once",
+			"create_PV",
+			"pvcreate",
+			"-ff",
+			"-y",
+			"-M",
+			"lvm2",
+			profile.RAIDDevice,
+		},
+		{
+			"cloud-init-per",
+			"once",
+			"create_VG",
+			"vgcreate",
+			profile.VolumeGroup,
+			profile.RAIDDevice,
+		},
+	}
+	for _, lv := range profile.LogicalVolumes {
+		sizeFlag := "-L"
+		if strings.Contains(
+			lv.Size,
+			"%",
+		) {
+			sizeFlag = "-l"
+		}
+		bootCMD = append(
+			bootCMD,
+			[]string{
+				"cloud-init-per",
+				"once",
+				fmt.Sprintf(
+					"create_LV_%s",
+					lv.Label,
+				),
+				"lvcreate",
+				sizeFlag,
+				lv.Size,
+				"-n",
+				lv.Label,
+				"-y",
+				profile.VolumeGroup,
+			},
+		)
+	}
+	return bootCMD
+}
+
+// FileSystems returns the cloud-init fs_setup of the profile's logical volumes.
+func (profile DiskProfile) FileSystems() []map[string]interface{} {
+	fileSystems := make(
+		[]map[string]interface{},
+		0,
+		len(profile.LogicalVolumes),
+	)
+	for _, lv := range profile.LogicalVolumes {
+		overwrite := true
+		if lv.Overwrite != nil {
+			overwrite = *lv.Overwrite
+		}
+		fileSystems = append(
+			fileSystems,
+			map[string]interface{}{
+				"label":      lv.Label,
+				"filesystem": lv.Filesystem,
+				"device": fmt.Sprintf(
+					"/dev/disk/by-id/dm-name-%s-%s",
+					profile.VolumeGroup,
+					lv.Label,
+				),
+				"partition": "auto",
+				"overwrite": overwrite,
+			},
+		)
+	}
+	return fileSystems
+}
+
+// Mounts returns the cloud-init mounts of the profile's logical volumes.
+func (profile DiskProfile) Mounts() [][]string {
+	mounts := make(
+		[][]string,
+		0,
+		len(profile.LogicalVolumes),
+	)
+	for _, lv := range profile.LogicalVolumes {
+		mountType := lv.MountType
+		if mountType == "" {
+			mountType = lv.Filesystem
+		}
+		mountOptions := lv.MountOptions
+		if mountOptions == "" {
+			mountOptions = "defaults,nofail"
+		}
+		mounts = append(
+			mounts,
+			[]string{
+				fmt.Sprintf(
+					"LABEL=%s",
+					lv.Label,
+				),
+				lv.MountPoint,
+				mountType,
+				mountOptions,
+			},
+		)
+	}
+	return mounts
+}
+
+// UserData returns the cloud-init user-data of the profile.
+func (profile DiskProfile) UserData() map[string]interface{} {
+	return map[string]interface{}{
+		"bootcmd":  profile.BootCMD(),
+		"fs_setup": profile.FileSystems(),
+		"mounts":   profile.Mounts(),
+	}
+}
+
+// Validate checks that the profile names a volume group and RAID device, and that the labels and mount points of its
+// logical volumes are unique.
+func (profile DiskProfile) Validate() error {
+	var errs []error
+	if profile.VolumeGroup == "" {
+		errs = append(
+			errs,
+			fmt.Errorf("missing volume-group"),
+		)
+	}
+	if profile.RAIDDevice == "" {
+		errs = append(
+			errs,
+			fmt.Errorf("missing raid-device"),
+		)
+	}
+	labels := make(map[string]bool)
+	mountPoints := make(map[string]string)
+	for _, lv := range profile.LogicalVolumes {
+		if lv.Label == "" || lv.Size == "" || lv.Filesystem == "" || lv.MountPoint == "" {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"logical volume %q needs a label, size, filesystem, and mount-point",
+					lv.Label,
+				),
+			)
+			continue
+		}
+		if labels[lv.Label] {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"label %s is used by more than one logical volume",
+					lv.Label,
+				),
+			)
+		}
+		labels[lv.Label] = true
+		if other, ok := mountPoints[lv.MountPoint]; ok {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"mount-point %s is used by both %s and %s",
+					lv.MountPoint,
+					other,
+					lv.Label,
+				),
+			)
+		}
+		mountPoints[lv.MountPoint] = lv.Label
+		if maxLength, ok := maxLabelLength[lv.Filesystem]; ok && len(lv.Label) > maxLength {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"label %s is longer than the %d bytes %s allows",
+					lv.Label,
+					maxLength,
+					lv.Filesystem,
+				),
+			)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks every profile, and that every role and xname is given a profile that exists.
+func (diskProfiles DiskProfiles) Validate() error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(diskProfiles.Profiles)) {
+		if err := diskProfiles.Profiles[name].Validate(); err != nil {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"profile %s is invalid because %v",
+					name,
+					err,
+				),
+			)
+		}
+	}
+	for _, role := range slices.Sorted(maps.Keys(diskProfiles.Roles)) {
+		if !slices.Contains(
+			DiskRoles,
+			role,
+		) {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"role %s is not one of %v",
+					role,
+					DiskRoles,
+				),
+			)
+		}
+	}
+	for _, role := range DiskRoles {
+		if _, ok := diskProfiles.Profiles[diskProfiles.Roles[role]]; !ok {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"role %s uses profile %q which does not exist",
+					role,
+					diskProfiles.Roles[role],
+				),
+			)
+		}
+	}
+	for _, xname := range slices.Sorted(maps.Keys(diskProfiles.Xnames)) {
+		if _, ok := diskProfiles.Profiles[diskProfiles.Xnames[xname]]; !ok {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"xname %s uses profile %q which does not exist",
+					xname,
+					diskProfiles.Xnames[xname],
+				),
+			)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadDiskProfiles reads the disk profiles in path on top of the DefaultDiskProfiles, a profile of the same name as a
+// built-in profile replaces it.
+func LoadDiskProfiles(path string) (diskProfiles DiskProfiles, err error) {
+	diskProfiles = DiskProfiles{
+		Profiles: maps.Clone(DefaultDiskProfiles.Profiles),
+		Roles:    maps.Clone(DefaultDiskProfiles.Roles),
+		Xnames:   make(map[string]string),
+	}
+	if path == "" {
+		return diskProfiles, nil
+	}
+	var file DiskProfiles
+	err = files.ReadYAMLConfig(
+		path,
+		&file,
+	)
+	if err != nil {
+		return diskProfiles, fmt.Errorf(
+			"unable to parse disk profiles [%s] because %v",
+			path,
+			err,
+		)
+	}
+	maps.Copy(
+		diskProfiles.Profiles,
+		file.Profiles,
+	)
+	maps.Copy(
+		diskProfiles.Roles,
+		file.Roles,
+	)
+	maps.Copy(
+		diskProfiles.Xnames,
+		file.Xnames,
+	)
+	err = diskProfiles.Validate()
+	if err != nil {
+		return diskProfiles, fmt.Errorf(
+			"invalid disk profiles [%s] because %v",
+			path,
+			err,
+		)
+	}
+	return diskProfiles, nil
+}
@@ -0,0 +1,198 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package cloudinit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const diskFixtureDir = "../../../../../testdata/fixtures/cloud-init"
+
+const nvmeProfilesYAML = `profiles:
+  nvme-worker:
+    volume-group: metalvg1
+    raid-device: /dev/md/NVME
+    logical-volumes:
+      - label: CRAYS3CACHE
+        size: 200GB
+        filesystem: ext4
+        mount-point: /var/lib/s3fs_cache
+      - label: CONLIB
+        size: 60%PVS
+        filesystem: xfs
+        mount-point: /var/lib/containerd
+roles:
+  worker: nvme-worker
+xnames:
+  x3000c0s4b0n0: master
+`
+
+type DiskProfilesTestSuite struct {
+	suite.Suite
+}
+
+// writeProfiles writes the disk profiles to a temporary file.
+func (suite *DiskProfilesTestSuite) writeProfiles(contents string) string {
+	path := filepath.Join(
+		suite.T().TempDir(),
+		"profiles.yaml",
+	)
+	suite.Require().NoError(
+		os.WriteFile(
+			path,
+			[]byte(contents),
+			0644,
+		),
+	)
+	return path
+}
+
+// readUserData returns the user-data written to dir for the role or xname.
+func (suite *DiskProfilesTestSuite) readUserData(dir string, name string) string {
+	contents, err := os.ReadFile(
+		filepath.Join(
+			dir,
+			name,
+			"cloud-init",
+			"user-data.json",
+		),
+	)
+	suite.Require().NoError(err)
+	return string(contents)
+}
+
+func (suite *DiskProfilesTestSuite) TestWriteDiskTemplates_Default() {
+	fixtureDir, err := filepath.Abs(diskFixtureDir)
+	suite.Require().NoError(err)
+	dir := suite.T().TempDir()
+	suite.T().Chdir(dir)
+
+	diskProfiles, err := LoadDiskProfiles("")
+	suite.Require().NoError(err)
+	suite.Require().NoError(diskProfiles.Validate())
+	WriteDiskTemplates(diskProfiles)
+	// The built-in profiles write the same user-data as the layouts they replaced.
+	for _, role := range DiskRoles {
+		suite.Equal(
+			suite.readUserData(
+				fixtureDir,
+				"ncn-"+role,
+			),
+			suite.readUserData(
+				dir,
+				"ncn-"+role,
+			),
+			role,
+		)
+	}
+}
+
+func (suite *DiskProfilesTestSuite) TestWriteDiskTemplates_Profiles() {
+	diskProfiles, err := LoadDiskProfiles(suite.writeProfiles(nvmeProfilesYAML))
+	suite.Require().NoError(err)
+	dir := suite.T().TempDir()
+	suite.T().Chdir(dir)
+	WriteDiskTemplates(diskProfiles)
+
+	worker := diskProfiles.Profiles["nvme-worker"]
+	suite.Equal(
+		[]string{
+			"cloud-init-per",
+			"once",
+			"create_LV_CONLIB",
+			"lvcreate",
+			"-l",
+			"60%PVS",
+			"-n",
+			"CONLIB",
+			"-y",
+			"metalvg1",
+		},
+		worker.BootCMD()[3],
+	)
+	suite.Contains(
+		suite.readUserData(
+			dir,
+			"ncn-worker",
+		),
+		"/dev/disk/by-id/dm-name-metalvg1-CONLIB",
+	)
+	suite.Equal(
+		suite.readUserData(
+			dir,
+			"ncn-master",
+		),
+		suite.readUserData(
+			dir,
+			"x3000c0s4b0n0",
+		),
+	)
+}
+
+func (suite *DiskProfilesTestSuite) TestLoadDiskProfiles_Invalid() {
+	_, err := LoadDiskProfiles(
+		suite.writeProfiles(`profiles:
+  worker:
+    volume-group: metalvg0
+    raid-device: /dev/md/AUX
+    logical-volumes:
+      - label: CONTAINERDLIB
+        size: 60%PVS
+        filesystem: xfs
+        mount-point: /var/lib/containerd
+      - label: CONTAINERDLIB
+        size: 20%PVS
+        filesystem: ext4
+        mount-point: /var/lib/containerd
+roles:
+  compute: worker
+xnames:
+  x3000c0s4b0n0: nvme-worker
+`),
+	)
+	for _, expected := range []string{
+		"label CONTAINERDLIB is longer than the 12 bytes xfs allows",
+		"label CONTAINERDLIB is used by more than one logical volume",
+		"mount-point /var/lib/containerd is used by both CONTAINERDLIB and CONTAINERDLIB",
+		"role compute is not one of [master worker storage fabric]",
+		`xname x3000c0s4b0n0 uses profile "nvme-worker" which does not exist`,
+	} {
+		suite.ErrorContains(
+			err,
+			expected,
+		)
+	}
+}
+
+func TestDiskProfilesTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(DiskProfilesTestSuite),
+	)
+}
@@ -24,8 +24,6 @@
 
 package cloudinit
 
-import "fmt"
-
 // Provides configuration for lvm, filesystems and mounts for NCNS.
 const (
 	crays3cache = "CRAYS3CACHE"
@@ -41,376 +39,145 @@ const (
 	raidArray   = "/dev/md/AUX"
 )
 
-// MasterBootCMD (cloud-init user-data)
-var MasterBootCMD = [][]string{
-	{
-		"cloud-init-per",
-		"once",
-		"create_PV",
-		"pvcreate",
-		"-ff",
-		"-y",
-		"-M",
-		"lvm2",
-		raidArray,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		"create_VG",
-		"vgcreate",
-		volumeGroup,
-		raidArray,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", crays3cache),
-		"lvcreate",
-		"-l",
-		"25%PVS",
-		"-n",
-		crays3cache,
-		"-y",
-		volumeGroup,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", conrun),
-		"lvcreate",
-		"-l",
-		"4%PVS",
-		"-n",
-		conrun,
-		"-y",
-		volumeGroup,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", conlib),
-		"lvcreate",
-		"-l",
-		"36%PVS",
-		"-n",
-		conlib,
-		"-y",
-		volumeGroup,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", k8slet),
-		"lvcreate",
-		"-l",
-		"10%PVS",
-		"-n",
-		k8slet,
-		"-y",
-		volumeGroup,
+// noOverwrite keeps the filesystem cloud-init finds on a logical volume.
+var noOverwrite = false
+
+// DefaultDiskProfiles are the built-in disk profiles, one per role.
+var DefaultDiskProfiles = DiskProfiles{
+	Profiles: map[string]DiskProfile{
+		"master": {
+			VolumeGroup: volumeGroup,
+			RAIDDevice:  raidArray,
+			LogicalVolumes: []LogicalVolume{
+				{
+					Label:      crays3cache,
+					Size:       "25%PVS",
+					Filesystem: "ext4",
+					MountPoint: "/var/lib/s3fs_cache",
+				},
+				{
+					Label:      conrun,
+					Size:       "4%PVS",
+					Filesystem: "xfs",
+					MountPoint: "/run/containerd",
+				},
+				{
+					Label:      conlib,
+					Size:       "36%PVS",
+					Filesystem: "xfs",
+					MountPoint: "/var/lib/containerd",
+				},
+				{
+					Label:      k8slet,
+					Size:       "10%PVS",
+					Filesystem: "xfs",
+					MountPoint: "/var/lib/kubelet",
+				},
+			},
+		},
+		"worker": {
+			VolumeGroup: volumeGroup,
+			RAIDDevice:  raidArray,
+			LogicalVolumes: []LogicalVolume{
+				{
+					Label:      crays3cache,
+					Size:       "200GB",
+					Filesystem: "ext4",
+					MountPoint: "/var/lib/s3fs_cache",
+				},
+			},
+		},
+		"storage": {
+			VolumeGroup: volumeGroup,
+			RAIDDevice:  raidArray,
+			LogicalVolumes: []LogicalVolume{
+				{
+					Label:        cephetc,
+					Size:         "10GB",
+					Filesystem:   "ext4",
+					MountPoint:   "/etc/ceph",
+					MountType:    "auto",
+					MountOptions: "defaults",
+				},
+				{
+					Label:        cephvar,
+					Size:         "60GB",
+					Filesystem:   "ext4",
+					MountPoint:   "/var/lib/ceph",
+					MountType:    "auto",
+					MountOptions: "defaults",
+				},
+				{
+					Label:        contain,
+					Size:         "60GB",
+					Filesystem:   "xfs",
+					MountPoint:   "/var/lib/containers",
+					MountType:    "auto",
+					MountOptions: "defaults",
+				},
+			},
+		},
+		"fabric": {
+			VolumeGroup: volumeGroup,
+			RAIDDevice:  raidArray,
+			LogicalVolumes: []LogicalVolume{
+				{
+					Label:      scfirmware,
+					Size:       "80GB",
+					Filesystem: "ext4",
+					MountPoint: "/opt/cray/FW/sc-firmware",
+					Overwrite:  &noOverwrite,
+				},
+				{
+					Label:      slingshot,
+					Size:       "120GB",
+					Filesystem: "ext4",
+					MountPoint: "/opt/slingshot",
+					Overwrite:  &noOverwrite,
+				},
+			},
+		},
+	},
+	Roles: map[string]string{
+		"master":  "master",
+		"worker":  "worker",
+		"storage": "storage",
+		"fabric":  "fabric",
 	},
 }
 
+// MasterBootCMD (cloud-init user-data)
+var MasterBootCMD = DefaultDiskProfiles.Profiles["master"].BootCMD()
+
 // MasterFileSystems (cloud-init user-data)
-var MasterFileSystems = []map[string]interface{}{
-	{
-		"label":      crays3cache,
-		"filesystem": "ext4",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, crays3cache),
-		"partition":  "auto",
-		"overwrite":  true,
-	},
-	{
-		"label":      conrun,
-		"filesystem": "xfs",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, conrun),
-		"partition":  "auto",
-		"overwrite":  true,
-	},
-	{
-		"label":      conlib,
-		"filesystem": "xfs",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, conlib),
-		"partition":  "auto",
-		"overwrite":  true,
-	},
-	{
-		"label":      k8slet,
-		"filesystem": "xfs",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, k8slet),
-		"partition":  "auto",
-		"overwrite":  true,
-	},
-}
+var MasterFileSystems = DefaultDiskProfiles.Profiles["master"].FileSystems()
 
 // MasterMounts (cloud-init user-data)
-var MasterMounts = [][]string{
-	{
-		fmt.Sprintf("LABEL=%s", crays3cache),
-		"/var/lib/s3fs_cache",
-		"ext4",
-		"defaults,nofail",
-	},
-	{
-		fmt.Sprintf("LABEL=%s", conrun),
-		"/run/containerd",
-		"xfs",
-		"defaults,nofail",
-	},
-	{
-		fmt.Sprintf("LABEL=%s", conlib),
-		"/var/lib/containerd",
-		"xfs",
-		"defaults,nofail",
-	},
-	{
-		fmt.Sprintf("LABEL=%s", k8slet),
-		"/var/lib/kubelet",
-		"xfs",
-		"defaults,nofail",
-	},
-}
+var MasterMounts = DefaultDiskProfiles.Profiles["master"].Mounts()
 
 // WorkerBootCMD (cloud-init user-data)
-var WorkerBootCMD = [][]string{
-	{
-		"cloud-init-per",
-		"once",
-		"create_PV",
-		"pvcreate",
-		"-ff",
-		"-y",
-		"-M",
-		"lvm2",
-		raidArray,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		"create_VG",
-		"vgcreate",
-		volumeGroup,
-		raidArray,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", crays3cache),
-		"lvcreate",
-		"-L",
-		"200GB",
-		"-n",
-		crays3cache,
-		"-y",
-		volumeGroup,
-	},
-}
+var WorkerBootCMD = DefaultDiskProfiles.Profiles["worker"].BootCMD()
 
 // WorkerFileSystems (cloud-init user-data)
-var WorkerFileSystems = []map[string]interface{}{
-	{
-		"label":      crays3cache,
-		"filesystem": "ext4",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, crays3cache),
-		"partition":  "auto",
-		"overwrite":  true,
-	},
-}
+var WorkerFileSystems = DefaultDiskProfiles.Profiles["worker"].FileSystems()
 
 // WorkerMounts (cloud-init user-data)
-var WorkerMounts = [][]string{
-	{
-		fmt.Sprintf("LABEL=%s", crays3cache),
-		"/var/lib/s3fs_cache",
-		"ext4",
-		"defaults,nofail",
-	},
-}
+var WorkerMounts = DefaultDiskProfiles.Profiles["worker"].Mounts()
 
 // CephBootCMD (cloud-init user-data)
-var CephBootCMD = [][]string{
-	{
-		"cloud-init-per",
-		"once",
-		"create_PV",
-		"pvcreate",
-		"-ff",
-		"-y",
-		"-M",
-		"lvm2",
-		raidArray,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		"create_VG",
-		"vgcreate",
-		volumeGroup,
-		raidArray,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", cephetc),
-		"lvcreate",
-		"-L",
-		"10GB",
-		"-n",
-		cephetc,
-		"-y",
-		volumeGroup,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", cephvar),
-		"lvcreate",
-		"-L",
-		"60GB",
-		"-n",
-		cephvar,
-		"-y",
-		volumeGroup,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", contain),
-		"lvcreate",
-		"-L",
-		"60GB",
-		"-n",
-		contain,
-		"-y",
-		volumeGroup,
-	},
-}
+var CephBootCMD = DefaultDiskProfiles.Profiles["storage"].BootCMD()
 
 // CephFileSystems (cloud-init user-data)
-var CephFileSystems = []map[string]interface{}{
-	{
-		"label":      cephetc,
-		"filesystem": "ext4",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, cephetc),
-		"partition":  "auto",
-		"overwrite":  true,
-	},
-	{
-		"label":      cephvar,
-		"filesystem": "ext4",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, cephvar),
-		"partition":  "auto",
-		"overwrite":  true,
-	},
-	{
-		"label":      contain,
-		"filesystem": "xfs",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, contain),
-		"partition":  "auto",
-		"overwrite":  true,
-	},
-}
+var CephFileSystems = DefaultDiskProfiles.Profiles["storage"].FileSystems()
 
 // CephMounts (cloud-init user-data)
-var CephMounts = [][]string{
-	{
-		fmt.Sprintf("LABEL=%s", cephetc),
-		"/etc/ceph",
-		"auto",
-		"defaults",
-	},
-	{
-		fmt.Sprintf("LABEL=%s", cephvar),
-		"/var/lib/ceph",
-		"auto",
-		"defaults",
-	},
-	{
-		fmt.Sprintf("LABEL=%s", contain),
-		"/var/lib/containers",
-		"auto",
-		"defaults",
-	},
-}
+var CephMounts = DefaultDiskProfiles.Profiles["storage"].Mounts()
 
 // FabricManagerBootCMD (cloud-init user-data)
-var FabricManagerBootCMD = [][]string{
-	{
-		"cloud-init-per",
-		"once",
-		"create_PV",
-		"pvcreate",
-		"-ff",
-		"-y",
-		"-M",
-		"lvm2",
-		raidArray,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		"create_VG",
-		"vgcreate",
-		volumeGroup,
-		raidArray,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", scfirmware),
-		"lvcreate",
-		"-L",
-		"80GB",
-		"-n",
-		scfirmware,
-		"-y",
-		volumeGroup,
-	},
-	{
-		"cloud-init-per",
-		"once",
-		fmt.Sprintf("create_LV_%s", slingshot),
-		"lvcreate",
-		"-L",
-		"120GB",
-		"-n",
-		slingshot,
-		"-y",
-		volumeGroup,
-	},
-}
+var FabricManagerBootCMD = DefaultDiskProfiles.Profiles["fabric"].BootCMD()
 
 // FabricManagerFileSystems (cloud-init user-data)
-var FabricManagerFileSystems = []map[string]interface{}{
-	{
-		"label":      scfirmware,
-		"filesystem": "ext4",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, scfirmware),
-		"partition":  "auto",
-		"overwrite":  false,
-	},
-	{
-		"label":      slingshot,
-		"filesystem": "ext4",
-		"device":     fmt.Sprintf("/dev/disk/by-id/dm-name-%s-%s", volumeGroup, slingshot),
-		"partition":  "auto",
-		"overwrite":  false,
-	},
-}
+var FabricManagerFileSystems = DefaultDiskProfiles.Profiles["fabric"].FileSystems()
 
 // FabricManagerMounts (cloud-init user-data)
-var FabricManagerMounts = [][]string{
-	{
-		fmt.Sprintf("LABEL=%s", scfirmware),
-		"/opt/cray/FW/sc-firmware",
-		"ext4",
-		"defaults,nofail",
-	},
-	{
-		fmt.Sprintf("LABEL=%s", slingshot),
-		"/opt/slingshot",
-		"ext4",
-		"defaults,nofail",
-	},
-}
+var FabricManagerMounts = DefaultDiskProfiles.Profiles["fabric"].Mounts()
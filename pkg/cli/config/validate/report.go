@@ -0,0 +1,385 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package validate
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Problem is a key of the config file that is not what its flag expects, or a flag group it violates.
+type Problem struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// Report is every problem found in a config file. Errors would make the config be ignored or rejected, warnings are
+// keys that still work but should be looked at.
+type Report struct {
+	Errors   []Problem `json:"errors"`
+	Warnings []Problem `json:"warnings"`
+}
+
+// Empty returns whether the config file has no problems.
+func (report Report) Empty() bool {
+	return len(report.Errors) == 0 && len(report.Warnings) == 0
+}
+
+// WriteText writes the report in a human-readable form.
+func (report Report) WriteText(w io.Writer) (err error) {
+	for _, section := range []struct {
+		name     string
+		problems []Problem
+	}{
+		{
+			"errors",
+			report.Errors,
+		},
+		{
+			"warnings",
+			report.Warnings,
+		},
+	} {
+		if len(section.problems) == 0 {
+			continue
+		}
+		_, err = fmt.Fprintf(
+			w,
+			"%s:\n",
+			section.name,
+		)
+		if err != nil {
+			return err
+		}
+		for _, problem := range section.problems {
+			_, err = fmt.Fprintf(
+				w,
+				"  %s: %s\n",
+				problem.Key,
+				problem.Message,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (report *Report) addError(key string, format string, a ...interface{}) {
+	report.Errors = append(
+		report.Errors,
+		Problem{
+			Key: key,
+			Message: fmt.Sprintf(
+				format,
+				a...,
+			),
+		},
+	)
+}
+
+func (report *Report) addWarning(key string, format string, a ...interface{}) {
+	report.Warnings = append(
+		report.Warnings,
+		Problem{
+			Key: key,
+			Message: fmt.Sprintf(
+				format,
+				a...,
+			),
+		},
+	)
+}
+
+/*
+Validate checks every key of config against the flag it sets, where aliases maps every alias to the flag it stands for
+and configKeys are the keys without a flag:
+1. Keys that are not a flag, an alias, or a config key are reported with the nearest flag as a suggestion
+2. Values are parsed by their flag, so the flag set should not be used for anything else
+3. Deprecated keys are warned about, as are required flags the config does not set
+4. The cobra flag groups (required together, one required, mutually exclusive) are checked against the keys that are set
+*/
+func Validate(flags *pflag.FlagSet, aliases map[string]string, configKeys []string, config map[string]interface{}) (report Report) {
+	// Viper matches keys regardless of their case.
+	flagsByKey := make(map[string]*pflag.Flag)
+	var candidates []string
+	flags.VisitAll(
+		func(flag *pflag.Flag) {
+			flagsByKey[strings.ToLower(flag.Name)] = flag
+			candidates = append(
+				candidates,
+				flag.Name,
+			)
+		},
+	)
+	for alias, name := range aliases {
+		flag := flagsByKey[strings.ToLower(name)]
+		if _, ok := flagsByKey[strings.ToLower(alias)]; !ok && flag != nil {
+			flagsByKey[strings.ToLower(alias)] = flag
+		}
+		candidates = append(
+			candidates,
+			alias,
+		)
+	}
+
+	set := make(map[string]bool)
+	for _, key := range slices.Sorted(maps.Keys(config)) {
+		if _, ok := generatedKeys[strings.ToLower(key)]; ok || slices.Contains(
+			configKeys,
+			strings.ToLower(key),
+		) {
+			continue
+		}
+		flag := flagsByKey[strings.ToLower(key)]
+		if flag == nil {
+			if suggestion := nearest(
+				strings.ToLower(key),
+				candidates,
+			); suggestion != "" {
+				report.addError(
+					key,
+					"unknown key, did you mean %s?",
+					suggestion,
+				)
+			} else {
+				report.addError(
+					key,
+					"unknown key",
+				)
+			}
+			continue
+		}
+		if flag.Deprecated != "" {
+			report.addWarning(
+				key,
+				"deprecated, %s",
+				flag.Deprecated,
+			)
+			// A deprecated key only sets the flag that replaced it.
+			if name, ok := aliases[flag.Name]; ok {
+				set[name] = true
+			}
+		} else {
+			set[flag.Name] = true
+		}
+		value, ok := flagValue(config[key])
+		if !ok {
+			report.addError(
+				key,
+				"must be of type %s",
+				propertyForType(flag.Value.Type()).Type,
+			)
+			continue
+		}
+		err := flag.Value.Set(value)
+		if err != nil {
+			report.addError(
+				key,
+				"invalid value %q because %v",
+				value,
+				err,
+			)
+		}
+	}
+
+	groups := NewFlagGroups(flags)
+	for _, name := range groups.Required {
+		if !set[name] {
+			report.addWarning(
+				name,
+				"required, it must be given on the command line",
+			)
+		}
+	}
+	for _, group := range groups.RequiredTogether {
+		var missing []string
+		for _, name := range group {
+			if !set[name] {
+				missing = append(
+					missing,
+					name,
+				)
+			}
+		}
+		if len(missing) > 0 && len(missing) < len(group) {
+			report.addError(
+				strings.Join(
+					group,
+					" ",
+				),
+				"must be set together, missing %s",
+				strings.Join(
+					missing,
+					" ",
+				),
+			)
+		}
+	}
+	for _, group := range groups.OneRequired {
+		if !slices.ContainsFunc(
+			group,
+			func(name string) bool {
+				return set[name]
+			},
+		) {
+			report.addError(
+				strings.Join(
+					group,
+					" ",
+				),
+				"one of them must be set",
+			)
+		}
+	}
+	for _, group := range groups.MutuallyExclusive {
+		var found []string
+		for _, name := range group {
+			if set[name] {
+				found = append(
+					found,
+					name,
+				)
+			}
+		}
+		if len(found) > 1 {
+			report.addError(
+				strings.Join(
+					group,
+					" ",
+				),
+				"only one of them can be set, found %s",
+				strings.Join(
+					found,
+					" ",
+				),
+			)
+		}
+	}
+	return report
+}
+
+// flagValue returns value the way a flag is set from the command line, lists are comma-separated.
+func flagValue(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case nil:
+		return "", true
+	case map[string]interface{}:
+		return "", false
+	case []interface{}:
+		items := make(
+			[]string,
+			0,
+			len(value),
+		)
+		for _, item := range value {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return "", false
+			}
+			items = append(
+				items,
+				fmt.Sprint(item),
+			)
+		}
+		return strings.Join(
+			items,
+			",",
+		), true
+	default:
+		return fmt.Sprint(value), true
+	}
+}
+
+// nearest returns the candidate closest to key, or nothing if none is close enough to be what was meant.
+func nearest(key string, candidates []string) (suggestion string) {
+	best := max(
+		2,
+		len(key)/4,
+	) + 1
+	bestPrefix := 0
+	for _, candidate := range slices.Sorted(slices.Values(candidates)) {
+		distance := levenshtein(
+			key,
+			strings.ToLower(candidate),
+		)
+		// Ties go to the candidate that starts like the key, a typo is more likely at its end.
+		prefix := commonPrefix(
+			key,
+			strings.ToLower(candidate),
+		)
+		if distance < best || distance == best && prefix > bestPrefix {
+			best = distance
+			bestPrefix = prefix
+			suggestion = candidate
+		}
+	}
+	return suggestion
+}
+
+// levenshtein returns the number of single character edits that turn a into b.
+func levenshtein(a string, b string) int {
+	previous := make(
+		[]int,
+		len(b)+1,
+	)
+	current := make(
+		[]int,
+		len(b)+1,
+	)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitution := previous[j-1]
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			current[j] = min(
+				previous[j]+1,
+				current[j-1]+1,
+				substitution,
+			)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// commonPrefix returns the length of the prefix a and b share.
+func commonPrefix(a string, b string) (n int) {
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
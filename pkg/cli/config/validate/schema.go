@@ -0,0 +1,322 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package validate
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// The annotations cobra keeps its flag groups and required flags in.
+const (
+	requiredTogetherAnnotation  = "cobra_annotation_required_if_others_set"
+	oneRequiredAnnotation       = "cobra_annotation_one_required"
+	mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+	requiredAnnotation          = "cobra_annotation_bash_completion_one_required_flag"
+)
+
+// generatedKeys are the keys csi writes to a config file that are not flags, keyed by their lower case name.
+var generatedKeys = map[string]Property{
+	"versioninfo": {
+		Type:        "object",
+		Description: "The csi build that wrote the config file",
+	},
+}
+
+// Schema is a JSON Schema (draft 2020-12) of a config file, where every key is a flag.
+type Schema struct {
+	Schema               string               `json:"$schema"`
+	Title                string               `json:"title"`
+	Type                 string               `json:"type"`
+	Properties           map[string]*Property `json:"properties"`
+	Required             []string             `json:"required,omitempty"`
+	DependentRequired    map[string][]string  `json:"dependentRequired,omitempty"`
+	AllOf                []Constraint         `json:"allOf,omitempty"`
+	AdditionalProperties bool                 `json:"additionalProperties"`
+}
+
+// Property is the schema of a single key.
+type Property struct {
+	Ref         string      `json:"$ref,omitempty"`
+	Type        string      `json:"type,omitempty"`
+	Items       *Property   `json:"items,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Deprecated  bool        `json:"deprecated,omitempty"`
+}
+
+// Constraint is a subschema over several keys, used for the flag groups.
+type Constraint struct {
+	Not      *Constraint  `json:"not,omitempty"`
+	AnyOf    []Constraint `json:"anyOf,omitempty"`
+	Required []string     `json:"required,omitempty"`
+}
+
+// FlagGroups are the cobra flag groups of a flag set, each group is a list of flag names.
+type FlagGroups struct {
+	RequiredTogether  [][]string
+	OneRequired       [][]string
+	MutuallyExclusive [][]string
+	// Required are the flags that must always be set.
+	Required []string
+}
+
+// NewFlagGroups collects the flag groups cobra annotated the flags with.
+func NewFlagGroups(flags *pflag.FlagSet) (groups FlagGroups) {
+	seen := make(map[string]bool)
+	flags.VisitAll(
+		func(flag *pflag.Flag) {
+			if len(flag.Annotations[requiredAnnotation]) > 0 {
+				groups.Required = append(
+					groups.Required,
+					flag.Name,
+				)
+			}
+			for annotation, group := range map[string]*[][]string{
+				requiredTogetherAnnotation:  &groups.RequiredTogether,
+				oneRequiredAnnotation:       &groups.OneRequired,
+				mutuallyExclusiveAnnotation: &groups.MutuallyExclusive,
+			} {
+				for _, names := range flag.Annotations[annotation] {
+					if seen[annotation+names] {
+						continue
+					}
+					seen[annotation+names] = true
+					*group = append(
+						*group,
+						strings.Split(
+							names,
+							" ",
+						),
+					)
+				}
+			}
+		},
+	)
+	for _, group := range []*[][]string{
+		&groups.RequiredTogether,
+		&groups.OneRequired,
+		&groups.MutuallyExclusive,
+	} {
+		slices.SortFunc(
+			*group,
+			slices.Compare[[]string],
+		)
+	}
+	slices.Sort(groups.Required)
+	return groups
+}
+
+/*
+NewSchema builds the schema of a config file for the flags, where aliases maps every alias to the flag it stands for.
+The configKeys are keys without a flag, their values are not checked.
+*/
+func NewSchema(title string, flags *pflag.FlagSet, aliases map[string]string, configKeys []string) Schema {
+	schema := Schema{
+		Schema:     "https://json-schema.org/draft/2020-12/schema",
+		Title:      title,
+		Type:       "object",
+		Properties: make(map[string]*Property),
+	}
+	flags.VisitAll(
+		func(flag *pflag.Flag) {
+			property := propertyForType(flag.Value.Type())
+			property.Description = flag.Usage
+			property.Default = flagDefault(
+				flag,
+				property,
+			)
+			if flag.Deprecated != "" {
+				property.Deprecated = true
+				property.Description = fmt.Sprintf(
+					"%s (deprecated, %s)",
+					flag.Usage,
+					strings.TrimSuffix(
+						flag.Deprecated,
+						".",
+					),
+				)
+			}
+			schema.Properties[flag.Name] = property
+		},
+	)
+	for _, key := range configKeys {
+		if _, ok := schema.Properties[key]; !ok {
+			schema.Properties[key] = &Property{
+				Description: "Only read from the config file",
+			}
+		}
+	}
+	for key, property := range generatedKeys {
+		schema.Properties[key] = &property
+	}
+	for _, alias := range slices.Sorted(maps.Keys(aliases)) {
+		if _, ok := schema.Properties[alias]; ok {
+			continue
+		}
+		schema.Properties[alias] = &Property{
+			Ref: "#/properties/" + aliases[alias],
+			Description: fmt.Sprintf(
+				"Alias of %s",
+				aliases[alias],
+			),
+		}
+	}
+
+	groups := NewFlagGroups(flags)
+	schema.Required = groups.Required
+	for _, group := range groups.RequiredTogether {
+		if schema.DependentRequired == nil {
+			schema.DependentRequired = make(map[string][]string)
+		}
+		for _, name := range group {
+			for _, other := range group {
+				if other != name && !slices.Contains(
+					schema.DependentRequired[name],
+					other,
+				) {
+					schema.DependentRequired[name] = append(
+						schema.DependentRequired[name],
+						other,
+					)
+				}
+			}
+		}
+	}
+	for _, group := range groups.OneRequired {
+		constraint := Constraint{}
+		for _, name := range group {
+			constraint.AnyOf = append(
+				constraint.AnyOf,
+				Constraint{
+					Required: []string{name},
+				},
+			)
+		}
+		schema.AllOf = append(
+			schema.AllOf,
+			constraint,
+		)
+	}
+	for _, group := range groups.MutuallyExclusive {
+		for i, name := range group {
+			for _, other := range group[i+1:] {
+				schema.AllOf = append(
+					schema.AllOf,
+					Constraint{
+						Not: &Constraint{
+							Required: []string{
+								name,
+								other,
+							},
+						},
+					},
+				)
+			}
+		}
+	}
+	return schema
+}
+
+// propertyForType returns the property of a pflag value type.
+func propertyForType(valueType string) *Property {
+	for _, suffix := range []string{
+		"Slice",
+		"Array",
+	} {
+		if itemType, ok := strings.CutSuffix(
+			valueType,
+			suffix,
+		); ok {
+			return &Property{
+				Type:  "array",
+				Items: propertyForType(itemType),
+			}
+		}
+	}
+	switch {
+	case valueType == "bool":
+		return &Property{Type: "boolean"}
+	case strings.HasPrefix(
+		valueType,
+		"int",
+	), strings.HasPrefix(
+		valueType,
+		"uint",
+	):
+		return &Property{Type: "integer"}
+	case strings.HasPrefix(
+		valueType,
+		"float",
+	):
+		return &Property{Type: "number"}
+	default:
+		return &Property{Type: "string"}
+	}
+}
+
+// flagDefault returns the default of the flag as the type of its property.
+func flagDefault(flag *pflag.Flag, property *Property) interface{} {
+	switch property.Type {
+	case "boolean":
+		value, err := strconv.ParseBool(flag.DefValue)
+		if err != nil {
+			return nil
+		}
+		return value
+	case "integer":
+		value, err := strconv.ParseInt(
+			flag.DefValue,
+			10,
+			64,
+		)
+		if err != nil {
+			return nil
+		}
+		return value
+	case "number":
+		value, err := strconv.ParseFloat(
+			flag.DefValue,
+			64,
+		)
+		if err != nil {
+			return nil
+		}
+		return value
+	case "array":
+		values := []string{}
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok && !flag.Changed {
+			values = sliceValue.GetSlice()
+		}
+		return values
+	default:
+		return flag.DefValue
+	}
+}
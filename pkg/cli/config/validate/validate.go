@@ -0,0 +1,177 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize"
+)
+
+// NewCommand represents the validate command.
+func NewCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "validate [FILE]",
+		Short: "Checks a system_config.yaml against the flags of 'csi config init'",
+		Long: `Checks a config file for 'csi config init' (e.g. system_config.yaml) before it is used.
+
+	Every key of the config file sets the 'csi config init' flag of the same name, but keys that are not a flag are
+	ignored without a word. The config file is checked against the flags instead:
+	1. Keys that are not a flag or an alias are errors, with the nearest flag as a suggestion
+	2. Values that their flag can not parse are errors
+	3. Flags that must be set together, or that can not be set together, are errors
+	4. Deprecated keys, and required flags that are not set, are warnings
+
+	The JSON Schema of the config file is printed by --schema, it is built from the same flags.
+	`,
+		Args:              cobra.MaximumNArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(c *cobra.Command, args []string) {
+			v := viper.GetViper()
+			err := v.BindPFlags(c.Flags())
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			initCommand := initialize.NewCommand()
+			if v.GetBool("schema") {
+				b, err := json.MarshalIndent(
+					NewSchema(
+						"csi config init",
+						initCommand.Flags(),
+						initialize.AliasKeys,
+						configKeys(),
+					),
+					"",
+					"  ",
+				)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				fmt.Println(string(b))
+				return
+			}
+			if len(args) != 1 {
+				log.Fatalln("validate needs a config file, or --schema")
+			}
+
+			config, err := readConfig(args[0])
+			if err != nil {
+				log.Fatalln(err)
+			}
+			report := Validate(
+				initCommand.Flags(),
+				initialize.AliasKeys,
+				configKeys(),
+				config,
+			)
+
+			switch output := v.GetString("output"); output {
+			case "text":
+				if report.Empty() {
+					fmt.Printf(
+						"%s is valid.\n",
+						args[0],
+					)
+				}
+				err = report.WriteText(os.Stdout)
+				if err != nil {
+					log.Fatalln(err)
+				}
+			case "json":
+				b, err := json.MarshalIndent(
+					report,
+					"",
+					"  ",
+				)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				fmt.Println(string(b))
+			default:
+				log.Fatalf(
+					"unsupported output format %q, must be text or json",
+					output,
+				)
+			}
+
+			if len(report.Errors) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	c.Flags().StringP(
+		"output",
+		"o",
+		"text",
+		"output format text,json",
+	)
+	c.Flags().Bool(
+		"schema",
+		false,
+		"Print the JSON Schema of the config file instead of checking one",
+	)
+	return c
+}
+
+// configKeys are the keys of a config file that are not 'csi config init' flags, but are still used.
+func configKeys() []string {
+	return slices.Concat(
+		initialize.ConfigKeys,
+		initialize.NoWriteKeys,
+	)
+}
+
+// readConfig reads the keys of a YAML (or JSON) config file.
+func readConfig(path string) (config map[string]interface{}, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to read %s because %v",
+			path,
+			err,
+		)
+	}
+	err = yaml.Unmarshal(
+		data,
+		&config,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to parse %s because %v",
+			path,
+			err,
+		)
+	}
+	return config, nil
+}
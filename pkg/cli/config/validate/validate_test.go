@@ -0,0 +1,185 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize"
+)
+
+const systemConfigFixture = "../../../../testdata/fixtures/init/system_config.yaml"
+
+type ValidateTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ValidateTestSuite) TestValidate_Fixture() {
+	config, err := readConfig(systemConfigFixture)
+	suite.Require().NoError(err)
+	report := Validate(
+		initialize.NewCommand().Flags(),
+		initialize.AliasKeys,
+		configKeys(),
+		config,
+	)
+	suite.True(
+		report.Empty(),
+		report,
+	)
+}
+
+func (suite *ValidateTestSuite) TestValidate() {
+	report := Validate(
+		initialize.NewCommand().Flags(),
+		initialize.AliasKeys,
+		configKeys(),
+		map[string]interface{}{
+			"nmn-cidr4":             "10.252.0.0/17",
+			"starting-mountain-nid": 1000,
+			"can-bootstrap-vlan":    "seven",
+			"bgp-peer-types": []interface{}{
+				"spine",
+				"leaf",
+			},
+			"ntp-pools": map[string]interface{}{
+				"pool": "time.nist.gov",
+			},
+			"k8s-primary-cni": "cilium",
+			"chn-cidr":        "10.104.7.0/24",
+			"chn-gateway":     "10.104.7.1",
+			"chn-gateway4":    "10.104.7.1",
+			"site-ip":         "172.30.52.220/20",
+			"site-gw":         "172.30.48.1",
+			"VersionInfo": map[string]interface{}{
+				"version": "1.0.0",
+			},
+		},
+	)
+	suite.Equal(
+		[]Problem{
+			{
+				Key:     "can-bootstrap-vlan",
+				Message: `invalid value "seven" because strconv.ParseInt: parsing "seven": invalid syntax`,
+			},
+			{
+				Key:     "nmn-cidr4",
+				Message: "unknown key, did you mean nmn-cidr?",
+			},
+			{
+				Key:     "ntp-pools",
+				Message: "must be of type array",
+			},
+			{
+				Key:     "site-ip site-gw site-dns site-nic",
+				Message: "must be set together, missing site-dns site-nic",
+			},
+		},
+		report.Errors,
+	)
+	// The deprecated keys set the flags that replaced them, so chn-cidr4 is set along with chn-gateway4.
+	suite.Subset(
+		report.Warnings,
+		[]Problem{
+			{
+				Key:     "chn-cidr",
+				Message: "deprecated, Please use --chn-cidr4 instead",
+			},
+			{
+				Key:     "chn-gateway",
+				Message: "deprecated, Please use --chn-gateway4 instead",
+			},
+			{
+				Key:     "system-name",
+				Message: "required, it must be given on the command line",
+			},
+		},
+	)
+}
+
+func (suite *ValidateTestSuite) TestNewSchema() {
+	schema := NewSchema(
+		"csi config init",
+		initialize.NewCommand().Flags(),
+		map[string]string{
+			"can-gw":   "can-gateway",
+			"chn-cidr": "chn-cidr4",
+		},
+		configKeys(),
+	)
+	suite.Equal(
+		&Property{
+			Type:        "integer",
+			Description: schema.Properties["can-bootstrap-vlan"].Description,
+			Default:     int64(6),
+		},
+		schema.Properties["can-bootstrap-vlan"],
+	)
+	suite.Equal(
+		[]string{"spine"},
+		schema.Properties["bgp-peer-types"].Default,
+	)
+	suite.True(schema.Properties["chn-cidr"].Deprecated)
+	suite.Contains(
+		schema.Properties,
+		"k8s-primary-cni",
+	)
+	suite.Equal(
+		"#/properties/can-gateway",
+		schema.Properties["can-gw"].Ref,
+	)
+	suite.Equal(
+		[]string{
+			"site-gw",
+			"site-dns",
+			"site-nic",
+		},
+		schema.DependentRequired["site-ip"],
+	)
+	suite.Contains(
+		schema.Required,
+		"site-ip",
+	)
+	suite.Contains(
+		schema.AllOf,
+		Constraint{
+			Not: &Constraint{
+				Required: []string{
+					"chn-cidr",
+					"chn-cidr4",
+				},
+			},
+		},
+	)
+}
+
+func TestValidateTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(ValidateTestSuite),
+	)
+}
@@ -0,0 +1,415 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package verify
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+	"github.com/Cray-HPE/cray-site-init/pkg/version"
+)
+
+const (
+	// ManifestFile is the manifest of a generated system directory.
+	ManifestFile = "manifest.json"
+
+	// SignatureFile is the detached, base64 encoded ed25519 signature of the ManifestFile.
+	SignatureFile = "manifest.json.sig"
+)
+
+// InputFile is a seed file a system directory was generated from.
+type InputFile struct {
+	// Name is the base name of the file, the directory it was read from is left out so that the manifest does not
+	// change from one machine to another.
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest records the csi build and the sha256 of every input and output file of a generated system directory.
+type Manifest struct {
+	Version version.Info `json:"version"`
+	// Inputs are the seed files keyed by the config init flag that names them (e.g. "ncn-metadata").
+	Inputs map[string]InputFile `json:"inputs"`
+	// Files are the sha256 of every file in the system directory, keyed by their path relative to it.
+	Files map[string]string `json:"files"`
+}
+
+// NewManifest hashes the given input files, keyed by flag name, and every file of the system directory at basepath.
+func NewManifest(basepath string, inputs map[string]string) (manifest Manifest, err error) {
+	manifest, err = newManifest(inputs)
+	if err != nil {
+		return manifest, err
+	}
+	manifest.Files, err = hashDirectory(basepath)
+	return manifest, err
+}
+
+// NewTreeManifest hashes the given input files, keyed by flag name, and every file of a system directory rendered in
+// memory.
+func NewTreeManifest(tree files.Tree, inputs map[string]string) (manifest Manifest, err error) {
+	manifest, err = newManifest(inputs)
+	if err != nil {
+		return manifest, err
+	}
+	manifest.Files = make(map[string]string)
+	for name, contents := range tree {
+		if name == ManifestFile || name == SignatureFile {
+			continue
+		}
+		sum := sha256.Sum256(contents)
+		manifest.Files[name] = hex.EncodeToString(sum[:])
+	}
+	return manifest, nil
+}
+
+/*
+UpdateTreeManifest hashes every file of the system directory at basepath, with the files of a tree rendered in memory
+replacing or adding to them. The inputs of the current manifest of the system directory, if any, are kept.
+*/
+func UpdateTreeManifest(basepath string, tree files.Tree) (manifest Manifest, err error) {
+	manifest = Manifest{
+		Version: version.Get(),
+		Inputs:  make(map[string]InputFile),
+	}
+	_, err = os.Stat(
+		filepath.Join(
+			basepath,
+			ManifestFile,
+		),
+	)
+	if err == nil {
+		current, err := LoadManifest(basepath)
+		if err != nil {
+			return manifest, err
+		}
+		if current.Inputs != nil {
+			manifest.Inputs = current.Inputs
+		}
+	} else if !isNotExist(err) {
+		return manifest, err
+	}
+	manifest.Files, err = hashDirectory(basepath)
+	if err != nil {
+		return manifest, err
+	}
+	for name, contents := range tree {
+		if name == ManifestFile || name == SignatureFile {
+			continue
+		}
+		sum := sha256.Sum256(contents)
+		manifest.Files[name] = hex.EncodeToString(sum[:])
+	}
+	return manifest, nil
+}
+
+func newManifest(inputs map[string]string) (manifest Manifest, err error) {
+	manifest = Manifest{
+		Version: version.Get(),
+		Inputs:  make(map[string]InputFile),
+	}
+	for flag, path := range inputs {
+		sum, err := hashFile(path)
+		if err != nil {
+			return manifest, fmt.Errorf(
+				"unable to hash %s file because %v",
+				flag,
+				err,
+			)
+		}
+		manifest.Inputs[flag] = InputFile{
+			Name:   filepath.Base(path),
+			SHA256: sum,
+		}
+	}
+	return manifest, nil
+}
+
+// Write writes the manifest to the system directory at basepath.
+func (manifest Manifest) Write(basepath string) error {
+	return files.WriteJSONConfig(
+		filepath.Join(
+			basepath,
+			ManifestFile,
+		),
+		manifest,
+	)
+}
+
+// Render renders the manifest to a system directory rendered in memory.
+func (manifest Manifest) Render(tree files.Tree) error {
+	return tree.JSON(
+		ManifestFile,
+		manifest,
+	)
+}
+
+// LoadManifest reads the manifest of the system directory at basepath.
+func LoadManifest(basepath string) (manifest Manifest, err error) {
+	err = files.ReadJSONConfig(
+		filepath.Join(
+			basepath,
+			ManifestFile,
+		),
+		&manifest,
+	)
+	if err != nil {
+		return manifest, fmt.Errorf(
+			"unable to read %s because %v",
+			ManifestFile,
+			err,
+		)
+	}
+	return manifest, nil
+}
+
+/*
+Sign writes a detached signature of the manifest of the system directory at basepath, using the PEM encoded PKCS #8
+ed25519 private key at keyFile. Such a key can be made with:
+
+	openssl genpkey -algorithm ed25519 -out csi.pem
+*/
+func Sign(basepath string, keyFile string) error {
+	manifest, err := os.ReadFile(
+		filepath.Join(
+			basepath,
+			ManifestFile,
+		),
+	)
+	if err != nil {
+		return err
+	}
+	signature, err := sign(
+		manifest,
+		keyFile,
+	)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(
+		filepath.Join(
+			basepath,
+			SignatureFile,
+		),
+		signature,
+		0644,
+	)
+}
+
+// SignTree adds a detached signature of the manifest of a system directory rendered in memory, see Sign.
+func SignTree(tree files.Tree, keyFile string) error {
+	manifest, ok := tree[ManifestFile]
+	if !ok {
+		return fmt.Errorf(
+			"%s has not been rendered",
+			ManifestFile,
+		)
+	}
+	signature, err := sign(
+		manifest,
+		keyFile,
+	)
+	if err != nil {
+		return err
+	}
+	tree[SignatureFile] = signature
+	return nil
+}
+
+// sign returns the base64 encoded ed25519 signature of manifest.
+func sign(manifest []byte, keyFile string) ([]byte, error) {
+	block, err := readPEM(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to parse private key %s because %v",
+			keyFile,
+			err,
+		)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf(
+			"private key %s is not an ed25519 key",
+			keyFile,
+		)
+	}
+	signature := ed25519.Sign(
+		privateKey,
+		manifest,
+	)
+	return []byte(base64.StdEncoding.EncodeToString(signature) + "\n"), nil
+}
+
+/*
+VerifySignature checks the detached signature of the manifest of the system directory at basepath against the PEM
+encoded PKIX ed25519 public key at keyFile. The public key of a private key made for Sign can be had with:
+
+	openssl pkey -in csi.pem -pubout -out csi.pub
+*/
+func VerifySignature(basepath string, keyFile string) error {
+	block, err := readPEM(keyFile)
+	if err != nil {
+		return err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to parse public key %s because %v",
+			keyFile,
+			err,
+		)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf(
+			"public key %s is not an ed25519 key",
+			keyFile,
+		)
+	}
+	manifest, err := os.ReadFile(
+		filepath.Join(
+			basepath,
+			ManifestFile,
+		),
+	)
+	if err != nil {
+		return err
+	}
+	encoded, err := os.ReadFile(
+		filepath.Join(
+			basepath,
+			SignatureFile,
+		),
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to read %s because %v",
+			SignatureFile,
+			err,
+		)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf(
+			"unable to decode %s because %v",
+			SignatureFile,
+			err,
+		)
+	}
+	if !ed25519.Verify(
+		publicKey,
+		manifest,
+		signature,
+	) {
+		return fmt.Errorf(
+			"%s does not match %s, it was changed or signed with a different key",
+			SignatureFile,
+			ManifestFile,
+		)
+	}
+	return nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf(
+			"%s is not PEM encoded",
+			path,
+		)
+	}
+	return block, nil
+}
+
+// hashDirectory returns the sha256 of every file under basepath, except for the manifest and its signature.
+func hashDirectory(basepath string) (sums map[string]string, err error) {
+	sums = make(map[string]string)
+	err = filepath.WalkDir(
+		basepath,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			name, err := filepath.Rel(
+				basepath,
+				path,
+			)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(name)
+			if name == ManifestFile || name == SignatureFile {
+				return nil
+			}
+			sums[name], err = hashFile(path)
+			return err
+		},
+	)
+	return sums, err
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	_, err = io.Copy(
+		hash,
+		f,
+	)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isNotExist returns whether err is because a file does not exist.
+func isNotExist(err error) bool {
+	return errors.Is(
+		err,
+		fs.ErrNotExist,
+	)
+}
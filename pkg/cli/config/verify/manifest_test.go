@@ -0,0 +1,342 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package verify
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Cray-HPE/cray-site-init/internal/files"
+)
+
+type ManifestTestSuite struct {
+	suite.Suite
+	basepath string
+	inputDir string
+}
+
+func (suite *ManifestTestSuite) SetupTest() {
+	suite.basepath = suite.T().TempDir()
+	suite.inputDir = suite.T().TempDir()
+	suite.write(
+		suite.basepath,
+		"sls_input_file.json",
+		"{}",
+	)
+	suite.write(
+		suite.basepath,
+		"basecamp/data.json",
+		"{}",
+	)
+	suite.write(
+		suite.inputDir,
+		"ncn_metadata.csv",
+		"Xname,Role,Subrole",
+	)
+
+	manifest, err := NewManifest(
+		suite.basepath,
+		map[string]string{
+			"ncn-metadata": filepath.Join(
+				suite.inputDir,
+				"ncn_metadata.csv",
+			),
+		},
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(manifest.Write(suite.basepath))
+}
+
+func (suite *ManifestTestSuite) write(dir string, name string, contents string) {
+	path := filepath.Join(
+		dir,
+		name,
+	)
+	suite.Require().NoError(
+		os.MkdirAll(
+			filepath.Dir(path),
+			0755,
+		),
+	)
+	suite.Require().NoError(
+		os.WriteFile(
+			path,
+			[]byte(contents),
+			0644,
+		),
+	)
+}
+
+// writeKeys writes a new ed25519 key pair and returns the paths of its private and public key.
+func (suite *ManifestTestSuite) writeKeys() (privateKeyFile string, publicKeyFile string) {
+	dir := suite.T().TempDir()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	suite.Require().NoError(err)
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	suite.Require().NoError(err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	suite.Require().NoError(err)
+	suite.write(
+		dir,
+		"csi.pem",
+		string(
+			pem.EncodeToMemory(
+				&pem.Block{
+					Type:  "PRIVATE KEY",
+					Bytes: privateDER,
+				},
+			),
+		),
+	)
+	suite.write(
+		dir,
+		"csi.pub",
+		string(
+			pem.EncodeToMemory(
+				&pem.Block{
+					Type:  "PUBLIC KEY",
+					Bytes: publicDER,
+				},
+			),
+		),
+	)
+	return filepath.Join(
+			dir,
+			"csi.pem",
+		), filepath.Join(
+			dir,
+			"csi.pub",
+		)
+}
+
+func (suite *ManifestTestSuite) TestNewManifest() {
+	manifest, err := LoadManifest(suite.basepath)
+	suite.Require().NoError(err)
+	suite.Equal(
+		[]string{
+			"basecamp/data.json",
+			"sls_input_file.json",
+		},
+		slices.Sorted(maps.Keys(manifest.Files)),
+	)
+	suite.Equal(
+		"44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+		manifest.Files["basecamp/data.json"],
+	)
+	suite.NotContains(
+		manifest.Files,
+		ManifestFile,
+	)
+	suite.Equal(
+		"ncn_metadata.csv",
+		manifest.Inputs["ncn-metadata"].Name,
+	)
+
+	report, err := Verify(
+		suite.basepath,
+		suite.inputDir,
+	)
+	suite.Require().NoError(err)
+	suite.True(report.Empty())
+}
+
+func (suite *ManifestTestSuite) TestUpdateTreeManifest() {
+	tree := files.Tree{
+		"sls_input_file.json": []byte(`{"Hardware": {}}`),
+		"vlans.json":          []byte("[]"),
+	}
+	manifest, err := UpdateTreeManifest(
+		suite.basepath,
+		tree,
+	)
+	suite.Require().NoError(err)
+	suite.Require().NoError(manifest.Render(tree))
+	suite.Require().NoError(tree.Write(suite.basepath))
+
+	suite.Equal(
+		[]string{
+			"basecamp/data.json",
+			"sls_input_file.json",
+			"vlans.json",
+		},
+		slices.Sorted(maps.Keys(manifest.Files)),
+	)
+	suite.Equal(
+		"ncn_metadata.csv",
+		manifest.Inputs["ncn-metadata"].Name,
+	)
+	report, err := Verify(
+		suite.basepath,
+		suite.inputDir,
+	)
+	suite.Require().NoError(err)
+	suite.True(report.Empty())
+
+	// A system directory without a manifest gets one without inputs.
+	basepath := suite.T().TempDir()
+	suite.write(
+		basepath,
+		"sls_input_file.json",
+		"{}",
+	)
+	manifest, err = UpdateTreeManifest(
+		basepath,
+		files.Tree{},
+	)
+	suite.Require().NoError(err)
+	suite.Empty(manifest.Inputs)
+	suite.Contains(
+		manifest.Files,
+		"sls_input_file.json",
+	)
+}
+
+func (suite *ManifestTestSuite) TestVerify_Drift() {
+	suite.write(
+		suite.basepath,
+		"sls_input_file.json",
+		`{"Networks": {}}`,
+	)
+	suite.Require().NoError(
+		os.Remove(
+			filepath.Join(
+				suite.basepath,
+				"basecamp/data.json",
+			),
+		),
+	)
+	suite.write(
+		suite.basepath,
+		"dnsmasq.d/NMN.conf",
+		"",
+	)
+	suite.write(
+		suite.inputDir,
+		"ncn_metadata.csv",
+		"Xname,Role,Subrole,BMC MAC",
+	)
+
+	report, err := Verify(
+		suite.basepath,
+		suite.inputDir,
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		[]Change{
+			{
+				Kind: Missing,
+				Path: "basecamp/data.json",
+			},
+			{
+				Kind: Modified,
+				Path: "sls_input_file.json",
+			},
+			{
+				Kind: Added,
+				Path: "dnsmasq.d/NMN.conf",
+			},
+		},
+		report.Files,
+	)
+	suite.Equal(
+		[]Change{
+			{
+				Kind: Modified,
+				Path: "ncn_metadata.csv",
+			},
+		},
+		report.Inputs,
+	)
+
+	// Inputs are only rechecked when their directory is given.
+	report, err = Verify(
+		suite.basepath,
+		"",
+	)
+	suite.Require().NoError(err)
+	suite.Empty(report.Inputs)
+}
+
+func (suite *ManifestTestSuite) TestSign() {
+	privateKeyFile, publicKeyFile := suite.writeKeys()
+	suite.Require().NoError(
+		Sign(
+			suite.basepath,
+			privateKeyFile,
+		),
+	)
+	suite.NoError(
+		VerifySignature(
+			suite.basepath,
+			publicKeyFile,
+		),
+	)
+
+	// The signature is not part of the manifest.
+	report, err := Verify(
+		suite.basepath,
+		"",
+	)
+	suite.Require().NoError(err)
+	suite.True(report.Empty())
+
+	_, otherPublicKeyFile := suite.writeKeys()
+	suite.ErrorContains(
+		VerifySignature(
+			suite.basepath,
+			otherPublicKeyFile,
+		),
+		"signed with a different key",
+	)
+
+	suite.write(
+		suite.basepath,
+		ManifestFile,
+		`{"files": {}}`,
+	)
+	suite.ErrorContains(
+		VerifySignature(
+			suite.basepath,
+			publicKeyFile,
+		),
+		"does not match",
+	)
+}
+
+func TestManifestTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(ManifestTestSuite),
+	)
+}
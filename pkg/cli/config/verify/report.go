@@ -0,0 +1,189 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package verify
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/version"
+)
+
+// Kinds of drift.
+const (
+	Added    = "added"
+	Missing  = "missing"
+	Modified = "modified"
+)
+
+// Change is a single file that no longer matches the manifest.
+type Change struct {
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+}
+
+// Report is every file of a generated system directory, and of its inputs, that drifted from the manifest.
+type Report struct {
+	// Version is the csi build that wrote the manifest.
+	Version version.Info `json:"version"`
+	Files   []Change     `json:"files"`
+	Inputs  []Change     `json:"inputs"`
+}
+
+// Empty returns whether nothing drifted from the manifest.
+func (report Report) Empty() bool {
+	return len(report.Files) == 0 && len(report.Inputs) == 0
+}
+
+// WriteText writes the report in a human-readable form.
+func (report Report) WriteText(w io.Writer) (err error) {
+	symbols := map[string]string{
+		Added:    "+",
+		Missing:  "-",
+		Modified: "~",
+	}
+	for _, section := range []struct {
+		name    string
+		changes []Change
+	}{
+		{
+			"files",
+			report.Files,
+		},
+		{
+			"inputs",
+			report.Inputs,
+		},
+	} {
+		if len(section.changes) == 0 {
+			continue
+		}
+		_, err = fmt.Fprintf(
+			w,
+			"%s:\n",
+			section.name,
+		)
+		if err != nil {
+			return err
+		}
+		for _, change := range section.changes {
+			_, err = fmt.Fprintf(
+				w,
+				"  %s %s (%s)\n",
+				symbols[change.Kind],
+				change.Path,
+				change.Kind,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Verify rehashes the files of the system directory at basepath and reports those that drifted from its manifest. When
+inputDir is set, the input files are looked up by name in it and rehashed too.
+*/
+func Verify(basepath string, inputDir string) (report Report, err error) {
+	manifest, err := LoadManifest(basepath)
+	if err != nil {
+		return report, err
+	}
+	report.Version = manifest.Version
+
+	sums, err := hashDirectory(basepath)
+	if err != nil {
+		return report, err
+	}
+	for _, name := range slices.Sorted(maps.Keys(manifest.Files)) {
+		sum, ok := sums[name]
+		if !ok {
+			report.Files = append(
+				report.Files,
+				Change{
+					Kind: Missing,
+					Path: name,
+				},
+			)
+		} else if sum != manifest.Files[name] {
+			report.Files = append(
+				report.Files,
+				Change{
+					Kind: Modified,
+					Path: name,
+				},
+			)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(sums)) {
+		if _, ok := manifest.Files[name]; !ok {
+			report.Files = append(
+				report.Files,
+				Change{
+					Kind: Added,
+					Path: name,
+				},
+			)
+		}
+	}
+
+	if inputDir == "" {
+		return report, nil
+	}
+	for _, flag := range slices.Sorted(maps.Keys(manifest.Inputs)) {
+		input := manifest.Inputs[flag]
+		sum, err := hashFile(
+			filepath.Join(
+				inputDir,
+				input.Name,
+			),
+		)
+		if isNotExist(err) {
+			report.Inputs = append(
+				report.Inputs,
+				Change{
+					Kind: Missing,
+					Path: input.Name,
+				},
+			)
+		} else if err != nil {
+			return report, err
+		} else if sum != input.SHA256 {
+			report.Inputs = append(
+				report.Inputs,
+				Change{
+					Kind: Modified,
+					Path: input.Name,
+				},
+			)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,132 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewCommand represents the verify command.
+func NewCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "verify DIR",
+		Short: "Checks a generated system directory against its manifest",
+		Long: `Checks a system directory generated by 'csi config init' against the manifest.json written with it.
+
+	The manifest holds the csi version that generated the directory and the sha256 of its input and output files.
+	1. Every file in the directory is rehashed, files that were modified, removed, or added since are reported
+	2. With --input-dir, the input files (ncn_metadata.csv, switch_metadata.csv, hmn_connections.json, etc.) are
+	   looked up by name in that directory and rehashed too
+	3. With --public-key, the detached manifest.json.sig signature written by 'csi config init --manifest-signing-key'
+	   is checked, proving the manifest came from a run holding the matching private key
+
+	Exits with status 1 when anything drifted or the signature does not match.
+	`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(c *cobra.Command, args []string) {
+			v := viper.GetViper()
+			err := v.BindPFlags(c.Flags())
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			if v.GetString("public-key") != "" {
+				err = VerifySignature(
+					args[0],
+					v.GetString("public-key"),
+				)
+				if err != nil {
+					log.Fatalln(err)
+				}
+			}
+			report, err := Verify(
+				args[0],
+				v.GetString("input-dir"),
+			)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			switch output := v.GetString("output"); output {
+			case "text":
+				fmt.Printf(
+					"Generated by csi %s (%s)\n",
+					report.Version.String(),
+					report.Version.GitCommit,
+				)
+				if report.Empty() {
+					fmt.Println("No drift.")
+				}
+				err = report.WriteText(os.Stdout)
+				if err != nil {
+					log.Fatalln(err)
+				}
+			case "json":
+				b, err := json.MarshalIndent(
+					report,
+					"",
+					"  ",
+				)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				fmt.Println(string(b))
+			default:
+				log.Fatalf(
+					"unsupported output format %q, must be text or json",
+					output,
+				)
+			}
+
+			if !report.Empty() {
+				os.Exit(1)
+			}
+		},
+	}
+	c.Flags().StringP(
+		"output",
+		"o",
+		"text",
+		"output format text,json",
+	)
+	c.Flags().String(
+		"input-dir",
+		"",
+		"Directory holding the input files to recheck against the manifest",
+	)
+	c.Flags().String(
+		"public-key",
+		"",
+		"PEM encoded ed25519 public key to check the manifest signature with",
+	)
+	return c
+}
@@ -0,0 +1,271 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/config/initialize"
+	"github.com/Cray-HPE/cray-site-init/pkg/networking"
+)
+
+// CapacityReport is the utilization of every subnet of an SLS state.
+type CapacityReport []networking.SubnetCapacity
+
+// CapacityThresholds are the highest utilization, in percent, the subnets may be at. A threshold of 0 is no threshold.
+type CapacityThresholds struct {
+	Default float64
+
+	// Overrides are keyed by "<network>/<subnet>", or "<network>" for every subnet of a network.
+	Overrides map[string]float64
+}
+
+func newCapacityCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "capacity [system directory or SLS file]",
+		Short: "Reports the address utilization of every subnet",
+		Long: `Reports the IPv4 address utilization of every subnet of an SLS state, before AddReservation runs out of
+	addresses in it.
+
+	The SLS state is the sls_input_file.json of a system directory, or any SLS file such as a dump of a live SLS
+	(cray sls dumpstate list --format json). For every network and subnet it reports:
+
+	1. The usable host addresses of its CIDR, without the network and broadcast addresses
+	2. The reserved addresses, those of its IP reservations and its gateway
+	3. The size of its DHCP range
+	4. The addresses in smaller subnets of the same network, e.g. the MetalLB pools in the CAN bootstrap_dhcp subnet
+	5. The free addresses, in none of the above
+	6. The largest block of contiguous free addresses
+	7. Its utilization, the percentage of the usable addresses that are not free
+
+	The host addresses of a subnet start at the address of its CIDR, e.g. 10.103.7.0/23 has 10.103.7.0 to
+	10.103.7.254, since AddReservation hands out no address before it.
+
+	With --max-utilization, or --subnet-max-utilization for a network or subnet, the command exits non-zero when any
+	subnet is above its threshold. A threshold of 0 disables it, e.g. for the cabinet subnets whose DHCP range spans
+	them:
+
+	  csi network capacity ./eniac --max-utilization 90 --subnet-max-utilization CMN/bootstrap_dhcp=75,NMN_MTN=0
+	`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(c *cobra.Command, args []string) {
+			v := viper.GetViper()
+			err := v.BindPFlags(c.Flags())
+			if err != nil {
+				log.Fatalln(err)
+			}
+			thresholds, err := NewCapacityThresholds(
+				v.GetFloat64("max-utilization"),
+				v.GetStringMapString("subnet-max-utilization"),
+			)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			state, err := initialize.LoadPreviousSLS(args[0])
+			if err != nil {
+				log.Fatalln(err)
+			}
+			capacities, err := networking.NewCapacityReport(*state)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			report := CapacityReport(capacities)
+			output, err := report.Marshal(v.GetString("output"))
+			if err != nil {
+				log.Fatalln(err)
+			}
+			fmt.Print(string(output))
+
+			exceeded := report.Exceeded(thresholds)
+			for _, capacity := range exceeded {
+				log.Printf(
+					"ERROR: %s %s (%s) is %.1f%% utilized, above its threshold of %.1f%%\n",
+					capacity.Network,
+					capacity.Subnet,
+					capacity.CIDR,
+					capacity.Utilization,
+					thresholds.For(capacity),
+				)
+			}
+			if len(exceeded) > 0 {
+				log.Fatalf(
+					"%d subnet(s) are above their utilization threshold",
+					len(exceeded),
+				)
+			}
+		},
+	}
+	c.Flags().StringP(
+		"output",
+		"o",
+		"text",
+		"Format of the report: text, json, or yaml",
+	)
+	c.Flags().Float64(
+		"max-utilization",
+		0,
+		"Highest utilization in percent any subnet may be at, 0 for no threshold",
+	)
+	c.Flags().StringToString(
+		"subnet-max-utilization",
+		map[string]string{},
+		"Highest utilization in percent of a network or subnet, e.g. CMN/bootstrap_dhcp=75,CAN=80",
+	)
+	return c
+}
+
+// NewCapacityThresholds returns the thresholds of a default and the overrides of networks and subnets.
+func NewCapacityThresholds(utilization float64, overrides map[string]string) (thresholds CapacityThresholds, err error) {
+	thresholds = CapacityThresholds{
+		Default:   utilization,
+		Overrides: make(map[string]float64),
+	}
+	if utilization < 0 || utilization > 100 {
+		return thresholds, fmt.Errorf(
+			"max-utilization %v is not a percentage",
+			utilization,
+		)
+	}
+	for key, value := range overrides {
+		// viper lower cases the keys of a map.
+		key = strings.ToUpper(key)
+		if network, subnet, ok := strings.Cut(
+			key,
+			"/",
+		); ok {
+			key = network + "/" + strings.ToLower(subnet)
+		}
+		override, err := strconv.ParseFloat(
+			value,
+			64,
+		)
+		if err != nil || override < 0 || override > 100 {
+			return thresholds, fmt.Errorf(
+				"subnet-max-utilization of %s %q is not a percentage",
+				key,
+				value,
+			)
+		}
+		thresholds.Overrides[key] = override
+	}
+	return thresholds, nil
+}
+
+// For returns the threshold of a subnet, that of the subnet over that of its network over the default.
+func (thresholds CapacityThresholds) For(capacity networking.SubnetCapacity) float64 {
+	for _, key := range []string{
+		capacity.Network + "/" + capacity.Subnet,
+		capacity.Network,
+	} {
+		if threshold, ok := thresholds.Overrides[key]; ok {
+			return threshold
+		}
+	}
+	return thresholds.Default
+}
+
+// Exceeded returns the subnets that are above their threshold.
+func (report CapacityReport) Exceeded(thresholds CapacityThresholds) (exceeded []networking.SubnetCapacity) {
+	for _, capacity := range report {
+		threshold := thresholds.For(capacity)
+		if threshold > 0 && capacity.Utilization > threshold {
+			exceeded = append(
+				exceeded,
+				capacity,
+			)
+		}
+	}
+	return exceeded
+}
+
+// Marshal encodes the report as a text table, json or yaml.
+func (report CapacityReport) Marshal(format string) ([]byte, error) {
+	switch format {
+	case "text":
+		var table strings.Builder
+		fmt.Fprintf(
+			&table,
+			"%-12s %-24s %-18s %8s %8s %8s %8s %8s %-38s %6s\n",
+			"NETWORK",
+			"SUBNET",
+			"CIDR",
+			"USABLE",
+			"RESERVED",
+			"DHCP",
+			"NESTED",
+			"FREE",
+			"LARGEST FREE BLOCK",
+			"USED",
+		)
+		for _, capacity := range report {
+			largest := "-"
+			if capacity.LargestFreeSize > 0 {
+				largest = fmt.Sprintf(
+					"%s (%d)",
+					capacity.LargestFreeBlock,
+					capacity.LargestFreeSize,
+				)
+			}
+			fmt.Fprintf(
+				&table,
+				"%-12s %-24s %-18s %8d %8d %8d %8d %8d %-38s %5.1f%%\n",
+				capacity.Network,
+				capacity.Subnet,
+				capacity.CIDR,
+				capacity.Usable,
+				capacity.Reserved,
+				capacity.DHCP,
+				capacity.Nested,
+				capacity.Free,
+				largest,
+				capacity.Utilization,
+			)
+		}
+		return []byte(table.String()), nil
+	case "json":
+		return json.MarshalIndent(
+			report,
+			"",
+			"  ",
+		)
+	case "yaml":
+		return yaml.Marshal(report)
+	default:
+		return nil, fmt.Errorf(
+			"unsupported output format %q, must be text, json, or yaml",
+			format,
+		)
+	}
+}
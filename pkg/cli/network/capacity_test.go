@@ -0,0 +1,176 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CapacityTestSuite struct {
+	suite.Suite
+	report CapacityReport
+}
+
+func (suite *CapacityTestSuite) SetupTest() {
+	suite.report = CapacityReport{
+		{
+			Network:          "CMN",
+			Subnet:           "bootstrap_dhcp",
+			CIDR:             "10.103.7.0/23",
+			Usable:           255,
+			Reserved:         10,
+			DHCP:             100,
+			Nested:           143,
+			Free:             2,
+			LargestFreeBlock: "10.103.7.10-10.103.7.11",
+			LargestFreeSize:  2,
+			Utilization:      99.2,
+		},
+		{
+			Network:     "CMN",
+			Subnet:      "network_hardware",
+			CIDR:        "10.103.6.0/23",
+			Usable:      510,
+			Utilization: 30.2,
+		},
+		{
+			Network:     "NMN_MTN",
+			Subnet:      "cabinet_1000",
+			CIDR:        "10.100.0.0/22",
+			Usable:      1022,
+			Utilization: 99.2,
+		},
+	}
+}
+
+// exceeded returns the network and subnet of every subnet above its threshold.
+func (suite *CapacityTestSuite) exceeded(thresholds CapacityThresholds) (names []string) {
+	for _, capacity := range suite.report.Exceeded(thresholds) {
+		names = append(
+			names,
+			capacity.Network+"/"+capacity.Subnet,
+		)
+	}
+	return names
+}
+
+func (suite *CapacityTestSuite) TestExceeded() {
+	thresholds, err := NewCapacityThresholds(
+		0,
+		nil,
+	)
+	suite.Require().NoError(err)
+	suite.Empty(suite.exceeded(thresholds))
+
+	thresholds, err = NewCapacityThresholds(
+		90,
+		nil,
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		[]string{
+			"CMN/bootstrap_dhcp",
+			"NMN_MTN/cabinet_1000",
+		},
+		suite.exceeded(thresholds),
+	)
+
+	// viper lower cases the keys, the subnet overrides the network which overrides the default.
+	thresholds, err = NewCapacityThresholds(
+		90,
+		map[string]string{
+			"cmn":                "25",
+			"cmn/bootstrap_dhcp": "100",
+			"nmn_mtn":            "0",
+		},
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		[]string{
+			"CMN/network_hardware",
+		},
+		suite.exceeded(thresholds),
+	)
+	suite.Equal(
+		25.0,
+		thresholds.For(suite.report[1]),
+	)
+}
+
+func (suite *CapacityTestSuite) TestNewCapacityThresholds_Invalid() {
+	_, err := NewCapacityThresholds(
+		120,
+		nil,
+	)
+	suite.ErrorContains(
+		err,
+		"max-utilization 120 is not a percentage",
+	)
+	_, err = NewCapacityThresholds(
+		0,
+		map[string]string{
+			"can": "most",
+		},
+	)
+	suite.ErrorContains(
+		err,
+		`subnet-max-utilization of CAN "most" is not a percentage`,
+	)
+}
+
+func (suite *CapacityTestSuite) TestMarshal() {
+	text, err := suite.report.Marshal("text")
+	suite.Require().NoError(err)
+	suite.Contains(
+		string(text),
+		"CMN          bootstrap_dhcp           10.103.7.0/23           255       10      100      143        2 10.103.7.10-10.103.7.11 (2)             99.2%\n",
+	)
+	suite.Contains(
+		string(text),
+		"NMN_MTN      cabinet_1000             10.100.0.0/22          1022        0        0        0        0 -                                       99.2%\n",
+	)
+
+	json, err := suite.report.Marshal("json")
+	suite.Require().NoError(err)
+	suite.Contains(
+		string(json),
+		`"largest-free-block": "10.103.7.10-10.103.7.11"`,
+	)
+
+	_, err = suite.report.Marshal("csv")
+	suite.ErrorContains(
+		err,
+		`unsupported output format "csv"`,
+	)
+}
+
+func TestCapacityTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(CapacityTestSuite),
+	)
+}
@@ -0,0 +1,46 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package network
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewCommand represents the network command.
+func NewCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "network",
+		Short: "Reports on the networks of an SLS state",
+		Long: `Reports on the networks and subnets of a generated sls_input_file.json, or of a dump of a live SLS (e.g.
+	cray sls dumpstate list --format json).
+	`,
+		DisableAutoGenTag: true,
+		Args:              cobra.MinimumNArgs(1),
+	}
+	c.AddCommand(
+		newCapacityCommand(),
+	)
+	return c
+}
@@ -39,6 +39,7 @@ import (
 	slsClient "github.com/Cray-HPE/hms-sls/v2/pkg/sls-client"
 	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/spf13/viper"
 )
 
 func getSLSClient(client *slsClient.SLSClient) (err error) {
@@ -185,7 +186,7 @@ func addIPv6ToNetwork(network *slsCommon.Network, prefix netip.Prefix, gateway n
 
 	// Apply the supernet hack to our limited subnets.
 	networking.SupernetSubnets = subnetsToPatch
-	ipNetwork.ApplySupernetHack()
+	ipNetwork.ApplySupernetHack(viper.GetViper())
 	for _, subnet := range ipNetwork.Subnets {
 		_, index, _ := extraProperties.LookupSubnet(subnet.Name)
 		extraProperties.Subnets[index].CIDR6 = subnet.CIDR6
@@ -314,6 +315,8 @@ func allocateIPReservations(slsProperties *slsCommon.NetworkExtraProperties) (er
 				return iAddr.Less(jAddr)
 			},
 		)
+		// UpdateReservation releases and reserves the addresses it replaces, one allocator follows the whole subnet.
+		addresses := networking.NewAddressAllocator()
 		for j, IPReservation := range subnet.IPReservations {
 			if !force && IPReservation.IPAddress6 != nil {
 				skippedReservations++
@@ -323,7 +326,7 @@ func allocateIPReservations(slsProperties *slsCommon.NetworkExtraProperties) (er
 			// Free the reserved IPAddress and IPAddress6, allowing us to re-use it.
 			slsProperties.Subnets[i].IPReservations[j].IPAddress = nil
 			slsProperties.Subnets[i].IPReservations[j].IPAddress6 = nil
-			updatedReservation, err := networking.UpdateReservation(
+			updatedReservation, err := addresses.UpdateReservation(
 				&subnet,
 				IPReservation,
 				true,
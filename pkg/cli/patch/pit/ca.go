@@ -26,46 +26,14 @@ package pit
 
 import (
 	"bytes"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/sha256"
-	"crypto/x509"
-	"encoding/base64"
-	"encoding/binary"
 	"encoding/json"
 	"encoding/pem"
-	"errors"
 	"log"
-	"os"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
-)
 
-// SealedSecret - Minimum struct to determine secret scope
-// and access encrypted data
-type SealedSecret struct {
-	Spec struct {
-		EncryptedData map[string]string `yaml:"encryptedData"`
-		Template      struct {
-			Metadata struct {
-				Annotations map[string]string
-			}
-		}
-	}
-}
-
-// Customizations - Minimum customizations (shasta-cfg) struct
-// to access sealed secrets
-type Customizations struct {
-	Spec struct {
-		Kubernetes struct {
-			SealedSecrets map[string]SealedSecret `yaml:"sealed_secrets"`
-		}
-	}
-}
+	"github.com/Cray-HPE/cray-site-init/pkg/secrets"
+)
 
 // CACerts - For storage of ca-certs cloud-init update
 type CACerts struct {
@@ -99,7 +67,7 @@ Patches the Pre-Install Toolkit's (PIT) cloud-init meta-data, adding Certificate
 given Shasta configuration (shasta-cfg).
 `,
 		Run: func(c *cobra.Command, args []string) {
-			ciphertext, err := loadEncryptedCABundle(
+			ciphertext, err := secrets.LoadEncryptedData(
 				customizationsFile,
 				sealedSecretName,
 				"ca_bundle.crt",
@@ -111,7 +79,7 @@ given Shasta configuration (shasta-cfg).
 				)
 			}
 
-			privKey, err := loadPrivateKey(sealedSecretsKeyFile)
+			privKey, err := secrets.LoadPrivateKey(sealedSecretsKeyFile)
 			if err != nil {
 				log.Fatalf(
 					"Unable to load sealed secret private key, %v \n",
@@ -119,7 +87,7 @@ given Shasta configuration (shasta-cfg).
 				)
 			}
 
-			plaintext, err := decryptCABundle(
+			plaintext, err := secrets.Decrypt(
 				privKey,
 				ciphertext,
 			)
@@ -216,144 +184,6 @@ given Shasta configuration (shasta-cfg).
 	return c
 }
 
-// Load shasta-cfg customizations, then attempt to return the encrypted CA bundle
-// from secretName -> BundleName
-func loadEncryptedCABundle(
-	filePath string, secretName string, bundleName string,
-) (
-	[]byte, error,
-) {
-
-	customizations, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
-	}
-
-	var data Customizations
-	if err := yaml.Unmarshal(
-		customizations,
-		&data,
-	); err != nil {
-		return nil, err
-	}
-
-	// verify sealed secret scope is cluster-wide
-	clusterWide, ok := data.Spec.Kubernetes.SealedSecrets[secretName].Spec.Template.Metadata.Annotations["sealedsecrets.bitnami.com/cluster-wide"]
-
-	// CMS is currently only using cluster-wide sealed secrets,
-	// this is important as namespaced secrets include the
-	// namespace as part of encryption process.
-	if !ok || clusterWide != "true" {
-		return nil, errors.New("sealed secret does not have cluster-wide scope, namespaced scope decryption not implemented")
-	}
-
-	b64Bundle, ok := data.Spec.Kubernetes.SealedSecrets[secretName].Spec.EncryptedData[bundleName]
-	if !ok {
-		return nil, errors.New("sealed secret or data attribute does not exist")
-	}
-
-	ciphertext, err := base64.StdEncoding.DecodeString(b64Bundle)
-	if err != nil {
-		return nil, err
-	}
-
-	return ciphertext, nil
-}
-
-// Load and return the sealed secret RSA private key
-func loadPrivateKey(filePath string) (
-	*rsa.PrivateKey, error,
-) {
-
-	key, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
-	}
-	KeyPEM, _ := pem.Decode(key)
-
-	privKeyParse, err := x509.ParsePKCS8PrivateKey(KeyPEM.Bytes)
-	if err != nil {
-		return nil, err
-	}
-
-	privKey := privKeyParse.(*rsa.PrivateKey)
-	return privKey, nil
-}
-
-// Given an encrypted CA bundle from sealed secrets (ciphertext),
-// decrypt and return the plaintext
-func decryptCABundle(
-	privKey *rsa.PrivateKey, ciphertext []byte,
-) (
-	[]byte, error,
-) {
-
-	// Based on https://github.com/bitnami-labs/sealed-secrets/blob/master/pkg/crypto/crypto.go
-
-	// The first two bytes contain the length of the encrypted
-	// AES session key
-	if len(ciphertext) < 2 {
-		return nil, errors.New("truncated ciphertext, corrupt data")
-	}
-
-	// Get the RSA encrypted AES session key length,
-	// and then right shift the ciphertext
-	sessionKeyLen := int(binary.BigEndian.Uint16(ciphertext))
-	ciphertext = ciphertext[2:]
-
-	if len(ciphertext) < sessionKeyLen {
-		return nil, errors.New("ciphertext not long enough to hold session key, corrupt data")
-	}
-
-	// Get the RSA encrypted AES session key,
-	// then right shift the ciphertext
-	sessionKeyEncrypted := ciphertext[:sessionKeyLen]
-	ciphertext = ciphertext[sessionKeyLen:]
-
-	var label []byte // namespace-based scope not implemented, label is empty
-	rnd := rand.Reader
-
-	// Decrypt the AES session key
-	aesSessionKey, err := rsa.DecryptOAEP(
-		sha256.New(),
-		rnd,
-		privKey,
-		sessionKeyEncrypted,
-		label,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	block, err := aes.NewCipher(aesSessionKey)
-	if err != nil {
-		return nil, err
-	}
-
-	aed, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-
-	// Sealed Secrets use a zero Nonce, do the same
-	zeroNonce := make(
-		[]byte,
-		aed.NonceSize(),
-	)
-
-	plaintext, err := aed.Open(
-		nil,
-		zeroNonce,
-		ciphertext,
-		nil,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	return plaintext, nil
-}
-
 // Given a decrypted PEM bundle, populate and return appropriate
 // cloud-init structure
 func formatCABundle(raw []byte) (
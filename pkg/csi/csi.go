@@ -45,6 +45,7 @@ import (
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/automation"
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/config"
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/handoff"
+	"github.com/Cray-HPE/cray-site-init/pkg/cli/network"
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/patch"
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/pit"
 	"github.com/Cray-HPE/cray-site-init/pkg/cli/upload"
@@ -73,9 +74,7 @@ Tooling for the initial configuration and deployment of a Cray-HPE
 Exascale High-Performance Computer (HPC) or an HPCaaS (e.g. VShasta).
 `,
 		PersistentPreRunE: func(c *cobra.Command, args []string) error {
-			cli.Runtime = time.Now().UTC()
-			cli.RuntimeTimestamp = cli.Runtime.Format(time.RFC3339Nano)
-			cli.RuntimeTimestampShort = cli.Runtime.Format("20060102150405")
+			cli.SetRuntime(time.Now().UTC())
 			v, err := initializeConfig(c)
 			if err != nil {
 				return err
@@ -144,6 +143,7 @@ Exascale High-Performance Computer (HPC) or an HPCaaS (e.g. VShasta).
 		automation.NewCommand(),
 		config.NewCommand(),
 		handoff.NewCommand(),
+		network.NewCommand(),
 		patch.NewCommand(),
 		pit.NewCommand(),
 		DocsCommand(),
@@ -83,6 +83,31 @@ func UnmarshalComptypeNode(hardware *slsCommon.GenericHardware) (extraProperties
 	return extraProperties, nil
 }
 
+// UnmarshalComptypeMgmtSwitch reads the hardware.ExtraPropertiesRaw string into a slsCommon.ComptypeMgmtSwitch struct.
+// The Brand, Model, and Aliases of a MgmtHLSwitch or CDUMgmtSwitch are read the same way.
+func UnmarshalComptypeMgmtSwitch(hardware *slsCommon.GenericHardware) (extraProperties slsCommon.ComptypeMgmtSwitch, err error) {
+	extraPropertiesRaw, err := json.Marshal(hardware.ExtraPropertiesRaw)
+	if err != nil {
+		return extraProperties, fmt.Errorf(
+			"failed to marshal [%s] as ComptypeMgmtSwitch because %v",
+			hardware.Xname,
+			err,
+		)
+	}
+	err = json.Unmarshal(
+		extraPropertiesRaw,
+		&extraProperties,
+	)
+	if err != nil {
+		return extraProperties, fmt.Errorf(
+			"failed to unmarshal hardware [%s] as ComptypeMgmtSwitch because %v",
+			hardware.Xname,
+			err,
+		)
+	}
+	return extraProperties, nil
+}
+
 // UnmarshalNetworkExtraProperties reads the network.ExtraPropertiesRaw string into a struct.
 func UnmarshalNetworkExtraProperties(network *slsCommon.Network) (extraProperties slsCommon.NetworkExtraProperties, err error) {
 	extraPropertiesRaw, err := json.Marshal(network.ExtraPropertiesRaw)
@@ -74,7 +74,13 @@ func normalize(version string) string {
 func currentVersion() (
 	string, error,
 ) {
-	v := viper.GetViper()
+	return versionOf(viper.GetViper())
+}
+
+// versionOf returns the API version targeted by the given viper.
+func versionOf(v *viper.Viper) (
+	string, error,
+) {
 	version := v.GetString(APIKeyName)
 	if version == "" {
 		return "", fmt.Errorf(
@@ -89,7 +95,14 @@ func currentVersion() (
 func IsCompatible() (
 	string, error,
 ) {
-	version, err := currentVersion()
+	return IsCompatibleWith(viper.GetViper())
+}
+
+// IsCompatibleWith is IsCompatible for the version set in the given viper.
+func IsCompatibleWith(v *viper.Viper) (
+	string, error,
+) {
+	version, err := versionOf(v)
 	if err != nil {
 		return version, err
 	}
@@ -144,18 +157,28 @@ func DetectedVersion() (
 func Compare(version string) (
 	string, int,
 ) {
+	return CompareWith(
+		viper.GetViper(),
+		version,
+	)
+}
+
+// CompareWith is Compare for the version set in the given viper.
+func CompareWith(v *viper.Viper, version string) (
+	string, int,
+) {
 
-	currentVersion, err := currentVersion()
+	currentVersion, err := versionOf(v)
 	if err != nil {
 		return "", 1
 	}
 
 	c := semver.Canonical(normalize(currentVersion))
-	v := semver.Canonical(normalize(version))
+	t := semver.Canonical(normalize(version))
 
 	cmp := semver.Compare(
 		c,
-		v,
+		t,
 	)
 	return currentVersion, cmp
 }
@@ -166,18 +189,38 @@ func Compare(version string) (
 func CompareMajorMinor(version string) (
 	string, int,
 ) {
+	return CompareMajorMinorWith(
+		viper.GetViper(),
+		version,
+	)
+}
+
+// CompareMajorMinorWith is CompareMajorMinor for the version set in the given viper.
+func CompareMajorMinorWith(v *viper.Viper, version string) (
+	string, int,
+) {
 
-	currentVersion, err := currentVersion()
+	currentVersion, err := versionOf(v)
 	if err != nil {
 		return "", 1
 	}
+	return currentVersion, CompareMajorMinorOf(
+		currentVersion,
+		version,
+	)
+}
 
+// CompareMajorMinorOf is CompareMajorMinor for the given current version rather than a set one. Like an unset version,
+// an empty current version is newer than any given version.
+func CompareMajorMinorOf(currentVersion string, version string) int {
+	if currentVersion == "" {
+		return 1
+	}
 	c := semver.MajorMinor(semver.Canonical(normalize(currentVersion)))
-	v := semver.MajorMinor(semver.Canonical(normalize(version)))
+	t := semver.MajorMinor(semver.Canonical(normalize(version)))
 
-	cmp := semver.Compare(
+	return semver.Compare(
 		c,
-		v,
+		t,
 	)
-	return currentVersion, cmp
 }
@@ -0,0 +1,617 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+	"math/bits"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+)
+
+// allocatorFanout is the number of children of every allocatorNode, one for each bit of its bitmap.
+const allocatorFanout = 64
+
+// allocatorNodeBits is the number of bits of an address offset that select a child of an allocatorNode.
+const allocatorNodeBits = 6
+
+/*
+allocatorNode is a node of the bitmap trie behind an IPAllocator. The bitmap of a leaf holds one bit per address, and
+the bitmap of an inner node holds one bit per child that is entirely allocated. A missing child is entirely free, so
+only the parts of a subnet that hold allocations take up memory.
+*/
+type allocatorNode struct {
+	bitmap   uint64
+	children *[allocatorFanout]*allocatorNode
+}
+
+// set allocates the offset within this node, returning whether the node is now entirely allocated.
+func (node *allocatorNode) set(level int, offset uint64) (full bool) {
+	index := (offset >> (level * allocatorNodeBits)) % allocatorFanout
+	if level == 0 {
+		node.bitmap |= 1 << index
+		return node.bitmap == ^uint64(0)
+	}
+	if node.children == nil {
+		node.children = new([allocatorFanout]*allocatorNode)
+	}
+	child := node.children[index]
+	if child == nil {
+		child = &allocatorNode{}
+		node.children[index] = child
+	}
+	if child.set(
+		level-1,
+		offset,
+	) {
+		node.bitmap |= 1 << index
+	}
+	return node.bitmap == ^uint64(0)
+}
+
+// clear releases the offset within this node.
+func (node *allocatorNode) clear(level int, offset uint64) {
+	index := (offset >> (level * allocatorNodeBits)) % allocatorFanout
+	node.bitmap &^= 1 << index
+	if level == 0 || node.children == nil || node.children[index] == nil {
+		return
+	}
+	node.children[index].clear(
+		level-1,
+		offset,
+	)
+}
+
+// isSet returns whether the offset within this node is allocated.
+func (node *allocatorNode) isSet(level int, offset uint64) bool {
+	index := (offset >> (level * allocatorNodeBits)) % allocatorFanout
+	if node.bitmap&(1<<index) != 0 {
+		return true
+	}
+	if level == 0 || node.children == nil || node.children[index] == nil {
+		return false
+	}
+	return node.children[index].isSet(
+		level-1,
+		offset,
+	)
+}
+
+// next returns the first free offset at or after the given one within this node.
+func (node *allocatorNode) next(level int, from uint64) (offset uint64, ok bool) {
+	if node == nil {
+		return from, true
+	}
+	shift := level * allocatorNodeBits
+	first := (from >> shift) % allocatorFanout
+	candidates := ^node.bitmap & (^uint64(0) << first)
+	if level == 0 {
+		if candidates == 0 {
+			return offset, false
+		}
+		return from&^(allocatorFanout-1) | uint64(bits.TrailingZeros64(candidates)), true
+	}
+	// The root of an IPv6 trie spans more than 64 bits, its children past the last offset do not exist.
+	if shift+allocatorNodeBits > 64 {
+		children := uint64(1) << (64 - shift)
+		candidates &= uint64(1)<<children - 1
+	}
+	// The bits above this node's span, shifting by 64 or more yields zero which clears everything.
+	base := from &^ (uint64(1)<<(shift+allocatorNodeBits) - 1)
+	for ; candidates != 0; candidates &= candidates - 1 {
+		index := uint64(bits.TrailingZeros64(candidates))
+		childFrom := base | index<<shift
+		if index == first {
+			childFrom = from
+		}
+		var child *allocatorNode
+		if node.children != nil {
+			child = node.children[index]
+		}
+		if offset, ok = child.next(
+			level-1,
+			childFrom,
+		); ok {
+			return offset, true
+		}
+	}
+	return offset, false
+}
+
+/*
+IPAllocator tracks the allocated addresses of a prefix in a bitmap trie with 64 children per node. Allocating a
+specific address, allocating the next free address, and releasing an address each take one step per level of the
+trie, which is at most 6 levels for IPv4 and 11 levels for IPv6.
+
+IPv6 prefixes shorter than a /64 are tracked for the /64 holding the prefix's address, the addresses beyond it are
+never handed out.
+*/
+type IPAllocator struct {
+	prefix    netip.Prefix
+	levels    int
+	last      uint64
+	allocated uint64
+	root      allocatorNode
+}
+
+// NewIPAllocator returns an IPAllocator for the given prefix with every address free.
+func NewIPAllocator(prefix netip.Prefix) *IPAllocator {
+	addr := prefix.Addr().Unmap()
+	if addr.Is6() && prefix.Bits() < 64 {
+		prefix = netip.PrefixFrom(
+			addr,
+			64,
+		)
+	} else {
+		prefix = netip.PrefixFrom(
+			addr,
+			prefix.Bits(),
+		)
+	}
+	hostBits := addr.BitLen() - prefix.Bits()
+	allocator := &IPAllocator{
+		prefix: prefix.Masked(),
+		levels: max(
+			1,
+			(hostBits+allocatorNodeBits-1)/allocatorNodeBits,
+		),
+		last: ^uint64(0),
+	}
+	if hostBits < 64 {
+		allocator.last = uint64(1)<<hostBits - 1
+	}
+	return allocator
+}
+
+// Prefix returns the prefix of the addresses this IPAllocator hands out.
+func (allocator *IPAllocator) Prefix() netip.Prefix {
+	return allocator.prefix
+}
+
+// Allocated returns the number of allocated addresses.
+func (allocator *IPAllocator) Allocated() uint64 {
+	return allocator.allocated
+}
+
+// offset returns the distance of the address from the start of the prefix.
+func (allocator *IPAllocator) offset(addr netip.Addr) (offset uint64, ok bool) {
+	addr = addr.Unmap()
+	if !allocator.prefix.Contains(addr) {
+		return offset, false
+	}
+	if addr.Is4() {
+		a, base := addr.As4(), allocator.prefix.Addr().As4()
+		return uint64(binary.BigEndian.Uint32(a[:]) - binary.BigEndian.Uint32(base[:])), true
+	}
+	a, base := addr.As16(), allocator.prefix.Addr().As16()
+	return binary.BigEndian.Uint64(a[8:]) - binary.BigEndian.Uint64(base[8:]), true
+}
+
+// addr returns the address at the given distance from the start of the prefix.
+func (allocator *IPAllocator) addr(offset uint64) netip.Addr {
+	if allocator.prefix.Addr().Is4() {
+		base := allocator.prefix.Addr().As4()
+		var a [4]byte
+		binary.BigEndian.PutUint32(
+			a[:],
+			binary.BigEndian.Uint32(base[:])+uint32(offset),
+		)
+		return netip.AddrFrom4(a)
+	}
+	a := allocator.prefix.Addr().As16()
+	binary.BigEndian.PutUint64(
+		a[8:],
+		binary.BigEndian.Uint64(a[8:])+offset,
+	)
+	return netip.AddrFrom16(a)
+}
+
+// IsAllocated returns whether the address is allocated, addresses outside the prefix are never allocated.
+func (allocator *IPAllocator) IsAllocated(addr netip.Addr) bool {
+	offset, ok := allocator.offset(addr)
+	return ok && allocator.root.isSet(
+		allocator.levels-1,
+		offset,
+	)
+}
+
+// Allocate allocates the given address, it must be within the prefix and free.
+func (allocator *IPAllocator) Allocate(addr netip.Addr) error {
+	offset, ok := allocator.offset(addr)
+	if !ok {
+		return fmt.Errorf(
+			"%s is not within %s",
+			addr,
+			allocator.prefix,
+		)
+	}
+	if allocator.root.isSet(
+		allocator.levels-1,
+		offset,
+	) {
+		return fmt.Errorf(
+			"%s is already allocated",
+			addr,
+		)
+	}
+	allocator.root.set(
+		allocator.levels-1,
+		offset,
+	)
+	allocator.allocated++
+	return nil
+}
+
+// Release frees the given address, it must be allocated.
+func (allocator *IPAllocator) Release(addr netip.Addr) error {
+	if !allocator.IsAllocated(addr) {
+		return fmt.Errorf(
+			"%s is not allocated in %s",
+			addr,
+			allocator.prefix,
+		)
+	}
+	offset, _ := allocator.offset(addr)
+	allocator.root.clear(
+		allocator.levels-1,
+		offset,
+	)
+	allocator.allocated--
+	return nil
+}
+
+// Next returns the first free address at or after the given one, if there is one within the prefix.
+func (allocator *IPAllocator) Next(from netip.Addr) (addr netip.Addr, ok bool) {
+	offset, ok := allocator.offset(from)
+	if !ok {
+		return addr, false
+	}
+	offset, ok = allocator.root.next(
+		allocator.levels-1,
+		offset,
+	)
+	if !ok || offset > allocator.last {
+		return addr, false
+	}
+	return allocator.addr(offset), true
+}
+
+// AllocateNext allocates and returns the first free address at or after the given one.
+func (allocator *IPAllocator) AllocateNext(from netip.Addr) (addr netip.Addr, err error) {
+	addr, ok := allocator.Next(from)
+	if !ok {
+		return addr, fmt.Errorf(
+			"%s has no free address left after %s",
+			allocator.prefix,
+			from,
+		)
+	}
+	return addr, allocator.Allocate(addr)
+}
+
+// Free iterates over the free addresses of the prefix in order.
+func (allocator *IPAllocator) Free() iter.Seq[netip.Addr] {
+	return func(yield func(netip.Addr) bool) {
+		addr, ok := allocator.Next(allocator.prefix.Addr())
+		for ok && yield(addr) {
+			next := addr.Next()
+			if !next.IsValid() {
+				return
+			}
+			addr, ok = allocator.Next(next)
+		}
+	}
+}
+
+// mark allocates the address if it is within the prefix and free, anything else is left as it is.
+func (allocator *IPAllocator) mark(addr netip.Addr) {
+	if allocator == nil || allocator.IsAllocated(addr) {
+		return
+	}
+	offset, ok := allocator.offset(addr)
+	if !ok {
+		return
+	}
+	allocator.root.set(
+		allocator.levels-1,
+		offset,
+	)
+	allocator.allocated++
+}
+
+/*
+AddressAllocator accounts for the allocated addresses of every subnet reservations are made in during a run of
+cray-site-init, and for the reservations and conflicts of the subnets seeded from a previous run (see IPNetwork.Seed).
+Every run creates its own, alongside its VLANAllocator, so that runs in the same process do not see each other's
+addresses. It is safe for concurrent use on different subnets.
+
+The SubnetAllocator of a subnet is built the first time an address is looked up in it, and follows the reservations
+appended to the subnet afterwards. It is built again when the subnet's CIDRs, gateways, or list of reservations are
+replaced, or when reservations are removed. Checking every reservation on every lookup would make reserving a subnet's
+addresses quadratic, so a reservation rewritten in place before the last one, and the seeded addresses of a subnet, are
+not noticed: Rebuild has to be called after changing them.
+*/
+type AddressAllocator struct {
+	mutex      sync.Mutex
+	allocators map[*slsCommon.IPSubnet]*SubnetAllocator
+	seeds      map[*slsCommon.IPSubnet]*subnetSeed
+	conflicts  []SeedConflict
+}
+
+// NewAddressAllocator returns an AddressAllocator without any allocated addresses or seeded subnets.
+func NewAddressAllocator() *AddressAllocator {
+	return &AddressAllocator{
+		allocators: make(map[*slsCommon.IPSubnet]*SubnetAllocator),
+		seeds:      make(map[*slsCommon.IPSubnet]*subnetSeed),
+	}
+}
+
+/*
+SubnetAllocator tracks the addresses of an IPSubnet that can not be handed out: the network address, the gateway, the
+broadcast address, and every IP reservation. The AddressAllocator building it also marks the addresses held by the
+subnet's seeded reservations (see IPNetwork.Seed). IPv6 subnets have no broadcast address.
+
+The IPv4 or IPv6 allocator is nil if the subnet has no valid CIDR or gateway for it, Next reports why.
+*/
+type SubnetAllocator struct {
+	IPv4 *IPAllocator
+	IPv6 *IPAllocator
+
+	subnet *slsCommon.IPSubnet
+	from4  netip.Addr
+	from6  netip.Addr
+	err4   error
+	err6   error
+
+	// The subnet as it was when the allocator was built and last synced, to notice when it has to be rebuilt.
+	cidr     string
+	cidr6    string
+	gateway  string
+	gateway6 string
+	synced   int
+	// first is the first reservation of the subnet and last the addresses of the last reservation it was synced with.
+	first *slsCommon.IPReservation
+	last  [2]net.IP
+}
+
+/*
+NewSubnetAllocator returns a SubnetAllocator for the addresses the subnet currently holds. Reservations added to the
+subnet afterwards are not tracked until they are given to Reserve.
+*/
+func NewSubnetAllocator(subnet *slsCommon.IPSubnet) *SubnetAllocator {
+	allocator := &SubnetAllocator{
+		subnet:   subnet,
+		cidr:     subnet.CIDR,
+		cidr6:    subnet.CIDR6,
+		gateway:  subnet.Gateway.String(),
+		gateway6: subnet.Gateway6.String(),
+	}
+	allocator.IPv4, allocator.from4, allocator.err4 = newSubnetIPv4Allocator(subnet)
+	allocator.IPv6, allocator.from6, allocator.err6 = newSubnetIPv6Allocator(subnet)
+	allocator.sync()
+	return allocator
+}
+
+func newSubnetIPv4Allocator(subnet *slsCommon.IPSubnet) (allocator *IPAllocator, from netip.Addr, err error) {
+	prefix, err := netip.ParsePrefix(subnet.CIDR)
+	if err != nil {
+		return nil, from, fmt.Errorf(
+			"error parsing CIDR '%s' because %v",
+			subnet.CIDR,
+			err,
+		)
+	}
+	from = prefix.Addr()
+	subnetRoot, err := FindCIDRRootIP(prefix)
+	if err != nil {
+		return nil, from, fmt.Errorf(
+			"error finding subnet root IP address for %s subnet because %v",
+			prefix.String(),
+			err,
+		)
+	}
+	gateway, err := netip.ParseAddr(subnet.Gateway.String())
+	if err != nil {
+		return nil, from, fmt.Errorf(
+			"error reading gateway IP for %s subnet because %v",
+			subnet.Gateway.String(),
+			err,
+		)
+	}
+	broadcast, err := Broadcast(prefix)
+	if err != nil {
+		return nil, from, fmt.Errorf(
+			"error resolving broadcast address for %s subnet because %v",
+			prefix.String(),
+			err,
+		)
+	}
+	allocator = NewIPAllocator(prefix)
+	allocator.mark(subnetRoot)
+	allocator.mark(gateway)
+	allocator.mark(broadcast)
+	return allocator, from, nil
+}
+
+func newSubnetIPv6Allocator(subnet *slsCommon.IPSubnet) (allocator *IPAllocator, from netip.Addr, err error) {
+	prefix, err := netip.ParsePrefix(subnet.CIDR6)
+	if err != nil {
+		return nil, from, fmt.Errorf(
+			"error parsing CIDR '%s': %s",
+			subnet.CIDR6,
+			err,
+		)
+	}
+	from = prefix.Addr()
+	subnetRoot, err := FindCIDRRootIP(prefix)
+	if err != nil {
+		return nil, from, fmt.Errorf(
+			"error finding subnet root IP address for %s subnet because %v",
+			prefix.String(),
+			err,
+		)
+	}
+	gateway, err := netip.ParseAddr(subnet.Gateway6.String())
+	if err != nil {
+		return nil, from, fmt.Errorf(
+			"error resolving gateway address for %s subnet because %v",
+			subnet.Gateway6.String(),
+			err,
+		)
+	}
+	allocator = NewIPAllocator(prefix)
+	allocator.mark(subnetRoot)
+	allocator.mark(gateway)
+	return allocator, from, nil
+}
+
+// Reserve allocates the addresses of the reservation, addresses that are outside the subnet or taken are ignored.
+func (allocator *SubnetAllocator) Reserve(reservation slsCommon.IPReservation) {
+	if addr, err := netip.ParseAddr(reservation.IPAddress.String()); err == nil {
+		allocator.IPv4.mark(addr)
+	}
+	if addr, err := netip.ParseAddr(reservation.IPAddress6.String()); err == nil {
+		allocator.IPv6.mark(addr)
+	}
+}
+
+// Release frees the addresses of the reservation.
+func (allocator *SubnetAllocator) Release(reservation slsCommon.IPReservation) {
+	if addr, err := netip.ParseAddr(reservation.IPAddress.String()); err == nil && allocator.IPv4 != nil {
+		_ = allocator.IPv4.Release(addr)
+	}
+	if addr, err := netip.ParseAddr(reservation.IPAddress6.String()); err == nil && allocator.IPv6 != nil {
+		_ = allocator.IPv6.Release(addr)
+	}
+}
+
+/*
+Next returns the first free IPv4 and IPv6 addresses of the subnet without allocating them, each with their own error.
+The search starts at the address of the subnet's CIDR, the addresses before it are never handed out.
+*/
+func (allocator *SubnetAllocator) Next() (ipv4 netip.Addr, ipv6 netip.Addr, err4 error, err6 error) {
+	if allocator.err4 != nil {
+		ipv4, err4 = allocator.from4, allocator.err4
+	} else if addr, ok := allocator.IPv4.Next(allocator.from4); ok {
+		ipv4 = addr
+	} else {
+		ipv4 = netip.IPv4Unspecified()
+		err4 = fmt.Errorf(
+			"%s %s subnet has exhausted its available IPv4 addresses, failed to find a free address",
+			allocator.subnet.Name,
+			netip.MustParsePrefix(allocator.cidr).String(),
+		)
+	}
+	if allocator.err6 != nil {
+		ipv6, err6 = allocator.from6, allocator.err6
+	} else if addr, ok := allocator.IPv6.Next(allocator.from6); ok {
+		ipv6 = addr
+	} else {
+		ipv6 = netip.IPv6Unspecified()
+		err6 = fmt.Errorf(
+			"%s %s subnet has exhausted its available IPv6 addresses, failed to find a free address",
+			allocator.subnet.Name,
+			netip.MustParsePrefix(allocator.cidr6).String(),
+		)
+	}
+	return ipv4, ipv6, err4, err6
+}
+
+/*
+current returns whether the subnet still has the CIDRs and gateways it was synced with, and still holds the
+reservations it was synced with. The reservations are held when there are at least as many, in the same backing array,
+and the last of them has the same addresses. A subnet replaced by value or given a new list of reservations, removed
+reservations, and a rewritten last reservation make the allocator out of date.
+*/
+func (allocator *SubnetAllocator) current() bool {
+	subnet := allocator.subnet
+	if subnet.CIDR != allocator.cidr || subnet.CIDR6 != allocator.cidr6 ||
+		subnet.Gateway.String() != allocator.gateway || subnet.Gateway6.String() != allocator.gateway6 ||
+		len(subnet.IPReservations) < allocator.synced {
+		return false
+	}
+	if allocator.synced == 0 {
+		return true
+	}
+	last := subnet.IPReservations[allocator.synced-1]
+	return &subnet.IPReservations[0] == allocator.first &&
+		last.IPAddress.Equal(allocator.last[0]) && last.IPAddress6.Equal(allocator.last[1])
+}
+
+// sync allocates the addresses of the reservations appended to the subnet since the allocator last synced.
+func (allocator *SubnetAllocator) sync() {
+	reservations := allocator.subnet.IPReservations
+	for _, reservation := range reservations[allocator.synced:] {
+		allocator.Reserve(reservation)
+	}
+	allocator.synced = len(reservations)
+	if allocator.synced > 0 {
+		last := reservations[allocator.synced-1]
+		allocator.first = &reservations[0]
+		allocator.last = [2]net.IP{
+			slices.Clone(last.IPAddress),
+			slices.Clone(last.IPAddress6),
+		}
+	}
+}
+
+// allocator returns the SubnetAllocator of the subnet, building it the first time or when it is out of date.
+// A new SubnetAllocator holds the addresses of the subnet's unclaimed seeded reservations.
+func (addresses *AddressAllocator) allocator(subnet *slsCommon.IPSubnet) *SubnetAllocator {
+	addresses.mutex.Lock()
+	defer addresses.mutex.Unlock()
+	allocator, ok := addresses.allocators[subnet]
+	if ok && allocator.current() {
+		allocator.sync()
+		return allocator
+	}
+	allocator = NewSubnetAllocator(subnet)
+	seeded, seeded6 := addresses.seeds[subnet].held()
+	for _, addr := range seeded {
+		allocator.IPv4.mark(addr)
+	}
+	for _, addr := range seeded6 {
+		allocator.IPv6.mark(addr)
+	}
+	addresses.allocators[subnet] = allocator
+	return allocator
+}
+
+// Rebuild drops the SubnetAllocator of the subnet, it is built from the subnet's current reservations and seeded
+// addresses when next used.
+func (addresses *AddressAllocator) Rebuild(subnet *slsCommon.IPSubnet) {
+	addresses.mutex.Lock()
+	defer addresses.mutex.Unlock()
+	delete(
+		addresses.allocators,
+		subnet,
+	)
+}
@@ -0,0 +1,532 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/stretchr/testify/suite"
+)
+
+type AllocatorTestSuite struct {
+	suite.Suite
+	addresses *AddressAllocator
+}
+
+func (suite *AllocatorTestSuite) SetupTest() {
+	suite.addresses = NewAddressAllocator()
+}
+
+// linearFindFreeIPv4Address finds the first free address by scanning the subnet, the way it was done before the
+// SubnetAllocator, as a reference for the tests and benchmarks.
+func linearFindFreeIPv4Address(subnet *slsCommon.IPSubnet) (address netip.Addr, ok bool) {
+	prefix := netip.MustParsePrefix(subnet.CIDR)
+	broadcast, _ := Broadcast(prefix)
+	addresses := []netip.Addr{
+		prefix.Masked().Addr(),
+		netip.MustParseAddr(subnet.Gateway.String()),
+		broadcast,
+	}
+	for _, reservation := range subnet.IPReservations {
+		if addr, err := netip.ParseAddr(reservation.IPAddress.String()); err == nil {
+			addresses = append(
+				addresses,
+				addr,
+			)
+		}
+	}
+	address = prefix.Addr()
+	for slices.Contains(
+		addresses,
+		address,
+	) {
+		address = address.Next()
+	}
+	return address, prefix.Contains(address)
+}
+
+func (suite *AllocatorTestSuite) TestIPAllocator() {
+	allocator := NewIPAllocator(netip.MustParsePrefix("10.0.0.8/29"))
+	suite.Equal(
+		netip.MustParsePrefix("10.0.0.8/29"),
+		allocator.Prefix(),
+	)
+	suite.NoError(allocator.Allocate(netip.MustParseAddr("10.0.0.9")))
+	suite.EqualError(
+		allocator.Allocate(netip.MustParseAddr("10.0.0.9")),
+		"10.0.0.9 is already allocated",
+	)
+	suite.EqualError(
+		allocator.Allocate(netip.MustParseAddr("10.0.0.16")),
+		"10.0.0.16 is not within 10.0.0.8/29",
+	)
+	suite.True(allocator.IsAllocated(netip.MustParseAddr("10.0.0.9")))
+	suite.False(allocator.IsAllocated(netip.MustParseAddr("10.0.0.10")))
+
+	addr, ok := allocator.Next(netip.MustParseAddr("10.0.0.9"))
+	suite.True(ok)
+	suite.Equal(
+		"10.0.0.10",
+		addr.String(),
+	)
+	for range 7 {
+		_, err := allocator.AllocateNext(netip.MustParseAddr("10.0.0.8"))
+		suite.Require().NoError(err)
+	}
+	suite.Equal(
+		uint64(8),
+		allocator.Allocated(),
+	)
+	_, err := allocator.AllocateNext(netip.MustParseAddr("10.0.0.8"))
+	suite.EqualError(
+		err,
+		"10.0.0.8/29 has no free address left after 10.0.0.8",
+	)
+
+	suite.NoError(allocator.Release(netip.MustParseAddr("10.0.0.12")))
+	suite.EqualError(
+		allocator.Release(netip.MustParseAddr("10.0.0.12")),
+		"10.0.0.12 is not allocated in 10.0.0.8/29",
+	)
+	suite.Equal(
+		[]netip.Addr{netip.MustParseAddr("10.0.0.12")},
+		slices.Collect(allocator.Free()),
+	)
+}
+
+func (suite *AllocatorTestSuite) TestIPAllocator_Exhaust() {
+	// A /16 spans three levels of the trie, every inner node fills up and empties again along the way.
+	allocator := NewIPAllocator(netip.MustParsePrefix("10.253.0.0/16"))
+	from := netip.MustParseAddr("10.253.0.0")
+	for i := range 1 << 16 {
+		addr, err := allocator.AllocateNext(from)
+		suite.Require().NoError(err)
+		suite.Require().Equal(
+			Add(
+				netip.MustParsePrefix("10.253.0.0/16"),
+				uint64(i),
+			),
+			addr,
+		)
+	}
+	_, ok := allocator.Next(from)
+	suite.False(ok)
+
+	released := []netip.Addr{
+		netip.MustParseAddr("10.253.0.63"),
+		netip.MustParseAddr("10.253.16.0"),
+		netip.MustParseAddr("10.253.255.255"),
+	}
+	for _, addr := range released {
+		suite.Require().NoError(allocator.Release(addr))
+	}
+	suite.Equal(
+		released,
+		slices.Collect(allocator.Free()),
+	)
+	addr, ok := allocator.Next(netip.MustParseAddr("10.253.0.64"))
+	suite.True(ok)
+	suite.Equal(
+		"10.253.16.0",
+		addr.String(),
+	)
+}
+
+func (suite *AllocatorTestSuite) TestIPAllocator_IPv6() {
+	allocator := NewIPAllocator(netip.MustParsePrefix("fdf8:413:de2c:204::/64"))
+	last := netip.MustParseAddr("fdf8:413:de2c:204:ffff:ffff:ffff:ffff")
+	addr, ok := allocator.Next(last)
+	suite.True(ok)
+	suite.Equal(
+		last,
+		addr,
+	)
+	suite.NoError(allocator.Allocate(last))
+	_, ok = allocator.Next(last)
+	suite.False(ok)
+
+	// Prefixes shorter than a /64 hand out the /64 holding their address.
+	allocator = NewIPAllocator(netip.MustParsePrefix("fdf8:413:de2c:200::/56"))
+	suite.Equal(
+		netip.MustParsePrefix("fdf8:413:de2c:200::/64"),
+		allocator.Prefix(),
+	)
+	suite.False(allocator.IsAllocated(netip.MustParseAddr("fdf8:413:de2c:201::")))
+	suite.Error(allocator.Allocate(netip.MustParseAddr("fdf8:413:de2c:201::")))
+}
+
+func (suite *AllocatorTestSuite) TestFindFreeIPAddress() {
+	subnet := &slsCommon.IPSubnet{
+		Name:     "bootstrap_dhcp",
+		CIDR:     "10.252.0.0/17",
+		Gateway:  net.ParseIP("10.252.0.1"),
+		CIDR6:    "fdf8:413:de2c:204::/64",
+		Gateway6: net.ParseIP("fdf8:413:de2c:204::1"),
+	}
+	for i := range 4 {
+		_, err := suite.addresses.AddReservation(
+			subnet,
+			fmt.Sprintf(
+				"ncn-w%03d",
+				i+1,
+			),
+			"",
+		)
+		suite.Require().NoError(err)
+	}
+	suite.Equal(
+		"10.252.0.5",
+		subnet.IPReservations[3].IPAddress.String(),
+	)
+	suite.Equal(
+		"fdf8:413:de2c:204::5",
+		subnet.IPReservations[3].IPAddress6.String(),
+	)
+
+	// Dropping a reservation requires building the allocator again.
+	subnet.IPReservations = subnet.IPReservations[1:]
+	suite.addresses.Rebuild(subnet)
+	ipv4, _, err4, _ := suite.addresses.FindFreeIPAddress(subnet)
+	suite.NoError(err4)
+	suite.Equal(
+		"10.252.0.2",
+		ipv4.String(),
+	)
+
+	// Changing the CIDR builds it again by itself.
+	subnet.CIDR = "10.252.1.0/24"
+	subnet.Gateway = net.ParseIP("10.252.1.1")
+	ipv4, _, err4, _ = suite.addresses.FindFreeIPAddress(subnet)
+	suite.NoError(err4)
+	suite.Equal(
+		"10.252.1.2",
+		ipv4.String(),
+	)
+
+	subnet.CIDR = "10.252.1.0/30"
+	_, err := suite.addresses.AddReservation(
+		subnet,
+		"ncn-w005",
+		"",
+	)
+	suite.Require().NoError(err)
+	_, err = suite.addresses.AddReservation(
+		subnet,
+		"ncn-w006",
+		"",
+	)
+	suite.ErrorContains(
+		err,
+		"bootstrap_dhcp 10.252.1.0/30 subnet has exhausted its available IPv4 addresses",
+	)
+}
+
+func (suite *AllocatorTestSuite) TestUpdateReservation() {
+	subnet := &slsCommon.IPSubnet{
+		Name:     "bootstrap_dhcp",
+		CIDR:     "10.252.0.0/24",
+		Gateway:  net.ParseIP("10.252.0.1"),
+		CIDR6:    "fdf8:413:de2c:204::/64",
+		Gateway6: net.ParseIP("fdf8:413:de2c:204::1"),
+	}
+	for _, name := range []string{
+		"ncn-m001",
+		"ncn-m002",
+	} {
+		_, err := suite.addresses.AddReservation(
+			subnet,
+			name,
+			"",
+		)
+		suite.Require().NoError(err)
+	}
+
+	// The caller clears the reservation being updated, its previous IPv6 address is free to be handed out again.
+	reservation := subnet.IPReservations[0]
+	subnet.IPReservations[0].IPAddress6 = nil
+	updated, err := suite.addresses.UpdateReservation(
+		subnet,
+		reservation,
+		true,
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"10.252.0.2",
+		updated.IPAddress.String(),
+	)
+	suite.Equal(
+		"fdf8:413:de2c:204::2",
+		updated.IPAddress6.String(),
+	)
+	subnet.IPReservations[0] = updated
+	_, ipv6, _, err6 := suite.addresses.FindFreeIPAddress(subnet)
+	suite.NoError(err6)
+	suite.Equal(
+		"fdf8:413:de2c:204::4",
+		ipv6.String(),
+	)
+}
+
+func (suite *AllocatorTestSuite) TestFindFreeIPAddress_Linear() {
+	// Reservations scattered over the subnet in any order, as well as outside of it, give the same addresses as a scan.
+	random := rand.New(rand.NewSource(1))
+	subnet := &slsCommon.IPSubnet{
+		Name:    "network_hardware",
+		CIDR:    "10.254.0.0/22",
+		Gateway: net.ParseIP("10.254.0.1"),
+	}
+	prefix := netip.MustParsePrefix("10.254.0.0/21")
+	for range 512 {
+		subnet.IPReservations = append(
+			subnet.IPReservations,
+			slsCommon.IPReservation{
+				IPAddress: Add(
+					prefix,
+					uint64(random.Intn(2048)),
+				).AsSlice(),
+			},
+		)
+	}
+	for i := range 1024 {
+		expected, ok := linearFindFreeIPv4Address(subnet)
+		reservation, err := suite.addresses.AddReservation(
+			subnet,
+			fmt.Sprintf(
+				"x3000c0w%d",
+				i,
+			),
+			"",
+		)
+		if !ok {
+			suite.Error(err)
+			return
+		}
+		suite.Require().NoError(err)
+		suite.Require().Equal(
+			expected.String(),
+			reservation.IPAddress.String(),
+		)
+	}
+	suite.Fail("the subnet was never exhausted")
+}
+
+func (suite *AllocatorTestSuite) TestAddressAllocator_Rebuild() {
+	subnet := &slsCommon.IPSubnet{
+		Name:    "bootstrap_dhcp",
+		CIDR:    "10.252.0.0/24",
+		Gateway: net.ParseIP("10.252.0.1"),
+	}
+	for _, name := range []string{
+		"ncn-m001",
+		"ncn-m002",
+	} {
+		_, err := suite.addresses.AddReservation(
+			subnet,
+			name,
+			"",
+		)
+		suite.Require().NoError(err)
+	}
+
+	// Rewriting the last reservation in place frees its old address.
+	subnet.IPReservations[1].IPAddress = net.ParseIP("10.252.0.10")
+	ipv4, _, err4, _ := suite.addresses.FindFreeIPAddress(subnet)
+	suite.NoError(err4)
+	suite.Equal(
+		"10.252.0.3",
+		ipv4.String(),
+	)
+
+	// Rewriting an earlier one is not noticed until the subnet's allocator is rebuilt.
+	subnet.IPReservations[0].IPAddress = net.ParseIP("10.252.0.20")
+	ipv4, _, err4, _ = suite.addresses.FindFreeIPAddress(subnet)
+	suite.NoError(err4)
+	suite.Equal(
+		"10.252.0.3",
+		ipv4.String(),
+	)
+	suite.addresses.Rebuild(subnet)
+	ipv4, _, err4, _ = suite.addresses.FindFreeIPAddress(subnet)
+	suite.NoError(err4)
+	suite.Equal(
+		"10.252.0.2",
+		ipv4.String(),
+	)
+
+	// So does replacing the subnet with one holding as many reservations at other addresses.
+	*subnet = slsCommon.IPSubnet{
+		Name:    "bootstrap_dhcp",
+		CIDR:    "10.252.0.0/24",
+		Gateway: net.ParseIP("10.252.0.1"),
+		IPReservations: []slsCommon.IPReservation{
+			{
+				Name:      "ncn-m001",
+				IPAddress: net.ParseIP("10.252.0.2"),
+			},
+			{
+				Name:      "ncn-m002",
+				IPAddress: net.ParseIP("10.252.0.4"),
+			},
+		},
+	}
+	ipv4, _, err4, _ = suite.addresses.FindFreeIPAddress(subnet)
+	suite.NoError(err4)
+	suite.Equal(
+		"10.252.0.3",
+		ipv4.String(),
+	)
+
+	// Removing a reservation frees its address as well.
+	subnet.IPReservations = subnet.IPReservations[1:]
+	ipv4, _, err4, _ = suite.addresses.FindFreeIPAddress(subnet)
+	suite.NoError(err4)
+	suite.Equal(
+		"10.252.0.2",
+		ipv4.String(),
+	)
+
+	// Another run starts with allocators of its own.
+	ipv4, _, err4, _ = NewAddressAllocator().FindFreeIPAddress(subnet)
+	suite.NoError(err4)
+	suite.Equal(
+		"10.252.0.2",
+		ipv4.String(),
+	)
+}
+
+func TestAllocatorTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(AllocatorTestSuite),
+	)
+}
+
+// hsnSubnet returns a 64k host subnet, like the HSN or a large mountain NMN.
+func hsnSubnet() *slsCommon.IPSubnet {
+	return &slsCommon.IPSubnet{
+		Name:    "hsn_base_subnet",
+		CIDR:    "10.253.0.0/16",
+		Gateway: net.ParseIP("10.253.0.1"),
+	}
+}
+
+/*
+BenchmarkAddReservation reserves addresses one after the other in a /16. The linear scan rescans every reservation
+for every address it considers, the allocator only steps through the levels of its trie, which is why the linear
+scan is only run for the first thousand addresses.
+*/
+func BenchmarkAddReservation(b *testing.B) {
+	for _, count := range []int{
+		256,
+		1024,
+	} {
+		b.Run(
+			fmt.Sprintf(
+				"linear/%d",
+				count,
+			),
+			func(b *testing.B) {
+				for range b.N {
+					subnet := hsnSubnet()
+					for range count {
+						addr, _ := linearFindFreeIPv4Address(subnet)
+						subnet.IPReservations = append(
+							subnet.IPReservations,
+							slsCommon.IPReservation{
+								IPAddress: addr.AsSlice(),
+							},
+						)
+					}
+				}
+			},
+		)
+	}
+	for _, count := range []int{
+		256,
+		1024,
+		4096,
+		65533,
+	} {
+		b.Run(
+			fmt.Sprintf(
+				"allocator/%d",
+				count,
+			),
+			func(b *testing.B) {
+				for range b.N {
+					addresses := NewAddressAllocator()
+					subnet := hsnSubnet()
+					for range count {
+						_, err := addresses.AddReservation(
+							subnet,
+							"nid",
+							"",
+						)
+						if err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			},
+		)
+	}
+}
+
+// BenchmarkIPAllocator allocates every address of a /16, releases every other one, and allocates them again.
+func BenchmarkIPAllocator(b *testing.B) {
+	prefix := netip.MustParsePrefix("10.253.0.0/16")
+	for range b.N {
+		allocator := NewIPAllocator(prefix)
+		for range 1 << 16 {
+			_, err := allocator.AllocateNext(prefix.Addr())
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		for i := uint64(0); i < 1<<16; i += 2 {
+			err := allocator.Release(
+				Add(
+					prefix,
+					i,
+				),
+			)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		for range 1 << 15 {
+			_, err := allocator.AllocateNext(prefix.Addr())
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
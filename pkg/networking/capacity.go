@@ -0,0 +1,326 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"sort"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+
+	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
+)
+
+// SubnetCapacity is the utilization of the IPv4 addresses of a subnet in an SLS network.
+type SubnetCapacity struct {
+	Network string `json:"network" yaml:"network"`
+	Subnet  string `json:"subnet" yaml:"subnet"`
+	CIDR    string `json:"cidr" yaml:"cidr"`
+
+	// Usable is the number of host addresses in the subnet, see UsableHostAddresses.
+	Usable uint64 `json:"usable" yaml:"usable"`
+
+	// Reserved is the number of host addresses given to an IP reservation or the gateway.
+	Reserved uint64 `json:"reserved" yaml:"reserved"`
+
+	// DHCP is the number of host addresses in the DHCP range.
+	DHCP uint64 `json:"dhcp" yaml:"dhcp"`
+
+	// Nested is the number of host addresses in the smaller subnets of the same network, e.g. the MetalLB pools in
+	// the CAN bootstrap_dhcp subnet.
+	Nested uint64 `json:"nested" yaml:"nested"`
+
+	// Free is the number of host addresses that are not reserved, in the DHCP range, or in a nested subnet.
+	Free uint64 `json:"free" yaml:"free"`
+
+	// LargestFreeBlock is the largest range of contiguous free addresses, e.g. 10.103.7.112-10.103.7.126.
+	LargestFreeBlock string `json:"largest-free-block,omitempty" yaml:"largest-free-block,omitempty"`
+
+	// LargestFreeSize is the number of addresses in the LargestFreeBlock.
+	LargestFreeSize uint64 `json:"largest-free-size" yaml:"largest-free-size"`
+
+	// Utilization is the percentage of the usable addresses that are not free.
+	Utilization float64 `json:"utilization" yaml:"utilization"`
+}
+
+/*
+NewSubnetCapacity returns the utilization of the IPv4 addresses of a subnet, given the CIDRs of the other subnets in
+its network.
+
+The host addresses are those AddReservation hands out, from the address of the CIDR to the one before the broadcast
+address, e.g. 10.103.7.1 to 10.103.7.254 for 10.103.7.0/23. The network address is never a host address.
+
+Only IPv4 is reported, an IPv6 cabinet subnet holds as many addresses as its IPv4 subnet (see IPv6MaskFor).
+*/
+func NewSubnetCapacity(network string, subnet slsCommon.IPSubnet, others []netip.Prefix) (
+	capacity SubnetCapacity, err error,
+) {
+	capacity = SubnetCapacity{
+		Network: network,
+		Subnet:  subnet.Name,
+		CIDR:    subnet.CIDR,
+	}
+	cidr, err := netip.ParsePrefix(subnet.CIDR)
+	if err != nil || !cidr.Addr().Is4() {
+		return capacity, fmt.Errorf(
+			"%s %s subnet has the invalid IPv4 CIDR %q",
+			network,
+			subnet.Name,
+			subnet.CIDR,
+		)
+	}
+	prefix := cidr.Masked()
+	broadcast, err := Broadcast(prefix)
+	if err != nil {
+		return capacity, fmt.Errorf(
+			"error resolving broadcast address for %s subnet because %v",
+			prefix.String(),
+			err,
+		)
+	}
+	first := cidr.Addr()
+	if first == prefix.Addr() {
+		first = first.Next()
+	}
+	isHost := func(address netip.Addr) bool {
+		return !address.Less(first) && address.Less(broadcast)
+	}
+	usable, err := UsableHostAddresses(prefix)
+	if err != nil {
+		return capacity, err
+	}
+	capacity.Usable = usable.Uint64()
+	// The addresses before the address of the CIDR are not handed out.
+	below := IPRange{
+		start: prefix.Addr().Next(),
+		end:   first.Prev(),
+	}
+	if below.size() < capacity.Usable {
+		capacity.Usable -= below.size()
+	} else {
+		capacity.Usable = 0
+	}
+
+	// The smaller subnets within this one, without those nested in another of them.
+	var nested IPNets
+	for _, other := range others {
+		other = other.Masked()
+		if other.Bits() <= prefix.Bits() || !prefix.Overlaps(other) {
+			continue
+		}
+		nested = append(
+			nested,
+			other,
+		)
+	}
+	nested = slices.DeleteFunc(
+		nested,
+		func(other netip.Prefix) bool {
+			return slices.ContainsFunc(
+				nested,
+				func(outer netip.Prefix) bool {
+					return outer.Bits() < other.Bits() && outer.Overlaps(other)
+				},
+			)
+		},
+	)
+	inNested := func(address netip.Addr) bool {
+		return slices.ContainsFunc(
+			nested,
+			func(other netip.Prefix) bool {
+				return other.Contains(address)
+			},
+		)
+	}
+
+	occupied := make(map[netip.Addr]bool)
+	reserved := []net.IP{
+		subnet.Gateway,
+	}
+	for _, reservation := range subnet.IPReservations {
+		reserved = append(
+			reserved,
+			reservation.IPAddress,
+		)
+	}
+	for _, ip := range reserved {
+		address, ok := netip.AddrFromSlice(ip.To4())
+		if ok && isHost(address) && !occupied[address] {
+			occupied[address] = true
+			capacity.Reserved++
+		}
+	}
+
+	start, startOK := netip.AddrFromSlice(subnet.DHCPStart.To4())
+	end, endOK := netip.AddrFromSlice(subnet.DHCPEnd.To4())
+	if startOK && endOK {
+		// Only the part of the range within the subnet is counted.
+		if start.Less(first) {
+			start = first
+		}
+		if broadcast.Less(end) {
+			end = broadcast
+		}
+		for address := start; address.IsValid() && !end.Less(address); address = address.Next() {
+			if !isHost(address) {
+				continue
+			}
+			capacity.DHCP++
+			occupied[address] = true
+		}
+	}
+
+	for _, other := range nested {
+		otherRange := newIPRange(other)
+		for address := otherRange.start; address.IsValid() && !otherRange.end.Less(address); address = address.Next() {
+			if isHost(address) && !occupied[address] {
+				capacity.Nested++
+			}
+		}
+	}
+
+	used := capacity.Nested + uint64(len(occupied))
+	if capacity.Usable > used {
+		capacity.Free = capacity.Usable - used
+	}
+	if capacity.Usable > 0 {
+		capacity.Utilization = float64(capacity.Usable-capacity.Free) / float64(capacity.Usable) * 100
+	}
+
+	// The occupied addresses, the nested subnets, and the network and broadcast addresses split the subnet into its
+	// free ranges.
+	used4 := append(
+		IPNets{
+			netip.PrefixFrom(
+				prefix.Addr(),
+				IPv4Size,
+			),
+			netip.PrefixFrom(
+				broadcast,
+				IPv4Size,
+			),
+		},
+		nested...,
+	)
+	for address := range maps.Keys(occupied) {
+		if !inNested(address) {
+			used4 = append(
+				used4,
+				netip.PrefixFrom(
+					address,
+					IPv4Size,
+				),
+			)
+		}
+	}
+	sort.Sort(used4)
+	freeRanges, err := freeIPRanges(
+		prefix,
+		used4,
+	)
+	if err != nil {
+		return capacity, fmt.Errorf(
+			"failed to find the free IP ranges of %s because %v",
+			prefix.String(),
+			err,
+		)
+	}
+	for _, freeRange := range freeRanges {
+		if freeRange.end.Less(first) {
+			continue
+		}
+		if freeRange.start.Less(first) {
+			freeRange.start = first
+		}
+		size := freeRange.size()
+		if size > capacity.LargestFreeSize {
+			capacity.LargestFreeSize = size
+			capacity.LargestFreeBlock = fmt.Sprintf(
+				"%s-%s",
+				freeRange.start,
+				freeRange.end,
+			)
+		}
+	}
+	return capacity, nil
+}
+
+// NewCapacityReport returns the SubnetCapacity of every subnet with an IPv4 CIDR in the SLS state, ordered by network.
+func NewCapacityReport(state slsCommon.SLSState) (report []SubnetCapacity, err error) {
+	for _, name := range slices.Sorted(maps.Keys(state.Networks)) {
+		network := state.Networks[name]
+		extraProperties, err := sls.UnmarshalNetworkExtraProperties(&network)
+		if err != nil {
+			return report, fmt.Errorf(
+				"failed to read the subnets of the %s network because %v",
+				name,
+				err,
+			)
+		}
+		var cidrs []netip.Prefix
+		for _, subnet := range extraProperties.Subnets {
+			cidr, err := netip.ParsePrefix(subnet.CIDR)
+			if err == nil {
+				cidrs = append(
+					cidrs,
+					cidr,
+				)
+			}
+		}
+		for _, subnet := range extraProperties.Subnets {
+			if subnet.CIDR == "" {
+				continue
+			}
+			capacity, err := NewSubnetCapacity(
+				name,
+				subnet,
+				cidrs,
+			)
+			if err != nil {
+				return report, err
+			}
+			report = append(
+				report,
+				capacity,
+			)
+		}
+	}
+	return report, nil
+}
+
+// size returns the number of IPv4 addresses in the range.
+func (iprange IPRange) size() uint64 {
+	if !iprange.start.Is4() || !iprange.end.Is4() || iprange.end.Less(iprange.start) {
+		return 0
+	}
+	start := iprange.start.As4()
+	end := iprange.end.As4()
+	return uint64(binary.BigEndian.Uint32(end[:])) - uint64(binary.BigEndian.Uint32(start[:])) + 1
+}
@@ -0,0 +1,239 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"encoding/json"
+	"net"
+	"net/netip"
+	"testing"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/stretchr/testify/suite"
+)
+
+type CapacityTestSuite struct {
+	suite.Suite
+}
+
+// subnet returns a subnet of the CIDR with a gateway, reservations, and a DHCP range.
+func (suite *CapacityTestSuite) subnet(
+	cidr string, gateway string, start string, end string, reservations ...string,
+) slsCommon.IPSubnet {
+	subnet := slsCommon.IPSubnet{
+		Name:      "bootstrap_dhcp",
+		CIDR:      cidr,
+		Gateway:   net.ParseIP(gateway),
+		DHCPStart: net.ParseIP(start),
+		DHCPEnd:   net.ParseIP(end),
+	}
+	for _, address := range reservations {
+		subnet.IPReservations = append(
+			subnet.IPReservations,
+			slsCommon.IPReservation{
+				Name:      address,
+				IPAddress: net.ParseIP(address),
+			},
+		)
+	}
+	return subnet
+}
+
+func (suite *CapacityTestSuite) TestFreeIPRanges() {
+	network := netip.MustParsePrefix("10.0.0.0/24")
+	ranges, err := freeIPRanges(
+		network,
+		[]netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/32"),
+			netip.MustParsePrefix("10.0.0.5/32"),
+			netip.MustParsePrefix("10.0.0.7/32"),
+			netip.MustParsePrefix("10.0.0.128/25"),
+		},
+	)
+	suite.Require().NoError(err)
+	// A single address between two subnets is a range too.
+	suite.Equal(
+		[]IPRange{
+			{
+				start: netip.MustParseAddr("10.0.0.1"),
+				end:   netip.MustParseAddr("10.0.0.4"),
+			},
+			{
+				start: netip.MustParseAddr("10.0.0.6"),
+				end:   netip.MustParseAddr("10.0.0.6"),
+			},
+			{
+				start: netip.MustParseAddr("10.0.0.8"),
+				end:   netip.MustParseAddr("10.0.0.127"),
+			},
+		},
+		ranges,
+	)
+	suite.Equal(
+		uint64(120),
+		ranges[2].size(),
+	)
+}
+
+func (suite *CapacityTestSuite) TestNewSubnetCapacity() {
+	capacity, err := NewSubnetCapacity(
+		"CMN",
+		suite.subnet(
+			"10.103.6.0/24",
+			"10.103.6.1",
+			"10.103.6.10",
+			"10.103.6.99",
+			"10.103.6.2",
+			"10.103.6.3",
+			"10.103.6.3",
+			"10.103.6.50",
+		),
+		[]netip.Prefix{
+			netip.MustParsePrefix("10.103.6.0/24"),
+			netip.MustParsePrefix("10.103.6.128/25"),
+			netip.MustParsePrefix("10.103.6.192/26"),
+			netip.MustParsePrefix("10.103.7.0/24"),
+		},
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		SubnetCapacity{
+			Network:  "CMN",
+			Subnet:   "bootstrap_dhcp",
+			CIDR:     "10.103.6.0/24",
+			Usable:   254,
+			Reserved: 4,
+			// The reservation of 10.103.6.50 is in the DHCP range, it is only counted once.
+			DHCP:             90,
+			Nested:           127,
+			Free:             34,
+			LargestFreeBlock: "10.103.6.100-10.103.6.127",
+			LargestFreeSize:  28,
+			Utilization:      float64(220) / 254 * 100,
+		},
+		capacity,
+	)
+}
+
+func (suite *CapacityTestSuite) TestNewSubnetCapacity_CIDRAddress() {
+	// AddReservation hands out no address before 10.103.7.0.
+	capacity, err := NewSubnetCapacity(
+		"CMN",
+		suite.subnet(
+			"10.103.7.0/23",
+			"10.103.6.1",
+			"10.103.7.10",
+			"10.103.7.250",
+			"10.103.7.0",
+		),
+		nil,
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		uint64(255),
+		capacity.Usable,
+	)
+	suite.Equal(
+		uint64(1),
+		capacity.Reserved,
+	)
+	suite.Equal(
+		uint64(13),
+		capacity.Free,
+	)
+	suite.Equal(
+		"10.103.7.1-10.103.7.9",
+		capacity.LargestFreeBlock,
+	)
+
+	_, err = NewSubnetCapacity(
+		"CMN",
+		suite.subnet(
+			"fd00::/64",
+			"",
+			"",
+			"",
+		),
+		nil,
+	)
+	suite.ErrorContains(
+		err,
+		`CMN bootstrap_dhcp subnet has the invalid IPv4 CIDR "fd00::/64"`,
+	)
+}
+
+func (suite *CapacityTestSuite) TestNewCapacityReport() {
+	extraProperties, err := json.Marshal(
+		slsCommon.NetworkExtraProperties{
+			CIDR: "10.103.6.0/24",
+			Subnets: []slsCommon.IPSubnet{
+				suite.subnet(
+					"10.103.6.0/24",
+					"10.103.6.1",
+					"10.103.6.10",
+					"10.103.6.99",
+				),
+				{
+					Name:  "ipv6_only",
+					CIDR6: "fd00::/64",
+				},
+			},
+		},
+	)
+	suite.Require().NoError(err)
+	var properties interface{}
+	suite.Require().NoError(
+		json.Unmarshal(
+			extraProperties,
+			&properties,
+		),
+	)
+	report, err := NewCapacityReport(
+		slsCommon.SLSState{
+			Networks: map[string]slsCommon.Network{
+				"CMN": {
+					Name:               "CMN",
+					ExtraPropertiesRaw: properties,
+				},
+			},
+		},
+	)
+	suite.Require().NoError(err)
+	suite.Len(
+		report,
+		1,
+	)
+	suite.Equal(
+		uint64(163),
+		report[0].Free,
+	)
+}
+
+func TestCapacityTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(CapacityTestSuite),
+	)
+}
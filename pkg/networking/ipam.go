@@ -30,10 +30,8 @@ import (
 	"fmt"
 	"log"
 	"math"
-	"math/big"
 	"net"
 	"net/netip"
-	"slices"
 	"strings"
 
 	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
@@ -67,78 +65,98 @@ func ContainsSubnet(network netip.Prefix, subnet netip.Prefix) bool {
 //		Add(10.0.4.0/24, 300) -> 10.0.4.255
 //	 Add(fdf8:413:de2c:204::/64, 18446744073709551615) -> fdf8:413:de2c:204:ffff:ffff:ffff:ffff
 //
-// Negative numbers are ignored.
+// Negative numbers are ignored. Offsets beyond a uint64 are given to AddOffset.
 func Add(prefix netip.Prefix, number uint64) (newIP netip.Addr) {
-	if number < 1 {
+	return AddOffset(
+		prefix,
+		Uint128From64(number),
+	)
+}
+
+// AddOffset increments the given IP by a 128-bit offset, returning the last address of the prefix if the result
+// would be outside it.
+//
+// examples:
+//
+//	AddOffset(fdf8:413:de2c::/48, 2^64) -> fdf8:413:de2c:1::
+//	AddOffset(fdf8:413:de2c::/48, 2^80) -> fdf8:413:de2c:ffff:ffff:ffff:ffff:ffff
+func AddOffset(prefix netip.Prefix, offset Uint128) (newIP netip.Addr) {
+	if offset.IsZero() {
 		return prefix.Addr()
 	}
-	startingIP := prefix.Addr().AsSlice()
-	ipInt := new(big.Int).SetBytes(startingIP)
-	toAdd, ok := new(big.Int).SetString(
-		fmt.Sprintf(
-			"%0d",
-			number,
-		),
-		10,
-	)
-	if !ok {
-		log.Fatalf(
-			"Error adding %s to IP address: %d",
+	start := Uint128FromAddr(prefix.Addr())
+	sum := start.Add(offset)
+	newIP = sum.Addr(prefix.Addr().Is4())
+	// The sum wrapped around, or left the 32 bits of an IPv4 address.
+	overflow := sum.Cmp(start) < 0 || (prefix.Addr().Is4() && sum.Cmp(Uint128From64(math.MaxUint32)) > 0)
+	if overflow || !prefix.Contains(newIP) {
+		lastIP := LastAddress(prefix)
+		log.Printf(
+			"Tried adding %s to %s but the result was out-of-range.\n Returning %s\n",
+			offset,
 			prefix.String(),
-			number,
+			lastIP,
 		)
+		newIP = lastIP
 	}
-	ipInt.Add(
-		ipInt,
-		toAdd,
-	)
-	ipBytes := ipInt.Bytes()
-	var resultIP net.IP
-	if prefix.Addr().Is4() {
-		resultIP = ipBytes
-	} else if prefix.Addr().Is6() {
-		if len(ipBytes) < net.IPv6len {
-			paddedBytes := make(
-				[]byte,
-				net.IPv6len,
-			)
-			copy(
-				paddedBytes[net.IPv6len-len(ipBytes):],
-				ipBytes,
-			)
-			ipBytes = paddedBytes
-		}
-		resultIP = ipBytes
-	}
-	if resultIP == nil {
-		log.Fatalf(
-			"Failed to resolve IP address from adding %d to %s\n",
-			number,
-			prefix.String(),
+	return newIP
+}
+
+// LastAddress returns the last address of the prefix, the broadcast address of an IPv4 prefix.
+//
+// examples:
+//
+//   - (IPv4) 10.120.234.45/27 returns 10.120.234.63
+//   - (IPv6) fdf8:413:de2c::/48 returns fdf8:413:de2c:ffff:ffff:ffff:ffff:ffff
+func LastAddress(prefix netip.Prefix) netip.Addr {
+	if !prefix.IsValid() {
+		return netip.Addr{}
+	}
+	return Uint128FromAddr(prefix.Addr()).Or(
+		hostMask(prefix.Addr().BitLen() - prefix.Bits()),
+	).Addr(prefix.Addr().Is4())
+}
+
+/*
+NthSubnet splits the prefix into subnets with the given prefix length, returning the subnet at the given index.
+
+For example, the third /64 (index 2) of fdf8:413:de2c::/48 is fdf8:413:de2c:2::/64.
+*/
+func NthSubnet(prefix netip.Prefix, bits int, index Uint128) (subnet netip.Prefix, err error) {
+	if !prefix.IsValid() || bits < prefix.Bits() || bits > prefix.Addr().BitLen() {
+		return subnet, fmt.Errorf(
+			"%s can not be split into /%d subnets",
+			prefix,
+			bits,
 		)
 	}
-	newIP, err := netip.ParseAddr(resultIP.String())
-	if !prefix.Contains(newIP) {
-		lastIP, err := Broadcast(prefix)
-		if err == nil {
-			log.Printf(
-				"Tried adding %d to %s but the result was out-of-range.\n Returning %s\n",
-				number,
-				prefix.String(),
-				lastIP,
-			)
-			newIP = lastIP
-		}
-	}
-	if err != nil {
-		log.Fatalf(
-			"Failed to add %v to %v because %v.\n",
-			number,
-			prefix.String(),
-			err,
+	if !index.Rsh(uint(bits - prefix.Bits())).IsZero() {
+		return subnet, fmt.Errorf(
+			"%s does not have %s /%d subnets",
+			prefix,
+			index.Add(Uint128From64(1)),
+			bits,
 		)
 	}
-	return newIP
+	root := Uint128FromAddr(prefix.Masked().Addr())
+	offset := index.Lsh(uint(prefix.Addr().BitLen() - bits))
+	return netip.PrefixFrom(
+		root.Add(offset).Addr(prefix.Addr().Is4()),
+		bits,
+	), nil
+}
+
+/*
+AddReservation adds a reservation to the given subnet like AddressAllocator.AddReservation, with an AddressAllocator
+of its own. The subnet is scanned for every reservation, reserving many addresses in a row should be done with one
+AddressAllocator.
+*/
+func AddReservation(subnet *slsCommon.IPSubnet, name string, comment string) (IPReservation *slsCommon.IPReservation, err error) {
+	return NewAddressAllocator().AddReservation(
+		subnet,
+		name,
+		comment,
+	)
 }
 
 /*
@@ -147,9 +165,25 @@ Adds and returns a new IPReservation to the given subnet. If an IPReservation ex
 subnet for the given name, the existing IPReservation is returned.
 
 Both an IPv4 and IPv6 address will be reserved, IPv6 is only reserved if the given subnet has a valid IPv6 CIDR.
+
+If the subnet was seeded (see IPNetwork.Seed) with a previous reservation for the same hardware or service, the
+previous addresses are reserved again.
 */
-func AddReservation(subnet *slsCommon.IPSubnet, name string, comment string) (IPReservation *slsCommon.IPReservation, err error) {
-	ipv4, ipv6, err4, err6 := FindFreeIPAddress(subnet)
+func (addresses *AddressAllocator) AddReservation(subnet *slsCommon.IPSubnet, name string, comment string) (
+	IPReservation *slsCommon.IPReservation, err error,
+) {
+	if seeded, claimed := addresses.claimSeededReservation(
+		subnet,
+		name,
+		comment,
+	); claimed {
+		subnet.IPReservations = append(
+			subnet.IPReservations,
+			seeded,
+		)
+		return &subnet.IPReservations[len(subnet.IPReservations)-1], nil
+	}
+	ipv4, ipv6, err4, err6 := addresses.FindFreeIPAddress(subnet)
 	if err4 != nil {
 		return IPReservation, fmt.Errorf(
 			"error finding a free IP address in %s (ipv4: %s, ipv6: %s) because %v",
@@ -187,16 +221,25 @@ func AddReservation(subnet *slsCommon.IPSubnet, name string, comment string) (IP
 	return IPReservation, err
 }
 
+/*
+FindFreeIPAddress finds the first free addresses of the given subnet like AddressAllocator.FindFreeIPAddress, with an
+AddressAllocator of its own.
+*/
+func FindFreeIPAddress(subnet *slsCommon.IPSubnet) (ipv4 netip.Addr, ipv6 netip.Addr, err4 error, err6 error) {
+	return NewAddressAllocator().FindFreeIPAddress(subnet)
+}
+
 /*
 FindFreeIPAddress finds the first free, usable IP addresses in a given subnet.
 Returns an IPv4 and IPv6 address, each with their own error.
+
+The subnet's SubnetAllocator is built on the first call and kept up to date with the reservations appended to the
+subnet afterwards, so that reserving many addresses in a row does not rescan the subnet for every one of them.
 */
-func FindFreeIPAddress(subnet *slsCommon.IPSubnet) (ipv4 netip.Addr, ipv6 netip.Addr, err4 error, err6 error) {
-	IPAddresses, IPAddresses6 := getIPAddressesFromSubnet(subnet)
-	ipv4, err4 = findFirstFreeIPv4Address(
-		subnet,
-		IPAddresses,
-	)
+func (addresses *AddressAllocator) FindFreeIPAddress(subnet *slsCommon.IPSubnet) (
+	ipv4 netip.Addr, ipv6 netip.Addr, err4 error, err6 error,
+) {
+	ipv4, ipv6, err4, err6 = addresses.allocator(subnet).Next()
 	if err4 != nil && subnet.CIDR != "" {
 		err4 = fmt.Errorf(
 			"failed to find a free IPv4 address in %s because %v",
@@ -204,10 +247,6 @@ func FindFreeIPAddress(subnet *slsCommon.IPSubnet) (ipv4 netip.Addr, ipv6 netip.
 			err4,
 		)
 	}
-	ipv6, err6 = findFirstFreeIPv6Address(
-		subnet,
-		IPAddresses6,
-	)
 	if err6 != nil && subnet.CIDR6 != "" {
 		err6 = fmt.Errorf(
 			"failed to find a free IPv6 address in %s because %v",
@@ -218,158 +257,16 @@ func FindFreeIPAddress(subnet *slsCommon.IPSubnet) (ipv4 netip.Addr, ipv6 netip.
 	return ipv4, ipv6, err4, err6
 }
 
-func getIPAddressesFromSubnet(subnet *slsCommon.IPSubnet) (IPAddresses []netip.Addr, IPAddresses6 []netip.Addr) {
-	for _, reservation := range subnet.IPReservations {
-		IPReservation, err := netip.ParseAddr(reservation.IPAddress.String())
-		if err == nil {
-			IPAddresses = append(
-				IPAddresses,
-				IPReservation,
-			)
-		}
-		IPReservation6, err := netip.ParseAddr(reservation.IPAddress6.String())
-		if err == nil {
-			IPAddresses6 = append(
-				IPAddresses6,
-				IPReservation6,
-			)
-		}
-	}
-	return IPAddresses, IPAddresses6
-}
-
-func findFirstFreeIPv4Address(subnet *slsCommon.IPSubnet, addresses []netip.Addr) (address netip.Addr, err error) {
-	prefix, err := netip.ParsePrefix(subnet.CIDR)
-	if err != nil {
-		return address, fmt.Errorf(
-			"error parsing CIDR '%s' because %v",
-			subnet.CIDR,
-			err,
-		)
-	}
-
-	address = prefix.Addr()
-
-	/*
-		In IPv4, we will avoid leasing IPs to the
-		- Root address (e.g. 192.168.0.0 for 192.168.0.0/24)
-		- The gateway address (e.g. 192.168.0.1 for 192.168.0.0/24)
-		- The broadcast address (e.g. 192.168.0.255 for 192.168.0.0/24)
-
-		If we resolve an address that does not belong to the subnet the function returns with an error.
-	*/
-
-	subnetRoot, err := FindCIDRRootIP(prefix)
-	if err != nil {
-		return address, fmt.Errorf(
-			"error finding subnet root IP address for %s subnet because %v",
-			prefix.String(),
-			err,
-		)
-	}
-	addresses = append(
-		addresses,
-		subnetRoot,
-	)
-	gateway, err := netip.ParseAddr(subnet.Gateway.String())
-	if err != nil {
-		return address, fmt.Errorf(
-			"error reading gateway IP for %s subnet because %v",
-			subnet.Gateway.String(),
-			err,
-		)
-	}
-	addresses = append(
-		addresses,
-		gateway,
-	)
-	broadcast, err := Broadcast(prefix)
-	if err != nil {
-		return address, fmt.Errorf(
-			"error resolving broadcast address for %s subnet because %v",
-			prefix.String(),
-			err,
-		)
-	}
-	addresses = append(
-		addresses,
-		broadcast,
-	)
-	for slices.Contains(
-		addresses,
-		address,
-	) {
-		address = address.Next()
-	}
-	if !prefix.Contains(address) {
-		address = netip.IPv4Unspecified()
-		err = fmt.Errorf(
-			"%s %s subnet has exhausted its available IPv4 addresses, failed to find a free address",
-			subnet.Name,
-			prefix.String(),
-		)
-	}
-	return address, err
-}
-
-func findFirstFreeIPv6Address(subnet *slsCommon.IPSubnet, addresses []netip.Addr) (address netip.Addr, err error) {
-	prefix, err := netip.ParsePrefix(subnet.CIDR6)
-	if err != nil {
-		return address, fmt.Errorf(
-			"error parsing CIDR '%s': %s",
-			subnet.CIDR6,
-			err,
-		)
-	}
-
-	address = prefix.Addr()
-
-	/*
-		In IPv6, we will avoid leasing IPs to the
-		- Root address (e.g. 192.168.0.0 for 192.168.0.0/24)
-		- The gateway address (e.g. 192.168.0.1 for 192.168.0.0/24)
-
-		If we resolve an address that does not belong to the subnet the function returns with an error.
-	*/
-	subnetRoot, err := FindCIDRRootIP(prefix)
-	addresses = append(
-		addresses,
-		subnetRoot,
-	)
-	if err != nil {
-		return address, fmt.Errorf(
-			"error finding subnet root IP address for %s subnet because %v",
-			prefix.String(),
-			err,
-		)
-	}
-	gateway, err := netip.ParseAddr(subnet.Gateway6.String())
-	if err != nil {
-		return address, fmt.Errorf(
-			"error resolving gateway address for %s subnet because %v",
-			subnet.Gateway6.String(),
-			err,
-		)
-	}
-	addresses = append(
-		addresses,
-		gateway,
+/*
+UpdateReservation modifies an existing reservation like AddressAllocator.UpdateReservation, with an AddressAllocator
+of its own.
+*/
+func UpdateReservation(subnet *slsCommon.IPSubnet, IPReservation slsCommon.IPReservation, IPv6Only bool) (newIPReservation slsCommon.IPReservation, err error) {
+	return NewAddressAllocator().UpdateReservation(
+		subnet,
+		IPReservation,
+		IPv6Only,
 	)
-	for slices.Contains(
-		addresses,
-		address,
-	) {
-		address = address.Next()
-	}
-	if !prefix.Contains(address) {
-		address = netip.IPv6Unspecified()
-		err = fmt.Errorf(
-			"%s %s subnet has exhausted its available IPv6 addresses, failed to find a free address",
-			subnet.Name,
-			prefix.String(),
-		)
-	}
-	return address, err
 }
 
 /*
@@ -379,10 +276,23 @@ If IPv6Only is set to true, only IPSubnet.IPAddress6 values are updated. This is
 existing SLS subnet, and we do not want to change the IPv4 reservations.
 
 If the subnet does not have IPv6 defined, then IPv6Only has no effect.
-*/
 
-func UpdateReservation(subnet *slsCommon.IPSubnet, IPReservation slsCommon.IPReservation, IPv6Only bool) (newIPReservation slsCommon.IPReservation, err error) {
-	ipv4, ipv6, err4, err6 := FindFreeIPAddress(subnet)
+The addresses being replaced are released, the caller is expected to have cleared them from the subnet's reservations.
+The reservation is rewritten in place by the caller, updating every reservation of a subnet with the same
+AddressAllocator keeps its SubnetAllocator up to date without rebuilding it.
+*/
+func (addresses *AddressAllocator) UpdateReservation(
+	subnet *slsCommon.IPSubnet, IPReservation slsCommon.IPReservation, IPv6Only bool,
+) (newIPReservation slsCommon.IPReservation, err error) {
+	allocator := addresses.allocator(subnet)
+	released := slsCommon.IPReservation{
+		IPAddress6: IPReservation.IPAddress6,
+	}
+	if !IPv6Only {
+		released.IPAddress = IPReservation.IPAddress
+	}
+	allocator.Release(released)
+	ipv4, ipv6, err4, err6 := addresses.FindFreeIPAddress(subnet)
 	if err4 != nil {
 		return newIPReservation, fmt.Errorf(
 			"error finding a free IP address in %s because %v",
@@ -397,6 +307,7 @@ func UpdateReservation(subnet *slsCommon.IPSubnet, IPReservation slsCommon.IPRes
 	if err6 == nil && ipv6.IsValid() && !ipv6.IsUnspecified() {
 		newIPReservation.IPAddress6 = ipv6.AsSlice()
 	}
+	allocator.Reserve(newIPReservation)
 	return newIPReservation, err
 }
 
@@ -662,17 +573,16 @@ func FindGatewayIP(prefix netip.Prefix) (gateway netip.Addr) {
 }
 
 /*
-FindGatewayIP returns the specified gateway for the network if one was given. Otherwise, a gateway is assumed
-by finding the first IP in the network (using FindGatewayIP).
+FindGatewayIP returns the gateway given for the network by the gateway flags of v if one was given. Otherwise, a
+gateway is assumed by finding the first IP in the network (using FindGatewayIP).
 */
-func (network *IPNetwork) FindGatewayIP(prefix netip.Prefix) (gateway netip.Addr, err error) {
+func (network *IPNetwork) FindGatewayIP(v *viper.Viper, prefix netip.Prefix) (gateway netip.Addr, err error) {
 	if network.CIDR4 != prefix.String() && network.CIDR6 != prefix.String() {
 		return gateway, fmt.Errorf(
 			"network gateway resolution was called on non-contained network prefix: %s",
 			prefix.String(),
 		)
 	}
-	v := viper.GetViper()
 	var gatewayString string
 	if prefix.Addr().Is4() {
 		gw4Key := fmt.Sprintf(
@@ -723,52 +633,22 @@ For IPv4, the "usable" addresses starts at the root and excludes the broadcast a
 of the subnet (e.g. 192.168.0.0/24 the resulting number of addresses would be 254 ((2 ** (32 - 24)) - 2), we subtract two,
 one to move the index to 0, and another for excluding the broadcast address.
 
-For IPv6, the "usable" addresses can be very large, we accommodate this in two ways:
-
-  - We use the newer big.Int interface for handling large numbers
-  - We ignore masks smaller than 64 bits, a /63 or /20 will return the result for a /64.
-
-For example, fdf8:413:de2c:204::/64 will return 18446744073709551615 (2 ** (128 - 64) - 1), we subtract to move our index to 0.
+For IPv6, the "usable" addresses can be very large, they are counted in 128 bits. For example, fdf8:413:de2c:204::/64
+will return 18446744073709551615 (2 ** (128 - 64) - 1), we subtract to move our index to 0, and fdf8:413:de2c::/48 will
+return 2 ** 80 - 1.
 */
-func UsableHostAddresses(prefix netip.Prefix) (numHosts uint64, err error) {
-	var maxBits int
+func UsableHostAddresses(prefix netip.Prefix) (numHosts Uint128, err error) {
+	if !prefix.IsValid() {
+		return numHosts, err
+	}
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	numHosts = hostMask(hostBits)
 	if prefix.Addr().Is4() {
-		// var hosts float64 // can't be uint32, since 2^32 is 1 more than uint32.
-		maxBits = IPv4Size
-		if prefix.Bits() == IPv4Size-1 || prefix.Bits() == IPv4Size {
-			numHosts = 0
-		} else {
-			hostBits := maxBits - prefix.Bits()
-			// Subtract 2 because our IP addresses index at 0 and we need to exclude the broadcast IP on IPv4.
-			numHosts = uint64(
-				math.Pow(
-					2,
-					float64(hostBits),
-				) - 2,
-			)
+		// Subtract another one because the broadcast IP is excluded on IPv4, a /31 or /32 has no usable addresses.
+		if hostBits < 2 {
+			return Uint128{}, err
 		}
-	} else if prefix.Addr().Is6() {
-		maxBits = IPv6Size
-		var tempNum = big.NewInt(2)
-		var prefixBits int
-
-		// 2^64 is our largest subnet, anything more is just multiples of 2^64 (e.g. /63 is two subnets of 2^64).
-		if prefix.Bits() < IPv6MinimumSize {
-			prefixBits = IPv6MinimumSize
-		} else {
-			prefixBits = prefix.Bits()
-		}
-		hostBits := maxBits - prefixBits
-		tempHosts := tempNum.Exp(
-			big.NewInt(2),
-			big.NewInt(int64(hostBits)),
-			nil,
-		)
-		tempHosts = tempHosts.Sub(
-			tempNum,
-			big.NewInt(1),
-		)
-		numHosts = tempHosts.Uint64()
+		numHosts = numHosts.Sub(Uint128From64(1))
 	}
 	return numHosts, err
 }
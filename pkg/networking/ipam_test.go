@@ -27,14 +27,21 @@ package networking
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/netip"
 	"testing"
 
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
 	"github.com/stretchr/testify/suite"
 )
 
 type NetworksTestSuite struct {
 	suite.Suite
+	vlans *VLANAllocator
+}
+
+func (suite *NetworksTestSuite) SetupTest() {
+	suite.vlans = NewVLANAllocator()
 }
 
 func (suite *NetworksTestSuite) TestAdd() {
@@ -83,45 +90,45 @@ func (suite *NetworksTestSuite) TestUsableHostAddresses() {
 
 	tests := []struct {
 		prefix   netip.Prefix
-		expected uint64
+		expected string
 	}{
 		{
 			prefix:   netip.MustParsePrefix("10.1.0.0/24"),
-			expected: 254,
+			expected: "254",
 		},
 		{
 			prefix:   netip.MustParsePrefix("10.1.0.0/16"),
-			expected: 65534,
+			expected: "65534",
 		},
 		{
 			prefix:   netip.MustParsePrefix("10.1.0.0/8"),
-			expected: 16777214,
+			expected: "16777214",
 		},
 		{
 			prefix:   netip.MustParsePrefix("192.168.4.3/17"),
-			expected: 32766,
+			expected: "32766",
 		},
 		{
 			prefix:   netip.MustParsePrefix("fdf8:413:de2c:204::/64"),
-			expected: 18446744073709551615,
+			expected: "18446744073709551615",
 		},
 		{
-			// Test that CIDRs larger than /64 return /64 because they're too big.
+			// CIDRs larger than a /64 are counted in full.
 			prefix:   netip.MustParsePrefix("fdf8:413:de2c:204::/63"),
-			expected: 18446744073709551615,
+			expected: "36893488147419103231",
 		},
 		{
 			prefix:   netip.MustParsePrefix("fdf8:413:de2c:204::/126"),
-			expected: 3,
+			expected: "3",
 		},
 	}
 	for _, test := range tests {
 		actual, err := UsableHostAddresses(test.prefix)
 		suite.Equal(
 			test.expected,
-			actual,
+			actual.String(),
 			fmt.Sprintf(
-				"UsableHostAddresses(%x) expected %d, actual %d",
+				"UsableHostAddresses(%x) expected %s, actual %s",
 				test.prefix.String(),
 				test.expected,
 				actual,
@@ -131,6 +138,83 @@ func (suite *NetworksTestSuite) TestUsableHostAddresses() {
 	}
 }
 
+func (suite *NetworksTestSuite) TestSpace() {
+
+	tests := []struct {
+		ranges   []IPRange
+		bits     int
+		expected string
+	}{
+		{
+			// The free range crosses an octet boundary.
+			ranges: []IPRange{
+				{
+					start: netip.MustParseAddr("10.0.0.128"),
+					end:   netip.MustParseAddr("10.0.1.127"),
+				},
+			},
+			bits:     25,
+			expected: "10.0.0.128/25",
+		},
+		{
+			// The first free range is too small once aligned to a /25.
+			ranges: []IPRange{
+				{
+					start: netip.MustParseAddr("10.0.0.192"),
+					end:   netip.MustParseAddr("10.0.1.63"),
+				},
+				{
+					start: netip.MustParseAddr("10.0.2.0"),
+					end:   netip.MustParseAddr("10.0.2.255"),
+				},
+			},
+			bits:     25,
+			expected: "10.0.2.0/25",
+		},
+		{
+			ranges: []IPRange{
+				{
+					start: netip.MustParseAddr("fd00::8000"),
+					end:   netip.MustParseAddr("fd00::1:7fff"),
+				},
+			},
+			bits:     113,
+			expected: "fd00::8000/113",
+		},
+	}
+	for _, test := range tests {
+		actual, err := space(
+			test.ranges,
+			net.CIDRMask(
+				test.bits,
+				test.ranges[0].start.BitLen(),
+			),
+		)
+		suite.NoError(err)
+		suite.Equal(
+			test.expected,
+			actual.String(),
+		)
+	}
+
+	_, err := space(
+		[]IPRange{
+			{
+				start: netip.MustParseAddr("10.0.0.192"),
+				end:   netip.MustParseAddr("10.0.1.63"),
+			},
+		},
+		net.CIDRMask(
+			25,
+			32,
+		),
+	)
+	suite.ErrorContains(
+		err,
+		"tried to fit a /25",
+	)
+}
+
 func (suite *NetworksTestSuite) TestBroadcast() {
 
 	tests := []struct {
@@ -276,6 +360,71 @@ func (suite *NetworksTestSuite) TestFindGatewayIP() {
 	}
 }
 
+func (suite *NetworksTestSuite) TestIPv6MaskFor() {
+	tests := []struct {
+		mask4    net.IPMask
+		expected int
+	}{
+		{
+			mask4:    net.CIDRMask(22, IPv4Size),
+			expected: 118,
+		},
+		{
+			mask4:    net.CIDRMask(24, IPv4Size),
+			expected: 120,
+		},
+	}
+	for _, test := range tests {
+		ones, bits := IPv6MaskFor(test.mask4).Size()
+		suite.Equal(
+			test.expected,
+			ones,
+		)
+		suite.Equal(
+			IPv6Size,
+			bits,
+		)
+	}
+}
+
+func (suite *NetworksTestSuite) TestAddReservationIPv6() {
+	subnet := slsCommon.IPSubnet{
+		Name:     "cabinet_1000",
+		CIDR:     "10.100.0.0/22",
+		Gateway:  net.ParseIP("10.100.0.1"),
+		CIDR6:    "fd00:100::400/118",
+		Gateway6: net.ParseIP("fd00:100::401"),
+	}
+	for _, expected := range []string{
+		"fd00:100::402",
+		"fd00:100::403",
+	} {
+		reservation, err := AddReservation(
+			&subnet,
+			expected,
+			"",
+		)
+		suite.Require().NoError(err)
+		suite.Equal(
+			expected,
+			reservation.IPAddress6.String(),
+		)
+	}
+
+	// An IPv6 subnet without a gateway hands out no addresses, the gateway would be unknown.
+	subnet.Gateway6 = nil
+	subnet.IPReservations = nil
+	_, err := AddReservation(
+		&subnet,
+		"x1000c0s0b0",
+		"",
+	)
+	suite.ErrorContains(
+		err,
+		"error resolving gateway address",
+	)
+}
+
 func (suite *NetworksTestSuite) TestIsVlanAllocatedBadVlans() {
 	tests := []struct {
 		vlan          int16
@@ -297,7 +446,7 @@ func (suite *NetworksTestSuite) TestIsVlanAllocatedBadVlans() {
 	}
 
 	for _, test := range tests {
-		val, err := IsVLANAllocated(uint16(test.vlan))
+		val, err := suite.vlans.IsAllocated(uint16(test.vlan))
 		suite.Equal(
 			val,
 			test.expectedBool,
@@ -327,7 +476,7 @@ func (suite *NetworksTestSuite) TestAllocateVlanBadVlans() {
 		{
 			// VLAN 0 is untagged (not a real VLAN).
 			vlan:          0,
-			expectedError: nil,
+			expectedError: errors.New("VLAN 0 is reserved (IEEE 802.1Q)"),
 		},
 		{
 			// VLAN 7 not allocated and this should work
@@ -337,7 +486,10 @@ func (suite *NetworksTestSuite) TestAllocateVlanBadVlans() {
 	}
 
 	for _, test := range tests {
-		err := AllocateVLAN(uint16(test.vlan))
+		err := suite.vlans.Allocate(
+			uint16(test.vlan),
+			"NMN",
+		)
 		suite.Equal(
 			test.expectedError,
 			err,
@@ -350,12 +502,18 @@ func (suite *NetworksTestSuite) TestVlanAllocate() {
 	var vlan uint16 = 2
 	suite.Equal(
 		nil,
-		AllocateVLAN(vlan),
+		suite.vlans.Allocate(
+			vlan,
+			"NMN",
+		),
 	)
 	// Allocate VLAN 2 the 2nd time should fail as it's already used
 	suite.Equal(
 		errors.New("VLAN already used"),
-		AllocateVLAN(vlan),
+		suite.vlans.Allocate(
+			vlan,
+			"NMN",
+		),
 	)
 }
 
@@ -363,9 +521,10 @@ func (suite *NetworksTestSuite) TestVlanAllocateRange() {
 	// Bad start and end range
 	suite.Equal(
 		errors.New("VLAN out of range"),
-		AllocateVlanRange(
+		suite.vlans.AllocateRange(
 			4092,
 			4099,
+			"NMN",
 		),
 	)
 
@@ -375,35 +534,50 @@ func (suite *NetworksTestSuite) TestVlanAllocateRange() {
 	// Bad start and end range
 	suite.Equal(
 		errors.New("VLAN range is bad - start is larger than end"),
-		AllocateVlanRange(
+		suite.vlans.AllocateRange(
 			endVlan,
 			startVlan,
+			"NMN",
 		),
 	)
 
 	// VLANs already in use in the range cannot be allocated, including ends
-	_ = AllocateVLAN(200)
-	_ = AllocateVLAN(207)
-	_ = AllocateVLAN(213)
-	_ = AllocateVLAN(299)
+	_ = suite.vlans.Allocate(
+		200,
+		"NMN",
+	)
+	_ = suite.vlans.Allocate(
+		207,
+		"NMN",
+	)
+	_ = suite.vlans.Allocate(
+		213,
+		"NMN",
+	)
+	_ = suite.vlans.Allocate(
+		299,
+		"NMN",
+	)
 	suite.Equal(
 		errors.New("VLANs already used: [200 207 213 299]"),
-		AllocateVlanRange(
+		suite.vlans.AllocateRange(
 			startVlan,
 			endVlan,
+			"NMN",
 		),
 	)
 
 	// Successfully allocate a range of VLANs
-	FreeVLAN(200)
-	FreeVLAN(207)
-	FreeVLAN(213)
-	FreeVLAN(299)
+	suite.vlans.Free(200)
+	suite.vlans.Free(207)
+	suite.vlans.Free(213)
+	suite.vlans.Free(299)
 	suite.Equal(
 		nil,
-		AllocateVlanRange(
+		suite.vlans.AllocateRange(
 			startVlan,
 			endVlan,
+			"NMN",
 		),
 	)
 }
@@ -411,43 +585,46 @@ func (suite *NetworksTestSuite) TestVlanAllocateRange() {
 func (suite *NetworksTestSuite) TestVlanFree() {
 	// Allocate VLAN 4
 	var vlan uint16 = 4
-	err := AllocateVLAN(vlan)
+	err := suite.vlans.Allocate(
+		vlan,
+		"NMN",
+	)
 	suite.Nil(
 		err,
 		fmt.Sprintf(
-			"AllocateVLAN(%d) returned an error: %v",
+			"Allocate(%d) returned an error: %v",
 			vlan,
 			err,
 		),
 	)
 	suite.True(
-		VLANs[vlan],
+		suite.vlans.allocated[vlan],
 		fmt.Sprintf(
 			"expected VLAN %d to be allocated but allocation status was %v",
 			vlan,
-			VLANs[vlan],
+			suite.vlans.allocated[vlan],
 		),
 	)
 
 	// Deallocate VLAN 4 the 1st time should succeed
-	FreeVLAN(vlan)
+	suite.vlans.Free(vlan)
 	suite.False(
-		VLANs[vlan],
+		suite.vlans.allocated[vlan],
 		fmt.Sprintf(
 			"expected VLAN %d to be freed but allocation status was %v",
 			vlan,
-			VLANs[vlan],
+			suite.vlans.allocated[vlan],
 		),
 	)
 
-	FreeVLAN(vlan)
+	suite.vlans.Free(vlan)
 	// Deallocate VLAN 4 the 2nd time should succeed
 	suite.False(
-		VLANs[vlan],
+		suite.vlans.allocated[vlan],
 		fmt.Sprintf(
 			"expected VLAN %d to be freed but its allocation status was %v",
 			vlan,
-			VLANs[vlan],
+			suite.vlans.allocated[vlan],
 		),
 	)
 }
@@ -455,34 +632,40 @@ func (suite *NetworksTestSuite) TestVlanFree() {
 func (suite *NetworksTestSuite) TestVlanFreeRange() {
 	var startVlan uint16 = 400
 	var endVlan uint16 = 499
-	err := freeVLANRange(
+	err := suite.vlans.freeRange(
 		endVlan,
 		startVlan,
 	)
 	suite.Error(
 		err,
 		fmt.Sprintf(
-			"freeVLANRange(%d,%d) did not return an error when it should have.",
+			"freeRange(%d,%d) did not return an error when it should have.",
 			endVlan,
 			startVlan,
 		),
 	)
 
-	err = AllocateVLAN(startVlan)
+	err = suite.vlans.Allocate(
+		startVlan,
+		"NMN",
+	)
 	suite.Nil(
 		err,
 		fmt.Sprintf(
-			"AllocateVLAN(%d) returned an error: %v",
+			"Allocate(%d) returned an error: %v",
 			startVlan,
 			err,
 		),
 	)
 
-	err = AllocateVLAN(endVlan)
+	err = suite.vlans.Allocate(
+		endVlan,
+		"NMN",
+	)
 	suite.Nil(
 		err,
 		fmt.Sprintf(
-			"AllocateVLAN(%d) returned an error: %v",
+			"Allocate(%d) returned an error: %v",
 			endVlan,
 			err,
 		),
@@ -490,25 +673,25 @@ func (suite *NetworksTestSuite) TestVlanFreeRange() {
 
 	// Deallocate the range the first time - should succeed
 
-	err = freeVLANRange(
+	err = suite.vlans.freeRange(
 		startVlan,
 		endVlan,
 	)
 	suite.Nil(
 		err,
 		fmt.Sprintf(
-			"freeVLANRange(%d,%d) returned an error: %v",
+			"freeRange(%d,%d) returned an error: %v",
 			startVlan,
 			endVlan,
 			err,
 		),
 	)
 
-	startAllocation, err := IsVLANAllocated(uint16(startVlan))
+	startAllocation, err := suite.vlans.IsAllocated(uint16(startVlan))
 	suite.Nil(
 		err,
 		fmt.Sprintf(
-			"IsVLANAllocated(%d) returned an error: %v",
+			"IsAllocated(%d) returned an error: %v",
 			startVlan,
 			err,
 		),
@@ -517,11 +700,11 @@ func (suite *NetworksTestSuite) TestVlanFreeRange() {
 		startAllocation,
 	)
 
-	endAllocation, err := IsVLANAllocated(uint16(endVlan))
+	endAllocation, err := suite.vlans.IsAllocated(uint16(endVlan))
 	suite.Nil(
 		err,
 		fmt.Sprintf(
-			"IsVLANAllocated(%d) returned an error: %v",
+			"IsAllocated(%d) returned an error: %v",
 			endVlan,
 			err,
 		),
@@ -531,14 +714,14 @@ func (suite *NetworksTestSuite) TestVlanFreeRange() {
 	)
 
 	// Deallocate the range the second time - should still succeed
-	err = freeVLANRange(
+	err = suite.vlans.freeRange(
 		startVlan,
 		endVlan,
 	)
 	suite.Nil(
 		err,
 		fmt.Sprintf(
-			"freeVLANRange(%d,%d) returned an error: %v",
+			"freeRange(%d,%d) returned an error: %v",
 			startVlan,
 			endVlan,
 			err,
@@ -25,7 +25,6 @@
 package networking
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -35,11 +34,9 @@ import (
 
 	"github.com/Cray-HPE/cray-site-init/pkg/csm/hms/sls"
 	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/spf13/viper"
 )
 
-// VLANs accounts for all used VLANs during a run of cray-site-init.
-var VLANs = [MaxVLAN]bool{MaxUsableVLAN: true}
-
 // IPNetwork is a type for managing IP Networks.
 type IPNetwork struct {
 	FullName           string                `yaml:"full_name"`
@@ -102,87 +99,6 @@ func (s IPNets) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
 
-/*
-IsVLANAllocated takes an int16 and tests if a given VLAN is already allocated and managed.
-If the VLAN is above our MaxUsableVLAN or below the MinVLAN an error is returned.
-*/
-func IsVLANAllocated(vlan uint16) (bool, error) {
-	if vlan > MaxUsableVLAN || vlan < MinVLAN {
-		return true, errors.New("VLAN out of range")
-	}
-	return VLANs[vlan], nil
-}
-
-// AllocateVLAN takes an uint16 and manages a single VLAN.
-func AllocateVLAN(vlan uint16) error {
-	allocated, err := IsVLANAllocated(vlan)
-	if allocated {
-		if err != nil {
-			return err
-		}
-		return errors.New("VLAN already used")
-	}
-	VLANs[vlan] = true
-	return nil
-}
-
-// FreeVLAN frees a given VLAN by setting its allocation status to "false".
-func FreeVLAN(vlan uint16) {
-	VLANs[vlan] = false
-}
-
-// freeVLANRange is strictly for expediting tests. This will free a chunk of VLANs
-func freeVLANRange(startVLAN uint16, endVLAN uint16) (err error) {
-	if startVLAN > endVLAN {
-		return fmt.Errorf(
-			"VLAN range is bad - start is larger than end (%d !> %d)",
-			startVLAN,
-			endVLAN,
-		)
-	}
-	for vlan := startVLAN; vlan <= endVLAN; vlan++ {
-		FreeVLAN(vlan)
-	}
-	return err
-}
-
-// AllocateVlanRange takes two int16 and manages a range of VLANs.
-func AllocateVlanRange(startVLAN int16, endVLAN int16) error {
-	if startVLAN > endVLAN {
-		return errors.New("VLAN range is bad - start is larger than end")
-	}
-	// Pre-test all VLANs for previous allocation
-	hasAllocationErrors := false
-	var allocatedVlans []uint16
-	for vlan := uint16(startVLAN); vlan <= uint16(endVLAN); vlan++ {
-		allocated, err := IsVLANAllocated(vlan)
-		if err != nil {
-			return err
-		}
-		if allocated {
-			hasAllocationErrors = true
-			allocatedVlans = append(
-				allocatedVlans,
-				vlan,
-			)
-		}
-	}
-	if hasAllocationErrors {
-		return fmt.Errorf(
-			"VLANs already used: %v",
-			allocatedVlans,
-		)
-	}
-
-	for vlan := uint16(startVLAN); vlan <= uint16(endVLAN); vlan++ {
-		err := AllocateVLAN(vlan)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 /*
 SupernetSubnets is a list of subnets that should report the real subnet mask of their parent network (instead of their
 own SLS subnet mask).
@@ -219,8 +135,10 @@ Example:
 - NCNs were allocated IPs in 10.1.1.0/24 but their CIDR now shows 10.1.1.0/16
 
 This allows us to reflect the real network that those devices live in as well as their starting address,.
+
+The gateways are the ones given by the gateway flags of v, see FindGatewayIP.
 */
-func (network *IPNetwork) ApplySupernetHack() {
+func (network *IPNetwork) ApplySupernetHack(v *viper.Viper) {
 	net4, err := netip.ParsePrefix(network.CIDR4)
 	if err != nil && network.CIDR4 != "" {
 		log.Fatalf(
@@ -230,7 +148,10 @@ func (network *IPNetwork) ApplySupernetHack() {
 			err,
 		)
 	}
-	gw4, err := network.FindGatewayIP(net4)
+	gw4, err := network.FindGatewayIP(
+		v,
+		net4,
+	)
 	if err != nil && net4.IsValid() {
 		log.Fatalf(
 			"couldn't find the IPv4 gateway for %s %s because %v",
@@ -249,7 +170,10 @@ func (network *IPNetwork) ApplySupernetHack() {
 			err,
 		)
 	}
-	gw6, err := network.FindGatewayIP(net6)
+	gw6, err := network.FindGatewayIP(
+		v,
+		net6,
+	)
 	if err != nil && net6.IsValid() {
 		log.Fatalf(
 			"couldn't find the IPv6 gateway for %s %s because %v",
@@ -305,9 +229,10 @@ func (network *IPNetwork) ApplySupernetHack() {
 	}
 }
 
-// GenSubnets subdivides a network into a set of subnets
+// GenSubnets subdivides a network into a set of subnets, recording the VLAN of each of them in the VLANAllocator.
 func (network *IPNetwork) GenSubnets(
 	cabinetDetails []sls.CabinetGroupDetail, cidr net.IPMask, cabinetFilter sls.CabinetFilterFunc,
+	vlans *VLANAllocator,
 ) error {
 	networkPrefix, err := netip.ParsePrefix(network.CIDR4)
 	if err != nil {
@@ -319,6 +244,8 @@ func (network *IPNetwork) GenSubnets(
 
 	// IPv6 subnetting.
 	var prefix6 netip.Prefix
+	var mask6 net.IPMask
+	subnets6 := network.AllocatedIPv6Subnets()
 	if network.CIDR6 != "" {
 		prefix6, err = netip.ParsePrefix(network.CIDR6)
 		if err != nil {
@@ -328,6 +255,8 @@ func (network *IPNetwork) GenSubnets(
 				err,
 			)
 		}
+		// Each cabinet gets as many IPv6 addresses as IPv4 addresses, not a /64, see IPv6MaskFor.
+		mask6 = IPv6MaskFor(cidr)
 	}
 
 	for _, cabinetDetail := range cabinetDetails {
@@ -392,8 +321,33 @@ func (network *IPNetwork) GenSubnets(
 
 				// IPv6 subnetting.
 				if prefix6.IsValid() {
-					tempSubnet.CIDR6 = prefix6.String()
-					tempSubnet.Gateway6 = prefix6.Addr().Next().AsSlice()
+					newSubnet6, err := free(
+						prefix6,
+						mask6,
+						subnets6,
+					)
+					if err != nil {
+						return fmt.Errorf(
+							"couldn't add IPv6 subnet for %s because %v",
+							tempSubnet.Name,
+							err,
+						)
+					}
+					subnets6 = append(
+						subnets6,
+						newSubnet6,
+					)
+					tempSubnet.CIDR6 = newSubnet6.String()
+					tempSubnet.Gateway6 = newSubnet6.Addr().Next().AsSlice()
+				}
+
+				err = vlans.Assign(
+					tmpVlanID,
+					network.Name,
+					tempSubnet.Name,
+				)
+				if err != nil {
+					return err
 				}
 
 				// Add the new subnet and move the VLANs along.
@@ -416,6 +370,23 @@ func (network *IPNetwork) GenSubnets(
 	return err
 }
 
+/*
+IPv6MaskFor returns the mask of an IPv6 subnet with as many addresses as an IPv4 subnet with the given mask, e.g. a
+/118 for a cabinet's /22.
+
+The cabinet subnets are carved this way on purpose rather than as a /64 each: the IPv6 CIDRs of the cabinet networks
+(e.g. nmn-mtn-cidr6) are a /64 themselves, like those of the other networks and their /120 bootstrap_dhcp subnets
+(see DefaultIPv6Block), and a cabinet's IPv6 addresses are assigned alongside its IPv4 ones, by the same host index.
+A subnet smaller than a /64 has no SLAAC, its addresses must be assigned by DHCPv6 or statically.
+*/
+func IPv6MaskFor(mask4 net.IPMask) net.IPMask {
+	ones, bits := mask4.Size()
+	return net.CIDRMask(
+		IPv6Size-(bits-ones),
+		IPv6Size,
+	)
+}
+
 // AllocatedIPv4Subnets returns a list of the allocated IPv4 CIDRs.
 func (network *IPNetwork) AllocatedIPv4Subnets() (subnets []netip.Prefix) {
 	for _, v := range network.Subnets {
@@ -572,7 +543,7 @@ func (network *IPNetwork) CreateSubnetByMask(
 		}
 	}
 
-	if v6mask != nil {
+	if v6mask != nil && err == nil {
 		var prefix6 netip.Prefix
 		prefix6, err = netip.ParsePrefix(network.CIDR6)
 		if err != nil {
@@ -788,7 +759,7 @@ func smallestIPv4SubnetWithin(prefix netip.Prefix, desiredHosts uint64) (smalles
 			i,
 		)
 		usableHosts, _ := UsableHostAddresses(smallestSubnet)
-		if usableHosts > desiredHosts {
+		if usableHosts.Cmp(Uint128From64(desiredHosts)) > 0 {
 			smallestPrefix = i
 		}
 	}
@@ -828,7 +799,7 @@ func smallestIPv6SubnetWithin(prefix netip.Prefix, desiredHosts uint64) (smalles
 			i,
 		)
 		usableHosts, _ := UsableHostAddresses(smallestSubnet)
-		if usableHosts > desiredHosts {
+		if usableHosts.Cmp(Uint128From64(desiredHosts)) > 0 {
 			smallestPrefix = i
 		}
 	}
@@ -853,14 +824,14 @@ func (network *IPNetwork) SubnetByName(name string) slsCommon.IPSubnet {
 }
 
 // ReserveEdgeSwitchIPs reserves (n) IP addresses for edge switches
-func ReserveEdgeSwitchIPs(subnet *slsCommon.IPSubnet, edges []string) (err error) {
+func ReserveEdgeSwitchIPs(subnet *slsCommon.IPSubnet, edges []string, addresses *AddressAllocator) (err error) {
 	for i := 0; i < len(edges); i++ {
 		name := fmt.Sprintf(
 			"chn-switch-%01d",
 			i+1,
 		)
 
-		_, err := AddReservation(
+		_, err := addresses.AddReservation(
 			subnet,
 			name,
 			edges[i],
@@ -874,14 +845,14 @@ func ReserveEdgeSwitchIPs(subnet *slsCommon.IPSubnet, edges []string) (err error
 
 // ReserveNetMgmtIPs reserves (n) IP addresses for management networking equipment
 func ReserveNetMgmtIPs(
-	subnet *slsCommon.IPSubnet, spines []string, leafs []string, leafbmcs []string, cdus []string,
+	subnet *slsCommon.IPSubnet, spines []string, leafs []string, leafbmcs []string, cdus []string, addresses *AddressAllocator,
 ) (err error) {
 	for i := 0; i < len(spines); i++ {
 		name := fmt.Sprintf(
 			"sw-spine-%03d",
 			i+1,
 		)
-		_, err := AddReservation(
+		_, err := addresses.AddReservation(
 			subnet,
 			name,
 			spines[i],
@@ -895,7 +866,7 @@ func ReserveNetMgmtIPs(
 			"sw-leaf-%03d",
 			i+1,
 		)
-		_, err := AddReservation(
+		_, err := addresses.AddReservation(
 			subnet,
 			name,
 			leafs[i],
@@ -909,7 +880,7 @@ func ReserveNetMgmtIPs(
 			"sw-leaf-bmc-%03d",
 			i+1,
 		)
-		_, err := AddReservation(
+		_, err := addresses.AddReservation(
 			subnet,
 			name,
 			leafbmcs[i],
@@ -923,7 +894,7 @@ func ReserveNetMgmtIPs(
 			"sw-cdu-%03d",
 			i+1,
 		)
-		_, err := AddReservation(
+		_, err := addresses.AddReservation(
 			subnet,
 			name,
 			cdus[i],
@@ -949,7 +920,7 @@ func UpdateDHCPRange(subnet *slsCommon.IPSubnet, applySupernetHack bool) (err er
 		)
 		return
 	}
-	if uint64(len(myReservedIPs)) > usable {
+	if Uint128From64(uint64(len(myReservedIPs))).Cmp(usable) > 0 {
 		return fmt.Errorf(
 			"could not create %s subnet in %s. There are %d reservations and only %d usable ip addresses in the subnet %v",
 			subnet.FullName,
@@ -1070,9 +1041,13 @@ func free(network netip.Prefix, mask net.IPMask, subnets []netip.Prefix) (freeNe
 
 	maskOnes, _ := mask.Size()
 	networkCapacity, _ := UsableHostAddresses(network)
-	maskPrefix, _ := netip.ParsePrefix(mask.String())
-	maskCapacity, _ := UsableHostAddresses(maskPrefix)
-	if networkCapacity < maskCapacity {
+	maskCapacity, _ := UsableHostAddresses(
+		netip.PrefixFrom(
+			network.Addr(),
+			maskOnes,
+		),
+	)
+	if networkCapacity.Cmp(maskCapacity) < 0 {
 		return freeNetwork, fmt.Errorf(
 			"prefix was %s, mask requested did not fit /%v (bit)",
 			network.String(),
@@ -1174,7 +1149,7 @@ func freeIPRanges(network netip.Prefix, subnets []netip.Prefix) (freeSubnets []I
 			currentSubnetRange := newIPRange(subnets[i])
 			nextSubnetRange := newIPRange(subnets[i+1])
 			// If the two subnets are not contiguous, then there is a free-range between them.
-			if currentSubnetRange.end.Next().Less(nextSubnetRange.start.Prev()) {
+			if currentSubnetRange.end.Next().Less(nextSubnetRange.start) {
 				freeSubnets = append(
 					freeSubnets,
 					IPRange{
@@ -1207,34 +1182,20 @@ func freeIPRanges(network netip.Prefix, subnets []netip.Prefix) (freeSubnets []I
 }
 
 func newIPRange(network netip.Prefix) (iprange IPRange) {
-	usableIPs, err := UsableHostAddresses(network)
-	if err != nil {
-		return iprange
-	}
-
-	// UsableHostAddresses will return "2^(bits - prefix) - 2" for IPv4, automatically subtracting the root and broadcast
-	// address. However, for newIPRange we want to include these. We already include the root address via our returned
-	// iprange, but we need to add one for the would-be broadcast address.
-	if network.Addr().Is4() {
-		usableIPs++
-	}
-	endAddress := Add(
-		network,
-		usableIPs,
-	)
-	startAddress, err := FindCIDRRootIP(network)
-	if err != nil {
-		log.Fatalf(
-			"failed to find subnet root IP: %v",
-			err,
-		)
+	// A single address has no usable hosts, but it is a range of its own.
+	if network.IsSingleIP() {
+		return IPRange{
+			start: network.Addr(),
+			end:   network.Addr(),
+		}
 	}
-	if startAddress == endAddress {
+	if !network.IsValid() {
 		return iprange
 	}
+	// The range includes the root and the would-be broadcast address, unlike UsableHostAddresses.
 	iprange = IPRange{
-		start: startAddress,
-		end:   endAddress,
+		start: network.Masked().Addr(),
+		end:   LastAddress(network),
 	}
 	return iprange
 }
@@ -1299,38 +1260,12 @@ func space(freeIPRanges []IPRange, mask net.IPMask) (firstFree netip.Prefix, err
 			continue
 		}
 
-		// Check that our firstFree network fits our desired mask's addresses.
-		startBinary, _ := start.MarshalBinary()
-		endBinary, _ := end.MarshalBinary()
-		var resultBinary []byte
-		for i, startOctet := range startBinary {
-			endOctet := endBinary[i]
-			resultBinary = append(
-				resultBinary,
-				endOctet-startOctet,
-			)
-		}
-		resultAddr, _ := netip.AddrFromSlice(resultBinary)
-		resultPrefix := netip.PrefixFrom(
-			resultAddr,
-			prefixLength,
-		)
-		usableHosts, err := UsableHostAddresses(resultPrefix)
-		if err != nil {
-			return firstFree, err
-		}
-		maxHosts, err := UsableHostAddresses(
-			netip.PrefixFrom(
-				start,
-				prefixLength,
-			),
-		)
-		if err != nil {
-			return firstFree, err
-		}
-		if usableHosts >= maxHosts {
-			return firstFree, err
+		// Check that our firstFree network fits our desired mask's addresses, i.e. that the free range holds its last one.
+		free := Uint128FromAddr(end).Sub(Uint128FromAddr(firstFree.Addr()))
+		if free.Cmp(hostMask(firstFree.Addr().BitLen()-prefixLength)) >= 0 {
+			return firstFree, nil
 		}
+		firstFree = netip.Prefix{}
 	}
 	err = fmt.Errorf(
 		"tried to fit a /%v",
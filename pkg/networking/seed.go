@@ -0,0 +1,569 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/Cray-HPE/hms-xname/xnametypes"
+)
+
+// SeedConflict is a subnet or IP reservation from a previous run that could not be kept as it was.
+type SeedConflict struct {
+	Network  string `json:"network" yaml:"network"`
+	Subnet   string `json:"subnet,omitempty" yaml:"subnet,omitempty"`
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	Previous string `json:"previous,omitempty" yaml:"previous,omitempty"`
+	Reason   string `json:"reason" yaml:"reason"`
+}
+
+func (conflict SeedConflict) String() string {
+	path := strings.Join(
+		slices.DeleteFunc(
+			[]string{
+				conflict.Network,
+				conflict.Subnet,
+				conflict.Name,
+			},
+			func(s string) bool {
+				return s == ""
+			},
+		),
+		"/",
+	)
+	if conflict.Previous == "" {
+		return fmt.Sprintf(
+			"%s: %s",
+			path,
+			conflict.Reason,
+		)
+	}
+	return fmt.Sprintf(
+		"%s (%s): %s",
+		path,
+		conflict.Previous,
+		conflict.Reason,
+	)
+}
+
+// subnetSeed holds the IP reservations carried over into a subnet that no hardware has claimed yet.
+type subnetSeed struct {
+	network      string
+	reservations []slsCommon.IPReservation
+}
+
+// SeedConflicts returns every subnet and IP reservation that could not be kept while seeding with this AddressAllocator.
+func (addresses *AddressAllocator) SeedConflicts() []SeedConflict {
+	addresses.mutex.Lock()
+	defer addresses.mutex.Unlock()
+	return slices.Clone(addresses.conflicts)
+}
+
+/*
+reservationKey identifies the hardware or service an IP reservation was made for. Reservations for hardware are
+identified by the xname in their comment, because their names may be hostnames that are only known after allocation.
+Everything else is identified by name.
+
+BMC reservations are named after the BMC's xname, so they are identified by name either way.
+*/
+func reservationKey(name string, comment string) string {
+	if xnametypes.IsHMSCompIDValid(comment) {
+		return comment
+	}
+	return name
+}
+
+/*
+Seed carries the subnets, VLANs, and IP reservations of this network from a previous run over, so that re-generating
+a running system does not renumber it. Subnets that existed before keep their CIDRs, gateways, VLANs, and DHCP ranges,
+and subnets that are new to this run are moved out of their way.
+
+Reservations already made on this network are given their previous addresses. The remaining previous reservations are
+held aside by the AddressAllocator, its AddReservation hands them out again when it is asked to reserve an address for
+the same hardware or service. Reservations for new hardware never receive an address held by a previous reservation.
+
+Anything that can not be kept is recorded for the AddressAllocator's SeedConflicts instead of being renumbered.
+*/
+func (network *IPNetwork) Seed(previous slsCommon.NetworkExtraProperties, addresses *AddressAllocator) {
+	if previous.CIDR != "" && previous.CIDR != network.CIDR4 {
+		addresses.addSeedConflict(
+			SeedConflict{
+				Network:  network.Name,
+				Previous: previous.CIDR,
+				Reason: fmt.Sprintf(
+					"the network's CIDR is now %s",
+					network.CIDR4,
+				),
+			},
+		)
+		return
+	}
+	networkPrefix, err := netip.ParsePrefix(network.CIDR4)
+	if err != nil {
+		addresses.addSeedConflict(
+			SeedConflict{
+				Network: network.Name,
+				Reason: fmt.Sprintf(
+					"failed to parse the network's CIDR because %v",
+					err,
+				),
+			},
+		)
+		return
+	}
+	if len(previous.VlanRange) == len(network.VlanRange) {
+		copy(
+			network.VlanRange,
+			previous.VlanRange,
+		)
+	}
+
+	previousSubnets := make(map[string]slsCommon.IPSubnet)
+	for _, subnet := range previous.Subnets {
+		previousSubnets[subnet.Name] = subnet
+	}
+	var kept, added []*slsCommon.IPSubnet
+	for _, subnet := range network.Subnets {
+		previousSubnet, ok := previousSubnets[subnet.Name]
+		if !ok {
+			added = append(
+				added,
+				subnet,
+			)
+			continue
+		}
+		previousPrefix, err := netip.ParsePrefix(previousSubnet.CIDR)
+		if err != nil || !ContainsSubnet(
+			networkPrefix,
+			previousPrefix,
+		) {
+			addresses.addSeedConflict(
+				SeedConflict{
+					Network:  network.Name,
+					Subnet:   subnet.Name,
+					Previous: previousSubnet.CIDR,
+					Reason: fmt.Sprintf(
+						"the subnet is not within the network's CIDR %s",
+						network.CIDR4,
+					),
+				},
+			)
+			continue
+		}
+		subnet.CIDR = previousSubnet.CIDR
+		subnet.Gateway = previousSubnet.Gateway
+		subnet.VlanID = previousSubnet.VlanID
+		if network.CIDR6 != "" && previousSubnet.CIDR6 != "" {
+			subnet.CIDR6 = previousSubnet.CIDR6
+			subnet.Gateway6 = previousSubnet.Gateway6
+		}
+		if previousSubnet.DHCPStart != nil && previousSubnet.DHCPEnd != nil {
+			subnet.DHCPStart = previousSubnet.DHCPStart
+			subnet.DHCPEnd = previousSubnet.DHCPEnd
+		}
+		if previousSubnet.ReservationStart != nil && previousSubnet.ReservationEnd != nil {
+			subnet.ReservationStart = previousSubnet.ReservationStart
+			subnet.ReservationEnd = previousSubnet.ReservationEnd
+		}
+		kept = append(
+			kept,
+			subnet,
+		)
+		network.seedReservations(
+			subnet,
+			previousSubnet.IPReservations,
+			addresses,
+		)
+	}
+	network.moveAddedSubnets(
+		networkPrefix,
+		kept,
+		added,
+		addresses,
+	)
+}
+
+// seedReservations gives the subnet's reservations their previous addresses, and holds the rest aside to be claimed.
+func (network *IPNetwork) seedReservations(
+	subnet *slsCommon.IPSubnet, previous []slsCommon.IPReservation, addresses *AddressAllocator,
+) {
+	prefix, _ := netip.ParsePrefix(subnet.CIDR)
+	prefix6, _ := netip.ParsePrefix(subnet.CIDR6)
+
+	previousByKey := make(map[string]slsCommon.IPReservation)
+	held := make(map[netip.Addr]bool)
+	for _, reservation := range previous {
+		addr, err := netip.ParseAddr(reservation.IPAddress.String())
+		if err != nil || !prefix.Contains(addr) {
+			addresses.addSeedConflict(
+				SeedConflict{
+					Network:  network.Name,
+					Subnet:   subnet.Name,
+					Name:     reservation.Name,
+					Previous: reservation.IPAddress.String(),
+					Reason: fmt.Sprintf(
+						"the address is not within the subnet's CIDR %s",
+						subnet.CIDR,
+					),
+				},
+			)
+			continue
+		}
+		if !prefix6.Contains(parseIP(reservation.IPAddress6)) {
+			reservation.IPAddress6 = nil
+		}
+		previousByKey[reservationKey(
+			reservation.Name,
+			reservation.Comment,
+		)] = reservation
+		held[addr] = true
+	}
+
+	var renumber []int
+	for index := range subnet.IPReservations {
+		reservation := &subnet.IPReservations[index]
+		key := reservationKey(
+			reservation.Name,
+			reservation.Comment,
+		)
+		previousReservation, ok := previousByKey[key]
+		if !ok {
+			addr := parseIP(reservation.IPAddress)
+			if held[addr] || !prefix.Contains(addr) {
+				renumber = append(
+					renumber,
+					index,
+				)
+			}
+			continue
+		}
+		delete(
+			previousByKey,
+			key,
+		)
+		reservation.IPAddress = previousReservation.IPAddress
+		if previousReservation.IPAddress6 != nil {
+			reservation.IPAddress6 = previousReservation.IPAddress6
+		}
+	}
+
+	seed := &subnetSeed{
+		network: network.Name,
+	}
+	for _, reservation := range previous {
+		key := reservationKey(
+			reservation.Name,
+			reservation.Comment,
+		)
+		if previousReservation, ok := previousByKey[key]; ok {
+			seed.reservations = append(
+				seed.reservations,
+				previousReservation,
+			)
+			delete(
+				previousByKey,
+				key,
+			)
+		}
+	}
+	addresses.mutex.Lock()
+	addresses.seeds[subnet] = seed
+	addresses.mutex.Unlock()
+	// The reservations were renumbered in place and the seeded addresses are held now, the allocator starts over.
+	addresses.Rebuild(subnet)
+
+	for _, index := range renumber {
+		ipv4, ipv6, err4, err6 := addresses.FindFreeIPAddress(subnet)
+		if err4 != nil {
+			addresses.addSeedConflict(
+				SeedConflict{
+					Network: network.Name,
+					Subnet:  subnet.Name,
+					Name:    subnet.IPReservations[index].Name,
+					Reason: fmt.Sprintf(
+						"no address is left for this new reservation because %v",
+						err4,
+					),
+				},
+			)
+			continue
+		}
+		subnet.IPReservations[index].IPAddress = ipv4.AsSlice()
+		if err6 == nil && subnet.CIDR6 != "" {
+			subnet.IPReservations[index].IPAddress6 = ipv6.AsSlice()
+		}
+		addresses.allocator(subnet).Reserve(subnet.IPReservations[index])
+	}
+}
+
+// moveAddedSubnets moves the subnets that are new to this run out of the way of the subnets that were kept.
+func (network *IPNetwork) moveAddedSubnets(
+	networkPrefix netip.Prefix, kept []*slsCommon.IPSubnet, added []*slsCommon.IPSubnet, addresses *AddressAllocator,
+) {
+	// Subnets spanning the whole network (see ApplySupernetHack) overlap everything and are left alone.
+	var taken []netip.Prefix
+	keptVLANs := make(map[int16]bool)
+	for _, subnet := range kept {
+		prefix, err := netip.ParsePrefix(subnet.CIDR)
+		if err == nil && prefix != networkPrefix {
+			taken = append(
+				taken,
+				prefix,
+			)
+		}
+		if isCabinetSubnet(subnet) {
+			keptVLANs[subnet.VlanID] = true
+		}
+	}
+
+	for _, subnet := range added {
+		if isCabinetSubnet(subnet) && keptVLANs[subnet.VlanID] {
+			network.moveCabinetVLAN(subnet)
+		}
+		prefix, err := netip.ParsePrefix(subnet.CIDR)
+		if err != nil || prefix == networkPrefix {
+			continue
+		}
+		if !slices.ContainsFunc(
+			taken,
+			prefix.Overlaps,
+		) {
+			taken = append(
+				taken,
+				prefix,
+			)
+			continue
+		}
+		moved, err := free(
+			networkPrefix,
+			net.CIDRMask(
+				prefix.Bits(),
+				IPv4Size,
+			),
+			slices.Clone(taken),
+		)
+		if err != nil {
+			addresses.addSeedConflict(
+				SeedConflict{
+					Network: network.Name,
+					Subnet:  subnet.Name,
+					Reason: fmt.Sprintf(
+						"no room is left for this new subnet because %v",
+						err,
+					),
+				},
+			)
+			continue
+		}
+		network.moveSubnet(
+			subnet,
+			moved,
+			addresses,
+		)
+		taken = append(
+			taken,
+			moved,
+		)
+	}
+}
+
+// moveSubnet moves a subnet to the given prefix, renumbering its reservations and DHCP range along with it.
+func (network *IPNetwork) moveSubnet(subnet *slsCommon.IPSubnet, prefix netip.Prefix, addresses *AddressAllocator) {
+	network.SetSubnetIP(
+		subnet,
+		prefix,
+	)
+	reservations := subnet.IPReservations
+	subnet.IPReservations = nil
+	addresses.Rebuild(subnet)
+	for _, reservation := range reservations {
+		ipv4, _, err4, _ := addresses.FindFreeIPAddress(subnet)
+		if err4 != nil {
+			addresses.addSeedConflict(
+				SeedConflict{
+					Network: network.Name,
+					Subnet:  subnet.Name,
+					Name:    reservation.Name,
+					Reason: fmt.Sprintf(
+						"no address is left for this new reservation because %v",
+						err4,
+					),
+				},
+			)
+			continue
+		}
+		reservation.IPAddress = ipv4.AsSlice()
+		subnet.IPReservations = append(
+			subnet.IPReservations,
+			reservation,
+		)
+	}
+	if subnet.DHCPStart != nil {
+		err := UpdateDHCPRange(
+			subnet,
+			false,
+		)
+		if err != nil {
+			addresses.addSeedConflict(
+				SeedConflict{
+					Network: network.Name,
+					Subnet:  subnet.Name,
+					Reason: fmt.Sprintf(
+						"failed to update the DHCP range because %v",
+						err,
+					),
+				},
+			)
+		}
+	}
+}
+
+// moveCabinetVLAN gives a new cabinet subnet the VLAN after the highest one in use, widening the network's VLAN range.
+func (network *IPNetwork) moveCabinetVLAN(subnet *slsCommon.IPSubnet) {
+	vlan := slices.Max(network.AllocatedVLANs()) + 1
+	subnet.VlanID = vlan
+	if len(network.VlanRange) == 2 && network.VlanRange[1] < vlan {
+		network.VlanRange[1] = vlan
+	}
+}
+
+func isCabinetSubnet(subnet *slsCommon.IPSubnet) bool {
+	return strings.HasPrefix(
+		subnet.Name,
+		"cabinet_",
+	)
+}
+
+/*
+claimSeededReservation returns the seeded reservation for the same hardware or service as the given name and comment,
+renamed to them. The seeded reservation is only returned if its address is still free, otherwise a conflict is recorded.
+*/
+func (addresses *AddressAllocator) claimSeededReservation(subnet *slsCommon.IPSubnet, name string, comment string) (
+	reservation slsCommon.IPReservation, claimed bool,
+) {
+	addresses.mutex.Lock()
+	seed, ok := addresses.seeds[subnet]
+	addresses.mutex.Unlock()
+	if !ok {
+		return reservation, false
+	}
+	key := reservationKey(
+		name,
+		comment,
+	)
+	index := slices.IndexFunc(
+		seed.reservations,
+		func(seeded slsCommon.IPReservation) bool {
+			return reservationKey(
+				seeded.Name,
+				seeded.Comment,
+			) == key
+		},
+	)
+	if index == -1 {
+		return reservation, false
+	}
+	seeded := seed.reservations[index]
+	seed.reservations = slices.Delete(
+		seed.reservations,
+		index,
+		index+1,
+	)
+	for _, existing := range subnet.IPReservations {
+		if existing.IPAddress.Equal(seeded.IPAddress) {
+			addresses.addSeedConflict(
+				SeedConflict{
+					Network:  seed.network,
+					Subnet:   subnet.Name,
+					Name:     name,
+					Previous: seeded.IPAddress.String(),
+					Reason: fmt.Sprintf(
+						"the address is now reserved for %s",
+						existing.Name,
+					),
+				},
+			)
+			return reservation, false
+		}
+	}
+	reservation = slsCommon.IPReservation{
+		Name:       name,
+		Comment:    comment,
+		IPAddress:  seeded.IPAddress,
+		IPAddress6: seeded.IPAddress6,
+	}
+	if reservation.IPAddress6 == nil && subnet.CIDR6 != "" {
+		_, ipv6, _, err6 := addresses.FindFreeIPAddress(subnet)
+		if err6 == nil {
+			reservation.IPAddress6 = ipv6.AsSlice()
+		}
+	}
+	return reservation, true
+}
+
+// held returns the addresses held by the unclaimed seeded reservations, a subnet that was not seeded holds none.
+func (seed *subnetSeed) held() (IPAddresses []netip.Addr, IPAddresses6 []netip.Addr) {
+	if seed == nil {
+		return IPAddresses, IPAddresses6
+	}
+	for _, reservation := range seed.reservations {
+		if addr := parseIP(reservation.IPAddress); addr.IsValid() {
+			IPAddresses = append(
+				IPAddresses,
+				addr,
+			)
+		}
+		if addr := parseIP(reservation.IPAddress6); addr.IsValid() {
+			IPAddresses6 = append(
+				IPAddresses6,
+				addr,
+			)
+		}
+	}
+	return IPAddresses, IPAddresses6
+}
+
+func (addresses *AddressAllocator) addSeedConflict(conflict SeedConflict) {
+	addresses.mutex.Lock()
+	defer addresses.mutex.Unlock()
+	addresses.conflicts = append(
+		addresses.conflicts,
+		conflict,
+	)
+}
+
+// parseIP converts a net.IP to a netip.Addr, returning the zero netip.Addr if it is unset or invalid.
+func parseIP(ip net.IP) netip.Addr {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
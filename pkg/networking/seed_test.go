@@ -0,0 +1,334 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	slsCommon "github.com/Cray-HPE/hms-sls/v2/pkg/sls-common"
+	"github.com/stretchr/testify/suite"
+)
+
+type SeedTestSuite struct {
+	suite.Suite
+	previous  slsCommon.NetworkExtraProperties
+	addresses *AddressAllocator
+}
+
+func (suite *SeedTestSuite) SetupTest() {
+	suite.addresses = NewAddressAllocator()
+	suite.previous = slsCommon.NetworkExtraProperties{
+		CIDR:      "10.252.0.0/17",
+		VlanRange: []int16{2},
+		Subnets: []slsCommon.IPSubnet{
+			{
+				Name:    "network_hardware",
+				CIDR:    "10.252.0.0/24",
+				Gateway: net.ParseIP("10.252.0.1"),
+				VlanID:  2,
+				IPReservations: []slsCommon.IPReservation{
+					{
+						Name:      "sw-spine-001",
+						Comment:   "x3000c0h37s1",
+						IPAddress: net.ParseIP("10.252.0.2"),
+					},
+				},
+			},
+			{
+				Name:    "bootstrap_dhcp",
+				CIDR:    "10.252.1.0/24",
+				Gateway: net.ParseIP("10.252.1.1"),
+				VlanID:  2,
+				IPReservations: []slsCommon.IPReservation{
+					{
+						Name:      "kubeapi-vip",
+						Comment:   "k8s-virtual-ip",
+						IPAddress: net.ParseIP("10.252.1.2"),
+					},
+					{
+						Name:      "ncn-m001",
+						Comment:   "x3000c0s1b0n0",
+						IPAddress: net.ParseIP("10.252.1.3"),
+					},
+					{
+						Name:      "ncn-m002",
+						Comment:   "x3000c0s2b0n0",
+						IPAddress: net.ParseIP("10.252.1.4"),
+					},
+				},
+			},
+		},
+	}
+}
+
+// network returns the network as a fresh run would build it, with a new spine switch and the subnets swapped around.
+func (suite *SeedTestSuite) network() *IPNetwork {
+	return &IPNetwork{
+		Name:      "NMN",
+		CIDR4:     "10.252.0.0/17",
+		VlanRange: []int16{3},
+		Subnets: []*slsCommon.IPSubnet{
+			{
+				Name:    "bootstrap_dhcp",
+				CIDR:    "10.252.0.0/24",
+				Gateway: net.ParseIP("10.252.0.1"),
+				VlanID:  3,
+				IPReservations: []slsCommon.IPReservation{
+					{
+						Name:      "kubeapi-vip",
+						Comment:   "k8s-virtual-ip",
+						IPAddress: net.ParseIP("10.252.0.2"),
+					},
+				},
+			},
+			{
+				Name:    "network_hardware",
+				CIDR:    "10.252.1.0/24",
+				Gateway: net.ParseIP("10.252.1.1"),
+				VlanID:  3,
+				IPReservations: []slsCommon.IPReservation{
+					{
+						Name:      "sw-spine-001",
+						Comment:   "x3000c0h38s1",
+						IPAddress: net.ParseIP("10.252.1.2"),
+					},
+					{
+						Name:      "sw-spine-002",
+						Comment:   "x3000c0h37s1",
+						IPAddress: net.ParseIP("10.252.1.3"),
+					},
+				},
+			},
+		},
+	}
+}
+
+func (suite *SeedTestSuite) TestSeed() {
+	network := suite.network()
+	network.Seed(
+		suite.previous,
+		suite.addresses,
+	)
+	suite.Empty(suite.addresses.SeedConflicts())
+
+	suite.Equal(
+		[]int16{2},
+		network.VlanRange,
+	)
+	hardware, err := network.LookUpSubnet("network_hardware")
+	suite.Require().NoError(err)
+	suite.Equal(
+		"10.252.0.0/24",
+		hardware.CIDR,
+	)
+	suite.Equal(
+		int16(2),
+		hardware.VlanID,
+	)
+	// The spine switch keeps its address by xname, while the new one is moved off of it.
+	suite.Equal(
+		"10.252.0.3",
+		hardware.LookupReservation("sw-spine-001").IPAddress.String(),
+	)
+	suite.Equal(
+		"10.252.0.2",
+		hardware.LookupReservation("sw-spine-002").IPAddress.String(),
+	)
+
+	bootstrap, err := network.LookUpSubnet("bootstrap_dhcp")
+	suite.Require().NoError(err)
+	suite.Equal(
+		"10.252.1.2",
+		bootstrap.LookupReservation("kubeapi-vip").IPAddress.String(),
+	)
+
+	// New hardware is given an address that is not held by an unclaimed reservation.
+	reservation, err := suite.addresses.AddReservation(
+		bootstrap,
+		"x3000c0s3b0n0",
+		"x3000c0s3b0n0",
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"10.252.1.5",
+		reservation.IPAddress.String(),
+	)
+	// Existing hardware claims its previous address.
+	reservation, err = suite.addresses.AddReservation(
+		bootstrap,
+		"x3000c0s2b0n0",
+		"x3000c0s2b0n0",
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"10.252.1.4",
+		reservation.IPAddress.String(),
+	)
+	suite.Equal(
+		"x3000c0s2b0n0",
+		reservation.Name,
+	)
+	suite.Empty(suite.addresses.SeedConflicts())
+
+	// Another run does not see the reservations seeded by this one.
+	reservation, err = NewAddressAllocator().AddReservation(
+		bootstrap,
+		"x3000c0s1b0n0",
+		"x3000c0s1b0n0",
+	)
+	suite.Require().NoError(err)
+	suite.Equal(
+		"10.252.1.3",
+		reservation.IPAddress.String(),
+	)
+}
+
+func (suite *SeedTestSuite) TestSeed_Conflicts() {
+	network := suite.network()
+	network.CIDR4 = "10.252.0.0/16"
+	network.Seed(
+		suite.previous,
+		suite.addresses,
+	)
+	suite.Equal(
+		[]SeedConflict{
+			{
+				Network:  "NMN",
+				Previous: "10.252.0.0/17",
+				Reason:   "the network's CIDR is now 10.252.0.0/16",
+			},
+		},
+		suite.addresses.SeedConflicts(),
+	)
+
+	suite.addresses = NewAddressAllocator()
+	network = suite.network()
+	network.Seed(
+		suite.previous,
+		suite.addresses,
+	)
+	bootstrap, err := network.LookUpSubnet("bootstrap_dhcp")
+	suite.Require().NoError(err)
+	_, err = AddReservationWithIP(
+		bootstrap,
+		"ncn-m001-pinned",
+		netip.MustParseAddr("10.252.1.3"),
+		"",
+	)
+	suite.Require().NoError(err)
+	_, err = suite.addresses.AddReservation(
+		bootstrap,
+		"x3000c0s1b0n0",
+		"x3000c0s1b0n0",
+	)
+	suite.Require().NoError(err)
+	conflicts := suite.addresses.SeedConflicts()
+	suite.Require().Len(
+		conflicts,
+		1,
+	)
+	suite.Equal(
+		"NMN/bootstrap_dhcp/x3000c0s1b0n0 (10.252.1.3): the address is now reserved for ncn-m001-pinned",
+		conflicts[0].String(),
+	)
+}
+
+func (suite *SeedTestSuite) TestSeed_NewCabinet() {
+	network := &IPNetwork{
+		Name:      "NMN_MTN",
+		CIDR4:     "10.100.0.0/17",
+		VlanRange: []int16{3000, 3001},
+		Subnets: []*slsCommon.IPSubnet{
+			{
+				Name:      "cabinet_1000",
+				CIDR:      "10.100.0.0/22",
+				Gateway:   net.ParseIP("10.100.0.1"),
+				VlanID:    3000,
+				DHCPStart: net.ParseIP("10.100.0.10"),
+				DHCPEnd:   net.ParseIP("10.100.3.254"),
+			},
+			{
+				Name:      "cabinet_1001",
+				CIDR:      "10.100.4.0/22",
+				Gateway:   net.ParseIP("10.100.4.1"),
+				VlanID:    3001,
+				DHCPStart: net.ParseIP("10.100.4.10"),
+				DHCPEnd:   net.ParseIP("10.100.7.254"),
+			},
+		},
+	}
+	network.Seed(
+		slsCommon.NetworkExtraProperties{
+			CIDR:      "10.100.0.0/17",
+			VlanRange: []int16{3000, 3000},
+			Subnets: []slsCommon.IPSubnet{
+				{
+					Name:      "cabinet_1001",
+					CIDR:      "10.100.0.0/22",
+					Gateway:   net.ParseIP("10.100.0.1"),
+					VlanID:    3000,
+					DHCPStart: net.ParseIP("10.100.0.10"),
+					DHCPEnd:   net.ParseIP("10.100.3.254"),
+				},
+			},
+		},
+		suite.addresses,
+	)
+	suite.Empty(suite.addresses.SeedConflicts())
+
+	kept, err := network.LookUpSubnet("cabinet_1001")
+	suite.Require().NoError(err)
+	suite.Equal(
+		"10.100.0.0/22",
+		kept.CIDR,
+	)
+	added, err := network.LookUpSubnet("cabinet_1000")
+	suite.Require().NoError(err)
+	suite.Equal(
+		"10.100.4.0/22",
+		added.CIDR,
+	)
+	suite.Equal(
+		"10.100.4.1",
+		added.Gateway.String(),
+	)
+	suite.Equal(
+		int16(3001),
+		added.VlanID,
+	)
+	suite.Equal(
+		[]int16{3000, 3001},
+		network.VlanRange,
+	)
+}
+
+func TestSeedTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(SeedTestSuite),
+	)
+}
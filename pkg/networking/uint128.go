@@ -0,0 +1,235 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+	"net/netip"
+)
+
+/*
+Uint128 is an unsigned 128-bit integer, wide enough for any IPv6 address, offset, or host count. Every arithmetic
+method wraps around like the built-in unsigned integers, callers that care about overflow compare the result.
+*/
+type Uint128 struct {
+	Hi uint64
+	Lo uint64
+}
+
+// MaxUint128 is the largest Uint128, the last address of ::/0.
+var MaxUint128 = Uint128{
+	Hi: ^uint64(0),
+	Lo: ^uint64(0),
+}
+
+// Uint128From64 returns the Uint128 of a uint64.
+func Uint128From64(v uint64) Uint128 {
+	return Uint128{
+		Lo: v,
+	}
+}
+
+// Uint128FromAddr returns the address as a Uint128, IPv4 addresses use the low 32 bits.
+func Uint128FromAddr(addr netip.Addr) Uint128 {
+	if addr.Is4() {
+		a := addr.As4()
+		return Uint128From64(uint64(binary.BigEndian.Uint32(a[:])))
+	}
+	a := addr.As16()
+	return Uint128{
+		Hi: binary.BigEndian.Uint64(a[:8]),
+		Lo: binary.BigEndian.Uint64(a[8:]),
+	}
+}
+
+// Addr returns the Uint128 as an IPv4 address when is4 is set, using the low 32 bits, or as an IPv6 address otherwise.
+func (u Uint128) Addr(is4 bool) netip.Addr {
+	if is4 {
+		var a [4]byte
+		binary.BigEndian.PutUint32(
+			a[:],
+			uint32(u.Lo),
+		)
+		return netip.AddrFrom4(a)
+	}
+	var a [16]byte
+	binary.BigEndian.PutUint64(
+		a[:8],
+		u.Hi,
+	)
+	binary.BigEndian.PutUint64(
+		a[8:],
+		u.Lo,
+	)
+	return netip.AddrFrom16(a)
+}
+
+// Add returns u+v.
+func (u Uint128) Add(v Uint128) Uint128 {
+	lo, carry := bits.Add64(
+		u.Lo,
+		v.Lo,
+		0,
+	)
+	hi, _ := bits.Add64(
+		u.Hi,
+		v.Hi,
+		carry,
+	)
+	return Uint128{
+		Hi: hi,
+		Lo: lo,
+	}
+}
+
+// Sub returns u-v.
+func (u Uint128) Sub(v Uint128) Uint128 {
+	lo, borrow := bits.Sub64(
+		u.Lo,
+		v.Lo,
+		0,
+	)
+	hi, _ := bits.Sub64(
+		u.Hi,
+		v.Hi,
+		borrow,
+	)
+	return Uint128{
+		Hi: hi,
+		Lo: lo,
+	}
+}
+
+// Lsh returns u<<n, shifting by 128 or more returns zero.
+func (u Uint128) Lsh(n uint) Uint128 {
+	switch {
+	case n >= 128:
+		return Uint128{}
+	case n >= 64:
+		return Uint128{
+			Hi: u.Lo << (n - 64),
+		}
+	case n == 0:
+		return u
+	}
+	return Uint128{
+		Hi: u.Hi<<n | u.Lo>>(64-n),
+		Lo: u.Lo << n,
+	}
+}
+
+// Rsh returns u>>n, shifting by 128 or more returns zero.
+func (u Uint128) Rsh(n uint) Uint128 {
+	switch {
+	case n >= 128:
+		return Uint128{}
+	case n >= 64:
+		return Uint128{
+			Lo: u.Hi >> (n - 64),
+		}
+	case n == 0:
+		return u
+	}
+	return Uint128{
+		Hi: u.Hi >> n,
+		Lo: u.Lo>>n | u.Hi<<(64-n),
+	}
+}
+
+// And returns u&v.
+func (u Uint128) And(v Uint128) Uint128 {
+	return Uint128{
+		Hi: u.Hi & v.Hi,
+		Lo: u.Lo & v.Lo,
+	}
+}
+
+// Or returns u|v.
+func (u Uint128) Or(v Uint128) Uint128 {
+	return Uint128{
+		Hi: u.Hi | v.Hi,
+		Lo: u.Lo | v.Lo,
+	}
+}
+
+// Not returns ^u.
+func (u Uint128) Not() Uint128 {
+	return Uint128{
+		Hi: ^u.Hi,
+		Lo: ^u.Lo,
+	}
+}
+
+// Cmp returns -1, 0, or +1 depending on whether u is less than, equal to, or greater than v.
+func (u Uint128) Cmp(v Uint128) int {
+	switch {
+	case u.Hi < v.Hi:
+		return -1
+	case u.Hi > v.Hi:
+		return 1
+	case u.Lo < v.Lo:
+		return -1
+	case u.Lo > v.Lo:
+		return 1
+	}
+	return 0
+}
+
+// IsZero returns whether u is zero.
+func (u Uint128) IsZero() bool {
+	return u.Hi == 0 && u.Lo == 0
+}
+
+// Uint64 returns u as a uint64, saturating at the largest uint64 when u does not fit.
+func (u Uint128) Uint64() uint64 {
+	if u.Hi != 0 {
+		return ^uint64(0)
+	}
+	return u.Lo
+}
+
+// Big returns u as a big.Int.
+func (u Uint128) Big() *big.Int {
+	hi := new(big.Int).SetUint64(u.Hi)
+	return hi.Lsh(
+		hi,
+		64,
+	).Or(
+		hi,
+		new(big.Int).SetUint64(u.Lo),
+	)
+}
+
+// String returns u in decimal.
+func (u Uint128) String() string {
+	return u.Big().String()
+}
+
+// hostMask returns the mask of the host bits of a prefix, i.e. 2^hostBits - 1.
+func hostMask(hostBits int) Uint128 {
+	return MaxUint128.Rsh(uint(128 - hostBits))
+}
@@ -0,0 +1,332 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"math/big"
+	"math/rand"
+	"net/netip"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type Uint128TestSuite struct {
+	suite.Suite
+}
+
+// randomPrefix is a prefix of any length with a random address, for the property-based tests.
+type randomPrefix struct {
+	netip.Prefix
+}
+
+func (randomPrefix) Generate(random *rand.Rand, _ int) reflect.Value {
+	var addr netip.Addr
+	if random.Intn(4) == 0 {
+		var a [4]byte
+		random.Read(a[:])
+		addr = netip.AddrFrom4(a)
+	} else {
+		var a [16]byte
+		random.Read(a[:])
+		addr = netip.AddrFrom16(a)
+	}
+	return reflect.ValueOf(
+		randomPrefix{
+			netip.PrefixFrom(
+				addr,
+				random.Intn(addr.BitLen()+1),
+			),
+		},
+	)
+}
+
+// bigAddr returns the address as a big.Int.
+func bigAddr(addr netip.Addr) *big.Int {
+	return new(big.Int).SetBytes(addr.AsSlice())
+}
+
+// bigPow2 returns 2^n.
+func bigPow2(n int) *big.Int {
+	return new(big.Int).Lsh(
+		big.NewInt(1),
+		uint(n),
+	)
+}
+
+// bigMod128 wraps the big.Int around like a Uint128.
+func bigMod128(v *big.Int) *big.Int {
+	return v.Mod(
+		v,
+		bigPow2(128),
+	)
+}
+
+func (suite *Uint128TestSuite) quickCheck(property any) {
+	suite.NoError(
+		quick.Check(
+			property,
+			&quick.Config{
+				MaxCount: 2000,
+				Rand:     rand.New(rand.NewSource(1)),
+			},
+		),
+	)
+}
+
+func (suite *Uint128TestSuite) TestArithmetic() {
+	suite.quickCheck(
+		func(u Uint128, v Uint128, n uint8) bool {
+			shift := uint(n % 130)
+			return u.Add(v).Big().Cmp(bigMod128(new(big.Int).Add(u.Big(), v.Big()))) == 0 &&
+				u.Sub(v).Big().Cmp(bigMod128(new(big.Int).Sub(u.Big(), v.Big()))) == 0 &&
+				u.Lsh(shift).Big().Cmp(bigMod128(new(big.Int).Lsh(u.Big(), shift))) == 0 &&
+				u.Rsh(shift).Big().Cmp(new(big.Int).Rsh(u.Big(), shift)) == 0 &&
+				u.Cmp(v) == u.Big().Cmp(v.Big())
+		},
+	)
+	suite.Equal(
+		"340282366920938463463374607431768211455",
+		MaxUint128.String(),
+	)
+	suite.Equal(
+		^uint64(0),
+		MaxUint128.Uint64(),
+	)
+	suite.Equal(
+		uint64(42),
+		Uint128From64(42).Uint64(),
+	)
+}
+
+func (suite *Uint128TestSuite) TestAddr() {
+	suite.quickCheck(
+		func(prefix randomPrefix) bool {
+			addr := prefix.Addr()
+			return Uint128FromAddr(addr).Addr(addr.Is4()) == addr &&
+				Uint128FromAddr(addr).Big().Cmp(bigAddr(addr)) == 0
+		},
+	)
+}
+
+func (suite *Uint128TestSuite) TestLastAddress() {
+	suite.quickCheck(
+		func(prefix randomPrefix) bool {
+			last := LastAddress(prefix.Prefix)
+			broadcast, err := Broadcast(prefix.Prefix)
+			next := last.Next()
+			return err == nil && last == broadcast && prefix.Contains(last) &&
+				(!next.IsValid() || !prefix.Contains(next))
+		},
+	)
+	suite.Equal(
+		"fdf8:413:de2c:ffff:ffff:ffff:ffff:ffff",
+		LastAddress(netip.MustParsePrefix("fdf8:413:de2c::/48")).String(),
+	)
+	suite.False(LastAddress(netip.Prefix{}).IsValid())
+}
+
+func (suite *Uint128TestSuite) TestUsableHostAddresses() {
+	suite.quickCheck(
+		func(prefix randomPrefix) bool {
+			hosts, err := UsableHostAddresses(prefix.Prefix)
+			hostBits := prefix.Addr().BitLen() - prefix.Bits()
+			expected := new(big.Int).Sub(
+				bigPow2(hostBits),
+				big.NewInt(1),
+			)
+			if prefix.Addr().Is4() {
+				expected.Sub(
+					expected,
+					big.NewInt(1),
+				)
+				if hostBits < 2 {
+					expected.SetInt64(0)
+				}
+			}
+			return err == nil && hosts.Big().Cmp(expected) == 0
+		},
+	)
+	for cidr, expected := range map[string]string{
+		"fdf8:413:de2c::/48": "1208925819614629174706175",
+		"::/0":               "340282366920938463463374607431768211455",
+		"10.0.0.0/31":        "0",
+		"10.0.0.0/30":        "2",
+	} {
+		hosts, err := UsableHostAddresses(netip.MustParsePrefix(cidr))
+		suite.NoError(err)
+		suite.Equal(
+			expected,
+			hosts.String(),
+			cidr,
+		)
+	}
+}
+
+func (suite *Uint128TestSuite) TestAddOffset() {
+	suite.quickCheck(
+		func(prefix randomPrefix, offset Uint128) bool {
+			masked := prefix.Masked()
+			hostBits := prefix.Addr().BitLen() - prefix.Bits()
+			// Only offsets within the prefix, every other one returns the last address.
+			offset = offset.And(hostMask(hostBits))
+			addr := AddOffset(
+				masked,
+				offset,
+			)
+			expected := new(big.Int).Add(
+				bigAddr(masked.Addr()),
+				offset.Big(),
+			)
+			return masked.Contains(addr) && bigAddr(addr).Cmp(expected) == 0
+		},
+	)
+	suite.quickCheck(
+		func(prefix randomPrefix, n uint8) bool {
+			expected := prefix.Addr()
+			for range n {
+				expected = expected.Next()
+			}
+			if !prefix.Contains(expected) {
+				return true
+			}
+			return AddOffset(
+				prefix.Prefix,
+				Uint128From64(uint64(n)),
+			) == expected
+		},
+	)
+	prefix := netip.MustParsePrefix("fdf8:413:de2c::/48")
+	suite.Equal(
+		"fdf8:413:de2c:1::",
+		AddOffset(
+			prefix,
+			Uint128From64(1).Lsh(64),
+		).String(),
+	)
+	suite.Equal(
+		LastAddress(prefix),
+		AddOffset(
+			prefix,
+			Uint128From64(1).Lsh(80),
+		),
+	)
+	suite.Equal(
+		"255.255.255.255",
+		AddOffset(
+			netip.MustParsePrefix("255.255.255.0/24"),
+			Uint128From64(1<<32),
+		).String(),
+	)
+}
+
+func (suite *Uint128TestSuite) TestNthSubnet() {
+	suite.quickCheck(
+		func(prefix randomPrefix, extra uint8, index Uint128) bool {
+			bits := prefix.Bits() + int(extra)%(prefix.Addr().BitLen()-prefix.Bits()+1)
+			index = index.And(hostMask(bits - prefix.Bits()))
+			subnet, err := NthSubnet(
+				prefix.Prefix,
+				bits,
+				index,
+			)
+			if err != nil || subnet.Bits() != bits || subnet.Masked() != subnet {
+				return false
+			}
+			if !prefix.Contains(subnet.Addr()) || !prefix.Contains(LastAddress(subnet)) {
+				return false
+			}
+			// The next subnet starts right after this one.
+			next, err := NthSubnet(
+				prefix.Prefix,
+				bits,
+				index.Add(Uint128From64(1)),
+			)
+			if index == hostMask(bits-prefix.Bits()) {
+				return err != nil
+			}
+			return err == nil && next.Addr() == LastAddress(subnet).Next()
+		},
+	)
+	subnet, err := NthSubnet(
+		netip.MustParsePrefix("fdf8:413:de2c::/48"),
+		64,
+		Uint128From64(2),
+	)
+	suite.NoError(err)
+	suite.Equal(
+		"fdf8:413:de2c:2::/64",
+		subnet.String(),
+	)
+	_, err = NthSubnet(
+		netip.MustParsePrefix("10.252.0.0/17"),
+		24,
+		Uint128From64(128),
+	)
+	suite.EqualError(
+		err,
+		"10.252.0.0/17 does not have 129 /24 subnets",
+	)
+	_, err = NthSubnet(
+		netip.MustParsePrefix("10.252.0.0/17"),
+		16,
+		Uint128{},
+	)
+	suite.EqualError(
+		err,
+		"10.252.0.0/17 can not be split into /16 subnets",
+	)
+}
+
+func (suite *Uint128TestSuite) TestSmallestIPv6SubnetWithin() {
+	// More hosts than a /64 holds need a larger subnet.
+	subnet, err := smallestIPv6SubnetWithin(
+		netip.MustParsePrefix("fdf8:413:de2c::/48"),
+		^uint64(0),
+	)
+	suite.NoError(err)
+	suite.Equal(
+		"fdf8:413:de2c::/63",
+		subnet.String(),
+	)
+	subnet, err = smallestIPv6SubnetWithin(
+		netip.MustParsePrefix("fdf8:413:de2c::/48"),
+		1000,
+	)
+	suite.NoError(err)
+	suite.Equal(
+		"fdf8:413:de2c::/118",
+		subnet.String(),
+	)
+}
+
+func TestUint128TestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(Uint128TestSuite),
+	)
+}
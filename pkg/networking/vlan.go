@@ -0,0 +1,401 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// VLANOwner is a network, or a subnet of it, using a VLAN. An owner without a subnet allocated the VLAN for its network.
+type VLANOwner struct {
+	Network string `json:"network"`
+	Subnet  string `json:"subnet,omitempty"`
+}
+
+// VLANOwnership is the reservation and the owners of a VLAN that is in use.
+type VLANOwnership struct {
+	VLAN uint16 `json:"vlan"`
+
+	// Reserved is why the VLAN can not be allocated, e.g. because it is used upstream of the system.
+	Reserved string      `json:"reserved,omitempty"`
+	Owners   []VLANOwner `json:"owners,omitempty"`
+}
+
+/*
+VLANAllocator accounts for the VLANs used during a run of cray-site-init. Every run creates its own, so that runs in
+the same process do not see each other's VLANs. It is safe for concurrent use.
+
+VLANs are either allocated by a network, reserved (see Reserve), or free. Subnets record which VLAN they use with
+Assign, which is reported by Report along with the network that allocated it.
+*/
+type VLANAllocator struct {
+	mutex     sync.Mutex
+	allocated [MaxVLAN]bool
+	reserved  map[uint16]string
+	owners    map[uint16][]VLANOwner
+}
+
+/*
+NewVLANAllocator returns a VLANAllocator with every usable VLAN free, the first and the last VLAN ID are reserved by
+IEEE 802.1Q.
+*/
+func NewVLANAllocator() *VLANAllocator {
+	allocator := &VLANAllocator{
+		reserved: make(map[uint16]string),
+		owners:   make(map[uint16][]VLANOwner),
+	}
+	for _, vlan := range []uint16{
+		MinVLAN,
+		MaxUsableVLAN,
+	} {
+		allocator.allocated[vlan] = true
+		allocator.reserved[vlan] = "IEEE 802.1Q"
+	}
+	return allocator
+}
+
+/*
+ParseVLANRange parses a VLAN ID (e.g. 100) or an inclusive range of VLAN IDs (e.g. 3000-3099), returning its first
+and last VLAN. VLAN 0 is not a VLAN ID, it only carries the priority of untagged frames.
+*/
+func ParseVLANRange(vlans string) (start uint16, end uint16, err error) {
+	first, last, isRange := strings.Cut(
+		strings.TrimSpace(vlans),
+		"-",
+	)
+	if !isRange {
+		last = first
+	}
+	parsedStart, errStart := strconv.ParseUint(
+		strings.TrimSpace(first),
+		10,
+		16,
+	)
+	parsedEnd, errEnd := strconv.ParseUint(
+		strings.TrimSpace(last),
+		10,
+		16,
+	)
+	if errStart != nil || errEnd != nil {
+		return start, end, fmt.Errorf(
+			"%q is not a VLAN ID or a range of VLAN IDs",
+			vlans,
+		)
+	}
+	start, end = uint16(parsedStart), uint16(parsedEnd)
+	if start <= MinVLAN || start > end || end > MaxUsableVLAN {
+		return start, end, fmt.Errorf(
+			"%q is not a range of VLAN IDs between %d and %d",
+			vlans,
+			MinVLAN+1,
+			MaxUsableVLAN,
+		)
+	}
+	return start, end, nil
+}
+
+// Reserve takes the VLANs from start to end out of allocation, for the given reason. They may already be allocated.
+func (allocator *VLANAllocator) Reserve(start uint16, end uint16, reason string) error {
+	if start > end {
+		return errors.New("VLAN range is bad - start is larger than end")
+	}
+	if end > MaxUsableVLAN {
+		return errors.New("VLAN out of range")
+	}
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+	for vlan := start; vlan <= end; vlan++ {
+		allocator.allocated[vlan] = true
+		allocator.reserved[vlan] = reason
+	}
+	return nil
+}
+
+/*
+IsAllocated takes an uint16 and tests if a given VLAN is already allocated, or reserved.
+If the VLAN is above our MaxUsableVLAN or below the MinVLAN an error is returned.
+*/
+func (allocator *VLANAllocator) IsAllocated(vlan uint16) (bool, error) {
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+	return allocator.isAllocated(vlan)
+}
+
+func (allocator *VLANAllocator) isAllocated(vlan uint16) (bool, error) {
+	if vlan > MaxUsableVLAN || vlan < MinVLAN {
+		return true, errors.New("VLAN out of range")
+	}
+	return allocator.allocated[vlan], nil
+}
+
+// Allocate takes an uint16 and allocates a single VLAN for the given network.
+func (allocator *VLANAllocator) Allocate(vlan uint16, network string) error {
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+	return allocator.allocate(
+		vlan,
+		network,
+	)
+}
+
+func (allocator *VLANAllocator) allocate(vlan uint16, network string) error {
+	allocated, err := allocator.isAllocated(vlan)
+	if allocated {
+		if err != nil {
+			return err
+		}
+		if reason, ok := allocator.reserved[vlan]; ok {
+			return fmt.Errorf(
+				"VLAN %d is reserved (%s)",
+				vlan,
+				reason,
+			)
+		}
+		return errors.New("VLAN already used")
+	}
+	allocator.allocated[vlan] = true
+	allocator.addOwner(
+		vlan,
+		VLANOwner{
+			Network: network,
+		},
+	)
+	return nil
+}
+
+// AllocateRange takes two int16 and allocates a range of VLANs for the given network, or none of them at all.
+func (allocator *VLANAllocator) AllocateRange(startVLAN int16, endVLAN int16, network string) error {
+	if startVLAN > endVLAN {
+		return errors.New("VLAN range is bad - start is larger than end")
+	}
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+
+	// Pre-test all VLANs for previous allocation
+	var allocatedVlans []uint16
+	var reservedVlans []uint16
+	for vlan := uint16(startVLAN); vlan <= uint16(endVLAN); vlan++ {
+		allocated, err := allocator.isAllocated(vlan)
+		if err != nil {
+			return err
+		}
+		if _, ok := allocator.reserved[vlan]; ok {
+			reservedVlans = append(
+				reservedVlans,
+				vlan,
+			)
+		} else if allocated {
+			allocatedVlans = append(
+				allocatedVlans,
+				vlan,
+			)
+		}
+	}
+	if len(allocatedVlans) > 0 {
+		return fmt.Errorf(
+			"VLANs already used: %v",
+			allocatedVlans,
+		)
+	}
+	if len(reservedVlans) > 0 {
+		return fmt.Errorf(
+			"VLANs reserved: %v",
+			reservedVlans,
+		)
+	}
+
+	for vlan := uint16(startVLAN); vlan <= uint16(endVLAN); vlan++ {
+		err := allocator.allocate(
+			vlan,
+			network,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Assign records that the subnet of the network uses the VLAN, allocating it if it is free. Subnets of different
+networks may share a VLAN, e.g. the MetalLB pools use the VLAN of their network, only reserved VLANs are refused.
+A nil VLANAllocator records nothing.
+*/
+func (allocator *VLANAllocator) Assign(vlan int16, network string, subnet string) error {
+	if allocator == nil {
+		return nil
+	}
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+	if _, err := allocator.isAllocated(uint16(vlan)); err != nil {
+		return fmt.Errorf(
+			"VLAN %d of the %s %s subnet is out of range",
+			vlan,
+			network,
+			subnet,
+		)
+	}
+	if reason, ok := allocator.reserved[uint16(vlan)]; ok {
+		return fmt.Errorf(
+			"VLAN %d of the %s %s subnet is reserved (%s)",
+			vlan,
+			network,
+			subnet,
+			reason,
+		)
+	}
+	allocator.allocated[vlan] = true
+	allocator.addOwner(
+		uint16(vlan),
+		VLANOwner{
+			Network: network,
+			Subnet:  subnet,
+		},
+	)
+	return nil
+}
+
+func (allocator *VLANAllocator) addOwner(vlan uint16, owner VLANOwner) {
+	if !slices.Contains(
+		allocator.owners[vlan],
+		owner,
+	) {
+		allocator.owners[vlan] = append(
+			allocator.owners[vlan],
+			owner,
+		)
+	}
+}
+
+// Free frees a given VLAN, dropping its owners. Reserved VLANs stay reserved.
+func (allocator *VLANAllocator) Free(vlan uint16) {
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+	if vlan > MaxUsableVLAN {
+		return
+	}
+	delete(
+		allocator.owners,
+		vlan,
+	)
+	if _, ok := allocator.reserved[vlan]; !ok {
+		allocator.allocated[vlan] = false
+	}
+}
+
+// freeRange is strictly for expediting tests. This will free a chunk of VLANs
+func (allocator *VLANAllocator) freeRange(startVLAN uint16, endVLAN uint16) (err error) {
+	if startVLAN > endVLAN {
+		return fmt.Errorf(
+			"VLAN range is bad - start is larger than end (%d !> %d)",
+			startVLAN,
+			endVLAN,
+		)
+	}
+	for vlan := startVLAN; vlan <= endVLAN; vlan++ {
+		allocator.Free(vlan)
+	}
+	return err
+}
+
+// Report returns the ownership of every VLAN in use, ordered by VLAN ID.
+func (allocator *VLANAllocator) Report() (report []VLANOwnership) {
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+	for vlan := range uint16(MaxVLAN) {
+		if !allocator.allocated[vlan] {
+			continue
+		}
+		owners := slices.Clone(allocator.owners[vlan])
+		slices.SortFunc(
+			owners,
+			func(a VLANOwner, b VLANOwner) int {
+				return strings.Compare(
+					a.Network+"/"+a.Subnet,
+					b.Network+"/"+b.Subnet,
+				)
+			},
+		)
+		report = append(
+			report,
+			VLANOwnership{
+				VLAN:     vlan,
+				Reserved: allocator.reserved[vlan],
+				Owners:   owners,
+			},
+		)
+	}
+	return report
+}
+
+// MarshalJSON encodes the Report of the VLANAllocator.
+func (allocator *VLANAllocator) MarshalJSON() ([]byte, error) {
+	return json.Marshal(allocator.Report())
+}
+
+// UnmarshalJSON restores the VLANs of a Report, e.g. the vlans.json of a system directory, in the VLANAllocator.
+func (allocator *VLANAllocator) UnmarshalJSON(data []byte) error {
+	var report []VLANOwnership
+	err := json.Unmarshal(
+		data,
+		&report,
+	)
+	if err != nil {
+		return err
+	}
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+	if allocator.reserved == nil {
+		allocator.reserved = make(map[uint16]string)
+	}
+	if allocator.owners == nil {
+		allocator.owners = make(map[uint16][]VLANOwner)
+	}
+	for _, ownership := range report {
+		if ownership.VLAN >= MaxVLAN {
+			return fmt.Errorf(
+				"VLAN %d is out of range",
+				ownership.VLAN,
+			)
+		}
+		allocator.allocated[ownership.VLAN] = true
+		if ownership.Reserved != "" {
+			allocator.reserved[ownership.VLAN] = ownership.Reserved
+		}
+		for _, owner := range ownership.Owners {
+			allocator.addOwner(
+				ownership.VLAN,
+				owner,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package networking
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type VLANAllocatorTestSuite struct {
+	suite.Suite
+	vlans *VLANAllocator
+}
+
+func (suite *VLANAllocatorTestSuite) SetupTest() {
+	suite.vlans = NewVLANAllocator()
+}
+
+func (suite *VLANAllocatorTestSuite) TestParseVLANRange() {
+	for _, test := range []struct {
+		vlans string
+		start uint16
+		end   uint16
+		err   bool
+	}{
+		{
+			vlans: "100",
+			start: 100,
+			end:   100,
+		},
+		{
+			vlans: " 3000 - 3099 ",
+			start: 3000,
+			end:   3099,
+		},
+		{
+			vlans: "3099-3000",
+			err:   true,
+		},
+		{
+			vlans: "4000-4096",
+			err:   true,
+		},
+		{
+			vlans: "vlan100",
+			err:   true,
+		},
+		{
+			vlans: "-1",
+			err:   true,
+		},
+		{
+			vlans: "0-10",
+			err:   true,
+		},
+	} {
+		start, end, err := ParseVLANRange(test.vlans)
+		if test.err {
+			suite.Error(
+				err,
+				test.vlans,
+			)
+			continue
+		}
+		suite.NoError(err)
+		suite.Equal(
+			test.start,
+			start,
+		)
+		suite.Equal(
+			test.end,
+			end,
+		)
+	}
+}
+
+func (suite *VLANAllocatorTestSuite) TestReserve() {
+	suite.Require().NoError(
+		suite.vlans.Reserve(
+			100,
+			109,
+			"site",
+		),
+	)
+	allocated, err := suite.vlans.IsAllocated(105)
+	suite.NoError(err)
+	suite.True(allocated)
+	suite.EqualError(
+		suite.vlans.Allocate(
+			105,
+			"NMN",
+		),
+		"VLAN 105 is reserved (site)",
+	)
+	suite.EqualError(
+		suite.vlans.AllocateRange(
+			95,
+			104,
+			"NMN",
+		),
+		"VLANs reserved: [100 101 102 103 104]",
+	)
+	suite.ErrorContains(
+		suite.vlans.Assign(
+			109,
+			"NMN",
+			"cabinet_3000",
+		),
+		"VLAN 109 of the NMN cabinet_3000 subnet is reserved (site)",
+	)
+
+	// Reserved VLANs can not be freed.
+	suite.vlans.Free(105)
+	allocated, err = suite.vlans.IsAllocated(105)
+	suite.NoError(err)
+	suite.True(allocated)
+
+	suite.Error(
+		suite.vlans.Reserve(
+			4000,
+			4096,
+			"site",
+		),
+	)
+	suite.EqualError(
+		suite.vlans.Allocate(
+			MaxUsableVLAN,
+			"NMN",
+		),
+		"VLAN 4095 is reserved (IEEE 802.1Q)",
+	)
+	suite.EqualError(
+		suite.vlans.Allocate(
+			MinVLAN,
+			"NMN",
+		),
+		"VLAN 0 is reserved (IEEE 802.1Q)",
+	)
+}
+
+func (suite *VLANAllocatorTestSuite) TestAssign() {
+	suite.Require().NoError(
+		suite.vlans.Allocate(
+			2,
+			"NMN",
+		),
+	)
+
+	// Subnets may share the VLAN of their network, and of other networks.
+	for _, owner := range []VLANOwner{
+		{
+			Network: "NMN",
+			Subnet:  "bootstrap_dhcp",
+		},
+		{
+			Network: "NMNLB",
+			Subnet:  "nmn_metallb_address_pool",
+		},
+		{
+			Network: "NMN",
+			Subnet:  "bootstrap_dhcp",
+		},
+	} {
+		suite.NoError(
+			suite.vlans.Assign(
+				2,
+				owner.Network,
+				owner.Subnet,
+			),
+		)
+	}
+	suite.NoError(
+		suite.vlans.Assign(
+			7,
+			"HMN",
+			"bootstrap_dhcp",
+		),
+	)
+	allocated, err := suite.vlans.IsAllocated(7)
+	suite.NoError(err)
+	suite.True(allocated)
+	suite.Error(
+		suite.vlans.Assign(
+			-1,
+			"HMN",
+			"bootstrap_dhcp",
+		),
+	)
+
+	suite.NoError(
+		(*VLANAllocator)(nil).Assign(
+			7,
+			"HMN",
+			"bootstrap_dhcp",
+		),
+	)
+	suite.Equal(
+		[]VLANOwnership{
+			{
+				VLAN:     MinVLAN,
+				Reserved: "IEEE 802.1Q",
+			},
+			{
+				VLAN: 2,
+				Owners: []VLANOwner{
+					{
+						Network: "NMN",
+					},
+					{
+						Network: "NMN",
+						Subnet:  "bootstrap_dhcp",
+					},
+					{
+						Network: "NMNLB",
+						Subnet:  "nmn_metallb_address_pool",
+					},
+				},
+			},
+			{
+				VLAN: 7,
+				Owners: []VLANOwner{
+					{
+						Network: "HMN",
+						Subnet:  "bootstrap_dhcp",
+					},
+				},
+			},
+			{
+				VLAN:     MaxUsableVLAN,
+				Reserved: "IEEE 802.1Q",
+			},
+		},
+		suite.vlans.Report(),
+	)
+}
+
+func (suite *VLANAllocatorTestSuite) TestMarshalJSON() {
+	suite.Require().NoError(
+		suite.vlans.Reserve(
+			100,
+			100,
+			"site",
+		),
+	)
+	suite.Require().NoError(
+		suite.vlans.Assign(
+			4,
+			"HMN",
+			"bootstrap_dhcp",
+		),
+	)
+	data, err := json.Marshal(suite.vlans)
+	suite.Require().NoError(err)
+	suite.JSONEq(
+		`[
+			{"vlan": 0, "reserved": "IEEE 802.1Q"},
+			{"vlan": 4, "owners": [{"network": "HMN", "subnet": "bootstrap_dhcp"}]},
+			{"vlan": 100, "reserved": "site"},
+			{"vlan": 4095, "reserved": "IEEE 802.1Q"}
+		]`,
+		string(data),
+	)
+}
+
+func (suite *VLANAllocatorTestSuite) TestUnmarshalJSON() {
+	vlans := NewVLANAllocator()
+	suite.Require().NoError(
+		json.Unmarshal(
+			[]byte(`[
+				{"vlan": 4, "owners": [{"network": "HMN", "subnet": "bootstrap_dhcp"}]},
+				{"vlan": 100, "reserved": "site"},
+				{"vlan": 4095, "reserved": "IEEE 802.1Q"}
+			]`),
+			vlans,
+		),
+	)
+	suite.Require().NoError(
+		vlans.Assign(
+			4,
+			"HMN",
+			"cabinet_3000",
+		),
+	)
+	suite.Error(
+		vlans.Allocate(
+			100,
+			"NMN",
+		),
+	)
+	suite.Equal(
+		[]VLANOwnership{
+			{
+				VLAN:     MinVLAN,
+				Reserved: "IEEE 802.1Q",
+			},
+			{
+				VLAN: 4,
+				Owners: []VLANOwner{
+					{
+						Network: "HMN",
+						Subnet:  "bootstrap_dhcp",
+					},
+					{
+						Network: "HMN",
+						Subnet:  "cabinet_3000",
+					},
+				},
+			},
+			{
+				VLAN:     100,
+				Reserved: "site",
+			},
+			{
+				VLAN:     MaxUsableVLAN,
+				Reserved: "IEEE 802.1Q",
+			},
+		},
+		vlans.Report(),
+	)
+
+	suite.Error(
+		json.Unmarshal(
+			[]byte(`[{"vlan": 4096}]`),
+			vlans,
+		),
+	)
+}
+
+func (suite *VLANAllocatorTestSuite) TestConcurrentAllocate() {
+	var wg sync.WaitGroup
+	failed := make(chan uint16, 2*MaxUsableVLAN)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for vlan := uint16(FirstVLAN); vlan < MaxUsableVLAN; vlan++ {
+				err := suite.vlans.Allocate(
+					vlan,
+					"NMN",
+				)
+				if err != nil {
+					failed <- vlan
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(failed)
+
+	// Every VLAN was allocated by exactly one of the goroutines.
+	suite.Len(
+		failed,
+		MaxUsableVLAN-FirstVLAN,
+	)
+	suite.Len(
+		suite.vlans.Report(),
+		MaxVLAN,
+	)
+}
+
+func TestVLANAllocatorTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(VLANAllocatorTestSuite),
+	)
+}
@@ -0,0 +1,215 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SealedSecret - Minimum struct to determine secret scope
+// and access encrypted data
+type SealedSecret struct {
+	Spec struct {
+		EncryptedData map[string]string `yaml:"encryptedData"`
+		Template      struct {
+			Metadata struct {
+				Annotations map[string]string
+			}
+		}
+	}
+}
+
+// Customizations - Minimum customizations (shasta-cfg) struct
+// to access sealed secrets
+type Customizations struct {
+	Spec struct {
+		Kubernetes struct {
+			SealedSecrets map[string]SealedSecret `yaml:"sealed_secrets"`
+		}
+	}
+}
+
+// LoadEncryptedData loads shasta-cfg customizations, then attempts to return the encrypted data
+// from secretName -> dataName
+func LoadEncryptedData(
+	filePath string, secretName string, dataName string,
+) (
+	[]byte, error,
+) {
+
+	customizations, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var data Customizations
+	if err := yaml.Unmarshal(
+		customizations,
+		&data,
+	); err != nil {
+		return nil, err
+	}
+
+	// verify sealed secret scope is cluster-wide
+	clusterWide, ok := data.Spec.Kubernetes.SealedSecrets[secretName].Spec.Template.Metadata.Annotations["sealedsecrets.bitnami.com/cluster-wide"]
+
+	// CMS is currently only using cluster-wide sealed secrets,
+	// this is important as namespaced secrets include the
+	// namespace as part of encryption process.
+	if !ok || clusterWide != "true" {
+		return nil, errors.New("sealed secret does not have cluster-wide scope, namespaced scope decryption not implemented")
+	}
+
+	b64Data, ok := data.Spec.Kubernetes.SealedSecrets[secretName].Spec.EncryptedData[dataName]
+	if !ok {
+		return nil, errors.New("sealed secret or data attribute does not exist")
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(b64Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return ciphertext, nil
+}
+
+// LoadPrivateKey loads and returns the sealed secret RSA private key
+func LoadPrivateKey(filePath string) (
+	*rsa.PrivateKey, error,
+) {
+
+	key, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	KeyPEM, _ := pem.Decode(key)
+	if KeyPEM == nil {
+		return nil, fmt.Errorf(
+			"no PEM data found in %s",
+			filePath,
+		)
+	}
+
+	privKeyParse, err := x509.ParsePKCS8PrivateKey(KeyPEM.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privKey, ok := privKeyParse.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf(
+			"%s is not an RSA private key",
+			filePath,
+		)
+	}
+	return privKey, nil
+}
+
+// Decrypt decrypts and returns the plaintext of encrypted data
+// from sealed secrets (ciphertext)
+func Decrypt(
+	privKey *rsa.PrivateKey, ciphertext []byte,
+) (
+	[]byte, error,
+) {
+
+	// Based on https://github.com/bitnami-labs/sealed-secrets/blob/master/pkg/crypto/crypto.go
+
+	// The first two bytes contain the length of the encrypted
+	// AES session key
+	if len(ciphertext) < 2 {
+		return nil, errors.New("truncated ciphertext, corrupt data")
+	}
+
+	// Get the RSA encrypted AES session key length,
+	// and then right shift the ciphertext
+	sessionKeyLen := int(binary.BigEndian.Uint16(ciphertext))
+	ciphertext = ciphertext[2:]
+
+	if len(ciphertext) < sessionKeyLen {
+		return nil, errors.New("ciphertext not long enough to hold session key, corrupt data")
+	}
+
+	// Get the RSA encrypted AES session key,
+	// then right shift the ciphertext
+	sessionKeyEncrypted := ciphertext[:sessionKeyLen]
+	ciphertext = ciphertext[sessionKeyLen:]
+
+	var label []byte // namespace-based scope not implemented, label is empty
+	rnd := rand.Reader
+
+	// Decrypt the AES session key
+	aesSessionKey, err := rsa.DecryptOAEP(
+		sha256.New(),
+		rnd,
+		privKey,
+		sessionKeyEncrypted,
+		label,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(aesSessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aed, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sealed Secrets use a zero Nonce, do the same
+	zeroNonce := make(
+		[]byte,
+		aed.NonceSize(),
+	)
+
+	plaintext, err := aed.Open(
+		nil,
+		zeroNonce,
+		ciphertext,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,233 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+/*
+Package secrets resolves secret references, values that say where a secret is kept rather than being the secret:
+
+	file:<path>                                    the contents of a file
+	env:<name>                                     an environment variable
+	cmd:<command>                                  the output of a shell command (e.g. a password manager)
+	sealed:<customizations.yaml>#<secret>/<key>    a cluster-wide sealed secret of a Shasta configuration (shasta-cfg)
+
+A reference can be written to a config file or printed as it is, a secret is only ever shown as Redacted.
+*/
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Redacted is shown in place of a secret in config files and output.
+const Redacted = "<redacted>"
+
+// The prefixes of the secret references.
+const (
+	FilePrefix   = "file:"
+	EnvPrefix    = "env:"
+	CmdPrefix    = "cmd:"
+	SealedPrefix = "sealed:"
+)
+
+// IsReference returns whether value is a secret reference rather than a secret.
+func IsReference(value string) bool {
+	for _, prefix := range []string{
+		FilePrefix,
+		EnvPrefix,
+		CmdPrefix,
+		SealedPrefix,
+	} {
+		if strings.HasPrefix(
+			value,
+			prefix,
+		) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns the value to show in place of value, a secret is Redacted while a reference or an empty value is
+// shown as it is.
+func Redact(value string) string {
+	if value == "" || IsReference(value) {
+		return value
+	}
+	return Redacted
+}
+
+// Resolver resolves secret references.
+type Resolver struct {
+	// SealedSecretKeyFile is the sealed secrets private key that sealed references are decrypted with.
+	SealedSecretKeyFile string
+}
+
+/*
+Resolve returns the secret that value references, a value that is not a reference is the secret itself. The trailing
+newline of a file or command output is removed. The errors never contain the secret, and a Redacted value is an error
+since the secret it stood for is gone.
+*/
+func (resolver Resolver) Resolve(value string) (secret string, err error) {
+	switch {
+	case value == Redacted:
+		return "", fmt.Errorf(
+			"the value is %s, give the secret again or use a secret reference (%s, %s, %s, or %s)",
+			Redacted,
+			FilePrefix,
+			EnvPrefix,
+			CmdPrefix,
+			SealedPrefix,
+		)
+	case strings.HasPrefix(
+		value,
+		FilePrefix,
+	):
+		path := strings.TrimPrefix(
+			value,
+			FilePrefix,
+		)
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf(
+				"unable to read secret file because %v",
+				err,
+			)
+		}
+		return strings.TrimRight(
+			string(contents),
+			"\r\n",
+		), nil
+	case strings.HasPrefix(
+		value,
+		EnvPrefix,
+	):
+		name := strings.TrimPrefix(
+			value,
+			EnvPrefix,
+		)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf(
+				"environment variable %s is not set",
+				name,
+			)
+		}
+		return secret, nil
+	case strings.HasPrefix(
+		value,
+		CmdPrefix,
+	):
+		command := strings.TrimPrefix(
+			value,
+			CmdPrefix,
+		)
+		cmd := exec.Command(
+			"sh",
+			"-c",
+			command,
+		)
+		cmd.Stderr = os.Stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf(
+				"command %q failed because %v",
+				command,
+				err,
+			)
+		}
+		return strings.TrimRight(
+			string(output),
+			"\r\n",
+		), nil
+	case strings.HasPrefix(
+		value,
+		SealedPrefix,
+	):
+		return resolver.resolveSealed(
+			strings.TrimPrefix(
+				value,
+				SealedPrefix,
+			),
+		)
+	}
+	return value, nil
+}
+
+// resolveSealed decrypts the data of a sealed secret given as <customizations.yaml>#<secret>/<key>.
+func (resolver Resolver) resolveSealed(reference string) (secret string, err error) {
+	path, selector, found := strings.Cut(
+		reference,
+		"#",
+	)
+	secretName, dataName, hasData := strings.Cut(
+		selector,
+		"/",
+	)
+	if !found || !hasData || path == "" || secretName == "" || dataName == "" {
+		return "", fmt.Errorf(
+			"sealed secret reference %s is not of the form %s<customizations.yaml>#<secret>/<key>",
+			reference,
+			SealedPrefix,
+		)
+	}
+	if resolver.SealedSecretKeyFile == "" {
+		return "", fmt.Errorf(
+			"a sealed secrets private key is needed to decrypt %s",
+			reference,
+		)
+	}
+	ciphertext, err := LoadEncryptedData(
+		path,
+		secretName,
+		dataName,
+	)
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to load %s because %v",
+			reference,
+			err,
+		)
+	}
+	privKey, err := LoadPrivateKey(resolver.SealedSecretKeyFile)
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to load sealed secret private key because %v",
+			err,
+		)
+	}
+	plaintext, err := Decrypt(
+		privKey,
+		ciphertext,
+	)
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to decrypt %s because %v",
+			reference,
+			err,
+		)
+	}
+	return string(plaintext), nil
+}
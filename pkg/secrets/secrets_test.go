@@ -0,0 +1,253 @@
+/*
+ MIT License
+
+ (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+
+ Permission is hereby granted, free of charge, to any person obtaining a
+ copy of this software and associated documentation files (the "Software"),
+ to deal in the Software without restriction, including without limitation
+ the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ and/or sell copies of the Software, and to permit persons to whom the
+ Software is furnished to do so, subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included
+ in all copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+ OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+ OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const customizationsYAML = `spec:
+  kubernetes:
+    sealed_secrets:
+      cray_reds_credentials:
+        spec:
+          encryptedData:
+            bmc_password: %s
+          template:
+            metadata:
+              annotations:
+                sealedsecrets.bitnami.com/cluster-wide: "true"
+`
+
+type SecretsTestSuite struct {
+	suite.Suite
+}
+
+// writeFile writes contents to a file in a temporary directory and returns its path.
+func (suite *SecretsTestSuite) writeFile(name string, contents []byte) string {
+	path := filepath.Join(
+		suite.T().TempDir(),
+		name,
+	)
+	suite.Require().NoError(
+		os.WriteFile(
+			path,
+			contents,
+			0600,
+		),
+	)
+	return path
+}
+
+// seal encrypts plaintext the way kubeseal does for a cluster-wide sealed secret.
+func (suite *SecretsTestSuite) seal(pubKey *rsa.PublicKey, plaintext []byte) []byte {
+	sessionKey := make(
+		[]byte,
+		32,
+	)
+	_, err := rand.Read(sessionKey)
+	suite.Require().NoError(err)
+	sessionKeyEncrypted, err := rsa.EncryptOAEP(
+		sha256.New(),
+		rand.Reader,
+		pubKey,
+		sessionKey,
+		nil,
+	)
+	suite.Require().NoError(err)
+	block, err := aes.NewCipher(sessionKey)
+	suite.Require().NoError(err)
+	aed, err := cipher.NewGCM(block)
+	suite.Require().NoError(err)
+
+	ciphertext := binary.BigEndian.AppendUint16(
+		nil,
+		uint16(len(sessionKeyEncrypted)),
+	)
+	ciphertext = append(
+		ciphertext,
+		sessionKeyEncrypted...,
+	)
+	return aed.Seal(
+		ciphertext,
+		make(
+			[]byte,
+			aed.NonceSize(),
+		),
+		plaintext,
+		nil,
+	)
+}
+
+func (suite *SecretsTestSuite) TestResolve() {
+	resolver := Resolver{}
+	path := suite.writeFile(
+		"bmc-pass",
+		[]byte("from-file\n"),
+	)
+	suite.T().Setenv(
+		"CSI_TEST_BMC_PASS",
+		"from-env",
+	)
+
+	for value, expected := range map[string]string{
+		"changeme":                      "changeme",
+		"":                              "",
+		FilePrefix + path:               "from-file",
+		EnvPrefix + "CSI_TEST_BMC_PASS": "from-env",
+		CmdPrefix + "echo from-cmd":     "from-cmd",
+	} {
+		secret, err := resolver.Resolve(value)
+		suite.Require().NoError(
+			err,
+			value,
+		)
+		suite.Equal(
+			expected,
+			secret,
+			value,
+		)
+	}
+}
+
+func (suite *SecretsTestSuite) TestResolve_Errors() {
+	resolver := Resolver{}
+	for value, expected := range map[string]string{
+		Redacted: "give the secret again or use a secret reference",
+		FilePrefix + filepath.Join(
+			suite.T().TempDir(),
+			"missing",
+		): "unable to read secret file",
+		EnvPrefix + "CSI_TEST_UNSET":                    "environment variable CSI_TEST_UNSET is not set",
+		CmdPrefix + "echo leaked; exit 3":               `command "echo leaked; exit 3" failed`,
+		SealedPrefix + "customizations.yaml":            "is not of the form",
+		SealedPrefix + "customizations.yaml#secret/key": "a sealed secrets private key is needed",
+	} {
+		secret, err := resolver.Resolve(value)
+		suite.ErrorContains(
+			err,
+			expected,
+			value,
+		)
+		suite.Empty(secret)
+		// The output of a failed command is never part of the error.
+		suite.NotContains(
+			err.Error(),
+			"leaked\n",
+		)
+	}
+}
+
+func (suite *SecretsTestSuite) TestResolve_Sealed() {
+	privKey, err := rsa.GenerateKey(
+		rand.Reader,
+		2048,
+	)
+	suite.Require().NoError(err)
+	der, err := x509.MarshalPKCS8PrivateKey(privKey)
+	suite.Require().NoError(err)
+	keyFile := suite.writeFile(
+		"sealed_secrets.key",
+		pem.EncodeToMemory(
+			&pem.Block{
+				Type:  "PRIVATE KEY",
+				Bytes: der,
+			},
+		),
+	)
+	customizations := suite.writeFile(
+		"customizations.yaml",
+		fmt.Appendf(
+			nil,
+			customizationsYAML,
+			base64.StdEncoding.EncodeToString(
+				suite.seal(
+					&privKey.PublicKey,
+					[]byte("from-sealed"),
+				),
+			),
+		),
+	)
+
+	resolver := Resolver{
+		SealedSecretKeyFile: keyFile,
+	}
+	secret, err := resolver.Resolve(SealedPrefix + customizations + "#cray_reds_credentials/bmc_password")
+	suite.Require().NoError(err)
+	suite.Equal(
+		"from-sealed",
+		secret,
+	)
+
+	_, err = resolver.Resolve(SealedPrefix + customizations + "#cray_reds_credentials/ipmi_password")
+	suite.ErrorContains(
+		err,
+		"sealed secret or data attribute does not exist",
+	)
+
+	resolver.SealedSecretKeyFile = customizations
+	_, err = resolver.Resolve(SealedPrefix + customizations + "#cray_reds_credentials/bmc_password")
+	suite.ErrorContains(
+		err,
+		"no PEM data found",
+	)
+}
+
+func (suite *SecretsTestSuite) TestRedact() {
+	for value, expected := range map[string]string{
+		"changeme":            Redacted,
+		"":                    "",
+		Redacted:              Redacted,
+		"env:BMC_PASS":        "env:BMC_PASS",
+		"file:/root/bmc-pass": "file:/root/bmc-pass",
+	} {
+		suite.Equal(
+			expected,
+			Redact(value),
+			value,
+		)
+	}
+}
+
+func TestSecretsTestSuite(t *testing.T) {
+	suite.Run(
+		t,
+		new(SecretsTestSuite),
+	)
+}